@@ -0,0 +1,108 @@
+// Command admin manages admin accounts from the server's own configuration,
+// for operators with database access.
+//
+//	admin bootstrap -name NAME -email EMAIL   create the first owner (once per install)
+//	admin create -name NAME -email EMAIL [-role ROLE]
+//
+// The password is read from LOGI_ADMIN_PASSWORD, or else from the first line
+// of standard input.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"logi/internal/mail"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/services"
+	"logi/internal/utils"
+	"logi/pkg/auth"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	name := flags.String("name", "", "admin name")
+	email := flags.String("email", "", "admin email")
+	role := flags.String("role", models.AdminRoleAdmin, "admin role, e.g. owner, admin or dispatcher (create only)")
+	configPath := flags.String("config", "configs/config.yaml", "path to the server config")
+	flags.Parse(os.Args[2:])
+
+	config, err := utils.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	utils.SetDBOperationTimeout(time.Duration(config.DBOperationTimeoutSeconds) * time.Second)
+	dbClient, err := utils.ConnectDB(config.MongoURI)
+	if err != nil {
+		log.Fatalf("failed to connect to mongodb: %v", err)
+	}
+	defer utils.DisconnectDB(dbClient)
+
+	password, err := readPassword()
+	if err != nil {
+		log.Fatalf("failed to read password: %v", err)
+	}
+
+	adminRepo := repositories.NewAdminRepository(dbClient)
+	roleService := services.NewRoleService(repositories.NewRoleRepository(dbClient), adminRepo)
+	ctx := context.Background()
+	if err := roleService.SeedDefaultRoles(ctx); err != nil {
+		log.Fatalf("failed to seed default roles: %v", err)
+	}
+
+	// Only password hashing is needed here, which uses no keys.
+	service := services.NewAdminAccountService(
+		adminRepo,
+		repositories.NewAdminInviteRepository(dbClient),
+		roleService,
+		&auth.AuthService{},
+		nil,
+		mail.LogMailer{},
+		"",
+		0,
+		"",
+	)
+
+	admin := &models.Admin{Name: *name, Email: *email}
+	switch command {
+	case "bootstrap":
+		err = service.Bootstrap(ctx, admin, password)
+	case "create":
+		admin.Role = *role
+		err = service.Provision(ctx, admin, password)
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatalf("%s failed: %v", command, err)
+	}
+	fmt.Printf("created %s %s (%s)\n", admin.Role, admin.Email, admin.ID)
+}
+
+func readPassword() (string, error) {
+	if password := os.Getenv("LOGI_ADMIN_PASSWORD"); password != "" {
+		return password, nil
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: admin bootstrap|create -name NAME -email EMAIL [-role ROLE] [-config PATH]")
+	os.Exit(2)
+}
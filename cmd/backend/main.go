@@ -9,17 +9,25 @@ import (
 	"time"
 
 	"logi/internal/api"
+	"logi/internal/events"
 	"logi/internal/handlers"
+	"logi/internal/mail"
 	"logi/internal/messaging"
+	"logi/internal/outbox"
+	"logi/internal/ratelimit"
 	"logi/internal/repositories"
 	"logi/internal/services"
 	"logi/internal/services/distance"
+	"logi/internal/services/geocoding"
+	"logi/internal/sms"
 	"logi/internal/utils"
+	"logi/internal/webhooks"
 	"logi/pkg/auth"
 	"logi/pkg/scheduler"
 	"logi/pkg/websocket"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
 )
 
 func main() {
@@ -41,62 +49,320 @@ func main() {
 	}
 	defer utils.DisconnectDB(dbClient)
 
-	authService := auth.NewAuthService(config.JWTSecret, config.JWTExpirationHours)
+	authService, err := newAuthService(config)
+	if err != nil {
+		utils.Fatal("failed to configure jwt signing", "error", err)
+	}
+	tokenService := services.NewTokenService(
+		authService,
+		repositories.NewRefreshTokenRepository(dbClient),
+		repositories.NewRevokedTokenRepository(dbClient),
+		time.Duration(config.RefreshTokenTTLHours)*time.Hour,
+	)
+	authService.Revocations = tokenService
 
-	wsHub := websocket.NewWebSocketHub()
+	var wsOutbox websocket.Outbox
+	switch config.WebSocketOutboxType {
+	case "memory":
+		wsOutbox = websocket.NewMemoryOutbox(config.WebSocketOutboxSize)
+	case "mongo":
+		wsOutbox = messaging.NewMongoOutbox(repositories.NewOutboxRepository(dbClient), config.WebSocketOutboxSize)
+	}
+
+	wsHub := websocket.NewWebSocketHubWithConfig(websocket.HubConfig{
+		SendQueueSize:        config.WebSocketSendQueueSize,
+		WriteTimeout:         time.Duration(config.WebSocketWriteTimeoutSeconds) * time.Second,
+		SlowConsumerPolicy:   config.WebSocketSlowConsumerPolicy,
+		PingInterval:         time.Duration(config.WebSocketPingIntervalSeconds) * time.Second,
+		PongWait:             time.Duration(config.WebSocketPongWaitSeconds) * time.Second,
+		MaxMessageSize:       int64(config.WebSocketMaxMessageBytes),
+		Outbox:               wsOutbox,
+		EphemeralTypes:       messaging.EphemeralTypes,
+		SessionCheckInterval: time.Duration(config.WebSocketSessionCheckSeconds) * time.Second,
+	})
 	go wsHub.Run()
 
 	var messagingClient messaging.MessagingClient
-	if config.MessagingType == "nats" {
+	// Sequence at the publisher so every instance relays the same seq.
+	var publisherOutbox websocket.Outbox
+	if config.WebSocketOutboxType == "mongo" {
+		publisherOutbox = wsOutbox
+	}
+	switch config.MessagingType {
+	case "nats":
 		natsClient, err := messaging.NewNATSClient(config.NATSURL)
 		if err != nil {
 			utils.Fatal("failed to connect to nats", "error", err)
 		}
 		defer natsClient.Conn.Close()
-		subscription, err := natsClient.SubscribeAll(func(message messaging.Message) error {
-			return wsHub.Broadcast(websocket.WebSocketMessage{
-				UserID:  message.UserID,
-				Type:    message.Type,
-				Payload: message.Payload,
-			})
-		})
+		natsClient.Outbox = publisherOutbox
+		natsRouter := messaging.NewNATSRouter(natsClient.Conn, wsHub)
+		natsRouter.Start()
+		defer natsRouter.Stop()
+		messagingClient = natsClient
+	case "kafka":
+		kafkaClient, err := messaging.NewKafkaClient(config.KafkaBrokers, config.KafkaTopic)
 		if err != nil {
-			utils.Fatal("failed to subscribe to nats", "error", err)
+			utils.Fatal("failed to connect to kafka", "error", err)
 		}
-		defer subscription.Unsubscribe()
-		messagingClient = natsClient
-	} else {
+		defer kafkaClient.Client.Close()
+		kafkaClient.Outbox = publisherOutbox
+		kafkaRouter, err := messaging.NewKafkaRouter(config.KafkaBrokers, config.KafkaTopic, wsHub)
+		if err != nil {
+			utils.Fatal("failed to create kafka consumer", "error", err)
+		}
+		kafkaRouter.Start()
+		defer kafkaRouter.Stop()
+		messagingClient = kafkaClient
+	case "redis":
+		redisClient, err := messaging.NewRedisStreamsClient(config.RedisURL, config.RedisStream, int64(config.RedisStreamMaxLen))
+		if err != nil {
+			utils.Fatal("failed to connect to redis", "error", err)
+		}
+		defer redisClient.Client.Close()
+		redisClient.Outbox = publisherOutbox
+		redisRouter := messaging.NewRedisStreamsRouter(redisClient.Client, config.RedisStream, wsHub)
+		redisRouter.Start()
+		defer redisRouter.Stop()
+		messagingClient = redisClient
+	default:
 		messagingClient = messaging.NewWebSocketClient(wsHub)
 	}
 
+	var eventPublisher events.Publisher
+	if config.EventStreamType == "jetstream" {
+		eventConn, err := nats.Connect(config.NATSURL)
+		if err != nil {
+			utils.Fatal("failed to connect to nats for events", "error", err)
+		}
+		defer eventConn.Close()
+		eventBus, err := events.NewJetStreamBus(context.Background(), eventConn, events.JetStreamConfig{
+			Stream:          config.EventStreamName,
+			DuplicateWindow: time.Duration(config.EventStreamDedupWindowSeconds) * time.Second,
+			MaxAge:          time.Duration(config.EventStreamMaxAgeHours) * time.Hour,
+		})
+		if err != nil {
+			utils.Fatal("failed to set up event stream", "error", err)
+		}
+		eventPublisher = eventBus
+	}
+
 	userRepo := repositories.NewUserRepository(dbClient)
+
+	// Booking events also go to the webhooks registered for the booking's
+	// user or organization.
+	var webhookService *services.WebhookService
+	if config.WebhooksEnabled {
+		webhookEndpointRepo := repositories.NewWebhookEndpointRepository(dbClient)
+		webhookDeliveryRepo := repositories.NewWebhookDeliveryRepository(dbClient, time.Duration(config.WebhookDeliveryRetentionHours)*time.Hour)
+		webhookDeliverer := webhooks.NewDeliverer(webhookDeliveryRepo, webhookEndpointRepo, webhooks.DelivererConfig{
+			Timeout:              time.Duration(config.WebhookTimeoutSeconds) * time.Second,
+			MaxAttempts:          config.WebhookMaxAttempts,
+			RetryDelay:           time.Duration(config.WebhookRetryDelaySeconds) * time.Second,
+			AllowPrivateNetworks: config.WebhookAllowPrivateNetworks,
+		})
+		dispatcher := webhooks.NewDispatcher(webhookEndpointRepo, webhookDeliveryRepo, userRepo, webhookDeliverer.Wake)
+		if eventPublisher != nil {
+			eventPublisher = events.Publishers{eventPublisher, dispatcher}
+		} else {
+			eventPublisher = dispatcher
+		}
+		webhookService = services.NewWebhookService(webhookEndpointRepo, webhookDeliveryRepo, config.Environment != "development", webhookDeliverer.Wake)
+		webhookService.AllowPrivateNetworks = config.WebhookAllowPrivateNetworks
+
+		delivererCtx, stopDeliverer := context.WithCancel(context.Background())
+		defer stopDeliverer()
+		go webhookDeliverer.Run(delivererCtx)
+	}
+
+	// Notifications and events are stored with the state change and
+	// published by the relay, so a crash in between cannot lose them.
+	var outboxWriter *outbox.Writer
+	if config.EventOutboxEnabled {
+		eventOutboxRepo := repositories.NewEventOutboxRepository(dbClient, time.Duration(config.EventOutboxRetentionHours)*time.Hour)
+		outboxRelay := outbox.NewRelay(eventOutboxRepo, messagingClient, eventPublisher, outbox.RelayConfig{
+			PollInterval: time.Duration(config.EventOutboxPollIntervalSeconds) * time.Second,
+			MaxAttempts:  config.EventOutboxMaxAttempts,
+		})
+		outboxWriter = outbox.NewWriter(eventOutboxRepo, repositories.NewMongoTransactor(dbClient), outboxRelay.Wake)
+
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		defer stopRelay()
+		go outboxRelay.Run(relayCtx)
+	}
+
 	bookingRepo := repositories.NewBookingRepository(dbClient)
 	driverRepo := repositories.NewDriverRepository(dbClient)
 	adminRepo := repositories.NewAdminRepository(dbClient)
 	vehicleRepo := repositories.NewVehicleRepository(dbClient)
+	speedProfileRepo := repositories.NewSpeedProfileRepository(dbClient)
+
+	haversineCalc := distance.NewHaversineCalculatorWithDetour(config.HaversineDetourFactor)
+	speedCalibrationService := services.NewSpeedCalibrationService(
+		bookingRepo,
+		speedProfileRepo,
+		haversineCalc,
+		config.SpeedProfile,
+		time.Duration(config.SpeedCalibrationDays)*24*time.Hour,
+		config.SpeedCalibrationMinTrips,
+	)
+	if err := speedCalibrationService.LoadActiveProfile(context.Background()); err != nil {
+		utils.ErrorBackground("failed to load speed profile, using configured defaults", "error", err)
+	}
 
 	var distanceCalc distance.DistanceCalculator
-	switch config.DistanceCalculatorType {
-	case "google_maps":
-		distanceCalc = distance.NewGoogleMapsCalculator(config.GoogleMapsAPIKey)
-	default:
-		distanceCalc = distance.NewHaversineCalculator()
+	if len(config.DistanceFallbackChain) > 0 {
+		backends := make([]distance.NamedCalculator, 0, len(config.DistanceFallbackChain))
+		for _, name := range config.DistanceFallbackChain {
+			backends = append(backends, distance.NamedCalculator{Name: name, Calculator: newDistanceCalculator(name, config, haversineCalc)})
+		}
+		distanceCalc = distance.NewFallbackCalculator(backends...)
+	} else {
+		distanceCalc = newDistanceCalculator(config.DistanceCalculatorType, config, haversineCalc)
+	}
+	if config.DistanceCacheEnabled {
+		distanceCalc = distance.NewCachingCalculator(
+			distanceCalc,
+			config.DistanceCacheMaxEntries,
+			time.Duration(config.DistanceCacheTTLSeconds)*time.Second,
+			config.DistanceCachePrecision,
+		)
+	}
+
+	var geocoder geocoding.Geocoder
+	if config.GeocoderType == "nominatim" {
+		geocoder = geocoding.NewNominatimGeocoder(config.NominatimBaseURL, config.NominatimUserAgent, config.NominatimEmail)
 	}
 
 	pricingService := services.NewPricingService(bookingRepo, driverRepo, distanceCalc)
 	userService := services.NewUserService(userRepo, bookingRepo, driverRepo, authService)
-	bookingService := services.NewBookingService(bookingRepo, driverRepo, pricingService, messagingClient)
-	driverService := services.NewDriverService(driverRepo, bookingRepo, userRepo, *bookingService, authService, messagingClient)
+	bookingService := services.NewBookingService(bookingRepo, driverRepo, pricingService, messagingClient, geocoder, distanceCalc, eventPublisher, outboxWriter)
+	driverService := services.NewDriverService(driverRepo, bookingRepo, userRepo, *bookingService, authService, messagingClient, eventPublisher, outboxWriter)
 	adminService := services.NewAdminService(adminRepo, authService, userRepo, driverRepo, bookingRepo, vehicleRepo)
 	vehicleService := services.NewVehicleService(vehicleRepo)
 
-	userHandler := handlers.NewUserHandler(userService, authService)
+	loginThrottle := services.NewLoginThrottle(
+		repositories.NewLoginAttemptRepository(dbClient),
+		repositories.NewAuditLogRepository(dbClient),
+		config.LoginMaxAccountFailures,
+		config.LoginMaxIPFailures,
+		time.Duration(config.LoginFailureWindowMinutes)*time.Minute,
+		time.Duration(config.LoginLockoutMinutes)*time.Minute,
+		time.Duration(config.LoginBaseDelaySeconds)*time.Second,
+	)
+	userService.Throttle = loginThrottle
+	driverService.Throttle = loginThrottle
+	adminService.Throttle = loginThrottle
+
+	roleService := services.NewRoleService(repositories.NewRoleRepository(dbClient), adminRepo)
+	if err := roleService.SeedDefaultRoles(context.Background()); err != nil {
+		utils.ErrorBackground("failed to seed default admin roles", "error", err)
+	}
+
+	var mailer mail.Mailer = mail.LogMailer{}
+	if config.MailerType == "smtp" {
+		mailer = mail.NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	}
+	adminAccountService := services.NewAdminAccountService(
+		adminRepo,
+		repositories.NewAdminInviteRepository(dbClient),
+		roleService,
+		authService,
+		tokenService,
+		mailer,
+		config.AdminInviteURL,
+		time.Duration(config.AdminInviteTTLHours)*time.Hour,
+		config.AdminBootstrapToken,
+	)
+
+	accountTokenSecret := []byte(config.AccountTokenSecret)
+	if config.AccountTokenSecret == "" {
+		accountTokenSecret = services.DeriveAccountTokenSecret(config.JWTSecret)
+	}
+	accountService := services.NewAccountService(
+		userRepo,
+		driverRepo,
+		authService,
+		tokenService,
+		mailer,
+		accountTokenSecret,
+		config.EmailVerifyURL,
+		config.PasswordResetURL,
+		time.Duration(config.EmailVerificationTTLHours)*time.Hour,
+		time.Duration(config.PasswordResetTTLMinutes)*time.Minute,
+	)
+	userService.Accounts = accountService
+	userService.RequireVerifiedEmail = config.RequireVerifiedEmail
+	driverService.Accounts = accountService
+	driverService.RequireVerifiedEmail = config.RequireVerifiedEmail
+
+	// log is the only sender so far; others implement sms.SMSSender.
+	if config.PhoneLoginEnabled() {
+		var smsSender sms.SMSSender = sms.LogSender{}
+		driverService.OTP = services.NewOTPService(
+			repositories.NewOTPRepository(dbClient),
+			smsSender,
+			accountTokenSecret,
+			config.OTPCodeLength,
+			time.Duration(config.OTPTTLMinutes)*time.Minute,
+			config.OTPMaxAttempts,
+			time.Duration(config.OTPResendSeconds)*time.Second,
+			time.Duration(config.OTPAttemptWindowMinutes)*time.Minute,
+		)
+		driverService.PhoneCountryCode = config.PhoneDefaultCountryCode
+	} else {
+		utils.WarnBackground("driver phone login is disabled: production needs an sms sender other than log")
+	}
+
+	userHandler := handlers.NewUserHandler(userService, tokenService)
 	bookingHandler := handlers.NewBookingHandler(bookingService)
-	driverHandler := handlers.NewDriverHandler(driverService, authService)
-	adminHandler := handlers.NewAdminHandler(adminService, authService, userService, driverService, bookingService, vehicleService)
+	driverHandler := handlers.NewDriverHandler(driverService, tokenService)
+	adminHandler := handlers.NewAdminHandler(adminService, tokenService, userService, driverService, bookingService, vehicleService, speedCalibrationService)
+	adminAccountHandler := handlers.NewAdminAccountHandler(adminAccountService)
+	roleHandler := handlers.NewRoleHandler(roleService)
+	lockoutHandler := handlers.NewLockoutHandler(loginThrottle)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	wsCommandHandler := handlers.NewWebSocketCommandHandler(driverService, wsHub)
 	testHandler := handlers.NewTestHandler(messagingClient)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+
+	authHandler := handlers.NewAuthHandler(tokenService)
 
-	router := api.SetupRouter(userHandler, bookingHandler, driverHandler, adminHandler, authService, wsHub, testHandler, config)
+	var limiter *ratelimit.Limiter
+	if config.RateLimitEnabled {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if config.RateLimitStore == "redis" {
+			redisStore, err := ratelimit.NewRedisStore(config.RedisURL)
+			if err != nil {
+				utils.Fatal("failed to connect to redis for rate limits", "error", err)
+			}
+			defer redisStore.Client.Close()
+			store = redisStore
+		}
+		limiter, err = ratelimit.NewLimiter(store, config.RateLimits)
+		if err != nil {
+			utils.Fatal("invalid rate limits", "error", err)
+		}
+	}
+	router := api.SetupRouter(api.Dependencies{
+		UserHandler:             userHandler,
+		BookingHandler:          bookingHandler,
+		DriverHandler:           driverHandler,
+		AdminHandler:            adminHandler,
+		AdminAccountHandler:     adminAccountHandler,
+		RoleHandler:             roleHandler,
+		LockoutHandler:          lockoutHandler,
+		AccountHandler:          accountHandler,
+		AuthHandler:             authHandler,
+		AuthService:             authService,
+		Permissions:             roleService,
+		Limiter:                 limiter,
+		WebSocketHub:            wsHub,
+		WebSocketCommandHandler: wsCommandHandler,
+		TestHandler:             testHandler,
+		WebhookHandler:          webhookHandler,
+		Config:                  config,
+	})
 
 	bookingScheduler := scheduler.StartScheduler(bookingService)
 
@@ -131,3 +397,41 @@ func main() {
 
 	utils.InfoBackground("server stopped gracefully")
 }
+
+func newDistanceCalculator(name string, config *utils.Config, haversineCalc *distance.HaversineCalculator) distance.DistanceCalculator {
+	switch name {
+	case "google_maps":
+		return distance.NewGoogleMapsCalculator(config.GoogleMapsAPIKey)
+	case "osrm":
+		return distance.NewOSRMCalculator(config.OSRMBaseURL, config.RoutingProfiles)
+	case "graphhopper":
+		return distance.NewGraphHopperCalculator(config.GraphHopperBaseURL, config.GraphHopperAPIKey, config.RoutingProfiles)
+	default:
+		return haversineCalc
+	}
+}
+
+// newAuthService loads the JWT keys in config. jwt_secret is only passed on
+// while HS256 tokens are signed or accepted.
+func newAuthService(config *utils.Config) (*auth.AuthService, error) {
+	authConfig := auth.Config{
+		AllowHS256:     config.JWTAllowHS256,
+		SigningKeyID:   config.JWTSigningKeyID,
+		AccessTokenTTL: time.Duration(config.JWTAccessTokenTTLMinutes) * time.Minute,
+	}
+	if config.UsesHS256() {
+		authConfig.HS256Secret = config.JWTSecret
+	}
+	for kid, path := range config.JWTKeyFiles {
+		key, err := auth.LoadKeyFile(kid, path)
+		if err != nil {
+			return nil, err
+		}
+		if until, ok := config.JWTKeyVerifyUntil[kid]; ok {
+			// Already validated by LoadConfig.
+			key.VerifyUntil, _ = time.Parse(time.RFC3339, until)
+		}
+		authConfig.Keys = append(authConfig.Keys, key)
+	}
+	return auth.NewAuthServiceWithConfig(authConfig)
+}
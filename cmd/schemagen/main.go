@@ -0,0 +1,31 @@
+// Command schemagen writes the JSON Schema of every websocket message payload
+// in messaging.Registry, one file per message type.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"logi/internal/messaging"
+)
+
+func main() {
+	out := flag.String("out", "schemas/messages", "directory to write the schemas to")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatalf("failed to create %s: %v", *out, err)
+	}
+	for _, messageType := range messaging.Registry {
+		schema, err := messaging.GenerateSchema(messageType)
+		if err != nil {
+			log.Fatalf("failed to generate schema for %s: %v", messageType.Name, err)
+		}
+		path := filepath.Join(*out, messaging.SchemaFileName(messageType.Name))
+		if err := os.WriteFile(path, schema, 0o644); err != nil {
+			log.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+}
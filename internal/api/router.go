@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"logi/internal/handlers"
+	"logi/internal/models"
+	"logi/internal/ratelimit"
 	"logi/internal/utils"
 	"logi/pkg/auth"
 	"logi/pkg/websocket"
@@ -39,21 +41,40 @@ func corsMiddleware(cfg *utils.Config) gin.HandlerFunc {
 	return cors.New(config)
 }
 
-func SetupRouter(
-	userHandler *handlers.UserHandler,
-	bookingHandler *handlers.BookingHandler,
-	driverHandler *handlers.DriverHandler,
-	adminHandler *handlers.AdminHandler,
-	authService *auth.AuthService,
-	wsHub *websocket.WebSocketHub,
-	testHandler *handlers.TestHandler,
-	cfg *utils.Config,
-) *gin.Engine {
+// Dependencies are the handlers and services SetupRouter wires into the
+// routes.
+type Dependencies struct {
+	UserHandler             *handlers.UserHandler
+	BookingHandler          *handlers.BookingHandler
+	DriverHandler           *handlers.DriverHandler
+	AdminHandler            *handlers.AdminHandler
+	AdminAccountHandler     *handlers.AdminAccountHandler
+	RoleHandler             *handlers.RoleHandler
+	LockoutHandler          *handlers.LockoutHandler
+	AccountHandler          *handlers.AccountHandler
+	AuthHandler             *handlers.AuthHandler
+	AuthService             *auth.AuthService
+	Permissions             utils.PermissionChecker
+	Limiter                 *ratelimit.Limiter
+	WebSocketHub            *websocket.WebSocketHub
+	WebSocketCommandHandler *handlers.WebSocketCommandHandler
+	TestHandler             *handlers.TestHandler
+	WebhookHandler          *handlers.WebhookHandler
+	Config                  *utils.Config
+}
+
+func SetupRouter(deps Dependencies) *gin.Engine {
 	router := gin.New()
+	// Only the configured proxies may set the client IP through
+	// X-Forwarded-For; login throttling keys on it.
+	if err := router.SetTrustedProxies(deps.Config.TrustedProxies); err != nil {
+		utils.Fatal("invalid trusted proxies", "error", err)
+	}
 	router.Use(utils.RequestIDMiddleware())
+	router.Use(utils.ClientIPMiddleware())
 	router.Use(utils.RequestLoggingMiddleware())
 	router.Use(utils.RecoveryMiddleware())
-	router.Use(corsMiddleware(cfg))
+	router.Use(corsMiddleware(deps.Config))
 
 	router.GET("/healthz", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
@@ -63,58 +84,128 @@ func SetupRouter(
 		c.JSON(http.StatusOK, gin.H{"status": "ready"})
 	})
 
+	// Every route below is rate limited by its policy in rate_limits. The
+	// limit runs after authentication, so policies can key on the caller;
+	// authenticated routes are also limited by IP before the token is checked.
+	limit := ratelimit.Middleware(deps.Limiter)
+	authLimit := ratelimit.AuthMiddleware(deps.Limiter)
+
 	// Public routes
-	router.POST("/users/register", userHandler.Register)
-	router.POST("/users/login", userHandler.Login)
-	router.POST("/drivers/register", driverHandler.Register)
-	router.POST("/drivers/login", driverHandler.Login)
-	router.POST("/admins/register", adminHandler.Register)
-	router.POST("/admins/login", adminHandler.Login)
-
-	router.GET("/ws", func(c *gin.Context) {
-		handlers.ServeWs(authService, wsHub, cfg.AllowedOriginsSet(), c)
+	public := router.Group("/", limit)
+	public.POST("/users/register", deps.UserHandler.Register)
+	public.POST("/users/login", deps.UserHandler.Login)
+	public.POST("/drivers/register", deps.DriverHandler.Register)
+	public.POST("/drivers/login", deps.DriverHandler.Login)
+	if deps.Config.PhoneLoginEnabled() {
+		public.POST("/drivers/otp/send", deps.DriverHandler.SendLoginCode)
+		public.POST("/drivers/otp/register", deps.DriverHandler.RegisterWithCode)
+		public.POST("/drivers/otp/login", deps.DriverHandler.LoginWithCode)
+	}
+	public.POST("/admins/login", deps.AdminHandler.Login)
+	public.POST("/admins/invites/accept", deps.AdminAccountHandler.AcceptInvite)
+	if deps.Config.AdminBootstrapToken != "" {
+		public.POST("/admins/bootstrap", deps.AdminAccountHandler.Bootstrap)
+	}
+	public.POST("/auth/refresh", deps.AuthHandler.Refresh)
+	public.POST("/auth/verify", deps.AccountHandler.Verify)
+	public.POST("/auth/verify/resend", deps.AccountHandler.ResendVerification)
+	public.POST("/auth/forgot-password", deps.AccountHandler.ForgotPassword)
+	public.POST("/auth/reset-password", deps.AccountHandler.ResetPassword)
+	router.POST("/auth/logout", authLimit, utils.JWTAuthMiddleware(deps.AuthService), limit, deps.AuthHandler.Logout)
+	public.GET("/.well-known/jwks.json", deps.AuthHandler.JWKS)
+
+	public.GET("/ws", func(c *gin.Context) {
+		handlers.ServeWs(deps.AuthService, deps.WebSocketHub, deps.WebSocketCommandHandler, deps.Config.AllowedOriginsSet(), c)
 	})
 
-	if cfg.EnableTestRoutes {
-		router.GET("/test", utils.JWTAuthMiddleware(authService, "admin"), testHandler.PublishTestMessages)
+	if deps.Config.EnableTestRoutes {
+		router.GET("/test", authLimit, utils.JWTAuthMiddleware(deps.AuthService, "admin"), limit, deps.TestHandler.PublishTestMessages)
 	}
 
 	// Protected routes with JWT middleware
-	userProtected := router.Group("/", utils.JWTAuthMiddleware(authService, "user"))
+	userProtected := router.Group("/", authLimit, utils.JWTAuthMiddleware(deps.AuthService, "user"), limit)
 	{
-		userProtected.GET("/active-booking", userHandler.GetActiveBooking)
-		userProtected.GET("/bookings/:bookingID/driver", userHandler.GetDriverForBooking)
-		userProtected.POST("/bookings", bookingHandler.CreateBooking)
-		userProtected.POST("/bookings/estimate", bookingHandler.GetPriceEstimate)
+		userProtected.GET("/active-booking", deps.UserHandler.GetActiveBooking)
+		userProtected.GET("/bookings/:bookingID/driver", deps.UserHandler.GetDriverForBooking)
+		userProtected.POST("/bookings", deps.BookingHandler.CreateBooking)
+		userProtected.POST("/bookings/estimate", deps.BookingHandler.GetPriceEstimate)
+
+		if deps.Config.WebhooksEnabled {
+			userProtected.POST("/webhooks", deps.WebhookHandler.CreateWebhook)
+			userProtected.GET("/webhooks", deps.WebhookHandler.GetWebhooks)
+			userProtected.DELETE("/webhooks/:webhookID", deps.WebhookHandler.DeleteWebhook)
+			userProtected.GET("/webhooks/:webhookID/deliveries", deps.WebhookHandler.GetWebhookDeliveries)
+		}
 	}
 
-	driverProtected := router.Group("/drivers", utils.JWTAuthMiddleware(authService, "driver"))
+	driverProtected := router.Group("/drivers", authLimit, utils.JWTAuthMiddleware(deps.AuthService, "driver"), limit)
 	{
-		driverProtected.GET("/active-bookings", driverHandler.GetActiveBookings)
-		driverProtected.GET("/bookings/:bookingID/user", driverHandler.GetUserForBooking)
-		driverProtected.GET("/bookings/:bookingID", driverHandler.GetBooking)
-		driverProtected.GET("/me", driverHandler.GetDriverInfo)
-		driverProtected.POST("/status", driverHandler.UpdateStatus)
-		driverProtected.POST("/booking-status", driverHandler.UpdateBookingStatus)
-		driverProtected.POST("/update-location", driverHandler.UpdateLocation)
-		driverProtected.GET("/pending-bookings", driverHandler.GetPendingBookings)
-		driverProtected.POST("/respond-booking", driverHandler.RespondToBooking)
+		driverProtected.GET("/active-bookings", deps.DriverHandler.GetActiveBookings)
+		driverProtected.GET("/bookings/:bookingID/user", deps.DriverHandler.GetUserForBooking)
+		driverProtected.GET("/bookings/:bookingID", deps.DriverHandler.GetBooking)
+		driverProtected.GET("/me", deps.DriverHandler.GetDriverInfo)
+		driverProtected.POST("/status", deps.DriverHandler.UpdateStatus)
+		driverProtected.POST("/booking-status", deps.DriverHandler.UpdateBookingStatus)
+		driverProtected.POST("/update-location", deps.DriverHandler.UpdateLocation)
+		driverProtected.GET("/pending-bookings", deps.DriverHandler.GetPendingBookings)
+		driverProtected.POST("/respond-booking", deps.DriverHandler.RespondToBooking)
 	}
 
-	adminProtected := router.Group("/admin", utils.JWTAuthMiddleware(authService, "admin"))
+	// Admin routes each require a permission, resolved from the admin's role
+	// on every request.
+	adminProtected := router.Group("/admin", authLimit, utils.JWTAuthMiddleware(deps.AuthService, "admin"), limit)
+	can := func(permission string) gin.HandlerFunc {
+		return utils.RequirePermission(deps.Permissions, permission)
+	}
 	{
-		adminProtected.GET("/drivers", adminHandler.GetAllDrivers)
-		adminProtected.GET("/drivers/:driverID", adminHandler.GetDriver)
-		adminProtected.PUT("/drivers/:driverID", adminHandler.UpdateDriver)
+		adminProtected.GET("/drivers", can(models.PermissionDriversRead), deps.AdminHandler.GetAllDrivers)
+		adminProtected.GET("/drivers/:driverID", can(models.PermissionDriversRead), deps.AdminHandler.GetDriver)
+		adminProtected.PUT("/drivers/:driverID", can(models.PermissionDriversWrite), deps.AdminHandler.UpdateDriver)
 
-		adminProtected.GET("/statistics", adminHandler.GetStatistics)
+		adminProtected.GET("/statistics", can(models.PermissionStatisticsRead), deps.AdminHandler.GetStatistics)
+		adminProtected.GET("/metrics", can(models.PermissionStatisticsRead), gin.WrapH(utils.MetricsHandler()))
+
+		// Offline travel time model
+		adminProtected.GET("/distance/speed-profile", can(models.PermissionSpeedProfileRead), deps.AdminHandler.GetSpeedProfile)
+		adminProtected.POST("/distance/calibrate", can(models.PermissionSpeedProfileWrite), deps.AdminHandler.CalibrateSpeedProfile)
 
 		// Vehicle management routes
-		adminProtected.POST("/vehicles", adminHandler.CreateVehicle)
-		adminProtected.GET("/vehicles", adminHandler.GetAllVehicles)
-		adminProtected.GET("/vehicles/:vehicleID", adminHandler.GetVehicle)
-		adminProtected.PUT("/vehicles/:vehicleID", adminHandler.UpdateVehicle)
-		adminProtected.DELETE("/vehicles/:vehicleID", adminHandler.DeleteVehicle)
+		adminProtected.POST("/vehicles", can(models.PermissionVehiclesWrite), deps.AdminHandler.CreateVehicle)
+		adminProtected.GET("/vehicles", can(models.PermissionVehiclesRead), deps.AdminHandler.GetAllVehicles)
+		adminProtected.GET("/vehicles/:vehicleID", can(models.PermissionVehiclesRead), deps.AdminHandler.GetVehicle)
+		adminProtected.PUT("/vehicles/:vehicleID", can(models.PermissionVehiclesWrite), deps.AdminHandler.UpdateVehicle)
+		adminProtected.DELETE("/vehicles/:vehicleID", can(models.PermissionVehiclesWrite), deps.AdminHandler.DeleteVehicle)
+
+		adminProtected.PUT("/users/:userID/organization", can(models.PermissionUsersWrite), deps.AdminHandler.SetUserOrganization)
+
+		// Admin accounts; granting a role also needs its permissions, and
+		// creating owners and revoking admins need the owner role
+		adminProtected.GET("/admins", can(models.PermissionAdminsRead), deps.AdminAccountHandler.GetAdmins)
+		adminProtected.POST("/admins", can(models.PermissionAdminsWrite), deps.AdminAccountHandler.CreateAdmin)
+		adminProtected.DELETE("/admins/:adminID", can(models.PermissionAdminsWrite), deps.AdminAccountHandler.RevokeAdmin)
+		adminProtected.PUT("/admins/:adminID/role", can(models.PermissionAdminsWrite), deps.AdminAccountHandler.SetAdminRole)
+		adminProtected.POST("/admins/invites", can(models.PermissionAdminsWrite), deps.AdminAccountHandler.CreateInvite)
+		adminProtected.GET("/admins/invites", can(models.PermissionAdminsRead), deps.AdminAccountHandler.GetInvites)
+		adminProtected.DELETE("/admins/invites/:inviteID", can(models.PermissionAdminsWrite), deps.AdminAccountHandler.DeleteInvite)
+
+		// Roles and permissions
+		adminProtected.GET("/permissions", deps.RoleHandler.GetPermissions)
+		adminProtected.GET("/roles", can(models.PermissionAdminsRead), deps.RoleHandler.GetRoles)
+		adminProtected.PUT("/roles/:role", can(models.PermissionRolesWrite), deps.RoleHandler.PutRole)
+		adminProtected.DELETE("/roles/:role", can(models.PermissionRolesWrite), deps.RoleHandler.DeleteRole)
+
+		// Login lockouts
+		adminProtected.GET("/lockouts", can(models.PermissionLockoutsRead), deps.LockoutHandler.GetLockouts)
+		adminProtected.POST("/lockouts/unlock", can(models.PermissionLockoutsWrite), deps.LockoutHandler.Unlock)
+
+		// Webhook management and delivery log
+		if deps.Config.WebhooksEnabled {
+			adminProtected.POST("/webhooks", can(models.PermissionWebhooksWrite), deps.WebhookHandler.AdminCreateWebhook)
+			adminProtected.GET("/webhooks", can(models.PermissionWebhooksRead), deps.WebhookHandler.AdminGetWebhooks)
+			adminProtected.DELETE("/webhooks/:webhookID", can(models.PermissionWebhooksWrite), deps.WebhookHandler.AdminDeleteWebhook)
+			adminProtected.GET("/webhooks/deliveries", can(models.PermissionWebhooksRead), deps.WebhookHandler.AdminGetDeliveries)
+			adminProtected.POST("/webhooks/deliveries/:deliveryID/redeliver", can(models.PermissionWebhooksWrite), deps.WebhookHandler.Redeliver)
+		}
 	}
 
 	return router
@@ -0,0 +1,84 @@
+// Package events defines the domain events published for other internal
+// services such as billing and analytics, and the transports that carry them.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Domain event types.
+const (
+	BookingCreated       = "booking.created"
+	BookingAssigned      = "booking.assigned"
+	BookingStatusChanged = "booking.status_changed"
+	BookingCompleted     = "booking.completed"
+	DriverStatusChanged  = "driver.status_changed"
+)
+
+// SubjectPrefix namespaces event subjects; an event of type booking.created
+// is published on logi.events.booking.created.
+const SubjectPrefix = "logi.events"
+
+// Subject returns the subject an event type is published on.
+func Subject(eventType string) string {
+	return SubjectPrefix + "." + eventType
+}
+
+// Event is a fact about one aggregate, a booking or a driver. ID is unique per
+// event and doubles as the deduplication key, so publishing the same Event
+// twice delivers it once.
+type Event struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// New returns an event with a fresh ID and data encoded as JSON.
+func New(eventType, aggregateID string, data interface{}) (Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:          uuid.NewString(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		OccurredAt:  time.Now().UTC(),
+		Data:        encoded,
+	}, nil
+}
+
+// Publisher stores an event durably.
+type Publisher interface {
+	PublishEvent(ctx context.Context, event Event) error
+}
+
+// Publishers publishes every event to each of its publishers. A failure in one
+// makes the caller retry all of them, so each must tolerate an event it has
+// already seen, as JetStreamBus does through the event ID.
+type Publishers []Publisher
+
+func (p Publishers) PublishEvent(ctx context.Context, event Event) error {
+	var errs []error
+	for _, publisher := range p {
+		if err := publisher.PublishEvent(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handler processes one event. Returning an error asks for redelivery.
+type Handler func(ctx context.Context, event Event) error
+
+// Subscription is a running consumer.
+type Subscription interface {
+	Stop()
+}
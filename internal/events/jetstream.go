@@ -0,0 +1,163 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"logi/internal/utils"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// JetStreamConfig describes the stream events are stored in and how durable
+// consumers retry.
+type JetStreamConfig struct {
+	Stream string
+	// DuplicateWindow is how long event IDs are remembered for deduplication.
+	DuplicateWindow time.Duration
+	MaxAge          time.Duration
+	// MaxDeliver caps delivery attempts per event and consumer; RetryDelay
+	// is the wait before redelivering an event whose handler failed.
+	MaxDeliver int
+	RetryDelay time.Duration
+	AckWait    time.Duration
+}
+
+// DefaultJetStreamConfig returns the settings used for zero fields.
+func DefaultJetStreamConfig() JetStreamConfig {
+	return JetStreamConfig{
+		Stream:          "LOGI_EVENTS",
+		DuplicateWindow: 2 * time.Minute,
+		MaxAge:          7 * 24 * time.Hour,
+		MaxDeliver:      5,
+		RetryDelay:      5 * time.Second,
+		AckWait:         30 * time.Second,
+	}
+}
+
+// JetStreamBus publishes events to a JetStream stream and runs durable
+// consumers on it.
+type JetStreamBus struct {
+	JS     jetstream.JetStream
+	Stream jetstream.Stream
+	config JetStreamConfig
+}
+
+// NewJetStreamBus creates or updates the stream described by cfg; zero fields
+// fall back to the defaults.
+func NewJetStreamBus(ctx context.Context, nc *nats.Conn, cfg JetStreamConfig) (*JetStreamBus, error) {
+	defaults := DefaultJetStreamConfig()
+	if cfg.Stream == "" {
+		cfg.Stream = defaults.Stream
+	}
+	if cfg.DuplicateWindow <= 0 {
+		cfg.DuplicateWindow = defaults.DuplicateWindow
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = defaults.MaxAge
+	}
+	if cfg.MaxDeliver <= 0 {
+		cfg.MaxDeliver = defaults.MaxDeliver
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaults.RetryDelay
+	}
+	if cfg.AckWait <= 0 {
+		cfg.AckWait = defaults.AckWait
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       cfg.Stream,
+		Subjects:   []string{SubjectPrefix + ".>"},
+		Storage:    jetstream.FileStorage,
+		MaxAge:     cfg.MaxAge,
+		Duplicates: cfg.DuplicateWindow,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &JetStreamBus{JS: js, Stream: stream, config: cfg}, nil
+}
+
+// PublishEvent stores event in the stream and waits for the server's ack.
+func (b *JetStreamBus) PublishEvent(ctx context.Context, event Event) error {
+	if event.ID == "" || event.Type == "" {
+		return errors.New("event id and type are required")
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ack, err := b.JS.Publish(ctx, Subject(event.Type), data,
+		jetstream.WithMsgID(event.ID),
+		jetstream.WithExpectStream(b.config.Stream),
+	)
+	if err != nil {
+		utils.IncrementCounter("events_publish_errors", 1)
+		return err
+	}
+	if ack.Duplicate {
+		utils.IncrementCounter("events_duplicates", 1)
+		return nil
+	}
+	utils.IncrementCounter("events_published", 1)
+	return nil
+}
+
+// Consume starts a durable consumer that passes events of the given types (all
+// types when none are given) to handler. Progress is kept by the server under
+// durable, so a restarted consumer resumes where it stopped, and consumers
+// with different names each receive every event. Events are acked when
+// handler returns nil and redelivered after RetryDelay otherwise, up to
+// MaxDeliver attempts.
+func (b *JetStreamBus) Consume(ctx context.Context, durable string, eventTypes []string, handler Handler) (Subscription, error) {
+	if durable == "" {
+		return nil, errors.New("durable consumer name is required")
+	}
+	subjects := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		subjects = append(subjects, Subject(eventType))
+	}
+	if len(subjects) == 0 {
+		subjects = append(subjects, SubjectPrefix+".>")
+	}
+
+	consumer, err := b.Stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:        durable,
+		FilterSubjects: subjects,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        b.config.AckWait,
+		MaxDeliver:     b.config.MaxDeliver,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return consumer.Consume(func(msg jetstream.Msg) {
+		var event Event
+		if err := json.Unmarshal(msg.Data(), &event); err != nil {
+			utils.WarnBackground("discarding malformed event", "consumer", durable, "subject", msg.Subject(), "error", err)
+			_ = msg.Term()
+			return
+		}
+
+		handlerCtx := utils.WithRequestID(context.Background(), event.ID)
+		if err := handler(handlerCtx, event); err != nil {
+			utils.IncrementCounter("events_handler_errors", 1)
+			utils.Warn(handlerCtx, "event handler failed", "consumer", durable, "event_type", event.Type, "aggregate_id", event.AggregateID, "error", err)
+			_ = msg.NakWithDelay(b.config.RetryDelay)
+			return
+		}
+		if err := msg.Ack(); err != nil {
+			utils.Warn(handlerCtx, "failed to ack event", "consumer", durable, "event_type", event.Type, "error", err)
+		}
+	})
+}
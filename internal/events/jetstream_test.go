@@ -0,0 +1,169 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func newTestBus(t *testing.T) *JetStreamBus {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create nats server: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server did not start")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect to nats: %v", err)
+	}
+	t.Cleanup(nc.Close)
+
+	bus, err := NewJetStreamBus(context.Background(), nc, JetStreamConfig{RetryDelay: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewJetStreamBus returned error: %v", err)
+	}
+	return bus
+}
+
+// collector records the IDs of handled events.
+type collector struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (c *collector) handle(ctx context.Context, event Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids = append(c.ids, event.ID)
+	return nil
+}
+
+func (c *collector) waitFor(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		if len(c.ids) >= n {
+			ids := append([]string(nil), c.ids...)
+			c.mu.Unlock()
+			return ids
+		}
+		c.mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d events", n)
+	return nil
+}
+
+func TestJetStreamBusDeduplicatesAndFiltersEvents(t *testing.T) {
+	t.Parallel()
+
+	bus := newTestBus(t)
+	ctx := context.Background()
+
+	created, err := New(BookingCreated, "booking-1", map[string]string{"booking_id": "booking-1"})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	driverStatus, _ := New(DriverStatusChanged, "driver-1", map[string]string{"status": "Busy"})
+	for _, event := range []Event{created, created, driverStatus} {
+		if err := bus.PublishEvent(ctx, event); err != nil {
+			t.Fatalf("PublishEvent returned error: %v", err)
+		}
+	}
+
+	info, err := bus.Stream.Info(ctx)
+	if err != nil {
+		t.Fatalf("stream info failed: %v", err)
+	}
+	if info.State.Msgs != 2 {
+		t.Fatalf("expected the duplicate publish to be dropped, stream holds %d messages", info.State.Msgs)
+	}
+
+	bookings := &collector{}
+	sub, err := bus.Consume(ctx, "analytics", []string{BookingCreated}, bookings.handle)
+	if err != nil {
+		t.Fatalf("Consume returned error: %v", err)
+	}
+	defer sub.Stop()
+
+	bookings.waitFor(t, 1)
+	// Leave time for a wrongly delivered driver event to show up.
+	time.Sleep(200 * time.Millisecond)
+	if ids := bookings.waitFor(t, 1); len(ids) != 1 || ids[0] != created.ID {
+		t.Fatalf("expected only the booking.created event, got %v", ids)
+	}
+}
+
+func TestJetStreamBusDurableConsumerResumesAndRetries(t *testing.T) {
+	t.Parallel()
+
+	bus := newTestBus(t)
+	ctx := context.Background()
+
+	first, _ := New(BookingCompleted, "booking-1", nil)
+	if err := bus.PublishEvent(ctx, first); err != nil {
+		t.Fatalf("PublishEvent returned error: %v", err)
+	}
+
+	billing := &collector{}
+	sub, err := bus.Consume(ctx, "billing", nil, billing.handle)
+	if err != nil {
+		t.Fatalf("Consume returned error: %v", err)
+	}
+	billing.waitFor(t, 1)
+	sub.Stop()
+
+	// Published while the consumer is down; a restart must pick it up, and
+	// only it, even though the first handling attempt fails.
+	second, _ := New(BookingCompleted, "booking-2", nil)
+	if err := bus.PublishEvent(ctx, second); err != nil {
+		t.Fatalf("PublishEvent returned error: %v", err)
+	}
+
+	attempts := 0
+	failOnce := func(ctx context.Context, event Event) error {
+		billing.mu.Lock()
+		attempts++
+		failing := attempts == 1
+		billing.mu.Unlock()
+		if failing {
+			return errors.New("billing unavailable")
+		}
+		return billing.handle(ctx, event)
+	}
+	sub, err = bus.Consume(ctx, "billing", nil, failOnce)
+	if err != nil {
+		t.Fatalf("Consume returned error: %v", err)
+	}
+	defer sub.Stop()
+
+	ids := billing.waitFor(t, 2)
+	if len(ids) != 2 || ids[0] != first.ID || ids[1] != second.ID {
+		t.Fatalf("expected resumed consumer to handle only the new event, got %v", ids)
+	}
+	billing.mu.Lock()
+	defer billing.mu.Unlock()
+	if attempts != 2 {
+		t.Fatalf("expected the failed event to be redelivered once, got %d attempts", attempts)
+	}
+}
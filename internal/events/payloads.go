@@ -0,0 +1,132 @@
+package events
+
+import (
+	"time"
+
+	"logi/internal/models"
+)
+
+// Data versions. Each event type's Data is the payload struct below; bump its
+// version whenever the payload changes in a way consumers can observe.
+// Consumers outside the backend, such as webhook receivers, see exactly these
+// fields, so a booking's dispatch bookkeeping is never part of them.
+const (
+	BookingCreatedVersion       = 1
+	BookingAssignedVersion      = 1
+	BookingStatusChangedVersion = 1
+	BookingCompletedVersion     = 1
+	DriverStatusChangedVersion  = 1
+)
+
+// BookingCreatedPayload describes a booking as the user requested it.
+type BookingCreatedPayload struct {
+	Version         int             `json:"version"`
+	BookingID       string          `json:"booking_id"`
+	UserID          string          `json:"user_id"`
+	PickupLocation  models.Location `json:"pickup_location"`
+	DropoffLocation models.Location `json:"dropoff_location"`
+	PickupPlace     *models.Place   `json:"pickup_place,omitempty"`
+	DropoffPlace    *models.Place   `json:"dropoff_place,omitempty"`
+	VehicleType     string          `json:"vehicle_type"`
+	PriceEstimate   float64         `json:"price_estimate"`
+	Status          string          `json:"status"`
+	CreatedAt       time.Time       `json:"created_at"`
+	ScheduledTime   *time.Time      `json:"scheduled_time,omitempty"`
+}
+
+func NewBookingCreated(booking *models.Booking) BookingCreatedPayload {
+	return BookingCreatedPayload{
+		Version:         BookingCreatedVersion,
+		BookingID:       booking.ID,
+		UserID:          booking.UserID,
+		PickupLocation:  booking.PickupLocation,
+		DropoffLocation: booking.DropoffLocation,
+		PickupPlace:     booking.PickupPlace,
+		DropoffPlace:    booking.DropoffPlace,
+		VehicleType:     booking.VehicleType,
+		PriceEstimate:   booking.PriceEstimate,
+		Status:          booking.Status,
+		CreatedAt:       booking.CreatedAt,
+		ScheduledTime:   booking.ScheduledTime,
+	}
+}
+
+// BookingAssignedPayload names the driver who accepted a booking.
+type BookingAssignedPayload struct {
+	Version   int    `json:"version"`
+	BookingID string `json:"booking_id"`
+	UserID    string `json:"user_id"`
+	DriverID  string `json:"driver_id"`
+}
+
+func NewBookingAssigned(bookingID, userID, driverID string) BookingAssignedPayload {
+	return BookingAssignedPayload{Version: BookingAssignedVersion, BookingID: bookingID, UserID: userID, DriverID: driverID}
+}
+
+// BookingStatusChangedPayload reports a booking's move from one status to
+// another.
+type BookingStatusChangedPayload struct {
+	Version        int    `json:"version"`
+	BookingID      string `json:"booking_id"`
+	UserID         string `json:"user_id"`
+	DriverID       string `json:"driver_id"`
+	PreviousStatus string `json:"previous_status"`
+	Status         string `json:"status"`
+}
+
+func NewBookingStatusChanged(bookingID, userID, driverID, previousStatus, status string) BookingStatusChangedPayload {
+	return BookingStatusChangedPayload{
+		Version:        BookingStatusChangedVersion,
+		BookingID:      bookingID,
+		UserID:         userID,
+		DriverID:       driverID,
+		PreviousStatus: previousStatus,
+		Status:         status,
+	}
+}
+
+// BookingCompletedPayload describes a finished trip.
+type BookingCompletedPayload struct {
+	Version         int             `json:"version"`
+	BookingID       string          `json:"booking_id"`
+	UserID          string          `json:"user_id"`
+	DriverID        string          `json:"driver_id"`
+	PickupLocation  models.Location `json:"pickup_location"`
+	DropoffLocation models.Location `json:"dropoff_location"`
+	PickupPlace     *models.Place   `json:"pickup_place,omitempty"`
+	DropoffPlace    *models.Place   `json:"dropoff_place,omitempty"`
+	VehicleType     string          `json:"vehicle_type"`
+	PriceEstimate   float64         `json:"price_estimate"`
+	CreatedAt       time.Time       `json:"created_at"`
+	StartedAt       *time.Time      `json:"started_at,omitempty"`
+	CompletedAt     *time.Time      `json:"completed_at,omitempty"`
+}
+
+func NewBookingCompleted(booking *models.Booking) BookingCompletedPayload {
+	return BookingCompletedPayload{
+		Version:         BookingCompletedVersion,
+		BookingID:       booking.ID,
+		UserID:          booking.UserID,
+		DriverID:        booking.DriverID,
+		PickupLocation:  booking.PickupLocation,
+		DropoffLocation: booking.DropoffLocation,
+		PickupPlace:     booking.PickupPlace,
+		DropoffPlace:    booking.DropoffPlace,
+		VehicleType:     booking.VehicleType,
+		PriceEstimate:   booking.PriceEstimate,
+		CreatedAt:       booking.CreatedAt,
+		StartedAt:       booking.StartedAt,
+		CompletedAt:     booking.CompletedAt,
+	}
+}
+
+// DriverStatusChangedPayload reports a driver's new availability.
+type DriverStatusChangedPayload struct {
+	Version  int    `json:"version"`
+	DriverID string `json:"driver_id"`
+	Status   string `json:"status"`
+}
+
+func NewDriverStatusChanged(driverID, status string) DriverStatusChangedPayload {
+	return DriverStatusChangedPayload{Version: DriverStatusChangedVersion, DriverID: driverID, Status: status}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"logi/internal/services"
+	"logi/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler serves email verification and password resets for users
+// and drivers.
+type AccountHandler struct {
+	Service *services.AccountService
+}
+
+func NewAccountHandler(service *services.AccountService) *AccountHandler {
+	return &AccountHandler{Service: service}
+}
+
+type accountEmailRequest struct {
+	// Role is user or driver; it defaults to user.
+	Role  string `json:"role"`
+	Email string `json:"email"`
+}
+
+// Verify marks an email verified with the token from a verification email.
+func (h *AccountHandler) Verify(c *gin.Context) {
+	var payload struct {
+		Token string `json:"token"`
+	}
+	if err := c.BindJSON(&payload); err != nil || payload.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.Service.Verify(c.Request.Context(), payload.Token); err != nil {
+		h.respondError(c, err, "Failed to verify email")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification email. It answers the same
+// whether or not the account exists.
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	payload, ok := bindAccountEmail(c)
+	if !ok {
+		return
+	}
+
+	err := h.Service.SendVerification(c.Request.Context(), payload.Role, payload.Email)
+	if errors.Is(err, services.ErrInvalidAccountRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		utils.Error(c.Request.Context(), "failed to send verification email", "role", payload.Role, "error", err)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists and is unverified, a verification email has been sent"})
+}
+
+// ForgotPassword emails a password reset link. It answers the same whether
+// or not the account exists.
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	payload, ok := bindAccountEmail(c)
+	if !ok {
+		return
+	}
+
+	err := h.Service.ForgotPassword(c.Request.Context(), payload.Role, payload.Email)
+	if errors.Is(err, services.ErrInvalidAccountRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		utils.Error(c.Request.Context(), "failed to send password reset email", "role", payload.Role, "error", err)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists, a password reset email has been sent"})
+}
+
+// ResetPassword sets a new password with the token from a reset email.
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var payload struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BindJSON(&payload); err != nil || payload.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.Service.ResetPassword(c.Request.Context(), payload.Token, payload.Password); err != nil {
+		h.respondError(c, err, "Failed to reset password")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+func bindAccountEmail(c *gin.Context) (accountEmailRequest, bool) {
+	var payload accountEmailRequest
+	if err := c.BindJSON(&payload); err != nil || payload.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return payload, false
+	}
+	if payload.Role == "" {
+		payload.Role = "user"
+	}
+	return payload, true
+}
+
+func (h *AccountHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAccountToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		utils.Error(c.Request.Context(), message, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
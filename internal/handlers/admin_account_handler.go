@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"logi/internal/models"
+	"logi/internal/services"
+	"logi/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAccountHandler serves admin account management, invites and the
+// one-time bootstrap.
+type AdminAccountHandler struct {
+	Service *services.AdminAccountService
+}
+
+func NewAdminAccountHandler(service *services.AdminAccountService) *AdminAccountHandler {
+	return &AdminAccountHandler{Service: service}
+}
+
+type adminAccountRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// Bootstrap creates the first owner when the request carries the configured
+// bootstrap token in the X-Bootstrap-Token header.
+func (h *AdminAccountHandler) Bootstrap(c *gin.Context) {
+	var payload adminAccountRequest
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	admin := &models.Admin{Name: payload.Name, Email: payload.Email}
+	err := h.Service.BootstrapWithToken(c.Request.Context(), c.GetHeader("X-Bootstrap-Token"), admin, payload.Password)
+	if err != nil {
+		h.respondError(c, err, "Failed to bootstrap owner")
+		return
+	}
+
+	c.JSON(http.StatusCreated, admin)
+}
+
+// AcceptInvite creates the invited admin from an invite token.
+func (h *AdminAccountHandler) AcceptInvite(c *gin.Context) {
+	var payload struct {
+		Token    string `json:"token"`
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	admin, err := h.Service.AcceptInvite(c.Request.Context(), payload.Token, payload.Name, payload.Password)
+	if err != nil {
+		h.respondError(c, err, "Failed to accept invite")
+		return
+	}
+
+	c.JSON(http.StatusCreated, admin)
+}
+
+func (h *AdminAccountHandler) GetAdmins(c *gin.Context) {
+	admins, err := h.Service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch admins"})
+		return
+	}
+	c.JSON(http.StatusOK, admins)
+}
+
+func (h *AdminAccountHandler) CreateAdmin(c *gin.Context) {
+	var payload adminAccountRequest
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	admin := &models.Admin{Name: payload.Name, Email: payload.Email, Role: payload.Role}
+	if err := h.Service.Create(c.Request.Context(), c.GetString("userID"), admin, payload.Password); err != nil {
+		h.respondError(c, err, "Failed to create admin")
+		return
+	}
+
+	c.JSON(http.StatusCreated, admin)
+}
+
+// RevokeAdmin disables an admin and ends their sessions. Owners only.
+func (h *AdminAccountHandler) RevokeAdmin(c *gin.Context) {
+	if err := h.Service.Revoke(c.Request.Context(), c.GetString("userID"), c.Param("adminID")); err != nil {
+		h.respondError(c, err, "Failed to revoke admin")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Admin revoked successfully"})
+}
+
+// SetAdminRole changes an admin's role.
+func (h *AdminAccountHandler) SetAdminRole(c *gin.Context) {
+	var payload struct {
+		Role string `json:"role"`
+	}
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.Service.SetRole(c.Request.Context(), c.GetString("userID"), c.Param("adminID"), payload.Role); err != nil {
+		h.respondError(c, err, "Failed to change role")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully"})
+}
+
+func (h *AdminAccountHandler) CreateInvite(c *gin.Context) {
+	var payload struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	invite, err := h.Service.Invite(c.Request.Context(), c.GetString("userID"), payload.Email, payload.Role)
+	if err != nil {
+		h.respondError(c, err, "Failed to send invite")
+		return
+	}
+
+	c.JSON(http.StatusCreated, invite)
+}
+
+func (h *AdminAccountHandler) GetInvites(c *gin.Context) {
+	invites, err := h.Service.ListInvites(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invites"})
+		return
+	}
+	c.JSON(http.StatusOK, invites)
+}
+
+func (h *AdminAccountHandler) DeleteInvite(c *gin.Context) {
+	if err := h.Service.CancelInvite(c.Request.Context(), c.Param("inviteID")); err != nil {
+		h.respondError(c, err, "Failed to cancel invite")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invite cancelled successfully"})
+}
+
+func (h *AdminAccountHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAdmin), errors.Is(err, services.ErrRoleNotFound), errors.Is(err, services.ErrCannotRevokeSelf), errors.Is(err, services.ErrCannotChangeOwnRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidInvite), errors.Is(err, services.ErrInvalidBootstrapToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOwnerRequired), errors.Is(err, services.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAdminNotFound), errors.Is(err, services.ErrInviteNotFound), errors.Is(err, services.ErrBootstrapDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAdminExists), errors.Is(err, services.ErrAlreadyBootstrapped):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		utils.Error(c.Request.Context(), message, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
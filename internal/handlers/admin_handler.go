@@ -1,9 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"logi/internal/models"
 	"logi/internal/services"
-	"logi/pkg/auth"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,52 +11,26 @@ import (
 
 type AdminHandler struct {
 	Service        *services.AdminService
-	AuthService    *auth.AuthService
+	Tokens         *services.TokenService
 	UserService    *services.UserService
 	DriverService  *services.DriverService
 	BookingService *services.BookingService
 	VehicleService *services.VehicleService
+	SpeedService   *services.SpeedCalibrationService
 }
 
-func NewAdminHandler(service *services.AdminService, authService *auth.AuthService, userService *services.UserService, driverService *services.DriverService, bookingService *services.BookingService, vehicleService *services.VehicleService) *AdminHandler {
+func NewAdminHandler(service *services.AdminService, tokens *services.TokenService, userService *services.UserService, driverService *services.DriverService, bookingService *services.BookingService, vehicleService *services.VehicleService, speedService *services.SpeedCalibrationService) *AdminHandler {
 	return &AdminHandler{
 		Service:        service,
-		AuthService:    authService,
+		Tokens:         tokens,
 		UserService:    userService,
 		DriverService:  driverService,
 		BookingService: bookingService,
 		VehicleService: vehicleService,
+		SpeedService:   speedService,
 	}
 }
 
-func (h *AdminHandler) Register(c *gin.Context) {
-	ctx := c.Request.Context()
-
-	var payload struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
-	if err := c.BindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
-		return
-	}
-
-	admin := &models.Admin{
-		Name:  payload.Name,
-		Email: payload.Email,
-	}
-
-	err := h.Service.Register(ctx, admin, payload.Password)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, gin.H{"message": "Admin registered successfully"})
-}
-
 func (h *AdminHandler) Login(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -72,17 +46,17 @@ func (h *AdminHandler) Login(c *gin.Context) {
 
 	admin, err := h.Service.Login(ctx, payload.Email, payload.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondLoginError(c, err)
 		return
 	}
 
-	token, err := h.AuthService.GenerateJWT(admin.ID, "admin")
+	tokens, err := h.Tokens.IssueTokens(ctx, admin.ID, "admin")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokens)
 }
 
 // Fleet Management Endpoints
@@ -236,3 +210,49 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// Travel Time Model Endpoints
+
+// CalibrateSpeedProfile refits the offline duration model from completed bookings.
+func (h *AdminHandler) CalibrateSpeedProfile(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	profile, err := h.SpeedService.Calibrate(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Speed profile calibrated", "profile": profile})
+}
+
+func (h *AdminHandler) GetSpeedProfile(c *gin.Context) {
+	c.JSON(http.StatusOK, h.SpeedService.ActiveProfile())
+}
+
+// User Management Endpoints
+
+// SetUserOrganization assigns a user to the organization whose webhooks
+// receive the user's booking events.
+func (h *AdminHandler) SetUserOrganization(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.Param("userID")
+	var payload struct {
+		OrganizationID string `json:"organization_id"`
+	}
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err := h.Service.SetUserOrganization(ctx, userID, payload.OrganizationID)
+	if errors.Is(err, services.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"logi/internal/services"
+	"logi/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler serves the session endpoints shared by users, drivers and admins.
+type AuthHandler struct {
+	Tokens *services.TokenService
+}
+
+func NewAuthHandler(tokens *services.TokenService) *AuthHandler {
+	return &AuthHandler{Tokens: tokens}
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var payload struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BindJSON(&payload); err != nil || payload.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	tokens, err := h.Tokens.Refresh(ctx, payload.RefreshToken)
+	if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		utils.Error(ctx, "failed to refresh token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the caller's access token and refresh tokens.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := h.Tokens.Logout(ctx, c.GetString("userID"), c.GetString("tokenID"), c.GetTime("tokenExpiresAt"))
+	if err != nil {
+		utils.Error(ctx, "failed to log out", "user_id", c.GetString("userID"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// JWKS publishes the public keys that verify access tokens.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.Tokens.Auth.JWKS())
+}
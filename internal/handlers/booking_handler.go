@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"logi/internal/models"
 	"logi/internal/services"
 	"net/http"
@@ -32,6 +33,10 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	}
 
 	booking, err := h.Service.CreateBooking(ctx, userID.(string), &bookingReq)
+	if errors.Is(err, services.ErrInvalidPlace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -53,6 +58,10 @@ func (h *BookingHandler) GetPriceEstimate(c *gin.Context) {
 
 	// Call the service to get the price estimate
 	estimatedPrice, err := h.Service.GetPriceEstimate(ctx, &estimateReq)
+	if errors.Is(err, services.ErrInvalidPlace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate price estimate"})
 		return
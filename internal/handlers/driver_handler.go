@@ -1,23 +1,27 @@
 package handlers
 
 import (
+	"errors"
 	"logi/internal/models"
 	"logi/internal/services"
-	"logi/pkg/auth"
+	"logi/internal/sms"
+	"logi/internal/utils"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 type DriverHandler struct {
-	Service     *services.DriverService
-	AuthService *auth.AuthService
+	Service *services.DriverService
+	Tokens  *services.TokenService
 }
 
-func NewDriverHandler(service *services.DriverService, authService *auth.AuthService) *DriverHandler {
+func NewDriverHandler(service *services.DriverService, tokens *services.TokenService) *DriverHandler {
 	return &DriverHandler{
-		Service:     service,
-		AuthService: authService,
+		Service: service,
+		Tokens:  tokens,
 	}
 }
 
@@ -66,17 +70,118 @@ func (h *DriverHandler) Login(c *gin.Context) {
 
 	driver, err := h.Service.Login(ctx, payload.Email, payload.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondLoginError(c, err)
 		return
 	}
 
-	token, err := h.AuthService.GenerateJWT(driver.ID, "driver")
+	tokens, err := h.Tokens.IssueTokens(ctx, driver.ID, "driver")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// SendLoginCode texts a one-time code to a phone number, for registering or
+// logging in by phone.
+func (h *DriverHandler) SendLoginCode(c *gin.Context) {
+	var payload struct {
+		Phone string `json:"phone"`
+	}
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.Service.SendLoginCode(c.Request.Context(), payload.Phone); err != nil {
+		respondPhoneLoginError(c, err, "Failed to send code")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Code sent"})
+}
+
+// RegisterWithCode creates a driver who logs in by phone and logs them in.
+func (h *DriverHandler) RegisterWithCode(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var payload struct {
+		Name        string `json:"name"`
+		Phone       string `json:"phone"`
+		Code        string `json:"code"`
+		VehicleType string `json:"vehicle_type"`
+	}
+	if err := c.BindJSON(&payload); err != nil || payload.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	driver := &models.Driver{
+		Name:        payload.Name,
+		Phone:       payload.Phone,
+		VehicleType: payload.VehicleType,
+	}
+	if err := h.Service.RegisterWithCode(ctx, driver, payload.Code); err != nil {
+		respondPhoneLoginError(c, err, "Failed to register driver")
+		return
+	}
+
+	tokens, err := h.Tokens.IssueTokens(ctx, driver.ID, "driver")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusCreated, tokens)
+}
+
+// LoginWithCode logs a driver in with a code sent to their phone.
+func (h *DriverHandler) LoginWithCode(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var payload struct {
+		Phone string `json:"phone"`
+		Code  string `json:"code"`
+	}
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	driver, err := h.Service.LoginWithCode(ctx, payload.Phone, payload.Code)
+	if err != nil {
+		respondPhoneLoginError(c, err, "Failed to log in")
+		return
+	}
+
+	tokens, err := h.Tokens.IssueTokens(ctx, driver.ID, "driver")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func respondPhoneLoginError(c *gin.Context, err error, message string) {
+	var locked *services.LoginLockedError
+	var tooSoon *services.OTPResendError
+	switch {
+	case errors.As(err, &locked):
+		respondLoginError(c, err)
+	case errors.As(err, &tooSoon):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(tooSoon.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, sms.ErrInvalidPhone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidOTP):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPhoneNotRegistered):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPhoneTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		utils.Error(c.Request.Context(), message, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
 }
 
 func (h *DriverHandler) UpdateStatus(c *gin.Context) {
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"logi/internal/services"
+	"logi/internal/utils"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LockoutHandler lets admins see and clear login lockouts.
+type LockoutHandler struct {
+	Throttle *services.LoginThrottle
+}
+
+func NewLockoutHandler(throttle *services.LoginThrottle) *LockoutHandler {
+	return &LockoutHandler{Throttle: throttle}
+}
+
+// GetLockouts lists the accounts and IPs that are locked out now.
+func (h *LockoutHandler) GetLockouts(c *gin.Context) {
+	lockouts, err := h.Throttle.Locked(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lockouts"})
+		return
+	}
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// Unlock clears the failed logins of an account or an IP, ending any lockout.
+func (h *LockoutHandler) Unlock(c *gin.Context) {
+	var payload struct {
+		Role  string `json:"role"`
+		Email string `json:"email"`
+		IP    string `json:"ip"`
+	}
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err := h.Throttle.Unlock(c.Request.Context(), c.GetString("userID"), payload.Role, payload.Email, payload.IP)
+	switch {
+	case errors.Is(err, services.ErrInvalidLockout):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLockoutNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		utils.Error(c.Request.Context(), "failed to unlock login", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock login"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Login unlocked successfully"})
+	}
+}
+
+// respondLoginError answers a failed login: 429 with Retry-After while the
+// account or IP is throttled, 403 for an unverified email, otherwise 401.
+func respondLoginError(c *gin.Context, err error) {
+	var locked *services.LoginLockedError
+	if errors.As(err, &locked) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+}
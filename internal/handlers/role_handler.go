@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"logi/internal/models"
+	"logi/internal/services"
+	"logi/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleHandler serves admin roles and the caller's effective permissions.
+type RoleHandler struct {
+	Service *services.RoleService
+}
+
+func NewRoleHandler(service *services.RoleService) *RoleHandler {
+	return &RoleHandler{Service: service}
+}
+
+// GetPermissions returns the caller's effective permissions, so clients can
+// hide what the admin cannot do.
+func (h *RoleHandler) GetPermissions(c *gin.Context) {
+	permissions, err := h.Service.Permissions(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"permissions": permissions})
+}
+
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	roles, err := h.Service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// PutRole creates or replaces the role named in the path.
+func (h *RoleHandler) PutRole(c *gin.Context) {
+	var payload struct {
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	role := &models.Role{Name: c.Param("role"), Description: payload.Description, Permissions: payload.Permissions}
+	if err := h.Service.Save(c.Request.Context(), c.GetString("userID"), role); err != nil {
+		h.respondError(c, err, "Failed to save role")
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	if err := h.Service.Delete(c.Request.Context(), c.Param("role")); err != nil {
+		h.respondError(c, err, "Failed to delete role")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+func (h *RoleHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrSystemRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRoleInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		utils.Error(c.Request.Context(), message, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
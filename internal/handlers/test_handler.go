@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"logi/internal/messaging"
+	"logi/internal/models"
 	"logi/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -26,46 +27,42 @@ func (h *TestHandler) PublishTestMessages(c *gin.Context) {
 	utils.Info(c.Request.Context(), "publishing test messages", "user_id", userID)
 
 	// Example: Publish a status update message
-	statusUpdate := map[string]interface{}{
-		"type":       "status_update",
-		"booking_id": "booking123",
-		"status":     "In Transit",
-	}
+	statusUpdate := messaging.NewStatusUpdate("booking123", models.BookingStatusInTransit)
 
-	err := h.MessagingClient.Publish(userID, "status_update", statusUpdate)
+	err := h.MessagingClient.Publish(messaging.ToAdmin(userID), messaging.TypeStatusUpdate, statusUpdate)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish status update"})
 		return
 	}
 
 	// Example: Publish a driver location update message
-	driverLocation := map[string]interface{}{
-		"type":       "driver_location",
-		"booking_id": "booking123",
-		"latitude":   37.7749,
-		"longitude":  -122.4194,
-	}
+	driverLocation := messaging.NewDriverLocation("booking123", 37.7749, -122.4194)
 
-	err = h.MessagingClient.Publish("user123", "driver_location", driverLocation)
+	err = h.MessagingClient.Publish(messaging.ToUser("user123"), messaging.TypeDriverLocation, driverLocation)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish driver location"})
 		return
 	}
 
 	// Example: Publish a booking accepted message
-	bookingAccepted := map[string]interface{}{
-		"type":       "booking_accepted",
-		"booking_id": "booking123",
-		"driver_id":  "driver456",
-	}
+	bookingAccepted := messaging.NewBookingAccepted("booking123", "driver456")
 
-	err = h.MessagingClient.Publish("user123", "booking_accepted", bookingAccepted)
+	err = h.MessagingClient.Publish(messaging.ToUser("user123"), messaging.TypeBookingAccepted, bookingAccepted)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish booking accepted"})
 		return
 	}
 
-	err = h.MessagingClient.Publish("b8fe009c-7cf4-435f-9161-59a0f954c5c4", "new_booking_request", map[string]interface{}{"booking_id": "booking123"})
+	newBookingRequest := messaging.NewBookingRequest(&models.Booking{
+		ID:              "booking123",
+		UserID:          "user123",
+		PickupLocation:  models.Location{Type: "Point", Coordinates: []float64{-122.4194, 37.7749}},
+		DropoffLocation: models.Location{Type: "Point", Coordinates: []float64{-122.4089, 37.7837}},
+		VehicleType:     "car",
+		PriceEstimate:   25.5,
+	})
+
+	err = h.MessagingClient.Publish(messaging.ToDriver("b8fe009c-7cf4-435f-9161-59a0f954c5c4"), messaging.TypeNewBookingRequest, newBookingRequest)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish new booking request"})
 		return
@@ -3,21 +3,20 @@ package handlers
 import (
 	"logi/internal/models"
 	"logi/internal/services"
-	"logi/pkg/auth"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
-	Service     *services.UserService
-	AuthService *auth.AuthService
+	Service *services.UserService
+	Tokens  *services.TokenService
 }
 
-func NewUserHandler(service *services.UserService, authService *auth.AuthService) *UserHandler {
+func NewUserHandler(service *services.UserService, tokens *services.TokenService) *UserHandler {
 	return &UserHandler{
-		Service:     service,
-		AuthService: authService,
+		Service: service,
+		Tokens:  tokens,
 	}
 }
 
@@ -64,17 +63,17 @@ func (h *UserHandler) Login(c *gin.Context) {
 
 	user, err := h.Service.Login(ctx, payload.Email, payload.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondLoginError(c, err)
 		return
 	}
 
-	token, err := h.AuthService.GenerateJWT(user.ID, user.Role) // Include role
+	tokens, err := h.Tokens.IssueTokens(ctx, user.ID, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokens)
 }
 
 func (h *UserHandler) GetActiveBooking(c *gin.Context) {
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	Service *services.WebhookService
+}
+
+func NewWebhookHandler(service *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{Service: service}
+}
+
+type webhookRequest struct {
+	URL            string   `json:"url"`
+	EventTypes     []string `json:"event_types"`
+	UserID         string   `json:"user_id"`
+	OrganizationID string   `json:"organization_id"`
+}
+
+// CreateWebhook registers an endpoint for the caller's own bookings.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var payload webhookRequest
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	h.register(c, &models.WebhookEndpoint{UserID: c.GetString("userID"), URL: payload.URL, EventTypes: payload.EventTypes})
+}
+
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	endpoints, err := h.Service.ListEndpoints(c.Request.Context(), c.GetString("userID"), "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, endpoints)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	h.delete(c, c.GetString("userID"))
+}
+
+func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	h.listDeliveries(c, c.GetString("userID"), c.Param("webhookID"))
+}
+
+// Admin endpoints manage every webhook, including organization ones.
+
+// AdminCreateWebhook registers an endpoint for a user or an organization.
+func (h *WebhookHandler) AdminCreateWebhook(c *gin.Context) {
+	var payload webhookRequest
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	h.register(c, &models.WebhookEndpoint{
+		UserID:         payload.UserID,
+		OrganizationID: payload.OrganizationID,
+		URL:            payload.URL,
+		EventTypes:     payload.EventTypes,
+	})
+}
+
+// AdminGetWebhooks lists endpoints, filtered by ?user_id= or ?organization_id=.
+func (h *WebhookHandler) AdminGetWebhooks(c *gin.Context) {
+	endpoints, err := h.Service.ListEndpoints(c.Request.Context(), c.Query("user_id"), c.Query("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, endpoints)
+}
+
+func (h *WebhookHandler) AdminDeleteWebhook(c *gin.Context) {
+	h.delete(c, "")
+}
+
+// AdminGetDeliveries lists the delivery log, filtered by ?endpoint_id=,
+// ?event_id= and ?status=.
+func (h *WebhookHandler) AdminGetDeliveries(c *gin.Context) {
+	h.listDeliveries(c, "", c.Query("endpoint_id"))
+}
+
+// Redeliver sends a delivery again, whatever its status.
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	delivery, err := h.Service.Redeliver(c.Request.Context(), c.Param("deliveryID"))
+	if errors.Is(err, services.ErrWebhookDeliveryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeliver webhook"})
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func (h *WebhookHandler) register(c *gin.Context, endpoint *models.WebhookEndpoint) {
+	err := h.Service.RegisterEndpoint(c.Request.Context(), endpoint)
+	if errors.Is(err, services.ErrInvalidWebhook) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register webhook"})
+		return
+	}
+	// The secret is only ever shown here.
+	c.JSON(http.StatusCreated, gin.H{"webhook": endpoint, "secret": endpoint.Secret})
+}
+
+func (h *WebhookHandler) delete(c *gin.Context, userID string) {
+	err := h.Service.DeleteEndpoint(c.Request.Context(), userID, c.Param("webhookID"))
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+func (h *WebhookHandler) listDeliveries(c *gin.Context, userID, endpointID string) {
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+	deliveries, err := h.Service.ListDeliveries(c.Request.Context(), userID, repositories.WebhookDeliveryFilter{
+		EndpointID: endpointID,
+		EventID:    c.Query("event_id"),
+		Status:     c.Query("status"),
+		Limit:      limit,
+	})
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"logi/internal/messaging"
+	"logi/internal/services"
+	"logi/internal/utils"
+	"logi/pkg/websocket"
+)
+
+// Inbound command types accepted over the websocket.
+const (
+	CommandUpdateLocation      = "update_location"
+	CommandRespondBooking      = "respond_booking"
+	CommandUpdateBookingStatus = "update_booking_status"
+	CommandUpdateStatus        = "update_status"
+	CommandAck                 = "ack"
+	CommandSubscribe           = "subscribe"
+	CommandUnsubscribe         = "unsubscribe"
+)
+
+// CommandResultType is the message type of every reply to an inbound command.
+const CommandResultType = messaging.TypeCommandResult
+
+// WebSocketCommand is a client-to-server frame. ID is chosen by the client and
+// echoed in the matching CommandResult.
+type WebSocketCommand struct {
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// CommandResult reports the outcome of one WebSocketCommand.
+type CommandResult = messaging.CommandResultPayload
+
+// WebSocketCommandHandler dispatches inbound websocket commands to the same
+// services the HTTP driver endpoints use.
+type WebSocketCommandHandler struct {
+	DriverService *services.DriverService
+	Hub           *websocket.WebSocketHub
+}
+
+func NewWebSocketCommandHandler(driverService *services.DriverService, hub *websocket.WebSocketHub) *WebSocketCommandHandler {
+	return &WebSocketCommandHandler{DriverService: driverService, Hub: hub}
+}
+
+// Handle implements websocket.InboundHandler.
+func (h *WebSocketCommandHandler) Handle(session *websocket.Session, data []byte) interface{} {
+	userID, role := session.UserID(), session.Role()
+
+	var cmd WebSocketCommand
+	if err := json.Unmarshal(data, &cmd); err != nil || cmd.Type == "" {
+		return commandReply(userID, role, CommandResult{Error: "Invalid input"})
+	}
+
+	ctx := utils.WithRequestID(context.Background(), cmd.ID)
+	result := CommandResult{ID: cmd.ID, Command: cmd.Type, OK: true}
+	if err := h.dispatch(ctx, session, cmd); err != nil {
+		utils.Warn(ctx, "websocket command failed", "user_id", userID, "role", role, "command", cmd.Type, "error", err)
+		result.OK = false
+		result.Error = err.Error()
+	} else if cmd.Type == CommandSubscribe || cmd.Type == CommandUnsubscribe {
+		result.Topics = session.Topics()
+	}
+	return commandReply(userID, role, result)
+}
+
+func (h *WebSocketCommandHandler) dispatch(ctx context.Context, session *websocket.Session, cmd WebSocketCommand) error {
+	userID, role := session.UserID(), session.Role()
+
+	switch cmd.Type {
+	case CommandAck:
+		var payload struct {
+			Seq    uint64 `json:"seq"`
+			Stream string `json:"stream"`
+		}
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil || payload.Seq == 0 {
+			return errors.New("Invalid input")
+		}
+		return h.Hub.Ack(ctx, userID, role, payload.Stream, payload.Seq)
+	case CommandSubscribe, CommandUnsubscribe:
+		var payload struct {
+			Topics []string `json:"topics"`
+		}
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil || len(payload.Topics) == 0 {
+			return errors.New("Invalid input")
+		}
+		if cmd.Type == CommandSubscribe {
+			return session.Subscribe(payload.Topics...)
+		}
+		return session.Unsubscribe(payload.Topics...)
+	}
+
+	if role != websocket.RoleDriver {
+		return errors.New("command not allowed for role")
+	}
+
+	switch cmd.Type {
+	case CommandUpdateLocation:
+		var payload struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		}
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return errors.New("Invalid input")
+		}
+		return h.DriverService.UpdateLocation(ctx, userID, payload.Latitude, payload.Longitude)
+	case CommandRespondBooking:
+		var payload struct {
+			BookingID string `json:"booking_id"`
+			Response  string `json:"response"` // "accept" or "reject"
+		}
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return errors.New("Invalid input")
+		}
+		return h.DriverService.RespondToBooking(ctx, userID, payload.BookingID, payload.Response)
+	case CommandUpdateBookingStatus:
+		var payload struct {
+			BookingID string `json:"booking_id"`
+			Status    string `json:"status"`
+		}
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return errors.New("Invalid input")
+		}
+		return h.DriverService.UpdateBookingStatus(ctx, userID, payload.BookingID, payload.Status)
+	case CommandUpdateStatus:
+		var payload struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+			return errors.New("Invalid input")
+		}
+		return h.DriverService.UpdateStatus(ctx, userID, payload.Status)
+	default:
+		return errors.New("unknown command")
+	}
+}
+
+func commandReply(userID string, role string, result CommandResult) websocket.WebSocketMessage {
+	result.Version = messaging.CommandResultVersion
+	return websocket.WebSocketMessage{
+		UserID:  userID,
+		Role:    role,
+		Type:    CommandResultType,
+		Payload: result,
+	}
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"logi/internal/utils"
 	"logi/pkg/auth"
 	"logi/pkg/websocket"
 
@@ -11,18 +16,46 @@ import (
 	websk "github.com/gorilla/websocket"
 )
 
-func ServeWs(authService *auth.AuthService, hub *websocket.WebSocketHub, allowedOrigins map[string]struct{}, c *gin.Context) {
+func ServeWs(authService *auth.AuthService, hub *websocket.WebSocketHub, commands *WebSocketCommandHandler, allowedOrigins map[string]struct{}, c *gin.Context) {
+	ctx := c.Request.Context()
 	tokenString := tokenFromRequest(c)
 	if tokenString == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token missing"})
 		return
 	}
 
-	userID, role, err := authService.ValidateJWT(tokenString)
-	if err != nil {
+	claims, err := authService.Authenticate(ctx, tokenString)
+	if errors.Is(err, auth.ErrTokenRevoked) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidToken) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 		return
 	}
+	if err != nil {
+		utils.Error(ctx, "failed to check token revocation", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+		return
+	}
+	userID, role := claims.Subject, claims.Role
+
+	var topics []string
+	if role == websocket.RoleAdmin {
+		// Admins only receive admin messages unless they subscribe to topics,
+		// e.g. ?topics=drivers.status,bookings.* or ?topics=> for everything.
+		// Subscriptions can also be changed later with subscribe frames.
+		for _, topic := range strings.Split(c.Query("topics"), ",") {
+			if topic = strings.TrimSpace(topic); topic == "" {
+				continue
+			}
+			if err := websocket.ValidateTopicPattern(topic); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid topic: " + topic})
+				return
+			}
+			topics = append(topics, topic)
+		}
+	}
 
 	upgrader := websk.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
@@ -44,16 +77,28 @@ func ServeWs(authService *auth.AuthService, hub *websocket.WebSocketHub, allowed
 		return
 	}
 
-	hub.RegisterClient(userID, role, conn)
-
-	go func() {
-		defer hub.UnregisterClient(userID, role, conn)
-		for {
-			if _, _, readErr := conn.ReadMessage(); readErr != nil {
-				break
-			}
+	hub.RegisterClient(userID, role, conn, topics...)
+	// The handshake is the only time the token is presented, so tie the
+	// connection to it: close at expiry, or once it is revoked by a logout,
+	// refresh family revocation or password reset.
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	hub.Authorize(userID, role, conn, expiresAt, func(ctx context.Context) (bool, error) {
+		_, err := authService.Authenticate(ctx, tokenString)
+		if errors.Is(err, auth.ErrTokenRevoked) || errors.Is(err, auth.ErrInvalidToken) {
+			return false, nil
 		}
-	}()
+		return err == nil, err
+	})
+	if lastSeq, parseErr := strconv.ParseUint(c.Query("last_seq"), 10, 64); parseErr == nil {
+		// Registering first means nothing published during the replay is missed.
+		if replayErr := hub.Replay(ctx, userID, role, conn, c.Query("stream"), lastSeq); replayErr != nil {
+			utils.Warn(ctx, "failed to replay websocket messages", "user_id", userID, "role", role, "last_seq", lastSeq, "error", replayErr)
+		}
+	}
+	go hub.ReadPump(userID, role, conn, commands.Handle)
 }
 
 func tokenFromRequest(c *gin.Context) string {
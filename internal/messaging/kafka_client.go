@@ -0,0 +1,63 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"logi/pkg/websocket"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// DefaultKafkaTopic carries every websocket message when messaging_type is kafka.
+const DefaultKafkaTopic = "logi.messages"
+
+// kafkaDeliveryTimeout bounds how long Publish waits for the brokers.
+const kafkaDeliveryTimeout = 10 * time.Second
+
+// KafkaClient publishes messages to a single Kafka topic read by every
+// instance's KafkaRouter. Records are keyed by the recipient's NATS-style
+// subject, so the messages of one recipient share a partition and stay in order.
+type KafkaClient struct {
+	Client *kgo.Client
+	Topic  string
+	// Outbox, when set, sequences messages for one recipient before they
+	// are published, as for NATSClient.Outbox.
+	Outbox websocket.Outbox
+}
+
+func NewKafkaClient(brokers []string, topic string) (*KafkaClient, error) {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.DefaultProduceTopic(topic),
+		kgo.AllowAutoTopicCreation(),
+		kgo.RecordDeliveryTimeout(kafkaDeliveryTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaDeliveryTimeout)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &KafkaClient{Client: client, Topic: topic}, nil
+}
+
+// Publish produces the message, topics included, and waits for the brokers to
+// acknowledge it. Topic copies are made by the routers reading the topic.
+func (k *KafkaClient) Publish(recipient Recipient, messageType string, payload interface{}) error {
+	message := newMessage(k.Outbox, recipient, messageType, payload)
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	key := RecipientSubject(recipient.Role, recipient.ID)
+	if recipient.ID == "" {
+		key = AdminBroadcastSubject(messageType)
+	}
+	record := &kgo.Record{Topic: k.Topic, Key: []byte(key), Value: data}
+	return k.Client.ProduceSync(context.Background(), record).FirstErr()
+}
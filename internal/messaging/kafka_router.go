@@ -0,0 +1,85 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"logi/internal/utils"
+	"logi/pkg/websocket"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// KafkaRouter relays the messages of a Kafka topic into the local hub. Every
+// instance reads every partition without a consumer group, starting with the
+// messages produced after the router was created, and drops those meant for
+// connections it does not hold.
+type KafkaRouter struct {
+	Client *kgo.Client
+	Hub    *websocket.WebSocketHub
+
+	relay  *streamRelay
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewKafkaRouter(brokers []string, topic string, hub *websocket.WebSocketHub) (*KafkaRouter, error) {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AfterMilli(time.Now().UnixMilli())),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaRouter{Client: client, Hub: hub, relay: newStreamRelay(hub)}, nil
+}
+
+// Start registers the router with the hub and starts consuming.
+func (r *KafkaRouter) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	r.Hub.SetPresenceListener(r.relay)
+	go r.run(ctx)
+}
+
+// Stop detaches the router from the hub, stops consuming and closes the client.
+func (r *KafkaRouter) Stop() {
+	r.Hub.SetPresenceListener(nil)
+	r.relay.reset()
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+	r.Client.Close()
+}
+
+func (r *KafkaRouter) run(ctx context.Context) {
+	defer close(r.done)
+	for {
+		fetches := r.Client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			utils.IncrementCounter("kafka_fetch_errors", 1)
+			utils.WarnBackground("failed to fetch kafka messages", "topic", topic, "partition", partition, "error", err)
+		})
+		fetches.EachRecord(func(record *kgo.Record) {
+			var message Message
+			if err := json.Unmarshal(record.Value, &message); err != nil {
+				utils.WarnBackground("discarding malformed kafka message", "topic", record.Topic, "offset", record.Offset, "error", err)
+				return
+			}
+			if err := r.relay.relay(message); err != nil {
+				utils.WarnBackground("failed to relay kafka message", "topic", record.Topic, "offset", record.Offset, "error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package messaging
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"logi/pkg/websocket"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+)
+
+func runKafkaCluster(t *testing.T) []string {
+	t.Helper()
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, DefaultKafkaTopic))
+	if err != nil {
+		t.Fatalf("failed to start kafka cluster: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+	return cluster.ListenAddrs()
+}
+
+func newKafkaInstance(t *testing.T, brokers []string) *websocket.WebSocketHub {
+	t.Helper()
+
+	hub := websocket.NewWebSocketHub()
+	go hub.Run()
+	router, err := NewKafkaRouter(brokers, DefaultKafkaTopic, hub)
+	if err != nil {
+		t.Fatalf("NewKafkaRouter returned error: %v", err)
+	}
+	router.Start()
+	t.Cleanup(router.Stop)
+	return hub
+}
+
+func TestKafkaRouterDeliversToLocalClientsAndMirrorsTopics(t *testing.T) {
+	t.Parallel()
+
+	brokers := runKafkaCluster(t)
+	hubA := newKafkaInstance(t, brokers)
+	hubB := newKafkaInstance(t, brokers)
+	publisher, err := NewKafkaClient(brokers, DefaultKafkaTopic)
+	if err != nil {
+		t.Fatalf("NewKafkaClient returned error: %v", err)
+	}
+	t.Cleanup(publisher.Client.Close)
+
+	adminServer, adminClient := connPair(t)
+	hubA.RegisterClient("admin-1", websocket.RoleAdmin, adminServer, "bookings.*", websocket.TopicAll)
+	userServer, userClient := connPair(t)
+	hubB.RegisterClient("user-1", websocket.RoleUser, userServer)
+
+	userMessage := ToUser("user-1").WithTopics(BookingTopic("b-1"), BookingTopic("b-1"))
+	for _, messageType := range []string{"booking_accepted", "status_update"} {
+		if err := publisher.Publish(userMessage, messageType, map[string]string{"booking_id": "b-1"}); err != nil {
+			t.Fatalf("Publish returned error: %v", err)
+		}
+	}
+	if err := publisher.Publish(ToDriver("driver-1"), "new_booking_request", nil); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+	if err := publisher.Publish(ToAdmins(), "driver_status_update", nil); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+
+	if got := readTypes(userClient, time.Second); strings.Join(got, " ") != "booking_accepted status_update" {
+		t.Fatalf("expected the user's messages in order, got %v", got)
+	}
+	got := readTypes(adminClient, time.Second)
+	sort.Strings(got)
+	if strings.Join(got, " ") != "booking_accepted driver_status_update status_update" {
+		t.Fatalf("expected each topic copy and the admin broadcast exactly once, got %v", got)
+	}
+}
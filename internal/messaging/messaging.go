@@ -1,11 +1,64 @@
 package messaging
 
+import "logi/pkg/websocket"
+
 type MessagingClient interface {
-	Publish(userID string, messageType string, payload interface{}) error
+	Publish(recipient Recipient, messageType string, payload interface{}) error
+}
+
+// Recipient addresses a message to a role and, except for admin broadcasts, an ID.
+// Topics tag the message so subscribed admins receive a copy.
+type Recipient struct {
+	Role   string
+	ID     string
+	Topics []string
+}
+
+// WithTopics returns a copy of r tagged with topics.
+func (r Recipient) WithTopics(topics ...string) Recipient {
+	r.Topics = append(append([]string(nil), r.Topics...), topics...)
+	return r
+}
+
+// TopicDriversStatus carries driver availability changes.
+const TopicDriversStatus = "drivers.status"
+
+// BookingTopic carries every update about one booking.
+func BookingTopic(bookingID string) string {
+	return "bookings." + bookingID
+}
+
+// DriverLocationTopic carries one driver's location updates.
+func DriverLocationTopic(driverID string) string {
+	return "driver." + driverID + ".location"
+}
+
+// ToUser addresses a single user.
+func ToUser(userID string) Recipient {
+	return Recipient{Role: websocket.RoleUser, ID: userID}
+}
+
+// ToDriver addresses a single driver.
+func ToDriver(driverID string) Recipient {
+	return Recipient{Role: websocket.RoleDriver, ID: driverID}
+}
+
+// ToAdmin addresses a single admin.
+func ToAdmin(adminID string) Recipient {
+	return Recipient{Role: websocket.RoleAdmin, ID: adminID}
+}
+
+// ToAdmins addresses every connected admin.
+func ToAdmins() Recipient {
+	return Recipient{Role: websocket.RoleAdmin}
 }
 
 type Message struct {
 	UserID  string      `json:"user_id"`
+	Role    string      `json:"role,omitempty"`
+	Seq     uint64      `json:"seq,omitempty"`
+	Stream  string      `json:"stream,omitempty"`
 	Type    string      `json:"type"`
+	Topics  []string    `json:"topics,omitempty"`
 	Payload interface{} `json:"payload"`
 }
@@ -0,0 +1,82 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/pkg/websocket"
+)
+
+// MongoOutbox implements websocket.Outbox on top of an OutboxRepository, so
+// replay survives restarts and works across instances sharing the database.
+// Its sequences are never lost, so every recipient has the one empty stream.
+type MongoOutbox struct {
+	Repo            repositories.OutboxRepository
+	MaxPerRecipient int
+}
+
+func NewMongoOutbox(repo repositories.OutboxRepository, maxPerRecipient int) *MongoOutbox {
+	return &MongoOutbox{Repo: repo, MaxPerRecipient: maxPerRecipient}
+}
+
+func (o *MongoOutbox) Append(ctx context.Context, msg websocket.WebSocketMessage) (websocket.WebSocketMessage, error) {
+	payload, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	seq, err := o.Repo.NextSeq(ctx, msg.Role, msg.UserID)
+	if err != nil {
+		return msg, err
+	}
+
+	err = o.Repo.Insert(ctx, &models.OutboxMessage{
+		Role:        msg.Role,
+		RecipientID: msg.UserID,
+		Seq:         seq,
+		Type:        msg.Type,
+		Payload:     payload,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		return msg, err
+	}
+	if seq > uint64(o.MaxPerRecipient) {
+		if err := o.Repo.DeleteUpTo(ctx, msg.Role, msg.UserID, seq-uint64(o.MaxPerRecipient)); err != nil {
+			return msg, err
+		}
+	}
+
+	msg.Seq = seq
+	return msg, nil
+}
+
+func (o *MongoOutbox) Since(ctx context.Context, role, userID, stream string, afterSeq uint64) ([]websocket.WebSocketMessage, error) {
+	if stream != "" {
+		afterSeq = 0
+	}
+	stored, err := o.Repo.FindAfter(ctx, role, userID, afterSeq)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]websocket.WebSocketMessage, 0, len(stored))
+	for _, m := range stored {
+		messages = append(messages, websocket.WebSocketMessage{
+			UserID:  m.RecipientID,
+			Role:    m.Role,
+			Seq:     m.Seq,
+			Type:    m.Type,
+			Payload: json.RawMessage(m.Payload),
+		})
+	}
+	return messages, nil
+}
+
+func (o *MongoOutbox) Ack(ctx context.Context, role, userID, stream string, seq uint64) error {
+	if stream != "" {
+		return nil
+	}
+	return o.Repo.DeleteUpTo(ctx, role, userID, seq)
+}
@@ -1,14 +1,52 @@
 package messaging
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"time"
+
+	"logi/internal/utils"
+	"logi/pkg/websocket"
 
 	"github.com/nats-io/nats.go"
 )
 
+// Subjects are namespaced under SubjectPrefix:
+//
+//	logi.user.<id>           messages for one user
+//	logi.driver.<id>         messages for one driver
+//	logi.admin.direct.<id>   messages for one admin
+//	logi.admin.<type>        broadcasts to every admin
+//	logi.topic.<topic>       copies for admins subscribed to a topic
+const SubjectPrefix = "logi"
+
+// RecipientSubject returns the subject carrying messages for one recipient.
+func RecipientSubject(role, id string) string {
+	if role == websocket.RoleAdmin {
+		return SubjectPrefix + ".admin.direct." + id
+	}
+	return SubjectPrefix + "." + role + "." + id
+}
+
+// AdminBroadcastSubject returns the subject of an admin broadcast.
+func AdminBroadcastSubject(messageType string) string {
+	return SubjectPrefix + ".admin." + messageType
+}
+
+// TopicSubject returns the subject carrying copies of messages tagged with
+// topic. Topic patterns use NATS wildcards, so they map onto subjects as is.
+func TopicSubject(topic string) string {
+	return SubjectPrefix + ".topic." + topic
+}
+
+const topicSubjectPrefix = SubjectPrefix + ".topic."
+
 type NATSClient struct {
 	Conn *nats.Conn
+	// Outbox, when set, sequences messages for one recipient before they
+	// are published, so every instance relays the same seq. It should be
+	// shared between instances, i.e. a MongoOutbox.
+	Outbox websocket.Outbox
 }
 
 func NewNATSClient(url string) (*NATSClient, error) {
@@ -19,31 +57,79 @@ func NewNATSClient(url string) (*NATSClient, error) {
 	return &NATSClient{Conn: nc}, nil
 }
 
-func (n *NATSClient) Publish(userID string, messageType string, payload interface{}) error {
-	message := Message{
-		UserID:  userID,
-		Type:    messageType,
-		Payload: payload,
-	}
+// Publish sends the message on the recipient's subject, and for user and
+// driver messages once more on the subject of each topic.
+func (n *NATSClient) Publish(recipient Recipient, messageType string, payload interface{}) error {
+	message := newMessage(n.Outbox, recipient, messageType, payload)
 	data, err := json.Marshal(message)
 	if err != nil {
 		return err
 	}
+	subject := RecipientSubject(recipient.Role, recipient.ID)
+	if recipient.ID == "" {
+		subject = AdminBroadcastSubject(messageType)
+	}
+	if err := n.Conn.Publish(subject, data); err != nil {
+		return err
+	}
 
-	subject := userID
-	if subject == "" {
-		subject = fmt.Sprintf("admin.%s", messageType)
+	if recipient.Role == websocket.RoleAdmin || len(recipient.Topics) == 0 {
+		return nil
 	}
+	message.Seq, message.Stream = 0, ""
+	if data, err = json.Marshal(message); err != nil {
+		return err
+	}
+	published := make(map[string]struct{}, len(recipient.Topics))
+	for _, topic := range recipient.Topics {
+		if _, ok := published[topic]; ok {
+			continue
+		}
+		published[topic] = struct{}{}
+		if err := n.Conn.Publish(TopicSubject(topic), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return n.Conn.Publish(subject, data)
+// outboxTimeout bounds sequencing one published message.
+const outboxTimeout = 5 * time.Second
+
+// newMessage builds the message published for recipient. When outbox is set,
+// messages for one recipient are sequenced before they are published, so every
+// instance relays the same seq. Ephemeral types are published without one.
+func newMessage(outbox websocket.Outbox, recipient Recipient, messageType string, payload interface{}) Message {
+	message := Message{
+		UserID:  recipient.ID,
+		Role:    recipient.Role,
+		Type:    messageType,
+		Topics:  recipient.Topics,
+		Payload: payload,
+	}
+	if outbox == nil || recipient.ID == "" || isEphemeral(messageType) {
+		return message
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), outboxTimeout)
+	defer cancel()
+	sequenced, err := outbox.Append(ctx, toWebSocketMessage(message))
+	if err != nil {
+		utils.IncrementCounter("websocket_outbox_errors", 1)
+		utils.ErrorBackground("failed to store websocket message in outbox", "user_id", recipient.ID, "role", recipient.Role, "type", messageType, "error", err)
+		return message
+	}
+	message.Seq, message.Stream = sequenced.Seq, sequenced.Stream
+	return message
 }
 
-func (n *NATSClient) SubscribeAll(handler func(Message) error) (*nats.Subscription, error) {
-	return n.Conn.Subscribe(">", func(msg *nats.Msg) {
-		var message Message
-		if err := json.Unmarshal(msg.Data, &message); err != nil {
-			return
-		}
-		_ = handler(message)
-	})
+func toWebSocketMessage(message Message) websocket.WebSocketMessage {
+	return websocket.WebSocketMessage{
+		UserID:  message.UserID,
+		Role:    message.Role,
+		Seq:     message.Seq,
+		Stream:  message.Stream,
+		Type:    message.Type,
+		Topics:  message.Topics,
+		Payload: message.Payload,
+	}
 }
@@ -0,0 +1,134 @@
+package messaging
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"logi/internal/utils"
+	"logi/pkg/websocket"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSRouter relays NATS messages into the local hub. It implements
+// websocket.PresenceListener, so an instance subscribes only to the subjects
+// of its own connections: one per connected recipient, admin broadcasts while
+// an admin is connected, and one per topic pattern admins subscribed to.
+type NATSRouter struct {
+	Conn *nats.Conn
+	Hub  *websocket.WebSocketHub
+
+	mu     sync.Mutex
+	subs   map[string]*nats.Subscription
+	admins int
+}
+
+func NewNATSRouter(conn *nats.Conn, hub *websocket.WebSocketHub) *NATSRouter {
+	return &NATSRouter{Conn: conn, Hub: hub, subs: make(map[string]*nats.Subscription)}
+}
+
+// Start registers the router with the hub, subscribing for connections that
+// are already open.
+func (r *NATSRouter) Start() {
+	r.Hub.SetPresenceListener(r)
+}
+
+// Stop detaches the router from the hub and drops its subscriptions.
+func (r *NATSRouter) Stop() {
+	r.Hub.SetPresenceListener(nil)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for subject, sub := range r.subs {
+		_ = sub.Unsubscribe()
+		delete(r.subs, subject)
+	}
+	r.admins = 0
+}
+
+func (r *NATSRouter) RecipientOnline(role, userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribe(RecipientSubject(role, userID), r.relayDirect)
+	if role == websocket.RoleAdmin {
+		r.admins++
+		if r.admins == 1 {
+			r.subscribe(AdminBroadcastSubject("*"), r.relayDirect)
+		}
+	}
+}
+
+func (r *NATSRouter) RecipientOffline(role, userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unsubscribe(RecipientSubject(role, userID))
+	if role == websocket.RoleAdmin {
+		r.admins--
+		if r.admins == 0 {
+			r.unsubscribe(AdminBroadcastSubject("*"))
+		}
+	}
+}
+
+func (r *NATSRouter) TopicSubscribed(pattern string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribe(TopicSubject(pattern), func(msg *nats.Msg) {
+		r.relayMirror(msg, pattern)
+	})
+}
+
+func (r *NATSRouter) TopicUnsubscribed(pattern string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unsubscribe(TopicSubject(pattern))
+}
+
+// subscribe and unsubscribe must be called with mu held.
+func (r *NATSRouter) subscribe(subject string, handler nats.MsgHandler) {
+	if _, ok := r.subs[subject]; ok {
+		return
+	}
+	sub, err := r.Conn.Subscribe(subject, handler)
+	if err != nil {
+		utils.ErrorBackground("failed to subscribe to nats subject", "subject", subject, "error", err)
+		return
+	}
+	r.subs[subject] = sub
+	utils.SetGauge("nats_subscriptions", int64(len(r.subs)))
+}
+
+func (r *NATSRouter) unsubscribe(subject string) {
+	sub, ok := r.subs[subject]
+	if !ok {
+		return
+	}
+	if err := sub.Unsubscribe(); err != nil {
+		utils.WarnBackground("failed to unsubscribe from nats subject", "subject", subject, "error", err)
+	}
+	delete(r.subs, subject)
+	utils.SetGauge("nats_subscriptions", int64(len(r.subs)))
+}
+
+func (r *NATSRouter) relayDirect(msg *nats.Msg) {
+	var message Message
+	if err := json.Unmarshal(msg.Data, &message); err != nil {
+		utils.WarnBackground("discarding malformed nats message", "subject", msg.Subject, "error", err)
+		return
+	}
+	if err := r.Hub.BroadcastDirect(toWebSocketMessage(message)); err != nil {
+		utils.WarnBackground("failed to relay nats message", "subject", msg.Subject, "error", err)
+	}
+}
+
+func (r *NATSRouter) relayMirror(msg *nats.Msg, pattern string) {
+	var message Message
+	if err := json.Unmarshal(msg.Data, &message); err != nil {
+		utils.WarnBackground("discarding malformed nats message", "subject", msg.Subject, "error", err)
+		return
+	}
+	topic := strings.TrimPrefix(msg.Subject, topicSubjectPrefix)
+	if err := r.Hub.BroadcastMirror(toWebSocketMessage(message), topic, pattern); err != nil {
+		utils.WarnBackground("failed to relay nats message", "subject", msg.Subject, "error", err)
+	}
+}
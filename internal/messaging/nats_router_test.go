@@ -0,0 +1,178 @@
+package messaging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"logi/pkg/websocket"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func runNATSServer(t *testing.T) string {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("failed to create nats server: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server did not start")
+	}
+	t.Cleanup(srv.Shutdown)
+	return srv.ClientURL()
+}
+
+// newInstance returns a hub relaying from NATS the way one backend instance does.
+func newInstance(t *testing.T, url string) (*websocket.WebSocketHub, *NATSRouter) {
+	t.Helper()
+
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatalf("failed to connect to nats: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	hub := websocket.NewWebSocketHub()
+	go hub.Run()
+	router := NewNATSRouter(conn, hub)
+	router.Start()
+	t.Cleanup(router.Stop)
+	return hub, router
+}
+
+func newPublisher(t *testing.T, url string) *NATSClient {
+	t.Helper()
+
+	client, err := NewNATSClient(url)
+	if err != nil {
+		t.Fatalf("failed to connect to nats: %v", err)
+	}
+	t.Cleanup(client.Conn.Close)
+	return client
+}
+
+// connPair returns the server and client ends of a real websocket connection.
+func connPair(t *testing.T) (*gorilla.Conn, *gorilla.Conn) {
+	t.Helper()
+
+	serverConns := make(chan *gorilla.Conn, 1)
+	upgrader := gorilla.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		serverConns <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	clientConn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { clientConn.Close() })
+
+	return <-serverConns, clientConn
+}
+
+func (r *NATSRouter) subjects() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subjects := make([]string, 0, len(r.subs))
+	for subject := range r.subs {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	return subjects
+}
+
+// readTypes collects the message types received on conn until it goes quiet.
+func readTypes(conn *gorilla.Conn, quiet time.Duration) []string {
+	var types []string
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(quiet))
+		var msg websocket.WebSocketMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return types
+		}
+		types = append(types, msg.Type)
+	}
+}
+
+func TestNATSRouterSubscribesOnlyForLocalClients(t *testing.T) {
+	t.Parallel()
+
+	url := runNATSServer(t)
+	_, routerA := newInstance(t, url)
+	hubB, routerB := newInstance(t, url)
+	publisher := newPublisher(t, url)
+
+	serverConn, clientConn := connPair(t)
+	hubB.RegisterClient("user-1", websocket.RoleUser, serverConn)
+
+	if got := routerB.subjects(); len(got) != 1 || got[0] != "logi.user.user-1" {
+		t.Fatalf("instance B should subscribe to its user only, got %v", got)
+	}
+	if got := routerA.subjects(); len(got) != 0 {
+		t.Fatalf("instance A has no clients and should not subscribe, got %v", got)
+	}
+	if err := routerB.Conn.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	if err := publisher.Publish(ToUser("user-1"), "booking_accepted", map[string]string{"booking_id": "b-1"}); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+	if got := readTypes(clientConn, 500*time.Millisecond); len(got) != 1 || got[0] != "booking_accepted" {
+		t.Fatalf("expected one booking_accepted message, got %v", got)
+	}
+
+	hubB.UnregisterClient("user-1", websocket.RoleUser, serverConn)
+	if got := routerB.subjects(); len(got) != 0 {
+		t.Fatalf("expected subscription to be dropped with the last connection, got %v", got)
+	}
+}
+
+func TestNATSRouterMirrorsTopicsOncePerAdmin(t *testing.T) {
+	t.Parallel()
+
+	url := runNATSServer(t)
+	hub, router := newInstance(t, url)
+	publisher := newPublisher(t, url)
+
+	serverConn, clientConn := connPair(t)
+	hub.RegisterClient("admin-1", websocket.RoleAdmin, serverConn, "bookings.*", websocket.TopicAll)
+
+	want := []string{"logi.admin.*", "logi.admin.direct.admin-1", "logi.topic.>", "logi.topic.bookings.*"}
+	if got := router.subjects(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("unexpected subscriptions: %v", got)
+	}
+	if err := router.Conn.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	// The driver message is relayed on two topic subjects and reaches two of
+	// the admin's patterns, yet must be delivered once.
+	driverMessage := ToDriver("driver-1").WithTopics(BookingTopic("b-1"), TopicDriversStatus)
+	if err := publisher.Publish(driverMessage, "new_booking_request", nil); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+	if err := publisher.Publish(ToAdmins(), "driver_status_update", nil); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+
+	got := readTypes(clientConn, 500*time.Millisecond)
+	sort.Strings(got)
+	if strings.Join(got, " ") != "driver_status_update new_booking_request" {
+		t.Fatalf("expected each message exactly once, got %v", got)
+	}
+}
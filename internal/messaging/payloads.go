@@ -0,0 +1,190 @@
+package messaging
+
+import (
+	"time"
+
+	"logi/internal/models"
+)
+
+// Server-to-client message types. Every type has a payload struct below and
+// an entry in Registry; schemas/messages holds the JSON Schema generated
+// from each struct.
+const (
+	TypeNewBookingRequest  = "new_booking_request"
+	TypeBookingAccepted    = "booking_accepted"
+	TypeDriverLocation     = "driver_location"
+	TypeStatusUpdate       = "status_update"
+	TypeDriverStatusUpdate = "driver_status_update"
+	TypeCommandResult      = "command_result"
+)
+
+// EphemeralTypes are only worth delivering live: a missed location update is
+// superseded by the next one, so they are never stored for replay.
+var EphemeralTypes = []string{TypeDriverLocation}
+
+func isEphemeral(messageType string) bool {
+	for _, t := range EphemeralTypes {
+		if t == messageType {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload versions. Bump a version whenever its payload changes in a way
+// clients can observe, and regenerate the schemas with go generate.
+const (
+	NewBookingRequestVersion  = 1
+	BookingAcceptedVersion    = 1
+	DriverLocationVersion     = 1
+	StatusUpdateVersion       = 1
+	DriverStatusUpdateVersion = 1
+	CommandResultVersion      = 1
+)
+
+// NewBookingRequestPayload offers a booking to a driver. It carries what the
+// driver needs to decide, not the booking's dispatch bookkeeping.
+type NewBookingRequestPayload struct {
+	Version         int             `json:"version"`
+	BookingID       string          `json:"booking_id"`
+	UserID          string          `json:"user_id"`
+	PickupLocation  models.Location `json:"pickup_location"`
+	DropoffLocation models.Location `json:"dropoff_location"`
+	PickupPlace     *models.Place   `json:"pickup_place,omitempty"`
+	DropoffPlace    *models.Place   `json:"dropoff_place,omitempty"`
+	VehicleType     string          `json:"vehicle_type"`
+	PriceEstimate   float64         `json:"price_estimate"`
+	ScheduledTime   *time.Time      `json:"scheduled_time,omitempty"`
+}
+
+// NewBookingRequest builds the offer of booking sent to each candidate driver.
+func NewBookingRequest(booking *models.Booking) NewBookingRequestPayload {
+	return NewBookingRequestPayload{
+		Version:         NewBookingRequestVersion,
+		BookingID:       booking.ID,
+		UserID:          booking.UserID,
+		PickupLocation:  booking.PickupLocation,
+		DropoffLocation: booking.DropoffLocation,
+		PickupPlace:     booking.PickupPlace,
+		DropoffPlace:    booking.DropoffPlace,
+		VehicleType:     booking.VehicleType,
+		PriceEstimate:   booking.PriceEstimate,
+		ScheduledTime:   booking.ScheduledTime,
+	}
+}
+
+// BookingAcceptedPayload tells a user which driver accepted their booking.
+type BookingAcceptedPayload struct {
+	Version   int    `json:"version"`
+	BookingID string `json:"booking_id"`
+	DriverID  string `json:"driver_id"`
+}
+
+func NewBookingAccepted(bookingID, driverID string) BookingAcceptedPayload {
+	return BookingAcceptedPayload{Version: BookingAcceptedVersion, BookingID: bookingID, DriverID: driverID}
+}
+
+// DriverLocationPayload reports the assigned driver's position during a trip.
+type DriverLocationPayload struct {
+	Version   int     `json:"version"`
+	BookingID string  `json:"booking_id"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func NewDriverLocation(bookingID string, latitude, longitude float64) DriverLocationPayload {
+	return DriverLocationPayload{Version: DriverLocationVersion, BookingID: bookingID, Latitude: latitude, Longitude: longitude}
+}
+
+// StatusUpdatePayload reports a booking's new status.
+type StatusUpdatePayload struct {
+	Version   int    `json:"version"`
+	BookingID string `json:"booking_id"`
+	Status    string `json:"status"`
+}
+
+func NewStatusUpdate(bookingID, status string) StatusUpdatePayload {
+	return StatusUpdatePayload{Version: StatusUpdateVersion, BookingID: bookingID, Status: status}
+}
+
+// DriverStatusUpdatePayload reports a driver's new availability to admins.
+type DriverStatusUpdatePayload struct {
+	Version  int    `json:"version"`
+	DriverID string `json:"driver_id"`
+	Status   string `json:"status"`
+}
+
+func NewDriverStatusUpdate(driverID, status string) DriverStatusUpdatePayload {
+	return DriverStatusUpdatePayload{Version: DriverStatusUpdateVersion, DriverID: driverID, Status: status}
+}
+
+// CommandResultPayload reports the outcome of one inbound websocket command.
+// ID echoes the command's client-chosen ID.
+type CommandResultPayload struct {
+	Version int    `json:"version"`
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	// Topics lists the connection's subscriptions after subscribe/unsubscribe.
+	Topics []string `json:"topics,omitempty"`
+}
+
+// MessageType documents one server-to-client message type.
+type MessageType struct {
+	Name        string
+	Version     int
+	Description string
+	// Payload is a zero value of the payload struct the schema is generated from.
+	Payload interface{}
+}
+
+// Registry lists every message type the server sends, in documentation order.
+var Registry = []MessageType{
+	{
+		Name:        TypeNewBookingRequest,
+		Version:     NewBookingRequestVersion,
+		Description: "Sent to each candidate driver when a booking is offered to them.",
+		Payload:     NewBookingRequestPayload{},
+	},
+	{
+		Name:        TypeBookingAccepted,
+		Version:     BookingAcceptedVersion,
+		Description: "Sent to the user when a driver accepts their booking.",
+		Payload:     BookingAcceptedPayload{},
+	},
+	{
+		Name:        TypeDriverLocation,
+		Version:     DriverLocationVersion,
+		Description: "Sent to the user with the assigned driver's position during a trip.",
+		Payload:     DriverLocationPayload{},
+	},
+	{
+		Name:        TypeStatusUpdate,
+		Version:     StatusUpdateVersion,
+		Description: "Sent to the user when their booking changes status.",
+		Payload:     StatusUpdatePayload{},
+	},
+	{
+		Name:        TypeDriverStatusUpdate,
+		Version:     DriverStatusUpdateVersion,
+		Description: "Sent to admins when a driver's availability changes.",
+		Payload:     DriverStatusUpdatePayload{},
+	},
+	{
+		Name:        TypeCommandResult,
+		Version:     CommandResultVersion,
+		Description: "Sent on the connection that issued a command, once per command.",
+		Payload:     CommandResultPayload{},
+	},
+}
+
+// LookupMessageType returns the registry entry for name.
+func LookupMessageType(name string) (MessageType, bool) {
+	for _, messageType := range Registry {
+		if messageType.Name == name {
+			return messageType, true
+		}
+	}
+	return MessageType{}, false
+}
@@ -0,0 +1,57 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+
+	"logi/pkg/websocket"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisStream carries every websocket message when messaging_type is redis.
+const DefaultRedisStream = "logi:messages"
+
+// redisMessageField is the stream entry field holding the encoded Message.
+const redisMessageField = "message"
+
+// RedisStreamsClient appends messages to a single Redis stream read by every
+// instance's RedisStreamsRouter. The stream is trimmed to about MaxLen entries.
+type RedisStreamsClient struct {
+	Client *redis.Client
+	Stream string
+	MaxLen int64
+	// Outbox, when set, sequences messages for one recipient before they
+	// are published, as for NATSClient.Outbox.
+	Outbox websocket.Outbox
+}
+
+// NewRedisStreamsClient connects to the redis:// or rediss:// URL.
+func NewRedisStreamsClient(url string, stream string, maxLen int64) (*RedisStreamsClient, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &RedisStreamsClient{Client: client, Stream: stream, MaxLen: maxLen}, nil
+}
+
+// Publish appends the message, topics included. Topic copies are made by the
+// routers reading the stream.
+func (r *RedisStreamsClient) Publish(recipient Recipient, messageType string, payload interface{}) error {
+	message := newMessage(r.Outbox, recipient, messageType, payload)
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return r.Client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: r.Stream,
+		MaxLen: r.MaxLen,
+		Approx: true,
+		Values: map[string]interface{}{redisMessageField: data},
+	}).Err()
+}
@@ -0,0 +1,110 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"logi/internal/utils"
+	"logi/pkg/websocket"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisReadBlock bounds each blocking read, so Stop returns promptly.
+const redisReadBlock = time.Second
+
+// RedisStreamsRouter relays the entries of a Redis stream into the local hub.
+// Every instance reads the whole stream without a consumer group, starting
+// after the last entry present when the router starts, and drops messages
+// meant for connections it does not hold.
+type RedisStreamsRouter struct {
+	Client *redis.Client
+	Stream string
+	Hub    *websocket.WebSocketHub
+
+	relay  *streamRelay
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewRedisStreamsRouter(client *redis.Client, stream string, hub *websocket.WebSocketHub) *RedisStreamsRouter {
+	return &RedisStreamsRouter{Client: client, Stream: stream, Hub: hub, relay: newStreamRelay(hub)}
+}
+
+// Start registers the router with the hub and starts reading the stream.
+func (r *RedisStreamsRouter) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	r.Hub.SetPresenceListener(r.relay)
+	go r.run(ctx, r.lastID(ctx))
+}
+
+// Stop detaches the router from the hub and stops reading. The client is
+// shared with the publisher and left open.
+func (r *RedisStreamsRouter) Stop() {
+	r.Hub.SetPresenceListener(nil)
+	r.relay.reset()
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+}
+
+// lastID returns the ID of the newest entry, so reading resumes right after it.
+func (r *RedisStreamsRouter) lastID(ctx context.Context) string {
+	entries, err := r.Client.XRevRangeN(ctx, r.Stream, "+", "-", 1).Result()
+	if err != nil {
+		utils.WarnBackground("failed to read last redis stream entry", "stream", r.Stream, "error", err)
+		return "$"
+	}
+	if len(entries) == 0 {
+		return "0-0"
+	}
+	return entries[0].ID
+}
+
+func (r *RedisStreamsRouter) run(ctx context.Context, lastID string) {
+	defer close(r.done)
+	for ctx.Err() == nil {
+		streams, err := r.Client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{r.Stream, lastID},
+			Block:   redisReadBlock,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			utils.IncrementCounter("redis_stream_read_errors", 1)
+			utils.WarnBackground("failed to read redis stream", "stream", r.Stream, "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(redisReadBlock):
+			}
+			continue
+		}
+		for _, stream := range streams {
+			for _, entry := range stream.Messages {
+				lastID = entry.ID
+				r.relayEntry(entry)
+			}
+		}
+	}
+}
+
+func (r *RedisStreamsRouter) relayEntry(entry redis.XMessage) {
+	data, _ := entry.Values[redisMessageField].(string)
+	var message Message
+	if err := json.Unmarshal([]byte(data), &message); err != nil {
+		utils.WarnBackground("discarding malformed redis stream entry", "stream", r.Stream, "id", entry.ID, "error", err)
+		return
+	}
+	if err := r.relay.relay(message); err != nil {
+		utils.WarnBackground("failed to relay redis stream entry", "stream", r.Stream, "id", entry.ID, "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package messaging
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"logi/pkg/websocket"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newRedisStreamsInstance(t *testing.T, url string) (*websocket.WebSocketHub, *RedisStreamsClient) {
+	t.Helper()
+
+	client, err := NewRedisStreamsClient(url, DefaultRedisStream, 1000)
+	if err != nil {
+		t.Fatalf("NewRedisStreamsClient returned error: %v", err)
+	}
+	t.Cleanup(func() { client.Client.Close() })
+
+	hub := websocket.NewWebSocketHub()
+	go hub.Run()
+	router := NewRedisStreamsRouter(client.Client, DefaultRedisStream, hub)
+	router.Start()
+	t.Cleanup(router.Stop)
+	return hub, client
+}
+
+func TestRedisStreamsRouterDeliversToLocalClientsAndMirrorsTopics(t *testing.T) {
+	t.Parallel()
+
+	redisServer := miniredis.RunT(t)
+	url := "redis://" + redisServer.Addr()
+
+	// An entry written before the routers start must not be relayed.
+	early, err := NewRedisStreamsClient(url, DefaultRedisStream, 1000)
+	if err != nil {
+		t.Fatalf("NewRedisStreamsClient returned error: %v", err)
+	}
+	t.Cleanup(func() { early.Client.Close() })
+	if err := early.Publish(ToUser("user-1"), "stale_update", nil); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+
+	hubA, publisher := newRedisStreamsInstance(t, url)
+	hubB, _ := newRedisStreamsInstance(t, url)
+
+	adminServer, adminClient := connPair(t)
+	hubA.RegisterClient("admin-1", websocket.RoleAdmin, adminServer, "drivers.status")
+	userServer, userClient := connPair(t)
+	hubB.RegisterClient("user-1", websocket.RoleUser, userServer)
+
+	if err := publisher.Publish(ToUser("user-1"), "booking_accepted", map[string]string{"booking_id": "b-1"}); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+	if err := publisher.Publish(ToDriver("driver-1").WithTopics(TopicDriversStatus), "driver_status_update", nil); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+	if err := publisher.Publish(ToAdmin("admin-2"), "booking_alert", nil); err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+
+	if got := readTypes(userClient, time.Second); strings.Join(got, " ") != "booking_accepted" {
+		t.Fatalf("expected only the new booking_accepted message, got %v", got)
+	}
+	if got := readTypes(adminClient, time.Second); strings.Join(got, " ") != "driver_status_update" {
+		t.Fatalf("expected only the topic copy, got %v", got)
+	}
+}
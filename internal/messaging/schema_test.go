@@ -0,0 +1,78 @@
+package messaging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"logi/internal/models"
+)
+
+const schemaDir = "../../schemas/messages"
+
+func TestCheckedInSchemasMatchPayloadStructs(t *testing.T) {
+	t.Parallel()
+
+	for _, messageType := range Registry {
+		generated, err := GenerateSchema(messageType)
+		if err != nil {
+			t.Fatalf("GenerateSchema(%s) returned error: %v", messageType.Name, err)
+		}
+		checkedIn, err := os.ReadFile(filepath.Join(schemaDir, SchemaFileName(messageType.Name)))
+		if err != nil {
+			t.Fatalf("missing schema for %s: %v", messageType.Name, err)
+		}
+		if !bytes.Equal(generated, checkedIn) {
+			t.Errorf("schema for %s is out of date; run go generate ./internal/messaging", messageType.Name)
+		}
+	}
+
+	entries, err := os.ReadDir(schemaDir)
+	if err != nil {
+		t.Fatalf("failed to list schemas: %v", err)
+	}
+	if len(entries) != len(Registry) {
+		t.Fatalf("expected one schema per registered message type, found %d files for %d types", len(entries), len(Registry))
+	}
+}
+
+func TestValidatePayloadRejectsDrift(t *testing.T) {
+	t.Parallel()
+
+	messageType, _ := LookupMessageType(TypeNewBookingRequest)
+	schema, err := GenerateSchema(messageType)
+	if err != nil {
+		t.Fatalf("GenerateSchema returned error: %v", err)
+	}
+	booking := &models.Booking{
+		ID:                "booking-1",
+		UserID:            "user-1",
+		PickupLocation:    models.Location{Type: "Point", Coordinates: []float64{1, 2}},
+		DropoffLocation:   models.Location{Type: "Point", Coordinates: []float64{3, 4}},
+		VehicleType:       "car",
+		OfferedDriverIDs:  []string{"driver-1"},
+		RejectedDriverIDs: []string{"driver-2"},
+	}
+
+	if err := ValidatePayload(schema, NewBookingRequest(booking)); err != nil {
+		t.Fatalf("expected the typed payload to validate, got %v", err)
+	}
+
+	err = ValidatePayload(schema, booking)
+	if err == nil {
+		t.Fatal("expected the raw booking to fail validation")
+	}
+	for _, want := range []string{`missing property "version"`, `missing property "booking_id"`, `undocumented property "offered_driver_ids"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %s in %v", want, err)
+		}
+	}
+
+	stale := NewBookingRequest(booking)
+	stale.Version = NewBookingRequestVersion + 1
+	if err := ValidatePayload(schema, stale); err == nil {
+		t.Fatal("expected a payload with another version to fail validation")
+	}
+}
@@ -0,0 +1,118 @@
+package messaging
+
+import (
+	"sync"
+
+	"logi/pkg/websocket"
+)
+
+// streamRelay feeds messages read from a shared log, a Kafka topic or a Redis
+// stream, into the local hub. Unlike NATS subjects, a log is read in full by
+// every instance, so the relay implements websocket.PresenceListener to track
+// the hub's connections and drops messages no local connection is waiting for.
+// Messages are published once, with their topics, and mirrored here to the
+// local admins subscribed to a matching pattern.
+type streamRelay struct {
+	hub *websocket.WebSocketHub
+
+	mu         sync.Mutex
+	recipients map[string]struct{}
+	admins     int
+	patterns   map[string]struct{}
+}
+
+func newStreamRelay(hub *websocket.WebSocketHub) *streamRelay {
+	return &streamRelay{
+		hub:        hub,
+		recipients: make(map[string]struct{}),
+		patterns:   make(map[string]struct{}),
+	}
+}
+
+func (r *streamRelay) RecipientOnline(role, userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recipients[RecipientSubject(role, userID)] = struct{}{}
+	if role == websocket.RoleAdmin {
+		r.admins++
+	}
+}
+
+func (r *streamRelay) RecipientOffline(role, userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.recipients, RecipientSubject(role, userID))
+	if role == websocket.RoleAdmin {
+		r.admins--
+	}
+}
+
+func (r *streamRelay) TopicSubscribed(pattern string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns[pattern] = struct{}{}
+}
+
+func (r *streamRelay) TopicUnsubscribed(pattern string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.patterns, pattern)
+}
+
+// reset forgets every connection, once the relay is detached from the hub.
+func (r *streamRelay) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recipients = make(map[string]struct{})
+	r.admins = 0
+	r.patterns = make(map[string]struct{})
+}
+
+// relay delivers message to its local recipient and to the local admins
+// subscribed to one of its topics.
+func (r *streamRelay) relay(message Message) error {
+	direct, mirrors := r.route(message)
+	msg := toWebSocketMessage(message)
+	if direct {
+		if err := r.hub.BroadcastDirect(msg); err != nil {
+			return err
+		}
+	}
+	for _, m := range mirrors {
+		if err := r.hub.BroadcastMirror(msg, m.topic, m.pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type mirrorMatch struct {
+	topic   string
+	pattern string
+}
+
+func (r *streamRelay) route(message Message) (direct bool, mirrors []mirrorMatch) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if message.UserID == "" {
+		return r.admins > 0, nil
+	}
+	_, direct = r.recipients[RecipientSubject(message.Role, message.UserID)]
+	if message.Role == websocket.RoleAdmin {
+		return direct, nil
+	}
+	seen := make(map[string]struct{}, len(message.Topics))
+	for _, topic := range message.Topics {
+		if _, ok := seen[topic]; ok {
+			continue
+		}
+		seen[topic] = struct{}{}
+		for pattern := range r.patterns {
+			if websocket.MatchTopic(pattern, topic) {
+				mirrors = append(mirrors, mirrorMatch{topic: topic, pattern: pattern})
+			}
+		}
+	}
+	return direct, mirrors
+}
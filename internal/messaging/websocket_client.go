@@ -11,9 +11,11 @@ func NewWebSocketClient(hub *websocket.WebSocketHub) *WebSocketClient {
 	return &WebSocketClient{Hub: hub}
 }
 
-func (w *WebSocketClient) Publish(userID string, messageType string, payload interface{}) error {
+func (w *WebSocketClient) Publish(recipient Recipient, messageType string, payload interface{}) error {
 	message := websocket.WebSocketMessage{
-		UserID:  userID,
+		UserID:  recipient.ID,
+		Role:    recipient.Role,
+		Topics:  recipient.Topics,
 		Type:    messageType,
 		Payload: payload,
 	}
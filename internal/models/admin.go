@@ -2,12 +2,48 @@ package models
 
 import "time"
 
+// System admin roles hold every permission. Owners can also create owners and
+// revoke other admins. Admins created before roles existed have no role and
+// count as AdminRoleAdmin. Other roles are defined in the admin_roles
+// collection; see Role.
+const (
+	AdminRoleOwner = "owner"
+	AdminRoleAdmin = "admin"
+)
+
 type Admin struct {
-	ID           string    `bson:"_id,omitempty" json:"id,omitempty"`
-	Name         string    `bson:"name" json:"name"`
-	Email        string    `bson:"email" json:"email"`
-	PasswordHash string    `bson:"password_hash" json:"-"`
-	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+	ID           string `bson:"_id,omitempty" json:"id,omitempty"`
+	Name         string `bson:"name" json:"name"`
+	Email        string `bson:"email" json:"email"`
+	PasswordHash string `bson:"password_hash" json:"-"`
+	Role         string `bson:"role,omitempty" json:"role"`
+	// CreatedBy is the admin who created or invited this one; empty for bootstrapped owners.
+	CreatedBy string     `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// IsOwner reports whether the admin holds the owner role.
+func (a *Admin) IsOwner() bool {
+	return a.Role == AdminRoleOwner
+}
+
+// IsActive reports whether the admin may still log in.
+func (a *Admin) IsActive() bool {
+	return a.RevokedAt == nil
+}
+
+// AdminInvite lets the holder of an emailed token create an admin account
+// for Email. Only the SHA-256 of the token is stored.
+type AdminInvite struct {
+	ID         string     `bson:"_id" json:"id"`
+	Email      string     `bson:"email" json:"email"`
+	Role       string     `bson:"role" json:"role"`
+	TokenHash  string     `bson:"token_hash" json:"-"`
+	InvitedBy  string     `bson:"invited_by" json:"invited_by"`
+	ExpiresAt  time.Time  `bson:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	AcceptedAt *time.Time `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
 }
 
 type AdminStatistics struct {
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Audit event types.
+const (
+	AuditLoginLocked   = "login_locked"
+	AuditLoginUnlocked = "login_unlocked"
+)
+
+// AuditEvent records a security-relevant action in the audit_log collection.
+type AuditEvent struct {
+	ID   string `bson:"_id" json:"id"`
+	Type string `bson:"type" json:"type"`
+	// ActorID is the admin who acted; empty for events the system raised.
+	ActorID   string            `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	Subject   string            `bson:"subject" json:"subject"`
+	IP        string            `bson:"ip,omitempty" json:"ip,omitempty"`
+	Details   map[string]string `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
+}
@@ -8,6 +8,8 @@ type Booking struct {
 	DriverID             string     `bson:"driver_id,omitempty" json:"driver_id,omitempty"`
 	PickupLocation       Location   `bson:"pickup_location" json:"pickup_location"`
 	DropoffLocation      Location   `bson:"dropoff_location" json:"dropoff_location"`
+	PickupPlace          *Place     `bson:"pickup_place,omitempty" json:"pickup_place,omitempty"`
+	DropoffPlace         *Place     `bson:"dropoff_place,omitempty" json:"dropoff_place,omitempty"`
 	VehicleType          string     `bson:"vehicle_type" json:"vehicle_type"`
 	PriceEstimate        float64    `bson:"price_estimate" json:"price_estimate"`
 	Status               string     `bson:"status" json:"status"`
@@ -20,9 +22,13 @@ type Booking struct {
 	RejectedDriverIDs    []string   `bson:"rejected_driver_ids,omitempty" json:"rejected_driver_ids,omitempty"`
 }
 
+// BookingRequest accepts either coordinates or a place with an address line for each end;
+// addresses are geocoded when coordinates are missing.
 type BookingRequest struct {
 	PickupLocation  Location   `json:"pickup_location"`
 	DropoffLocation Location   `json:"dropoff_location"`
+	PickupPlace     *Place     `json:"pickup_place,omitempty"`
+	DropoffPlace    *Place     `json:"dropoff_place,omitempty"`
 	VehicleType     string     `json:"vehicle_type"`
 	ScheduledTime   *time.Time `json:"scheduled_time,omitempty"`
 }
@@ -34,8 +40,10 @@ type BookingStatistics struct {
 }
 
 type PriceEstimateRequest struct {
-	PickupLocation  Location `json:"pickup_location" binding:"required"`
-	DropoffLocation Location `json:"dropoff_location" binding:"required"`
+	PickupLocation  Location `json:"pickup_location"`
+	DropoffLocation Location `json:"dropoff_location"`
+	PickupPlace     *Place   `json:"pickup_place,omitempty"`
+	DropoffPlace    *Place   `json:"dropoff_place,omitempty"`
 	VehicleType     string   `json:"vehicle_type" binding:"required"`
 }
 
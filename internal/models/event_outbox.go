@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Event outbox entry kinds.
+const (
+	// EventOutboxKindMessage is a websocket notification published through
+	// the messaging client.
+	EventOutboxKindMessage = "message"
+	// EventOutboxKindEvent is a domain event; Payload holds the whole event.
+	EventOutboxKindEvent = "event"
+)
+
+// Event outbox entry statuses.
+const (
+	EventOutboxStatusPending   = "pending"
+	EventOutboxStatusPublished = "published"
+	EventOutboxStatusDead      = "dead"
+)
+
+// EventOutboxEntry is a notification or domain event written in the same
+// transaction as the state change it describes, and published afterwards by
+// the outbox relay. Entries of one aggregate are published in CreatedAt order.
+type EventOutboxEntry struct {
+	ID            string     `bson:"_id" json:"id"`
+	AggregateID   string     `bson:"aggregate_id" json:"aggregate_id"`
+	Kind          string     `bson:"kind" json:"kind"`
+	Role          string     `bson:"role,omitempty" json:"role,omitempty"`
+	RecipientID   string     `bson:"recipient_id,omitempty" json:"recipient_id,omitempty"`
+	Topics        []string   `bson:"topics,omitempty" json:"topics,omitempty"`
+	Type          string     `bson:"type" json:"type"`
+	Payload       []byte     `bson:"payload" json:"payload"`
+	Status        string     `bson:"status" json:"status"`
+	Attempts      int        `bson:"attempts" json:"attempts"`
+	LastError     string     `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `bson:"next_attempt_at" json:"next_attempt_at"`
+	CreatedAt     time.Time  `bson:"created_at" json:"created_at"`
+	PublishedAt   *time.Time `bson:"published_at,omitempty" json:"published_at,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// LoginAttempt counts failed logins for one account or one client IP.
+type LoginAttempt struct {
+	// Key is "account:<role>:<email>" or "ip:<address>".
+	Key             string     `bson:"_id" json:"key"`
+	Failures        int        `bson:"failures" json:"failures"`
+	WindowStartedAt time.Time  `bson:"window_started_at" json:"window_started_at"`
+	LastFailureAt   time.Time  `bson:"last_failure_at" json:"last_failure_at"`
+	LockedUntil     *time.Time `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	ExpiresAt       time.Time  `bson:"expires_at" json:"-"`
+}
+
+// IsLocked reports whether the lockout is still in force at now.
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// OTPCode is the outstanding one-time login code for a phone number. Only a
+// hash of the code is stored.
+type OTPCode struct {
+	// Phone is the E.164 number the code was sent to.
+	Phone    string `bson:"_id"`
+	CodeHash string `bson:"code_hash"`
+	// Attempts counts the guesses since WindowStart, across resent codes.
+	Attempts    int       `bson:"attempts"`
+	WindowStart time.Time `bson:"window_start"`
+	SentAt      time.Time `bson:"sent_at"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+	// PurgeAt is when the record, and with it the attempt count, is removed:
+	// the later of ExpiresAt and the end of the attempt window.
+	PurgeAt time.Time `bson:"purge_at"`
+}
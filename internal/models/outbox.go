@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OutboxMessage is a sequenced websocket message kept until its recipient acks it.
+// Payload holds the JSON-encoded message payload.
+type OutboxMessage struct {
+	ID          string    `bson:"_id,omitempty" json:"id"`
+	Role        string    `bson:"role" json:"role"`
+	RecipientID string    `bson:"recipient_id" json:"recipient_id"`
+	Seq         uint64    `bson:"seq" json:"seq"`
+	Type        string    `bson:"type" json:"type"`
+	Payload     []byte    `bson:"payload" json:"payload"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+}
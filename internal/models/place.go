@@ -0,0 +1,16 @@
+package models
+
+// Place holds the human-readable details of a pickup or dropoff point.
+// FormattedAddress is filled in by the geocoder; the other fields come from the user.
+type Place struct {
+	AddressLine      string `bson:"address_line,omitempty" json:"address_line,omitempty"`
+	Landmark         string `bson:"landmark,omitempty" json:"landmark,omitempty"`
+	ContactName      string `bson:"contact_name,omitempty" json:"contact_name,omitempty"`
+	ContactPhone     string `bson:"contact_phone,omitempty" json:"contact_phone,omitempty"`
+	FormattedAddress string `bson:"formatted_address,omitempty" json:"formatted_address,omitempty"`
+}
+
+// HasCoordinates reports whether the location carries a usable lon/lat pair.
+func (l Location) HasCoordinates() bool {
+	return len(l.Coordinates) == 2
+}
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// Admin permissions, checked per route.
+const (
+	PermissionDriversRead       = "drivers:read"
+	PermissionDriversWrite      = "drivers:write"
+	PermissionVehiclesRead      = "vehicles:read"
+	PermissionVehiclesWrite     = "vehicles:write"
+	PermissionUsersWrite        = "users:write"
+	PermissionStatisticsRead    = "statistics:read"
+	PermissionSpeedProfileRead  = "speed_profile:read"
+	PermissionSpeedProfileWrite = "speed_profile:write"
+	PermissionWebhooksRead      = "webhooks:read"
+	PermissionWebhooksWrite     = "webhooks:write"
+	PermissionAdminsRead        = "admins:read"
+	PermissionAdminsWrite       = "admins:write"
+	PermissionRolesWrite        = "roles:write"
+	PermissionLockoutsRead      = "lockouts:read"
+	PermissionLockoutsWrite     = "lockouts:write"
+)
+
+// AllPermissions lists every permission, in documentation order.
+var AllPermissions = []string{
+	PermissionDriversRead,
+	PermissionDriversWrite,
+	PermissionVehiclesRead,
+	PermissionVehiclesWrite,
+	PermissionUsersWrite,
+	PermissionStatisticsRead,
+	PermissionSpeedProfileRead,
+	PermissionSpeedProfileWrite,
+	PermissionWebhooksRead,
+	PermissionWebhooksWrite,
+	PermissionAdminsRead,
+	PermissionAdminsWrite,
+	PermissionRolesWrite,
+	PermissionLockoutsRead,
+	PermissionLockoutsWrite,
+}
+
+// Default roles, seeded into admin_roles when missing and editable afterwards.
+const (
+	AdminRoleDispatcher   = "dispatcher"
+	AdminRoleFleetManager = "fleet_manager"
+	AdminRoleFinance      = "finance"
+)
+
+// Role is a named set of admin permissions.
+type Role struct {
+	Name        string   `bson:"_id" json:"name"`
+	Description string   `bson:"description" json:"description"`
+	Permissions []string `bson:"permissions" json:"permissions"`
+	// System roles (owner and admin) hold every permission and cannot be
+	// changed; they are not stored.
+	System    bool      `bson:"-" json:"system"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// HasPermission reports whether the role grants permission.
+func (r *Role) HasPermission(permission string) bool {
+	if r.System {
+		return true
+	}
+	for _, granted := range r.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// SystemRoles returns the owner and admin roles.
+func SystemRoles() []*Role {
+	return []*Role{
+		{Name: AdminRoleOwner, Description: "Every permission; can also create owners and revoke admins", Permissions: AllPermissions, System: true},
+		{Name: AdminRoleAdmin, Description: "Every permission", Permissions: AllPermissions, System: true},
+	}
+}
+
+// DefaultRoles returns the roles seeded on startup.
+func DefaultRoles() []*Role {
+	return []*Role{
+		{
+			Name:        AdminRoleDispatcher,
+			Description: "Watches the fleet and updates drivers",
+			Permissions: []string{PermissionDriversRead, PermissionDriversWrite, PermissionVehiclesRead, PermissionStatisticsRead},
+		},
+		{
+			Name:        AdminRoleFleetManager,
+			Description: "Manages drivers, vehicles and the travel time model",
+			Permissions: []string{PermissionDriversRead, PermissionDriversWrite, PermissionVehiclesRead, PermissionVehiclesWrite, PermissionSpeedProfileRead, PermissionSpeedProfileWrite},
+		},
+		{
+			Name:        AdminRoleFinance,
+			Description: "Reads statistics and manages organizations and their webhooks",
+			Permissions: []string{PermissionStatisticsRead, PermissionUsersWrite, PermissionWebhooksRead, PermissionWebhooksWrite},
+		},
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// HoursPerWeek is the number of hour-of-week buckets in a SpeedProfile, indexed
+// by weekday*24 + hour with Sunday as day 0.
+const HoursPerWeek = 7 * 24
+
+// SpeedProfile describes average road speeds used to turn distances into durations.
+// Lookups prefer the vehicle's hour-of-week speed, then the vehicle speed, then the default.
+type SpeedProfile struct {
+	ID                  string               `bson:"_id,omitempty" json:"-" yaml:"-"`
+	DefaultSpeedKmh     float64              `bson:"default_speed_kmh" json:"default_speed_kmh" yaml:"default_speed_kmh"`
+	VehicleSpeedsKmh    map[string]float64   `bson:"vehicle_speeds_kmh,omitempty" json:"vehicle_speeds_kmh,omitempty" yaml:"vehicle_speeds_kmh"`
+	HourOfWeekSpeedsKmh map[string][]float64 `bson:"hour_of_week_speeds_kmh,omitempty" json:"hour_of_week_speeds_kmh,omitempty" yaml:"hour_of_week_speeds_kmh"` // 0 marks an unknown hour
+	SampleCount         int                  `bson:"sample_count" json:"sample_count" yaml:"-"`
+	CalibratedAt        *time.Time           `bson:"calibrated_at,omitempty" json:"calibrated_at,omitempty" yaml:"-"`
+}
+
+// HourOfWeek returns the SpeedProfile bucket index for t.
+func HourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// SpeedKmh returns the expected speed for a vehicle type at time t, or 0 when the profile has no data.
+func (p *SpeedProfile) SpeedKmh(vehicleType string, t time.Time) float64 {
+	if p == nil {
+		return 0
+	}
+	if hours := p.HourOfWeekSpeedsKmh[vehicleType]; len(hours) == HoursPerWeek {
+		if speed := hours[HourOfWeek(t)]; speed > 0 {
+			return speed
+		}
+	}
+	if speed := p.VehicleSpeedsKmh[vehicleType]; speed > 0 {
+		return speed
+	}
+	return p.DefaultSpeedKmh
+}
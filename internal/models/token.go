@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RefreshToken is one link in a chain of rotating refresh tokens. Only the
+// SHA-256 of the token is stored. Every login starts a new family; each
+// refresh uses up the presented token and adds its successor to the family.
+type RefreshToken struct {
+	ID        string `bson:"_id"`
+	TokenHash string `bson:"token_hash"`
+	FamilyID  string `bson:"family_id"`
+	UserID    string `bson:"user_id"`
+	Role      string `bson:"role"`
+	// AccessTokenID is the jti of the access token issued with this token.
+	AccessTokenID   string     `bson:"access_token_id"`
+	AccessExpiresAt time.Time  `bson:"access_expires_at"`
+	ExpiresAt       time.Time  `bson:"expires_at"`
+	CreatedAt       time.Time  `bson:"created_at"`
+	UsedAt          *time.Time `bson:"used_at,omitempty"`
+	RevokedAt       *time.Time `bson:"revoked_at,omitempty"`
+}
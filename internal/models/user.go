@@ -3,10 +3,12 @@ package models
 import "time"
 
 type User struct {
-	ID           string    `bson:"_id,omitempty" json:"id,omitempty"`
-	Name         string    `bson:"name" json:"name"`
-	Email        string    `bson:"email" json:"email"`
-	PasswordHash string    `bson:"password_hash" json:"-"`
-	Role         string    `bson:"role" json:"role"` // Add this line
-	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+	ID              string     `bson:"_id,omitempty" json:"id,omitempty"`
+	Name            string     `bson:"name" json:"name"`
+	Email           string     `bson:"email" json:"email"`
+	PasswordHash    string     `bson:"password_hash" json:"-"`
+	Role            string     `bson:"role" json:"role"` // Add this line
+	OrganizationID  string     `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	EmailVerifiedAt *time.Time `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `bson:"created_at" json:"created_at"`
 }
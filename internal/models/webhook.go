@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// WebhookEndpoint receives signed booking events for one user's bookings, or
+// for the bookings of every user in one organization.
+type WebhookEndpoint struct {
+	ID             string `bson:"_id,omitempty" json:"id"`
+	UserID         string `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	OrganizationID string `bson:"organization_id,omitempty" json:"organization_id,omitempty"`
+	URL            string `bson:"url" json:"url"`
+	// Secret signs every payload; it is only returned when the endpoint is created.
+	Secret string `bson:"secret" json:"-"`
+	// EventTypes limits deliveries to these event types; empty means all.
+	EventTypes []string  `bson:"event_types,omitempty" json:"event_types,omitempty"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Wants reports whether the endpoint subscribed to eventType.
+func (e *WebhookEndpoint) Wants(eventType string) bool {
+	if len(e.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusSucceeded = "succeeded"
+	WebhookDeliveryStatusFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent, or still to be sent, to one endpoint.
+// Deliveries are kept as a log; a manual redelivery adds a new one.
+type WebhookDelivery struct {
+	ID         string `bson:"_id" json:"id"`
+	EndpointID string `bson:"endpoint_id" json:"endpoint_id"`
+	EventID    string `bson:"event_id" json:"event_id"`
+	EventType  string `bson:"event_type" json:"event_type"`
+	Payload    []byte `bson:"payload" json:"-"`
+	// RedeliveryOf is the delivery this one was manually copied from.
+	RedeliveryOf   string     `bson:"redelivery_of" json:"redelivery_of,omitempty"`
+	Status         string     `bson:"status" json:"status"`
+	Attempts       int        `bson:"attempts" json:"attempts"`
+	LastStatusCode int        `bson:"last_status_code,omitempty" json:"last_status_code,omitempty"`
+	LastError      string     `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `bson:"next_attempt_at" json:"next_attempt_at"`
+	CreatedAt      time.Time  `bson:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,198 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"logi/internal/events"
+	"logi/internal/messaging"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// RelayConfig controls how often the relay polls and how it retries.
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int64
+	// MaxAttempts is how often an entry is tried before it is dead-lettered.
+	MaxAttempts int
+	// RetryDelay is the wait after the first failure; it doubles with each
+	// further failure up to MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// LeaseTTL is how long one instance stays the only relay without renewing.
+	LeaseTTL time.Duration
+}
+
+// DefaultRelayConfig returns the settings used for zero fields.
+func DefaultRelayConfig() RelayConfig {
+	return RelayConfig{
+		PollInterval:  time.Second,
+		BatchSize:     100,
+		MaxAttempts:   10,
+		RetryDelay:    time.Second,
+		MaxRetryDelay: 5 * time.Minute,
+		LeaseTTL:      15 * time.Second,
+	}
+}
+
+// Relay publishes pending outbox entries. Entries of one aggregate are
+// published strictly in order: while one waits for a retry, the entries after
+// it wait too. Entries that keep failing are marked dead so they stop
+// blocking their aggregate.
+type Relay struct {
+	Repo            repositories.EventOutboxRepository
+	MessagingClient messaging.MessagingClient
+	Events          events.Publisher
+	config          RelayConfig
+	owner           string
+	wake            chan struct{}
+}
+
+func NewRelay(repo repositories.EventOutboxRepository, messagingClient messaging.MessagingClient, eventPublisher events.Publisher, cfg RelayConfig) *Relay {
+	defaults := DefaultRelayConfig()
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaults.RetryDelay
+	}
+	if cfg.MaxRetryDelay <= 0 {
+		cfg.MaxRetryDelay = defaults.MaxRetryDelay
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = defaults.LeaseTTL
+	}
+	return &Relay{
+		Repo:            repo,
+		MessagingClient: messagingClient,
+		Events:          eventPublisher,
+		config:          cfg,
+		owner:           uuid.NewString(),
+		wake:            make(chan struct{}, 1),
+	}
+}
+
+// Wake asks the relay to poll now instead of at its next interval.
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls until ctx is done. Only the instance holding the lease publishes.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+
+		leader, err := r.Repo.AcquireLease(ctx, r.owner, r.config.LeaseTTL)
+		if err != nil {
+			utils.ErrorBackground("failed to acquire outbox relay lease", "error", err)
+			continue
+		}
+		if !leader {
+			continue
+		}
+		if _, err := r.RelayOnce(ctx); err != nil && ctx.Err() == nil {
+			utils.ErrorBackground("outbox relay failed", "error", err)
+		}
+	}
+}
+
+// RelayOnce publishes one batch of due entries and returns how many were
+// published. Entries of aggregates waiting for a retry are left out of the
+// batch, so they cannot crowd out the entries that are due.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	entries, err := r.Repo.FindPending(ctx, time.Now(), r.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	blocked := make(map[string]struct{})
+	published := 0
+	for _, entry := range entries {
+		if _, ok := blocked[entry.AggregateID]; ok {
+			continue
+		}
+
+		if publishErr := r.publish(ctx, entry); publishErr != nil {
+			if err := r.fail(ctx, entry, publishErr); err != nil {
+				return published, err
+			}
+			if entry.Status == models.EventOutboxStatusPending {
+				blocked[entry.AggregateID] = struct{}{}
+			}
+			continue
+		}
+		if err := r.Repo.MarkPublished(ctx, entry.ID, time.Now()); err != nil {
+			return published, err
+		}
+		utils.IncrementCounter("event_outbox_published", 1)
+		published++
+	}
+	return published, nil
+}
+
+func (r *Relay) publish(ctx context.Context, entry *models.EventOutboxEntry) error {
+	switch entry.Kind {
+	case models.EventOutboxKindMessage:
+		recipient := messaging.Recipient{Role: entry.Role, ID: entry.RecipientID, Topics: entry.Topics}
+		return r.MessagingClient.Publish(recipient, entry.Type, json.RawMessage(entry.Payload))
+	case models.EventOutboxKindEvent:
+		if r.Events == nil {
+			return errors.New("no event publisher configured")
+		}
+		var event events.Event
+		if err := json.Unmarshal(entry.Payload, &event); err != nil {
+			return err
+		}
+		return r.Events.PublishEvent(ctx, event)
+	default:
+		return errors.New("unknown outbox entry kind: " + entry.Kind)
+	}
+}
+
+// fail records a failed attempt, dead-lettering the entry once it has used
+// up its attempts.
+func (r *Relay) fail(ctx context.Context, entry *models.EventOutboxEntry, publishErr error) error {
+	entry.Attempts++
+	entry.LastError = publishErr.Error()
+	if entry.Attempts >= r.config.MaxAttempts {
+		entry.Status = models.EventOutboxStatusDead
+		utils.IncrementCounter("event_outbox_dead_lettered", 1)
+		utils.ErrorBackground("dead-lettering outbox entry", "id", entry.ID, "aggregate_id", entry.AggregateID, "kind", entry.Kind, "type", entry.Type, "attempts", entry.Attempts, "error", publishErr)
+		return r.Repo.MarkDead(ctx, entry.ID, entry.Attempts, entry.LastError)
+	}
+
+	delay := r.config.RetryDelay
+	for i := 1; i < entry.Attempts && delay < r.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.config.MaxRetryDelay {
+		delay = r.config.MaxRetryDelay
+	}
+	entry.NextAttemptAt = time.Now().Add(delay)
+	utils.IncrementCounter("event_outbox_retries", 1)
+	utils.WarnBackground("failed to publish outbox entry, will retry", "id", entry.ID, "aggregate_id", entry.AggregateID, "type", entry.Type, "attempts", entry.Attempts, "retry_in", delay.String(), "error", publishErr)
+	return r.Repo.MarkFailed(ctx, entry.ID, entry.Attempts, entry.LastError, entry.NextAttemptAt)
+}
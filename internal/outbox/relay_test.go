@@ -0,0 +1,255 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"logi/internal/events"
+	"logi/internal/messaging"
+	"logi/internal/models"
+)
+
+// memoryRepository is an in-memory EventOutboxRepository.
+type memoryRepository struct {
+	mu      sync.Mutex
+	entries []*models.EventOutboxEntry
+}
+
+func (r *memoryRepository) Insert(ctx context.Context, entry *models.EventOutboxEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *entry
+	r.entries = append(r.entries, &copied)
+	return nil
+}
+
+func (r *memoryRepository) FindPending(ctx context.Context, now time.Time, limit int64) ([]*models.EventOutboxEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	blocked := make(map[string]bool)
+	for _, entry := range r.entries {
+		if entry.Status == models.EventOutboxStatusPending && entry.NextAttemptAt.After(now) {
+			blocked[entry.AggregateID] = true
+		}
+	}
+	var pending []*models.EventOutboxEntry
+	for _, entry := range r.entries {
+		if entry.Status == models.EventOutboxStatusPending && !entry.NextAttemptAt.After(now) && !blocked[entry.AggregateID] {
+			copied := *entry
+			pending = append(pending, &copied)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+	if int64(len(pending)) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+func (r *memoryRepository) update(id string, apply func(entry *models.EventOutboxEntry)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, entry := range r.entries {
+		if entry.ID == id {
+			apply(entry)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (r *memoryRepository) MarkPublished(ctx context.Context, id string, publishedAt time.Time) error {
+	return r.update(id, func(entry *models.EventOutboxEntry) {
+		entry.Status = models.EventOutboxStatusPublished
+		entry.PublishedAt = &publishedAt
+	})
+}
+
+func (r *memoryRepository) MarkFailed(ctx context.Context, id string, attempts int, lastError string, nextAttemptAt time.Time) error {
+	return r.update(id, func(entry *models.EventOutboxEntry) {
+		entry.Attempts = attempts
+		entry.LastError = lastError
+		entry.NextAttemptAt = nextAttemptAt
+	})
+}
+
+func (r *memoryRepository) MarkDead(ctx context.Context, id string, attempts int, lastError string) error {
+	return r.update(id, func(entry *models.EventOutboxEntry) {
+		entry.Status = models.EventOutboxStatusDead
+		entry.Attempts = attempts
+		entry.LastError = lastError
+	})
+}
+
+func (r *memoryRepository) AcquireLease(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (r *memoryRepository) status(id string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, entry := range r.entries {
+		if entry.ID == id {
+			return entry.Status
+		}
+	}
+	return ""
+}
+
+type directTransactor struct{}
+
+func (directTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// flakyMessagingClient fails messages of the given types and records the rest.
+type flakyMessagingClient struct {
+	failing   map[string]bool
+	published []string
+}
+
+func (c *flakyMessagingClient) Publish(recipient messaging.Recipient, messageType string, payload interface{}) error {
+	if c.failing[messageType] {
+		return errors.New("broker unavailable")
+	}
+	c.published = append(c.published, messageType)
+	return nil
+}
+
+type recordingEventPublisher struct {
+	published []events.Event
+}
+
+func (p *recordingEventPublisher) PublishEvent(ctx context.Context, event events.Event) error {
+	p.published = append(p.published, event)
+	return nil
+}
+
+func TestRelayKeepsAggregateOrderAcrossRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := &memoryRepository{}
+	client := &flakyMessagingClient{failing: map[string]bool{"booking_accepted": true}}
+	eventPublisher := &recordingEventPublisher{}
+	relay := NewRelay(repo, client, eventPublisher, RelayConfig{RetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond})
+	writer := NewWriter(repo, directTransactor{}, nil)
+
+	add := func(aggregateID, messageType string) {
+		t.Helper()
+		if err := writer.AddMessage(ctx, aggregateID, messaging.ToUser("user-1"), messageType, map[string]string{"booking_id": aggregateID}); err != nil {
+			t.Fatalf("AddMessage returned error: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	add("booking-1", "booking_accepted")
+	add("booking-1", "status_update")
+	add("booking-2", "status_update")
+	event, _ := events.New(events.BookingCompleted, "booking-2", nil)
+	if err := writer.AddEvent(ctx, event); err != nil {
+		t.Fatalf("AddEvent returned error: %v", err)
+	}
+
+	published, err := relay.RelayOnce(ctx)
+	if err != nil {
+		t.Fatalf("RelayOnce returned error: %v", err)
+	}
+	if published != 2 || strings.Join(client.published, ",") != "status_update" {
+		t.Fatalf("only booking-2 should be published while booking-1 waits for a retry, got %d %v", published, client.published)
+	}
+	if len(eventPublisher.published) != 1 || eventPublisher.published[0].ID != event.ID {
+		t.Fatalf("expected the stored event to be published unchanged, got %+v", eventPublisher.published)
+	}
+
+	delete(client.failing, "booking_accepted")
+	time.Sleep(5 * time.Millisecond)
+	if _, err := relay.RelayOnce(ctx); err != nil {
+		t.Fatalf("RelayOnce returned error: %v", err)
+	}
+	if got := strings.Join(client.published, ","); got != "status_update,booking_accepted,status_update" {
+		t.Fatalf("expected booking-1 to be published in order after the retry, got %s", got)
+	}
+}
+
+func TestRelayPublishesDueEntriesBehindMoreThanABatchOfRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := &memoryRepository{}
+	client := &flakyMessagingClient{}
+	relay := NewRelay(repo, client, nil, RelayConfig{BatchSize: 3})
+	writer := NewWriter(repo, directTransactor{}, nil)
+
+	add := func(aggregateID, messageType string) {
+		t.Helper()
+		if err := writer.AddMessage(ctx, aggregateID, messaging.ToUser("user-1"), messageType, nil); err != nil {
+			t.Fatalf("AddMessage returned error: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Older than everything else and all backing off: enough to fill two
+	// batches if they were fetched.
+	for i := 0; i < 2*int(relay.config.BatchSize); i++ {
+		add(fmt.Sprintf("booking-%d", i), "booking_accepted")
+	}
+	retryAt := time.Now().Add(time.Hour)
+	for _, entry := range repo.entries {
+		if err := repo.MarkFailed(ctx, entry.ID, 1, "broker unavailable", retryAt); err != nil {
+			t.Fatalf("MarkFailed returned error: %v", err)
+		}
+	}
+	add("booking-0", "status_update")
+	add("booking-new", "status_update")
+
+	published, err := relay.RelayOnce(ctx)
+	if err != nil {
+		t.Fatalf("RelayOnce returned error: %v", err)
+	}
+	if published != 1 || strings.Join(client.published, ",") != "status_update" {
+		t.Fatalf("expected only booking-new to be published, got %d %v", published, client.published)
+	}
+	for _, entry := range repo.entries {
+		if entry.AggregateID == "booking-0" && entry.Type == "status_update" && entry.Status != models.EventOutboxStatusPending {
+			t.Fatalf("expected booking-0's second entry to wait behind its retry, got status %q", entry.Status)
+		}
+	}
+}
+
+func TestRelayDeadLettersEntriesThatKeepFailing(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := &memoryRepository{}
+	client := &flakyMessagingClient{failing: map[string]bool{"new_booking_request": true}}
+	relay := NewRelay(repo, client, nil, RelayConfig{MaxAttempts: 2, RetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond})
+	writer := NewWriter(repo, directTransactor{}, nil)
+
+	if err := writer.AddMessage(ctx, "booking-1", messaging.ToDriver("driver-1"), "new_booking_request", nil); err != nil {
+		t.Fatalf("AddMessage returned error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := writer.AddMessage(ctx, "booking-1", messaging.ToUser("user-1"), "status_update", nil); err != nil {
+		t.Fatalf("AddMessage returned error: %v", err)
+	}
+	deadID := repo.entries[0].ID
+
+	for i := 0; i < 3; i++ {
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Fatalf("RelayOnce returned error: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if status := repo.status(deadID); status != models.EventOutboxStatusDead {
+		t.Fatalf("expected entry to be dead-lettered, got status %q", status)
+	}
+	if strings.Join(client.published, ",") != "status_update" {
+		t.Fatalf("expected the aggregate to continue after dead-lettering, got %v", client.published)
+	}
+}
@@ -0,0 +1,93 @@
+// Package outbox makes notifications and domain events as durable as the
+// state changes they describe: services write them to the event outbox in
+// the same transaction, and the Relay publishes them afterwards.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"logi/internal/events"
+	"logi/internal/messaging"
+	"logi/internal/models"
+	"logi/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Writer adds entries to the event outbox.
+type Writer struct {
+	Repo repositories.EventOutboxRepository
+	Tx   repositories.Transactor
+	// OnWrite, when set, is called after entries may have been committed so
+	// a local relay can publish them without waiting for its next poll.
+	OnWrite func()
+}
+
+func NewWriter(repo repositories.EventOutboxRepository, tx repositories.Transactor, onWrite func()) *Writer {
+	return &Writer{Repo: repo, Tx: tx, OnWrite: onWrite}
+}
+
+// InTransaction runs fn in a transaction; entries added with the ctx passed
+// to fn are committed or rolled back together with fn's other writes.
+func (w *Writer) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := w.Tx.RunInTransaction(ctx, fn); err != nil {
+		return err
+	}
+	w.wake()
+	return nil
+}
+
+// AddMessage stores a notification for recipient about aggregateID.
+func (w *Writer) AddMessage(ctx context.Context, aggregateID string, recipient messaging.Recipient, messageType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return w.insert(ctx, &models.EventOutboxEntry{
+		AggregateID: aggregateID,
+		Kind:        models.EventOutboxKindMessage,
+		Role:        recipient.Role,
+		RecipientID: recipient.ID,
+		Topics:      recipient.Topics,
+		Type:        messageType,
+		Payload:     data,
+	})
+}
+
+// AddEvent stores a domain event. Its ID lets the event stream drop the
+// duplicates a retried publish can produce.
+func (w *Writer) AddEvent(ctx context.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return w.insert(ctx, &models.EventOutboxEntry{
+		AggregateID: event.AggregateID,
+		Kind:        models.EventOutboxKindEvent,
+		Type:        event.Type,
+		Payload:     data,
+	})
+}
+
+func (w *Writer) insert(ctx context.Context, entry *models.EventOutboxEntry) error {
+	now := time.Now()
+	// ObjectIDs grow within a process, keeping entries created in the same
+	// millisecond in order.
+	entry.ID = primitive.NewObjectID().Hex()
+	entry.Status = models.EventOutboxStatusPending
+	entry.CreatedAt = now
+	entry.NextAttemptAt = now
+	if err := w.Repo.Insert(ctx, entry); err != nil {
+		return err
+	}
+	w.wake()
+	return nil
+}
+
+func (w *Writer) wake() {
+	if w.OnWrite != nil {
+		w.OnWrite()
+	}
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+
+	"logi/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware applies the limiter's policy for the matched route,
+// answering 429 with Retry-After once the caller's bucket is empty. Policies
+// keyed by user or role need the middleware after utils.JWTAuthMiddleware. A nil
+// limiter allows everything, and a failing store lets requests through.
+func Middleware(limiter *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		policy, ok := limiter.Policy(c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+		applyRateLimit(c, limiter, policy, rateLimitSubject(c, policy.Key))
+	}
+}
+
+// AuthMiddleware applies the limiter's AuthRoute policy by client IP. It goes
+// in front of utils.JWTAuthMiddleware, so requests with bad tokens are limited
+// too; Middleware still follows the auth check.
+func AuthMiddleware(limiter *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		policy, ok := limiter.AuthPolicy()
+		if !ok {
+			c.Next()
+			return
+		}
+		applyRateLimit(c, limiter, policy, "ip:"+c.ClientIP())
+	}
+}
+
+func applyRateLimit(c *gin.Context, limiter *Limiter, policy Policy, subject string) {
+	result, err := limiter.Allow(c.Request.Context(), policy, subject)
+	if err != nil {
+		utils.Error(c.Request.Context(), "rate limit check failed", "route", policy.Route, "error", err)
+		c.Next()
+		return
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(policy.Burst))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	if !result.Allowed {
+		utils.IncrementCounter("rate_limited_requests", 1)
+		utils.Warn(c.Request.Context(), "rate limit exceeded", "route", policy.Route, "key", policy.Key, "user_id", c.GetString("userID"), "client_ip", c.ClientIP())
+		c.Header("Retry-After", strconv.Itoa(RetryAfterSeconds(result.RetryAfter)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+func rateLimitSubject(c *gin.Context, key string) string {
+	switch key {
+	case KeyUser:
+		if userID := c.GetString("userID"); userID != "" {
+			return "user:" + userID
+		}
+	case KeyRole:
+		if role := c.GetString("role"); role != "" {
+			return "role:" + role
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := NewLimiter(NewMemoryStore(), map[string]string{
+		"POST /drivers/update-location": "user:1/1m:2",
+		"GET /healthz":                  "none",
+		DefaultRoute:                    "ip:1/1m",
+	})
+	if err != nil {
+		t.Fatalf("NewLimiter returned error: %v", err)
+	}
+	router := gin.New()
+	asDriver := func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Driver"))
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/drivers/update-location", asDriver, Middleware(limiter), ok)
+	router.GET("/healthz", Middleware(limiter), ok)
+	router.GET("/other", Middleware(limiter), ok)
+
+	send := func(method, path, driverID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Driver", driverID)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp := send(http.MethodPost, "/drivers/update-location", "driver-1"); resp.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst: expected 200, got %d", i+1, resp.Code)
+		}
+	}
+	resp := send(http.MethodPost, "/drivers/update-location", "driver-1")
+	if resp.Code != http.StatusTooManyRequests || resp.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After 60, got %d with %q", resp.Code, resp.Header().Get("Retry-After"))
+	}
+	if resp := send(http.MethodPost, "/drivers/update-location", "driver-2"); resp.Code != http.StatusOK {
+		t.Fatalf("expected another driver to have their own bucket, got %d", resp.Code)
+	}
+
+	if send(http.MethodGet, "/other", "").Code != http.StatusOK || send(http.MethodGet, "/other", "").Code != http.StatusTooManyRequests {
+		t.Fatal("expected the default policy to limit routes without their own")
+	}
+	for i := 0; i < 3; i++ {
+		if resp := send(http.MethodGet, "/healthz", ""); resp.Code != http.StatusOK {
+			t.Fatalf("expected a route with policy none to be unlimited, got %d", resp.Code)
+		}
+	}
+}
+
+func TestAuthMiddlewareLimitsBeforeAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := NewLimiter(NewMemoryStore(), map[string]string{
+		AuthRoute:    "ip:2/1m",
+		DefaultRoute: "user:10/1m",
+	})
+	if err != nil {
+		t.Fatalf("NewLimiter returned error: %v", err)
+	}
+	rejectAll := func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+	router := gin.New()
+	router.GET("/drivers/me", AuthMiddleware(limiter), rejectAll, Middleware(limiter))
+
+	send := func() int {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/drivers/me", nil))
+		return resp.Code
+	}
+	for i := 0; i < 2; i++ {
+		if code := send(); code != http.StatusUnauthorized {
+			t.Fatalf("request %d within the limit: expected 401, got %d", i+1, code)
+		}
+	}
+	if code := send(); code != http.StatusTooManyRequests {
+		t.Fatalf("expected requests with bad tokens to be limited by IP, got %d", code)
+	}
+}
@@ -0,0 +1,167 @@
+// Package ratelimit implements token bucket rate limits for the API, with an
+// in-process store and a Redis store shared by every instance.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket: it holds up to Burst tokens and refills at Rate
+// tokens per second. Each request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until a token is available, when not Allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// What a policy's buckets are keyed by.
+const (
+	// KeyUser gives each authenticated user a bucket; anonymous callers get
+	// one per IP.
+	KeyUser = "user"
+	// KeyRole shares one bucket between every caller with the same role.
+	KeyRole = "role"
+	KeyIP   = "ip"
+)
+
+// DefaultRoute is the route of the policy for routes without their own.
+const DefaultRoute = "*"
+
+// AuthRoute is the route of the policy checked on authenticated routes before
+// the token is, so bad tokens cannot be tried without limit. It must be keyed
+// by IP, since the caller is not known yet, and has no default fallback.
+const AuthRoute = "auth"
+
+// Policy limits one route, "METHOD /path" as registered with gin, or every
+// other route when Route is DefaultRoute.
+type Policy struct {
+	Route    string
+	Key      string
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// Limit returns the policy's token bucket.
+func (p Policy) Limit() Limit {
+	return Limit{Rate: float64(p.Requests) / p.Per.Seconds(), Burst: p.Burst}
+}
+
+// ParsePolicy parses a policy spec, KEY:REQUESTS/PERIOD[:BURST], such as
+// "user:10/1s:20" or "ip:300/1m". PERIOD is a Go duration, and BURST defaults
+// to REQUESTS. The spec "none" disables limits for route, returning ok false.
+func ParsePolicy(route, spec string) (policy Policy, ok bool, err error) {
+	route = strings.TrimSpace(route)
+	if route != DefaultRoute && route != AuthRoute {
+		method, path, found := strings.Cut(route, " ")
+		if !found || method == "" || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			return Policy{}, false, fmt.Errorf("rate limit route %q must be %q, %q or \"METHOD /path\"", route, DefaultRoute, AuthRoute)
+		}
+	}
+	spec = strings.TrimSpace(spec)
+	if spec == "none" {
+		return Policy{}, false, nil
+	}
+
+	invalid := fmt.Errorf("rate limit for %q must look like user:10/1s:20 (key user, role or ip), got %q", route, spec)
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Policy{}, false, invalid
+	}
+	policy = Policy{Route: route, Key: parts[0]}
+	switch policy.Key {
+	case KeyUser, KeyRole, KeyIP:
+	default:
+		return Policy{}, false, invalid
+	}
+	requests, period, found := strings.Cut(parts[1], "/")
+	if !found {
+		return Policy{}, false, invalid
+	}
+	if policy.Requests, err = strconv.Atoi(requests); err != nil || policy.Requests <= 0 {
+		return Policy{}, false, invalid
+	}
+	if policy.Per, err = time.ParseDuration(period); err != nil || policy.Per <= 0 {
+		return Policy{}, false, invalid
+	}
+	policy.Burst = policy.Requests
+	if len(parts) == 3 {
+		if policy.Burst, err = strconv.Atoi(parts[2]); err != nil || policy.Burst <= 0 {
+			return Policy{}, false, invalid
+		}
+	}
+	if route == AuthRoute && policy.Key != KeyIP {
+		return Policy{}, false, fmt.Errorf("rate limit for %q must be keyed by ip, got %q", route, spec)
+	}
+	return policy, true, nil
+}
+
+// Limiter applies per-route policies using a Store.
+type Limiter struct {
+	Store    Store
+	policies map[string]Policy
+}
+
+// NewLimiter parses policies, route to spec, as read from rate_limits.
+func NewLimiter(store Store, policies map[string]string) (*Limiter, error) {
+	limiter := &Limiter{Store: store, policies: make(map[string]Policy)}
+	disabled := make(map[string]bool)
+	for route, spec := range policies {
+		policy, ok, err := ParsePolicy(route, spec)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			limiter.policies[policy.Route] = policy
+		} else {
+			disabled[strings.TrimSpace(route)] = true
+		}
+	}
+	// A disabled route is exempt from the default policy as well.
+	for route := range disabled {
+		limiter.policies[route] = Policy{}
+	}
+	return limiter, nil
+}
+
+// AuthPolicy returns the policy for AuthRoute, if one is set.
+func (l *Limiter) AuthPolicy() (Policy, bool) {
+	policy, ok := l.policies[AuthRoute]
+	return policy, ok && policy.Requests > 0
+}
+
+// Policy returns the policy for the route registered as path, if any.
+func (l *Limiter) Policy(method, path string) (Policy, bool) {
+	policy, ok := l.policies[method+" "+path]
+	if !ok {
+		policy, ok = l.policies[DefaultRoute]
+	}
+	return policy, ok && policy.Requests > 0
+}
+
+// Allow takes a token from subject's bucket for policy.
+func (l *Limiter) Allow(ctx context.Context, policy Policy, subject string) (Result, error) {
+	return l.Store.Take(ctx, "ratelimit:"+policy.Route+":"+subject, policy.Limit(), time.Now())
+}
+
+// RetryAfterSeconds rounds a wait up to whole seconds for a Retry-After header.
+func RetryAfterSeconds(wait time.Duration) int {
+	return max(1, int(math.Ceil(wait.Seconds())))
+}
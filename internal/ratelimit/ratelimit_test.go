@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"logi/internal/utils"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestParsePolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, ok, err := ParsePolicy("POST /bookings/estimate", "user:30/1m:5")
+	if err != nil || !ok {
+		t.Fatalf("ParsePolicy returned %v, %v", ok, err)
+	}
+	if policy.Key != KeyUser || policy.Requests != 30 || policy.Per != time.Minute || policy.Burst != 5 || policy.Limit().Rate != 0.5 {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+	if policy, _, _ := ParsePolicy(DefaultRoute, "ip:10/1s"); policy.Burst != 10 {
+		t.Fatalf("expected the burst to default to the requests, got %d", policy.Burst)
+	}
+	if _, ok, err := ParsePolicy("GET /healthz", "none"); ok || err != nil {
+		t.Fatalf("expected none to disable the route, got %v, %v", ok, err)
+	}
+
+	for route, spec := range map[string]string{
+		"/bookings":      "user:1/1s",
+		"post /bookings": "user:1/1s",
+		"GET /a":         "session:1/1s",
+		"GET /b":         "user:0/1s",
+		"GET /c":         "user:1/soon",
+		"GET /d":         "user:1/1s:0",
+		"GET /e":         "user:1",
+		AuthRoute:        "user:1/1s",
+	} {
+		if _, _, err := ParsePolicy(route, spec); err == nil {
+			t.Fatalf("expected %q for %q to be rejected", spec, route)
+		}
+	}
+}
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+
+	ctx := context.Background()
+	limit := Limit{Rate: 2, Burst: 3}
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		result, err := store.Take(ctx, "driver-1", limit, now)
+		if err != nil || !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("take %d: got %+v, %v", i+1, result, err)
+		}
+	}
+	result, err := store.Take(ctx, "driver-1", limit, now)
+	if err != nil || result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected an empty bucket to wait half a second, got %+v, %v", result, err)
+	}
+	if result, _ := store.Take(ctx, "driver-2", limit, now); !result.Allowed {
+		t.Fatal("expected each key to have its own bucket")
+	}
+
+	if result, _ := store.Take(ctx, "driver-1", limit, now.Add(500*time.Millisecond)); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected one token after half a second, got %+v", result)
+	}
+	if result, _ := store.Take(ctx, "driver-1", limit, now.Add(time.Hour)); !result.Allowed || result.Remaining != 2 {
+		t.Fatalf("expected the bucket to refill only up to the burst, got %+v", result)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+	testStore(t, NewMemoryStore())
+}
+
+func TestRedisStore(t *testing.T) {
+	t.Parallel()
+
+	server := miniredis.RunT(t)
+	store, err := NewRedisStore("redis://" + server.Addr())
+	if err != nil {
+		t.Fatalf("NewRedisStore returned error: %v", err)
+	}
+	t.Cleanup(func() { store.Client.Close() })
+	testStore(t, store)
+
+	if ttl := server.TTL("driver-2"); ttl <= 0 || ttl > 3*time.Second {
+		t.Fatalf("expected buckets to expire once refilled, got a TTL of %v", ttl)
+	}
+}
+
+func TestLimiterPolicy(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := NewLimiter(NewMemoryStore(), map[string]string{
+		"POST /bookings": "user:10/1m",
+		"GET /healthz":   "none",
+		DefaultRoute:     "ip:100/1m",
+	})
+	if err != nil {
+		t.Fatalf("NewLimiter returned error: %v", err)
+	}
+	if policy, ok := limiter.Policy("POST", "/bookings"); !ok || policy.Key != KeyUser {
+		t.Fatalf("expected the route's own policy, got %+v, %v", policy, ok)
+	}
+	if policy, ok := limiter.Policy("GET", "/bookings"); !ok || policy.Route != DefaultRoute {
+		t.Fatalf("expected the default policy for another method, got %+v, %v", policy, ok)
+	}
+	if _, ok := limiter.Policy("GET", "/healthz"); ok {
+		t.Fatal("expected a route with policy none to be unlimited")
+	}
+	if _, ok := limiter.AuthPolicy(); ok {
+		t.Fatal("expected no auth policy without an auth entry")
+	}
+}
+
+// The config defaults name routes with plain strings, so check they still
+// build a limiter.
+func TestConfigDefaultsAreValidPolicies(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+
+	cfg, err := utils.LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	limiter, err := NewLimiter(NewMemoryStore(), cfg.RateLimits)
+	if err != nil {
+		t.Fatalf("NewLimiter rejected the default rate limits: %v", err)
+	}
+	if _, ok := limiter.AuthPolicy(); !ok {
+		t.Fatal("expected the defaults to set an auth policy")
+	}
+	if _, ok := limiter.Policy("GET", "/drivers/me"); !ok {
+		t.Fatal("expected the defaults to set a default policy")
+	}
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AdminInviteRepository interface {
+	Create(ctx context.Context, invite *models.AdminInvite) error
+	// ListPending returns the invites that are neither accepted nor expired.
+	ListPending(ctx context.Context, now time.Time) ([]*models.AdminInvite, error)
+	Delete(ctx context.Context, id string) error
+	// Accept uses up the pending invite with tokenHash and returns it, or
+	// returns mongo.ErrNoDocuments.
+	Accept(ctx context.Context, tokenHash string, now time.Time) (*models.AdminInvite, error)
+}
+
+type adminInviteRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAdminInviteRepository(dbClient *mongo.Client) AdminInviteRepository {
+	collection := dbClient.Database("logi").Collection("admin_invites")
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}
+	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		utils.ErrorBackground("failed to create admin invite indexes", "error", err)
+	}
+	return &adminInviteRepository{collection}
+}
+
+func (r *adminInviteRepository) Create(ctx context.Context, invite *models.AdminInvite) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(opCtx, invite)
+	return err
+}
+
+func (r *adminInviteRepository) ListPending(ctx context.Context, now time.Time) ([]*models.AdminInvite, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	filter := bson.M{"accepted_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}}
+	cursor, err := r.collection.Find(opCtx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var invites []*models.AdminInvite
+	if err := cursor.All(opCtx, &invites); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (r *adminInviteRepository) Delete(ctx context.Context, id string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(opCtx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *adminInviteRepository) Accept(ctx context.Context, tokenHash string, now time.Time) (*models.AdminInvite, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	filter := bson.M{"token_hash": tokenHash, "accepted_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}}
+	var invite models.AdminInvite
+	err := r.collection.FindOneAndUpdate(opCtx, filter,
+		bson.M{"$set": bson.M{"accepted_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&invite)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
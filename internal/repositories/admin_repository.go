@@ -4,6 +4,7 @@ import (
 	"context"
 	"logi/internal/models"
 	"logi/internal/utils"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,10 +14,27 @@ import (
 type AdminRepository interface {
 	Create(ctx context.Context, admin *models.Admin) error
 	FindByEmail(ctx context.Context, email string) (*models.Admin, error)
+	FindByID(ctx context.Context, id string) (*models.Admin, error)
+	List(ctx context.Context) ([]*models.Admin, error)
+	// CountActiveOwners counts owners that have not been revoked.
+	CountActiveOwners(ctx context.Context) (int64, error)
+	CountByRole(ctx context.Context, role string) (int64, error)
+	// UpdateRole returns mongo.ErrNoDocuments when there is no admin with that ID.
+	UpdateRole(ctx context.Context, id, role string) error
+	// Revoke disables an active admin; it returns mongo.ErrNoDocuments when
+	// there is none with that ID.
+	Revoke(ctx context.Context, id string, revokedAt time.Time) error
+	// MarkBootstrapped records that the one-time bootstrap ran and reports
+	// whether this call was the first to do so.
+	MarkBootstrapped(ctx context.Context, at time.Time) (bool, error)
+	// ClearBootstrapped removes the marker again, for a bootstrap that failed
+	// after marking.
+	ClearBootstrapped(ctx context.Context) error
 }
 
 type adminRepository struct {
 	collection *mongo.Collection
+	bootstrap  *mongo.Collection
 }
 
 func NewAdminRepository(dbClient *mongo.Client) AdminRepository {
@@ -28,7 +46,10 @@ func NewAdminRepository(dbClient *mongo.Client) AdminRepository {
 	if err != nil {
 		utils.ErrorBackground("failed to create admin email index", "error", err)
 	}
-	return &adminRepository{collection}
+	return &adminRepository{
+		collection: collection,
+		bootstrap:  dbClient.Database("logi").Collection("admin_bootstrap"),
+	}
 }
 
 func (r *adminRepository) Create(ctx context.Context, admin *models.Admin) error {
@@ -50,3 +71,96 @@ func (r *adminRepository) FindByEmail(ctx context.Context, email string) (*model
 	}
 	return &admin, nil
 }
+
+func (r *adminRepository) FindByID(ctx context.Context, id string) (*models.Admin, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var admin models.Admin
+	err := r.collection.FindOne(opCtx, bson.M{"_id": id}).Decode(&admin)
+	if err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+func (r *adminRepository) List(ctx context.Context) ([]*models.Admin, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	cursor, err := r.collection.Find(opCtx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var admins []*models.Admin
+	if err := cursor.All(opCtx, &admins); err != nil {
+		return nil, err
+	}
+	return admins, nil
+}
+
+func (r *adminRepository) CountActiveOwners(ctx context.Context) (int64, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	return r.collection.CountDocuments(opCtx, bson.M{"role": models.AdminRoleOwner, "revoked_at": bson.M{"$exists": false}})
+}
+
+func (r *adminRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	return r.collection.CountDocuments(opCtx, bson.M{"role": role})
+}
+
+func (r *adminRepository) UpdateRole(ctx context.Context, id, role string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(opCtx, bson.M{"_id": id}, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *adminRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(opCtx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *adminRepository) MarkBootstrapped(ctx context.Context, at time.Time) (bool, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.bootstrap.InsertOne(opCtx, bson.M{"_id": "bootstrap", "created_at": at})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *adminRepository) ClearBootstrapped(ctx context.Context) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.bootstrap.DeleteOne(opCtx, bson.M{"_id": "bootstrap"})
+	return err
+}
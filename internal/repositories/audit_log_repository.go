@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AuditLogRepository interface {
+	Insert(ctx context.Context, event *models.AuditEvent) error
+}
+
+type auditLogRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditLogRepository(dbClient *mongo.Client) AuditLogRepository {
+	collection := dbClient.Database("logi").Collection("audit_log")
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "subject", Value: 1}, {Key: "created_at", Value: -1}}},
+	}
+	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		utils.ErrorBackground("failed to create audit log indexes", "error", err)
+	}
+	return &auditLogRepository{collection}
+}
+
+func (r *auditLogRepository) Insert(ctx context.Context, event *models.AuditEvent) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(opCtx, event)
+	return err
+}
@@ -8,6 +8,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type BookingRepository interface {
@@ -25,6 +26,7 @@ type BookingRepository interface {
 	FindByIDAndDriverID(ctx context.Context, id string, driverID string) (*models.Booking, error)
 	GetAverageTripTime(ctx context.Context) (float64, error)
 	GetTotalBookings(ctx context.Context) (int64, error)
+	FindCompletedTrips(ctx context.Context, since time.Time, limit int64) ([]*models.Booking, error)
 }
 
 type bookingRepository struct {
@@ -347,3 +349,38 @@ func (r *bookingRepository) GetTotalBookings(ctx context.Context) (int64, error)
 	count, err := r.collection.CountDocuments(opCtx, bson.M{})
 	return count, err
 }
+
+// FindCompletedTrips returns the most recent completed bookings since the given time
+// that recorded both a start and a completion timestamp.
+func (r *bookingRepository) FindCompletedTrips(ctx context.Context, since time.Time, limit int64) ([]*models.Booking, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"status":       models.BookingStatusCompleted,
+		"started_at":   bson.M{"$exists": true},
+		"completed_at": bson.M{"$gte": since},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "completed_at", Value: -1}})
+	if limit > 0 {
+		findOptions.SetLimit(limit)
+	}
+	cursor, err := r.collection.Find(opCtx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var bookings []*models.Booking
+	for cursor.Next(opCtx) {
+		var booking models.Booking
+		if err := cursor.Decode(&booking); err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, &booking)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"logi/internal/models"
 	"logi/internal/utils"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,6 +15,7 @@ import (
 type DriverRepository interface {
 	Create(ctx context.Context, driver *models.Driver) error
 	FindByEmail(ctx context.Context, email string) (*models.Driver, error)
+	FindByPhone(ctx context.Context, phone string) (*models.Driver, error)
 	FindAvailableDrivers(ctx context.Context, location models.Location, vehicleType string) ([]*models.Driver, error)
 	UpdateStatus(ctx context.Context, driverID string, status string) error
 	AssignVehicle(ctx context.Context, driverID, vehicleID, vehicleType string) error
@@ -26,6 +29,11 @@ type DriverRepository interface {
 	IncrementTotalBookings(ctx context.Context, driverID string) error
 	IncrementCompletedBookings(ctx context.Context, driverID string) error
 	GetTotalDrivers(ctx context.Context) (int64, error)
+	// MarkEmailVerified records when the email was verified, keeping the first time.
+	MarkEmailVerified(ctx context.Context, driverID string, at time.Time) error
+	// UpdatePassword replaces the password hash if it is still currentHash,
+	// and returns mongo.ErrNoDocuments otherwise.
+	UpdatePassword(ctx context.Context, driverID, currentHash, newHash string) error
 }
 
 type driverRepository struct {
@@ -40,8 +48,18 @@ func NewDriverRepository(dbClient *mongo.Client) DriverRepository {
 			Keys: bson.D{{Key: "location", Value: "2dsphere"}},
 		},
 		{
-			Keys:    bson.D{{Key: "email", Value: 1}},
-			Options: options.Index().SetUnique(true).SetName("drivers_email_unique"),
+			Keys: bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"email": bson.M{"$type": "string"}}).
+				SetName("drivers_email_unique_partial"),
+		},
+		{
+			Keys: bson.D{{Key: "phone", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"phone": bson.M{"$type": "string"}}).
+				SetName("drivers_phone_unique"),
 		},
 		{
 			Keys: bson.D{
@@ -55,7 +73,13 @@ func NewDriverRepository(dbClient *mongo.Client) DriverRepository {
 			Options: options.Index().SetUnique(true).SetSparse(true).SetName("drivers_vehicle_id_unique"),
 		},
 	}
-	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+	// The first email index also covered drivers without an email, so it
+	// allowed only one phone-only driver. It is replaced by a partial one.
+	_, err := collection.Indexes().DropOne(context.Background(), "drivers_email_unique")
+	if err != nil && !isIndexNotFound(err) {
+		utils.ErrorBackground("failed to drop old driver email index", "error", err)
+	}
+	_, err = collection.Indexes().CreateMany(context.Background(), indexes)
 	if err != nil {
 		utils.ErrorBackground("failed to create driver indexes", "error", err)
 	}
@@ -63,6 +87,13 @@ func NewDriverRepository(dbClient *mongo.Client) DriverRepository {
 	return &driverRepository{collection}
 }
 
+// isIndexNotFound reports the IndexNotFound or NamespaceNotFound error from
+// dropping an index that, or whose collection, does not exist.
+func isIndexNotFound(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && (serverErr.HasErrorCode(27) || serverErr.HasErrorCode(26))
+}
+
 func (r *driverRepository) Create(ctx context.Context, driver *models.Driver) error {
 	opCtx, cancel := utils.DBContext(ctx)
 	defer cancel()
@@ -92,6 +123,18 @@ func (r *driverRepository) FindByEmail(ctx context.Context, email string) (*mode
 	return &driver, nil
 }
 
+func (r *driverRepository) FindByPhone(ctx context.Context, phone string) (*models.Driver, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var driver models.Driver
+	err := r.collection.FindOne(opCtx, bson.M{"phone": phone}).Decode(&driver)
+	if err != nil {
+		return nil, err
+	}
+	return &driver, nil
+}
+
 func (r *driverRepository) FindAvailableDrivers(ctx context.Context, location models.Location, vehicleType string) ([]*models.Driver, error) {
 	opCtx, cancel := utils.DBContext(ctx)
 	defer cancel()
@@ -276,3 +319,25 @@ func (r *driverRepository) GetTotalDrivers(ctx context.Context) (int64, error) {
 	count, err := r.collection.CountDocuments(opCtx, bson.M{})
 	return count, err
 }
+
+func (r *driverRepository) MarkEmailVerified(ctx context.Context, driverID string, at time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(opCtx, bson.M{"_id": driverID, "email_verified_at": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"email_verified_at": at}})
+	return err
+}
+
+func (r *driverRepository) UpdatePassword(ctx context.Context, driverID, currentHash, newHash string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(opCtx, bson.M{"_id": driverID, "password_hash": currentHash}, bson.M{"$set": bson.M{"password_hash": newHash}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EventOutboxRepository stores notifications and domain events until the
+// outbox relay has published them.
+type EventOutboxRepository interface {
+	Insert(ctx context.Context, entry *models.EventOutboxEntry) error
+	// FindPending returns up to limit pending entries that are due at now,
+	// oldest first. It skips every entry of an aggregate whose head is
+	// waiting for a retry, so entries behind it are not published early.
+	FindPending(ctx context.Context, now time.Time, limit int64) ([]*models.EventOutboxEntry, error)
+	MarkPublished(ctx context.Context, id string, publishedAt time.Time) error
+	MarkFailed(ctx context.Context, id string, attempts int, lastError string, nextAttemptAt time.Time) error
+	MarkDead(ctx context.Context, id string, attempts int, lastError string) error
+	// AcquireLease makes owner the only relay until ttl passes. It returns
+	// false while another owner holds an unexpired lease.
+	AcquireLease(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+}
+
+type eventOutboxRepository struct {
+	collection *mongo.Collection
+	leases     *mongo.Collection
+}
+
+// NewEventOutboxRepository keeps published entries for retention before
+// MongoDB expires them; pending and dead entries are kept until handled.
+func NewEventOutboxRepository(dbClient *mongo.Client, retention time.Duration) EventOutboxRepository {
+	db := dbClient.Database("logi")
+	collection := db.Collection("event_outbox")
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "created_at", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "next_attempt_at", Value: 1},
+			},
+		},
+		{
+			Keys:    bson.D{{Key: "published_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		},
+	}
+	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		utils.ErrorBackground("failed to create event outbox indexes", "error", err)
+	}
+	return &eventOutboxRepository{
+		collection: collection,
+		leases:     db.Collection("event_outbox_leases"),
+	}
+}
+
+func (r *eventOutboxRepository) Insert(ctx context.Context, entry *models.EventOutboxEntry) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(opCtx, entry)
+	return err
+}
+
+func (r *eventOutboxRepository) FindPending(ctx context.Context, now time.Time, limit int64) ([]*models.EventOutboxEntry, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	// Only an aggregate's head is ever retried, because the relay stops at
+	// the first failure. A pending entry that is not due is therefore the
+	// head of a blocked aggregate.
+	blocked, err := r.collection.Distinct(opCtx, "aggregate_id", bson.M{
+		"status":          models.EventOutboxStatusPending,
+		"next_attempt_at": bson.M{"$gt": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"status":          models.EventOutboxStatusPending,
+		"next_attempt_at": bson.M{"$not": bson.M{"$gt": now}},
+	}
+	if len(blocked) > 0 {
+		filter["aggregate_id"] = bson.M{"$nin": blocked}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit)
+	cursor, err := r.collection.Find(opCtx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var entries []*models.EventOutboxEntry
+	if err := cursor.All(opCtx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *eventOutboxRepository) MarkPublished(ctx context.Context, id string, publishedAt time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateByID(opCtx, id, bson.M{
+		"$set":   bson.M{"status": models.EventOutboxStatusPublished, "published_at": publishedAt},
+		"$inc":   bson.M{"attempts": 1},
+		"$unset": bson.M{"last_error": ""},
+	})
+	return err
+}
+
+func (r *eventOutboxRepository) MarkFailed(ctx context.Context, id string, attempts int, lastError string, nextAttemptAt time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateByID(opCtx, id, bson.M{
+		"$set": bson.M{"attempts": attempts, "last_error": lastError, "next_attempt_at": nextAttemptAt},
+	})
+	return err
+}
+
+func (r *eventOutboxRepository) MarkDead(ctx context.Context, id string, attempts int, lastError string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateByID(opCtx, id, bson.M{
+		"$set": bson.M{"status": models.EventOutboxStatusDead, "attempts": attempts, "last_error": lastError},
+	})
+	return err
+}
+
+func (r *eventOutboxRepository) AcquireLease(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"_id": "relay",
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expires_at": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(ttl)}}
+	_, err := r.leases.UpdateOne(opCtx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The lease exists and belongs to someone else.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
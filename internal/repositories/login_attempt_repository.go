@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoginAttemptRepository interface {
+	// Find returns mongo.ErrNoDocuments when key has no recorded failures.
+	Find(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// RecordFailure counts a failure for key at now, starting a new count when
+	// the current one began before windowStart, and returns the result.
+	RecordFailure(ctx context.Context, key string, now, windowStart, expiresAt time.Time) (*models.LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Delete clears key; it returns mongo.ErrNoDocuments when there was nothing to clear.
+	Delete(ctx context.Context, key string) error
+	FindLocked(ctx context.Context, now time.Time) ([]*models.LoginAttempt, error)
+}
+
+type loginAttemptRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLoginAttemptRepository(dbClient *mongo.Client) LoginAttemptRepository {
+	collection := dbClient.Database("logi").Collection("login_attempts")
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "locked_until", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}
+	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		utils.ErrorBackground("failed to create login attempt indexes", "error", err)
+	}
+	return &loginAttemptRepository{collection}
+}
+
+func (r *loginAttemptRepository) Find(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var attempt models.LoginAttempt
+	if err := r.collection.FindOne(opCtx, bson.M{"_id": key}).Decode(&attempt); err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, key string, now, windowStart, expiresAt time.Time) (*models.LoginAttempt, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	// An update pipeline, so the window check and the increment are atomic.
+	inWindow := bson.M{"$gte": bson.A{"$window_started_at", windowStart}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"failures":          bson.M{"$cond": bson.A{inWindow, bson.M{"$add": bson.A{"$failures", 1}}, 1}},
+		"window_started_at": bson.M{"$cond": bson.A{inWindow, "$window_started_at", now}},
+		"last_failure_at":   now,
+		"expires_at":        bson.M{"$max": bson.A{"$expires_at", expiresAt}},
+	}}}}
+	var attempt models.LoginAttempt
+	err := r.collection.FindOneAndUpdate(opCtx, bson.M{"_id": key}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(opCtx, bson.M{"_id": key}, bson.M{
+		"$set": bson.M{"locked_until": until},
+		"$max": bson.M{"expires_at": until},
+	})
+	return err
+}
+
+func (r *loginAttemptRepository) Delete(ctx context.Context, key string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(opCtx, bson.M{"_id": key})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) FindLocked(ctx context.Context, now time.Time) ([]*models.LoginAttempt, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	cursor, err := r.collection.Find(opCtx, bson.M{"locked_until": bson.M{"$gt": now}}, options.Find().SetSort(bson.D{{Key: "locked_until", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var attempts []*models.LoginAttempt
+	if err := cursor.All(opCtx, &attempts); err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
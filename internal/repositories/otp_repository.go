@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OTPRepository interface {
+	// Find returns mongo.ErrNoDocuments when phone has no outstanding code.
+	Find(ctx context.Context, phone string) (*models.OTPCode, error)
+	// Save replaces the outstanding code for the code's phone. The stored
+	// attempt count is kept when code.WindowStart matches the stored window
+	// and reset otherwise; code.Attempts is ignored.
+	Save(ctx context.Context, code *models.OTPCode) error
+	// ClaimAttempt counts a guess at phone's code and returns the code, as
+	// one atomic step, unless the code expired by now or maxAttempts guesses
+	// were already counted. It returns mongo.ErrNoDocuments in that case.
+	ClaimAttempt(ctx context.Context, phone string, maxAttempts int, now time.Time) (*models.OTPCode, error)
+	// Delete removes phone's code; it returns mongo.ErrNoDocuments when there
+	// was none, so only one caller can use a code.
+	Delete(ctx context.Context, phone string) error
+}
+
+type otpRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOTPRepository(dbClient *mongo.Client) OTPRepository {
+	collection := dbClient.Database("logi").Collection("otp_codes")
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "purge_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}
+	// Records used to be removed when the code expired, which also dropped
+	// the attempt count. They now live until purge_at.
+	_, err := collection.Indexes().DropOne(context.Background(), "expires_at_1")
+	if err != nil && !isIndexNotFound(err) {
+		utils.ErrorBackground("failed to drop old otp code index", "error", err)
+	}
+	_, err = collection.Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		utils.ErrorBackground("failed to create otp code indexes", "error", err)
+	}
+	return &otpRepository{collection}
+}
+
+func (r *otpRepository) Find(ctx context.Context, phone string) (*models.OTPCode, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var code models.OTPCode
+	if err := r.collection.FindOne(opCtx, bson.M{"_id": phone}).Decode(&code); err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *otpRepository) Save(ctx context.Context, code *models.OTPCode) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	// A pipeline update decides on the stored window in the same write, so a
+	// guess counted while the code is replaced is not lost.
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"code_hash":    code.CodeHash,
+		"sent_at":      code.SentAt,
+		"expires_at":   code.ExpiresAt,
+		"purge_at":     code.PurgeAt,
+		"window_start": code.WindowStart,
+		"attempts": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$window_start", code.WindowStart}},
+			"$attempts",
+			0,
+		}},
+	}}}}
+	_, err := r.collection.UpdateOne(opCtx, bson.M{"_id": code.Phone}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *otpRepository) ClaimAttempt(ctx context.Context, phone string, maxAttempts int, now time.Time) (*models.OTPCode, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":        phone,
+		"attempts":   bson.M{"$lt": maxAttempts},
+		"expires_at": bson.M{"$gt": now},
+	}
+	var code models.OTPCode
+	err := r.collection.FindOneAndUpdate(opCtx, filter, bson.M{"$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&code)
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *otpRepository) Delete(ctx context.Context, phone string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(opCtx, bson.M{"_id": phone})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OutboxRepository stores per-recipient websocket messages for replay after reconnects.
+type OutboxRepository interface {
+	// NextSeq atomically reserves the next sequence number for a recipient.
+	NextSeq(ctx context.Context, role, recipientID string) (uint64, error)
+	Insert(ctx context.Context, message *models.OutboxMessage) error
+	FindAfter(ctx context.Context, role, recipientID string, afterSeq uint64) ([]*models.OutboxMessage, error)
+	// DeleteUpTo removes every message for the recipient with seq <= seq.
+	DeleteUpTo(ctx context.Context, role, recipientID string, seq uint64) error
+}
+
+type outboxRepository struct {
+	collection *mongo.Collection
+	sequences  *mongo.Collection
+}
+
+func NewOutboxRepository(dbClient *mongo.Client) OutboxRepository {
+	db := dbClient.Database("logi")
+	collection := db.Collection("websocket_outbox")
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "role", Value: 1},
+				{Key: "recipient_id", Value: 1},
+				{Key: "seq", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		utils.ErrorBackground("failed to create websocket outbox indexes", "error", err)
+	}
+	return &outboxRepository{
+		collection: collection,
+		sequences:  db.Collection("websocket_outbox_sequences"),
+	}
+}
+
+func (r *outboxRepository) NextSeq(ctx context.Context, role, recipientID string) (uint64, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := r.sequences.FindOneAndUpdate(
+		opCtx,
+		bson.M{"_id": role + ":" + recipientID},
+		bson.M{"$inc": bson.M{"seq": int64(1)}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(counter.Seq), nil
+}
+
+func (r *outboxRepository) Insert(ctx context.Context, message *models.OutboxMessage) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(opCtx, message)
+	return err
+}
+
+func (r *outboxRepository) FindAfter(ctx context.Context, role, recipientID string, afterSeq uint64) ([]*models.OutboxMessage, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"role":         role,
+		"recipient_id": recipientID,
+		"seq":          bson.M{"$gt": int64(afterSeq)},
+	}
+	cursor, err := r.collection.Find(opCtx, filter, options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var messages []*models.OutboxMessage
+	if err := cursor.All(opCtx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *outboxRepository) DeleteUpTo(ctx context.Context, role, recipientID string, seq uint64) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(opCtx, bson.M{
+		"role":         role,
+		"recipient_id": recipientID,
+		"seq":          bson.M{"$lte": int64(seq)},
+	})
+	return err
+}
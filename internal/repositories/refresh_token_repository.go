@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RefreshTokenRepository interface {
+	Insert(ctx context.Context, token *models.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	FindByAccessTokenID(ctx context.Context, accessTokenID string) (*models.RefreshToken, error)
+	FindFamily(ctx context.Context, familyID string) ([]*models.RefreshToken, error)
+	// FindActiveByUser returns userID's refresh tokens that are not revoked.
+	FindActiveByUser(ctx context.Context, userID string) ([]*models.RefreshToken, error)
+	// MarkUsed uses up an unused, unrevoked token and reports whether this
+	// call did so; false means the token was already used or revoked.
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+}
+
+type refreshTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRefreshTokenRepository(dbClient *mongo.Client) RefreshTokenRepository {
+	collection := dbClient.Database("logi").Collection("refresh_tokens")
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "access_token_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}
+	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		utils.ErrorBackground("failed to create refresh token indexes", "error", err)
+	}
+	return &refreshTokenRepository{collection}
+}
+
+func (r *refreshTokenRepository) Insert(ctx context.Context, token *models.RefreshToken) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(opCtx, token)
+	return err
+}
+
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	return r.findOne(ctx, bson.M{"token_hash": tokenHash})
+}
+
+func (r *refreshTokenRepository) FindByAccessTokenID(ctx context.Context, accessTokenID string) (*models.RefreshToken, error) {
+	return r.findOne(ctx, bson.M{"access_token_id": accessTokenID})
+}
+
+func (r *refreshTokenRepository) findOne(ctx context.Context, filter bson.M) (*models.RefreshToken, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var token models.RefreshToken
+	if err := r.collection.FindOne(opCtx, filter).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *refreshTokenRepository) FindFamily(ctx context.Context, familyID string) ([]*models.RefreshToken, error) {
+	return r.find(ctx, bson.M{"family_id": familyID})
+}
+
+func (r *refreshTokenRepository) FindActiveByUser(ctx context.Context, userID string) ([]*models.RefreshToken, error) {
+	return r.find(ctx, bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}})
+}
+
+func (r *refreshTokenRepository) find(ctx context.Context, filter bson.M) ([]*models.RefreshToken, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	cursor, err := r.collection.Find(opCtx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var tokens []*models.RefreshToken
+	if err := cursor.All(opCtx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(opCtx,
+		bson.M{"_id": id, "used_at": bson.M{"$exists": false}, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": usedAt}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateMany(opCtx,
+		bson.M{"family_id": familyID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	return err
+}
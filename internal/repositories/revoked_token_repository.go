@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"logi/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RevokedTokenRepository is the access token revocation list, keyed by jti.
+// Entries expire with the token they revoke.
+type RevokedTokenRepository interface {
+	Revoke(ctx context.Context, tokenID string, expiresAt, revokedAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+type revokedTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRevokedTokenRepository(dbClient *mongo.Client) RevokedTokenRepository {
+	collection := dbClient.Database("logi").Collection("revoked_tokens")
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := collection.Indexes().CreateOne(context.Background(), index); err != nil {
+		utils.ErrorBackground("failed to create revoked token index", "error", err)
+	}
+	return &revokedTokenRepository{collection}
+}
+
+func (r *revokedTokenRepository) Revoke(ctx context.Context, tokenID string, expiresAt, revokedAt time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(opCtx,
+		bson.M{"_id": tokenID},
+		bson.M{"$setOnInsert": bson.M{"expires_at": expiresAt, "revoked_at": revokedAt}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *revokedTokenRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	err := r.collection.FindOne(opCtx, bson.M{"_id": tokenID}, options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
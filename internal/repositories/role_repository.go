@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RoleRepository interface {
+	// Seed inserts the roles that do not exist yet, leaving edited ones alone.
+	Seed(ctx context.Context, roles []*models.Role) error
+	FindAll(ctx context.Context) ([]*models.Role, error)
+	FindByName(ctx context.Context, name string) (*models.Role, error)
+	Upsert(ctx context.Context, role *models.Role) error
+	Delete(ctx context.Context, name string) error
+}
+
+type roleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRoleRepository(dbClient *mongo.Client) RoleRepository {
+	return &roleRepository{dbClient.Database("logi").Collection("admin_roles")}
+}
+
+func (r *roleRepository) Seed(ctx context.Context, roles []*models.Role) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	for _, role := range roles {
+		_, err := r.collection.UpdateOne(opCtx,
+			bson.M{"_id": role.Name},
+			bson.M{"$setOnInsert": bson.M{"description": role.Description, "permissions": role.Permissions, "updated_at": role.UpdatedAt}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *roleRepository) FindAll(ctx context.Context) ([]*models.Role, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	cursor, err := r.collection.Find(opCtx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var roles []*models.Role
+	if err := cursor.All(opCtx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var role models.Role
+	if err := r.collection.FindOne(opCtx, bson.M{"_id": name}).Decode(&role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) Upsert(ctx context.Context, role *models.Role) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.ReplaceOne(opCtx, bson.M{"_id": role.Name}, role, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *roleRepository) Delete(ctx context.Context, name string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(opCtx, bson.M{"_id": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// activeSpeedProfileID is the single document holding the calibrated profile in use.
+const activeSpeedProfileID = "active"
+
+type SpeedProfileRepository interface {
+	Save(ctx context.Context, profile *models.SpeedProfile) error
+	FindActive(ctx context.Context) (*models.SpeedProfile, error)
+}
+
+type speedProfileRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSpeedProfileRepository(dbClient *mongo.Client) SpeedProfileRepository {
+	collection := dbClient.Database("logi").Collection("speed_profiles")
+	return &speedProfileRepository{collection}
+}
+
+func (r *speedProfileRepository) Save(ctx context.Context, profile *models.SpeedProfile) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	profile.ID = activeSpeedProfileID
+	_, err := r.collection.ReplaceOne(
+		opCtx,
+		bson.M{"_id": activeSpeedProfileID},
+		profile,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *speedProfileRepository) FindActive(ctx context.Context) (*models.SpeedProfile, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var profile models.SpeedProfile
+	err := r.collection.FindOne(opCtx, bson.M{"_id": activeSpeedProfileID}).Decode(&profile)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
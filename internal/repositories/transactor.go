@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"logi/internal/utils"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs several repository calls atomically. Repository methods
+// called with the ctx passed to fn take part in the transaction.
+type Transactor interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type mongoTransactor struct {
+	client      *mongo.Client
+	unsupported atomic.Bool
+}
+
+// NewMongoTransactor uses MongoDB multi-document transactions, which need a
+// replica set or sharded cluster. On a standalone server, such as a local
+// development database, it logs a warning once and runs fn without one.
+func NewMongoTransactor(dbClient *mongo.Client) Transactor {
+	return &mongoTransactor{client: dbClient}
+}
+
+func (t *mongoTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Nested calls join the surrounding transaction.
+	if t.unsupported.Load() || mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	if isTransactionUnsupported(err) {
+		t.unsupported.Store(true)
+		utils.WarnBackground("mongodb does not support transactions, continuing without them", "error", err)
+		return fn(ctx)
+	}
+	return err
+}
+
+// isTransactionUnsupported reports the IllegalOperation error a standalone
+// server returns for the first operation in a transaction.
+func isTransactionUnsupported(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(20)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"logi/internal/models"
 	"logi/internal/utils"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -15,6 +16,12 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	FindByID(ctx context.Context, userID string) (*models.User, error)
 	GetTotalUsers(ctx context.Context) (int64, error)
+	UpdateOrganization(ctx context.Context, userID, organizationID string) error
+	// MarkEmailVerified records when the email was verified, keeping the first time.
+	MarkEmailVerified(ctx context.Context, userID string, at time.Time) error
+	// UpdatePassword replaces the password hash if it is still currentHash,
+	// and returns mongo.ErrNoDocuments otherwise.
+	UpdatePassword(ctx context.Context, userID, currentHash, newHash string) error
 }
 
 type userRepository struct {
@@ -72,3 +79,43 @@ func (r *userRepository) GetTotalUsers(ctx context.Context) (int64, error) {
 	count, err := r.collection.CountDocuments(opCtx, bson.M{})
 	return count, err
 }
+
+func (r *userRepository) UpdateOrganization(ctx context.Context, userID, organizationID string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"organization_id": organizationID}}
+	if organizationID == "" {
+		update = bson.M{"$unset": bson.M{"organization_id": ""}}
+	}
+	result, err := r.collection.UpdateByID(opCtx, userID, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, userID string, at time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(opCtx, bson.M{"_id": userID, "email_verified_at": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"email_verified_at": at}})
+	return err
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, userID, currentHash, newHash string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(opCtx, bson.M{"_id": userID, "password_hash": currentHash}, bson.M{"$set": bson.M{"password_hash": newHash}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
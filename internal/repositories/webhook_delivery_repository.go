@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookDeliveryFilter narrows a delivery log listing; empty fields match all.
+type WebhookDeliveryFilter struct {
+	EndpointID string
+	EventID    string
+	Status     string
+	Limit      int64
+}
+
+// WebhookDeliveryRepository is the webhook delivery log and the queue the
+// webhook deliverer works from.
+type WebhookDeliveryRepository interface {
+	// Insert records a delivery. A second delivery of the same event to the
+	// same endpoint, other than a manual redelivery, is ignored, so events
+	// published more than once are delivered once.
+	Insert(ctx context.Context, delivery *models.WebhookDelivery) error
+	FindByID(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	// List returns matching deliveries, newest first.
+	List(ctx context.Context, filter WebhookDeliveryFilter) ([]*models.WebhookDelivery, error)
+	// ClaimDue returns a pending delivery due by now and pushes its next
+	// attempt to lockUntil, so no other deliverer picks it up meanwhile. It
+	// returns nil when nothing is due.
+	ClaimDue(ctx context.Context, now, lockUntil time.Time) (*models.WebhookDelivery, error)
+	MarkSucceeded(ctx context.Context, id string, attempts int, statusCode int, deliveredAt time.Time) error
+	MarkRetrying(ctx context.Context, id string, attempts int, statusCode int, lastError string, nextAttemptAt time.Time) error
+	MarkFailed(ctx context.Context, id string, attempts int, statusCode int, lastError string) error
+}
+
+type webhookDeliveryRepository struct {
+	collection *mongo.Collection
+}
+
+// NewWebhookDeliveryRepository keeps deliveries for retention before MongoDB
+// expires them.
+func NewWebhookDeliveryRepository(dbClient *mongo.Client, retention time.Duration) WebhookDeliveryRepository {
+	collection := dbClient.Database("logi").Collection("webhook_deliveries")
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "event_id", Value: 1}, {Key: "endpoint_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"redelivery_of": ""}).
+				SetName("webhook_deliveries_event_endpoint_unique"),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "endpoint_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		},
+	}
+	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		utils.ErrorBackground("failed to create webhook delivery indexes", "error", err)
+	}
+	return &webhookDeliveryRepository{collection}
+}
+
+func (r *webhookDeliveryRepository) Insert(ctx context.Context, delivery *models.WebhookDelivery) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(opCtx, delivery)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *webhookDeliveryRepository) FindByID(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var delivery models.WebhookDelivery
+	err := r.collection.FindOne(opCtx, bson.M{"_id": id}).Decode(&delivery)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookDeliveryRepository) List(ctx context.Context, filter WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	query := bson.M{}
+	if filter.EndpointID != "" {
+		query["endpoint_id"] = filter.EndpointID
+	}
+	if filter.EventID != "" {
+		query["event_id"] = filter.EventID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+	cursor, err := r.collection.Find(opCtx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var deliveries []*models.WebhookDelivery
+	if err := cursor.All(opCtx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *webhookDeliveryRepository) ClaimDue(ctx context.Context, now, lockUntil time.Time) (*models.WebhookDelivery, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"status":          models.WebhookDeliveryStatusPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"next_attempt_at": lockUntil}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	err := r.collection.FindOneAndUpdate(opCtx, filter, update, opts).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookDeliveryRepository) MarkSucceeded(ctx context.Context, id string, attempts int, statusCode int, deliveredAt time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateByID(opCtx, id, bson.M{
+		"$set": bson.M{
+			"status":           models.WebhookDeliveryStatusSucceeded,
+			"attempts":         attempts,
+			"last_status_code": statusCode,
+			"delivered_at":     deliveredAt,
+		},
+		"$unset": bson.M{"last_error": ""},
+	})
+	return err
+}
+
+func (r *webhookDeliveryRepository) MarkRetrying(ctx context.Context, id string, attempts int, statusCode int, lastError string, nextAttemptAt time.Time) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateByID(opCtx, id, bson.M{
+		"$set": bson.M{
+			"attempts":         attempts,
+			"last_status_code": statusCode,
+			"last_error":       lastError,
+			"next_attempt_at":  nextAttemptAt,
+		},
+	})
+	return err
+}
+
+func (r *webhookDeliveryRepository) MarkFailed(ctx context.Context, id string, attempts int, statusCode int, lastError string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.UpdateByID(opCtx, id, bson.M{
+		"$set": bson.M{
+			"status":           models.WebhookDeliveryStatusFailed,
+			"attempts":         attempts,
+			"last_status_code": statusCode,
+			"last_error":       lastError,
+		},
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"logi/internal/models"
+	"logi/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type WebhookEndpointRepository interface {
+	Create(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	FindByID(ctx context.Context, id string) (*models.WebhookEndpoint, error)
+	// FindByOwner returns the endpoints of userID and of organizationID,
+	// ignoring an empty one; with both empty it returns every endpoint.
+	FindByOwner(ctx context.Context, userID, organizationID string) ([]*models.WebhookEndpoint, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type webhookEndpointRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookEndpointRepository(dbClient *mongo.Client) WebhookEndpointRepository {
+	collection := dbClient.Database("logi").Collection("webhook_endpoints")
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}}},
+	}
+	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		utils.ErrorBackground("failed to create webhook endpoint indexes", "error", err)
+	}
+	return &webhookEndpointRepository{collection}
+}
+
+func (r *webhookEndpointRepository) Create(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(opCtx, endpoint)
+	return err
+}
+
+func (r *webhookEndpointRepository) FindByID(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	var endpoint models.WebhookEndpoint
+	err := r.collection.FindOne(opCtx, bson.M{"_id": id}).Decode(&endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (r *webhookEndpointRepository) FindByOwner(ctx context.Context, userID, organizationID string) ([]*models.WebhookEndpoint, error) {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	owners := bson.A{}
+	if userID != "" {
+		owners = append(owners, bson.M{"user_id": userID})
+	}
+	if organizationID != "" {
+		owners = append(owners, bson.M{"organization_id": organizationID})
+	}
+	filter := bson.M{}
+	if len(owners) > 0 {
+		filter["$or"] = owners
+	}
+	cursor, err := r.collection.Find(opCtx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+
+	var endpoints []*models.WebhookEndpoint
+	if err := cursor.All(opCtx, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (r *webhookEndpointRepository) Delete(ctx context.Context, id string) error {
+	opCtx, cancel := utils.DBContext(ctx)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(opCtx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"logi/internal/mail"
+	"logi/internal/repositories"
+	"logi/internal/utils"
+	"logi/pkg/auth"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidAccountToken = errors.New("invalid or expired token")
+	ErrInvalidAccountRole  = errors.New("role must be user or driver")
+	ErrWeakPassword        = errors.New("password must be at least 8 characters")
+	ErrEmailNotVerified    = errors.New("email address is not verified")
+)
+
+const (
+	accountTokenAudience = "logi-account"
+	purposeVerifyEmail   = "verify_email"
+	purposeResetPassword = "reset_password"
+	minPasswordLength    = 8
+)
+
+// AccountService verifies the email addresses of users and drivers and resets
+// their passwords. Both flows email a link carrying a token signed with
+// Secret. Tokens are bound to the account's email and password hash, so
+// changing either invalidates every token sent before.
+type AccountService struct {
+	Users       repositories.UserRepository
+	Drivers     repositories.DriverRepository
+	AuthService *auth.AuthService
+	// Tokens, when set, ends the account's sessions after a password reset.
+	Tokens    *TokenService
+	Mailer    mail.Mailer
+	Secret    []byte
+	VerifyURL string
+	ResetURL  string
+	VerifyTTL time.Duration
+	ResetTTL  time.Duration
+}
+
+func NewAccountService(users repositories.UserRepository, drivers repositories.DriverRepository, authService *auth.AuthService, tokens *TokenService, mailer mail.Mailer, secret []byte, verifyURL, resetURL string, verifyTTL, resetTTL time.Duration) *AccountService {
+	return &AccountService{
+		Users:       users,
+		Drivers:     drivers,
+		AuthService: authService,
+		Tokens:      tokens,
+		Mailer:      mailer,
+		Secret:      secret,
+		VerifyURL:   verifyURL,
+		ResetURL:    resetURL,
+		VerifyTTL:   verifyTTL,
+		ResetTTL:    resetTTL,
+	}
+}
+
+// DeriveAccountTokenSecret derives a key for account tokens from the JWT
+// secret, for installs without account_token_secret, so the two never sign
+// with the same key.
+func DeriveAccountTokenSecret(jwtSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(jwtSecret))
+	mac.Write([]byte("logi account tokens"))
+	return mac.Sum(nil)
+}
+
+// account is the part of a user or driver the flows need.
+type account struct {
+	role         string
+	id           string
+	email        string
+	passwordHash string
+	verified     bool
+}
+
+type accountClaims struct {
+	Purpose     string `json:"purpose"`
+	Role        string `json:"role"`
+	Fingerprint string `json:"fp"`
+	jwt.RegisteredClaims
+}
+
+// SendVerification emails a verification link to the user or driver with
+// email, unless it is already verified. Unknown emails are ignored, so the
+// caller cannot tell which addresses have accounts.
+func (s *AccountService) SendVerification(ctx context.Context, role, email string) error {
+	if s == nil {
+		return nil
+	}
+	acct, err := s.findByEmail(ctx, role, email)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil || acct.verified {
+		return err
+	}
+
+	token, err := s.sign(acct, purposeVerifyEmail, s.VerifyTTL)
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      acct.email,
+		Subject: "Verify your Logi email address",
+		Body: fmt.Sprintf("Confirm that this is your email address by opening this link within %s:\n%s\n\nIf you did not sign up for Logi, ignore this email.\n",
+			formatTTL(s.VerifyTTL), tokenLink(s.VerifyURL, token)),
+	})
+}
+
+// Verify marks the email of the account a verification token was sent to as
+// verified.
+func (s *AccountService) Verify(ctx context.Context, token string) error {
+	acct, err := s.parse(ctx, token, purposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+	if acct.verified {
+		return nil
+	}
+	if err := s.markVerified(ctx, acct); err != nil {
+		return err
+	}
+	utils.Info(ctx, "email verified", "role", acct.role, "account_id", acct.id)
+	return nil
+}
+
+// ForgotPassword emails a password reset link to the user or driver with
+// email. Unknown emails are ignored, as in SendVerification.
+func (s *AccountService) ForgotPassword(ctx context.Context, role, email string) error {
+	acct, err := s.findByEmail(ctx, role, email)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		utils.Info(ctx, "password reset requested for unknown email", "role", role)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.sign(acct, purposeResetPassword, s.ResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      acct.email,
+		Subject: "Reset your Logi password",
+		Body: fmt.Sprintf("Choose a new password by opening this link within %s:\n%s\n\nIf you did not ask to reset your password, ignore this email; your password is unchanged.\n",
+			formatTTL(s.ResetTTL), tokenLink(s.ResetURL, token)),
+	})
+}
+
+// ResetPassword sets a new password with a reset token. The token stops
+// working once used, since it is bound to the old password hash, and the
+// account's sessions end. Receiving the email also proves the address, so the
+// email is marked verified.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	acct, err := s.parse(ctx, token, purposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	hash, err := s.AuthService.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	switch acct.role {
+	case "user":
+		err = s.Users.UpdatePassword(ctx, acct.id, acct.passwordHash, hash)
+	case "driver":
+		err = s.Drivers.UpdatePassword(ctx, acct.id, acct.passwordHash, hash)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// The password changed since the token was checked.
+		return ErrInvalidAccountToken
+	}
+	if err != nil {
+		return err
+	}
+	utils.Info(ctx, "password reset", "role", acct.role, "account_id", acct.id)
+
+	if !acct.verified {
+		if err := s.markVerified(ctx, acct); err != nil {
+			utils.Error(ctx, "failed to mark email verified after password reset", "account_id", acct.id, "error", err)
+		}
+	}
+	if s.Tokens != nil {
+		if err := s.Tokens.RevokeUserSessions(ctx, acct.id); err != nil {
+			utils.Error(ctx, "failed to end sessions after password reset", "account_id", acct.id, "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *AccountService) sign(acct *account, purpose string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := accountClaims{
+		Purpose:     purpose,
+		Role:        acct.role,
+		Fingerprint: s.fingerprint(acct),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   acct.id,
+			Audience:  jwt.ClaimStrings{accountTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.Secret)
+}
+
+// parse checks token's signature, expiry and purpose, and that the account's
+// email and password are unchanged since it was signed.
+func (s *AccountService) parse(ctx context.Context, token, purpose string) (*account, error) {
+	var claims accountClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return s.Secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(accountTokenAudience), jwt.WithExpirationRequired())
+	if err != nil || claims.Purpose != purpose {
+		return nil, ErrInvalidAccountToken
+	}
+
+	acct, err := s.findByID(ctx, claims.Role, claims.Subject)
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, ErrInvalidAccountRole) {
+		return nil, ErrInvalidAccountToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(claims.Fingerprint), []byte(s.fingerprint(acct))) {
+		return nil, ErrInvalidAccountToken
+	}
+	return acct, nil
+}
+
+// fingerprint changes whenever the account's email or password does.
+func (s *AccountService) fingerprint(acct *account) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(acct.email + "\x00" + acct.passwordHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func (s *AccountService) findByEmail(ctx context.Context, role, email string) (*account, error) {
+	email = strings.TrimSpace(email)
+	switch role {
+	case "user":
+		user, err := s.Users.FindByEmail(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		return &account{role: role, id: user.ID, email: user.Email, passwordHash: user.PasswordHash, verified: user.EmailVerifiedAt != nil}, nil
+	case "driver":
+		driver, err := s.Drivers.FindByEmail(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		return &account{role: role, id: driver.ID, email: driver.Email, passwordHash: driver.PasswordHash, verified: driver.EmailVerifiedAt != nil}, nil
+	}
+	return nil, ErrInvalidAccountRole
+}
+
+func (s *AccountService) findByID(ctx context.Context, role, id string) (*account, error) {
+	switch role {
+	case "user":
+		user, err := s.Users.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &account{role: role, id: user.ID, email: user.Email, passwordHash: user.PasswordHash, verified: user.EmailVerifiedAt != nil}, nil
+	case "driver":
+		driver, err := s.Drivers.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &account{role: role, id: driver.ID, email: driver.Email, passwordHash: driver.PasswordHash, verified: driver.EmailVerifiedAt != nil}, nil
+	}
+	return nil, ErrInvalidAccountRole
+}
+
+func (s *AccountService) markVerified(ctx context.Context, acct *account) error {
+	if acct.role == "driver" {
+		return s.Drivers.MarkEmailVerified(ctx, acct.id, time.Now())
+	}
+	return s.Users.MarkEmailVerified(ctx, acct.id, time.Now())
+}
+
+// tokenLink adds token to base as the token query parameter, or returns the
+// bare token when no base URL is configured.
+func tokenLink(base, token string) string {
+	link, err := url.Parse(base)
+	if err != nil || base == "" {
+		return token
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		return fmt.Sprintf("%d hours", int(ttl.Hours()))
+	}
+	return fmt.Sprintf("%d minutes", int(ttl.Minutes()))
+}
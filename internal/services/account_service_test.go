@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"logi/internal/mail"
+	"logi/internal/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// newTestAccountService returns an account service over an in-memory user
+// store with one user, rider@example.com, whose password is "old-password".
+func newTestAccountService(t *testing.T) (*AccountService, map[string]*models.User, *mail.MemoryMailer) {
+	t.Helper()
+	tokens, authService := newTestTokenService()
+	hash, err := authService.HashPassword("old-password")
+	if err != nil {
+		t.Fatalf("HashPassword returned error: %v", err)
+	}
+	users := map[string]*models.User{"user-1": {ID: "user-1", Email: "rider@example.com", PasswordHash: hash, Role: "user"}}
+	repo := &fakeUserRepository{
+		findByEmailFn: func(ctx context.Context, email string) (*models.User, error) {
+			for _, user := range users {
+				if user.Email == email {
+					copied := *user
+					return &copied, nil
+				}
+			}
+			return nil, mongo.ErrNoDocuments
+		},
+		findByIDFn: func(ctx context.Context, id string) (*models.User, error) {
+			user, ok := users[id]
+			if !ok {
+				return nil, mongo.ErrNoDocuments
+			}
+			copied := *user
+			return &copied, nil
+		},
+		markVerifiedFn: func(ctx context.Context, id string, at time.Time) error {
+			users[id].EmailVerifiedAt = &at
+			return nil
+		},
+		updatePasswordFn: func(ctx context.Context, id, currentHash, newHash string) error {
+			if users[id].PasswordHash != currentHash {
+				return mongo.ErrNoDocuments
+			}
+			users[id].PasswordHash = newHash
+			return nil
+		},
+	}
+	mailer := &mail.MemoryMailer{}
+	service := NewAccountService(repo, &fakeDriverRepository{}, authService, tokens, mailer, DeriveAccountTokenSecret("0123456789abcdef0123456789abcdef"), "https://app.example.com/verify", "https://app.example.com/reset", 48*time.Hour, 30*time.Minute)
+	return service, users, mailer
+}
+
+func TestAccountServiceVerifiesEmail(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, users, mailer := newTestAccountService(t)
+	userService := &UserService{Repo: service.Users, AuthService: service.AuthService, Accounts: service, RequireVerifiedEmail: true}
+
+	if err := service.SendVerification(ctx, "user", "rider@example.com"); err != nil {
+		t.Fatalf("SendVerification returned error: %v", err)
+	}
+	sent := mailer.Messages()
+	if len(sent) != 1 || sent[0].To != "rider@example.com" || !strings.Contains(sent[0].Body, "https://app.example.com/verify?token=") {
+		t.Fatalf("expected a verification link to be emailed, got %+v", sent)
+	}
+	token := tokenFromBody(t, sent[0].Body)
+
+	if _, err := userService.Login(ctx, "rider@example.com", "old-password"); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("expected login to need a verified email, got %v", err)
+	}
+	if err := service.Verify(ctx, token[:len(token)-2]+"xx"); !errors.Is(err, ErrInvalidAccountToken) {
+		t.Fatalf("expected a tampered token to be rejected, got %v", err)
+	}
+	if err := service.ResetPassword(ctx, token, "new-password"); !errors.Is(err, ErrInvalidAccountToken) {
+		t.Fatalf("expected a verification token to be unable to reset the password, got %v", err)
+	}
+	if err := service.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if users["user-1"].EmailVerifiedAt == nil {
+		t.Fatal("expected the email to be marked verified")
+	}
+	if _, err := userService.Login(ctx, "rider@example.com", "old-password"); err != nil {
+		t.Fatalf("expected login to work once verified, got %v", err)
+	}
+
+	if err := service.SendVerification(ctx, "user", "rider@example.com"); err != nil || len(mailer.Messages()) != 1 {
+		t.Fatalf("expected no email for a verified address, got %v, %d emails", err, len(mailer.Messages()))
+	}
+	if err := service.SendVerification(ctx, "admin", "rider@example.com"); !errors.Is(err, ErrInvalidAccountRole) {
+		t.Fatalf("expected admins to be rejected, got %v", err)
+	}
+}
+
+func TestAccountServiceResetPasswordInvalidatesOutstandingTokens(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, users, mailer := newTestAccountService(t)
+	session, err := service.Tokens.IssueTokens(ctx, "user-1", "user")
+	if err != nil {
+		t.Fatalf("IssueTokens returned error: %v", err)
+	}
+
+	if err := service.ForgotPassword(ctx, "user", "nobody@example.com"); err != nil || len(mailer.Messages()) != 0 {
+		t.Fatalf("expected unknown emails to be ignored silently, got %v, %d emails", err, len(mailer.Messages()))
+	}
+	if err := service.SendVerification(ctx, "user", "rider@example.com"); err != nil {
+		t.Fatalf("SendVerification returned error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := service.ForgotPassword(ctx, "user", "rider@example.com"); err != nil {
+			t.Fatalf("ForgotPassword returned error: %v", err)
+		}
+	}
+	sent := mailer.Messages()
+	verifyToken, first, second := tokenFromBody(t, sent[0].Body), tokenFromBody(t, sent[1].Body), tokenFromBody(t, sent[2].Body)
+
+	if err := service.ResetPassword(ctx, first, "short"); !errors.Is(err, ErrWeakPassword) {
+		t.Fatalf("expected a short password to be rejected, got %v", err)
+	}
+	if err := service.ResetPassword(ctx, first, "new-password"); err != nil {
+		t.Fatalf("ResetPassword returned error: %v", err)
+	}
+	if !service.AuthService.CheckPasswordHash("new-password", users["user-1"].PasswordHash) || users["user-1"].EmailVerifiedAt == nil {
+		t.Fatalf("expected the new password to be set and the email verified, got %+v", users["user-1"])
+	}
+
+	for name, token := range map[string]string{"used reset": first, "other reset": second} {
+		if err := service.ResetPassword(ctx, token, "another-password"); !errors.Is(err, ErrInvalidAccountToken) {
+			t.Fatalf("expected the %s token to stop working, got %v", name, err)
+		}
+	}
+	if err := service.Verify(ctx, verifyToken); !errors.Is(err, ErrInvalidAccountToken) {
+		t.Fatalf("expected the verification token to stop working, got %v", err)
+	}
+	if _, err := service.Tokens.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the reset to end existing sessions, got %v", err)
+	}
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"logi/internal/mail"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/utils"
+	"logi/pkg/auth"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrAdminNotFound         = errors.New("admin not found")
+	ErrAdminExists           = errors.New("admin already exists")
+	ErrInvalidAdmin          = errors.New("name, email and password are required")
+	ErrOwnerRequired         = errors.New("only an owner can do this")
+	ErrCannotRevokeSelf      = errors.New("admins cannot revoke themselves")
+	ErrCannotChangeOwnRole   = errors.New("admins cannot change their own role")
+	ErrInviteNotFound        = errors.New("invite not found")
+	ErrInvalidInvite         = errors.New("invalid or expired invite")
+	ErrAlreadyBootstrapped   = errors.New("an owner already exists")
+	ErrBootstrapDisabled     = errors.New("bootstrap is disabled")
+	ErrInvalidBootstrapToken = errors.New("invalid bootstrap token")
+)
+
+// AdminAccountService manages who can administer the platform. Admin accounts
+// are only created by existing admins, by accepting an emailed invite, or by
+// the one-time bootstrap of the first owner.
+type AdminAccountService struct {
+	Admins  repositories.AdminRepository
+	Invites repositories.AdminInviteRepository
+	// Roles decides which roles an admin may grant.
+	Roles       *RoleService
+	AuthService *auth.AuthService
+	// Tokens ends the sessions of revoked admins.
+	Tokens *TokenService
+	Mailer mail.Mailer
+	// InviteURL is the page that accepts invites; the token is added as the
+	// token query parameter.
+	InviteURL string
+	InviteTTL time.Duration
+	// BootstrapToken, when set, lets BootstrapWithToken create the first owner.
+	BootstrapToken string
+}
+
+func NewAdminAccountService(admins repositories.AdminRepository, invites repositories.AdminInviteRepository, roles *RoleService, authService *auth.AuthService, tokens *TokenService, mailer mail.Mailer, inviteURL string, inviteTTL time.Duration, bootstrapToken string) *AdminAccountService {
+	return &AdminAccountService{
+		Admins:         admins,
+		Invites:        invites,
+		Roles:          roles,
+		AuthService:    authService,
+		Tokens:         tokens,
+		Mailer:         mailer,
+		InviteURL:      inviteURL,
+		InviteTTL:      inviteTTL,
+		BootstrapToken: bootstrapToken,
+	}
+}
+
+// Bootstrap creates the first owner. It succeeds once per install, and only
+// while no owner exists.
+func (s *AdminAccountService) Bootstrap(ctx context.Context, admin *models.Admin, password string) error {
+	owners, err := s.Admins.CountActiveOwners(ctx)
+	if err != nil {
+		return err
+	}
+	if owners > 0 {
+		return ErrAlreadyBootstrapped
+	}
+	if err := validateNewAdmin(admin, password); err != nil {
+		return err
+	}
+	// Checked before the marker is written, so a typo can be retried.
+	if existing, _ := s.Admins.FindByEmail(ctx, admin.Email); existing != nil {
+		return ErrAdminExists
+	}
+	// The marker is claimed first so concurrent bootstraps cannot both create
+	// an owner, and released again if the owner is not created.
+	first, err := s.Admins.MarkBootstrapped(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if !first {
+		return ErrAlreadyBootstrapped
+	}
+	admin.Role = models.AdminRoleOwner
+	admin.CreatedBy = ""
+	if err := s.create(ctx, admin, password); err != nil {
+		if clearErr := s.Admins.ClearBootstrapped(context.WithoutCancel(ctx)); clearErr != nil {
+			utils.Error(ctx, "failed to clear bootstrap marker after failed bootstrap", "error", clearErr)
+		}
+		return err
+	}
+	utils.Info(ctx, "bootstrapped first owner", "admin_id", admin.ID)
+	return nil
+}
+
+// BootstrapWithToken is Bootstrap for callers that present the configured
+// bootstrap token.
+func (s *AdminAccountService) BootstrapWithToken(ctx context.Context, token string, admin *models.Admin, password string) error {
+	if s.BootstrapToken == "" {
+		return ErrBootstrapDisabled
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.BootstrapToken)) != 1 {
+		return ErrInvalidBootstrapToken
+	}
+	return s.Bootstrap(ctx, admin, password)
+}
+
+// Provision creates an admin without an acting admin. It is for operators
+// with database access, through the admin CLI.
+func (s *AdminAccountService) Provision(ctx context.Context, admin *models.Admin, password string) error {
+	if err := validateNewAdmin(admin, password); err != nil {
+		return err
+	}
+	if _, err := s.Roles.Role(ctx, admin.Role); err != nil {
+		return err
+	}
+	admin.CreatedBy = ""
+	return s.create(ctx, admin, password)
+}
+
+// Create adds an admin on behalf of actorID, who must be allowed to grant its
+// role.
+func (s *AdminAccountService) Create(ctx context.Context, actorID string, admin *models.Admin, password string) error {
+	if err := validateNewAdmin(admin, password); err != nil {
+		return err
+	}
+	if _, err := s.authorize(ctx, actorID, admin.Role); err != nil {
+		return err
+	}
+	admin.CreatedBy = actorID
+	return s.create(ctx, admin, password)
+}
+
+// Invite emails a single-use link that lets the recipient create an admin
+// account with role, which actorID must be allowed to grant.
+func (s *AdminAccountService) Invite(ctx context.Context, actorID, email, role string) (*models.AdminInvite, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, ErrInvalidAdmin
+	}
+	role = normalizeAdminRole(role)
+	if _, err := s.authorize(ctx, actorID, role); err != nil {
+		return nil, err
+	}
+	if existing, _ := s.Admins.FindByEmail(ctx, email); existing != nil {
+		return nil, ErrAdminExists
+	}
+
+	token, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	invite := &models.AdminInvite{
+		ID:        uuid.NewString(),
+		Email:     email,
+		Role:      role,
+		TokenHash: hashRefreshToken(token),
+		InvitedBy: actorID,
+		ExpiresAt: now.Add(s.InviteTTL),
+		CreatedAt: now,
+	}
+	if err := s.Invites.Create(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	err = s.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "You have been invited to administer Logi",
+		Body: fmt.Sprintf("You have been invited to join Logi as %s.\n\nAccept the invite before %s:\n%s\n",
+			role, invite.ExpiresAt.UTC().Format(time.RFC1123), s.inviteLink(token)),
+	})
+	if err != nil {
+		if deleteErr := s.Invites.Delete(ctx, invite.ID); deleteErr != nil {
+			utils.Error(ctx, "failed to delete unsent admin invite", "invite_id", invite.ID, "error", deleteErr)
+		}
+		return nil, err
+	}
+	utils.Info(ctx, "admin invited", "invite_id", invite.ID, "role", role, "invited_by", actorID)
+	return invite, nil
+}
+
+func (s *AdminAccountService) ListInvites(ctx context.Context) ([]*models.AdminInvite, error) {
+	return s.Invites.ListPending(ctx, time.Now())
+}
+
+func (s *AdminAccountService) CancelInvite(ctx context.Context, inviteID string) error {
+	err := s.Invites.Delete(ctx, inviteID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInviteNotFound
+	}
+	return err
+}
+
+// AcceptInvite uses up an invite token and creates the invited admin.
+func (s *AdminAccountService) AcceptInvite(ctx context.Context, token, name, password string) (*models.Admin, error) {
+	if token == "" {
+		return nil, ErrInvalidInvite
+	}
+	if strings.TrimSpace(name) == "" || password == "" {
+		return nil, ErrInvalidAdmin
+	}
+	invite, err := s.Invites.Accept(ctx, hashRefreshToken(token), time.Now())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidInvite
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	admin := &models.Admin{Name: strings.TrimSpace(name), Email: invite.Email, Role: invite.Role, CreatedBy: invite.InvitedBy}
+	if err := s.create(ctx, admin, password); err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+func (s *AdminAccountService) List(ctx context.Context) ([]*models.Admin, error) {
+	return s.Admins.List(ctx)
+}
+
+// SetRole changes the role of adminID. actorID must be allowed to grant both
+// the old and the new role, so admins cannot demote those above them.
+func (s *AdminAccountService) SetRole(ctx context.Context, actorID, adminID, role string) error {
+	role = normalizeAdminRole(role)
+	actor, err := s.authorize(ctx, actorID, role)
+	if err != nil {
+		return err
+	}
+	if actorID == adminID {
+		return ErrCannotChangeOwnRole
+	}
+	target, err := s.Admins.FindByID(ctx, adminID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrAdminNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.Roles.CanGrant(ctx, actor, normalizeAdminRole(target.Role)); err != nil && !errors.Is(err, ErrRoleNotFound) {
+		return err
+	}
+
+	err = s.Admins.UpdateRole(ctx, adminID, role)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrAdminNotFound
+	}
+	if err != nil {
+		return err
+	}
+	utils.Info(ctx, "admin role changed", "admin_id", adminID, "role", role, "changed_by", actorID)
+	return nil
+}
+
+// Revoke disables adminID and ends its sessions. Only owners can revoke, and
+// not themselves, so at least one owner always remains.
+func (s *AdminAccountService) Revoke(ctx context.Context, actorID, adminID string) error {
+	if _, err := s.authorize(ctx, actorID, models.AdminRoleOwner); err != nil {
+		return err
+	}
+	if actorID == adminID {
+		return ErrCannotRevokeSelf
+	}
+	err := s.Admins.Revoke(ctx, adminID, time.Now())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrAdminNotFound
+	}
+	if err != nil {
+		return err
+	}
+	utils.Info(ctx, "admin revoked", "admin_id", adminID, "revoked_by", actorID)
+	return s.Tokens.RevokeUserSessions(ctx, adminID)
+}
+
+// authorize checks that actorID is an active admin allowed to grant role.
+func (s *AdminAccountService) authorize(ctx context.Context, actorID, role string) (*models.Admin, error) {
+	actor, err := s.Roles.activeAdmin(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.AdminRoleOwner && !actor.IsOwner() {
+		return nil, ErrOwnerRequired
+	}
+	if err := s.Roles.CanGrant(ctx, actor, role); err != nil {
+		return nil, err
+	}
+	return actor, nil
+}
+
+func (s *AdminAccountService) create(ctx context.Context, admin *models.Admin, password string) error {
+	if existing, _ := s.Admins.FindByEmail(ctx, admin.Email); existing != nil {
+		return ErrAdminExists
+	}
+	hashedPassword, err := s.AuthService.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	admin.ID = uuid.NewString()
+	admin.PasswordHash = hashedPassword
+	admin.CreatedAt = time.Now()
+	admin.RevokedAt = nil
+
+	err = s.Admins.Create(ctx, admin)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAdminExists
+	}
+	return err
+}
+
+func (s *AdminAccountService) inviteLink(token string) string {
+	return tokenLink(s.InviteURL, token)
+}
+
+// validateNewAdmin trims admin and defaults its role to admin.
+func validateNewAdmin(admin *models.Admin, password string) error {
+	admin.Name = strings.TrimSpace(admin.Name)
+	admin.Email = strings.TrimSpace(admin.Email)
+	if admin.Name == "" || admin.Email == "" || password == "" {
+		return ErrInvalidAdmin
+	}
+	admin.Role = normalizeAdminRole(admin.Role)
+	return nil
+}
+
+func normalizeAdminRole(role string) string {
+	role = strings.TrimSpace(role)
+	if role == "" {
+		return models.AdminRoleAdmin
+	}
+	return role
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"logi/internal/mail"
+	"logi/internal/models"
+)
+
+func newTestAdminAccountService() (*AdminAccountService, *mail.MemoryMailer) {
+	tokens, authService := newTestTokenService()
+	mailer := &mail.MemoryMailer{}
+	admins := &fakeAdminRepository{}
+	roles := NewRoleService(&fakeRoleRepository{}, admins)
+	roles.SeedDefaultRoles(context.Background())
+	service := NewAdminAccountService(admins, &fakeAdminInviteRepository{}, roles, authService, tokens, mailer, "https://admin.example.com/accept", time.Hour, "bootstrap-token-that-is-32-characters")
+	return service, mailer
+}
+
+func TestAdminAccountServiceBootstrapsOnlyOnce(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, _ := newTestAdminAccountService()
+
+	if err := service.BootstrapWithToken(ctx, "wrong", &models.Admin{Name: "Owner", Email: "owner@example.com"}, "secret"); !errors.Is(err, ErrInvalidBootstrapToken) {
+		t.Fatalf("expected the wrong token to be rejected, got %v", err)
+	}
+	owner := &models.Admin{Name: "Owner", Email: "owner@example.com", Role: models.AdminRoleAdmin}
+	if err := service.BootstrapWithToken(ctx, service.BootstrapToken, owner, "secret"); err != nil {
+		t.Fatalf("BootstrapWithToken returned error: %v", err)
+	}
+	if owner.Role != models.AdminRoleOwner {
+		t.Fatalf("expected the bootstrapped admin to be an owner, got %q", owner.Role)
+	}
+	second := &models.Admin{Name: "Attacker", Email: "attacker@example.com"}
+	if err := service.BootstrapWithToken(ctx, service.BootstrapToken, second, "secret"); !errors.Is(err, ErrAlreadyBootstrapped) {
+		t.Fatalf("expected a second bootstrap to fail, got %v", err)
+	}
+
+	service.BootstrapToken = ""
+	if err := service.BootstrapWithToken(ctx, "", second, "secret"); !errors.Is(err, ErrBootstrapDisabled) {
+		t.Fatalf("expected bootstrap without a configured token to be disabled, got %v", err)
+	}
+}
+
+func TestAdminAccountServiceBootstrapCanBeRetriedAfterFailedCreate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, _ := newTestAdminAccountService()
+	admins := service.Admins.(*fakeAdminRepository)
+
+	admins.createErr = errors.New("database unavailable")
+	if err := service.Bootstrap(ctx, &models.Admin{Name: "Owner", Email: "owner@example.com"}, "secret"); err == nil {
+		t.Fatal("expected Bootstrap to fail when the owner cannot be created")
+	}
+	if admins.bootstrapped {
+		t.Fatal("expected a failed bootstrap to release the marker")
+	}
+
+	admins.createErr = nil
+	if err := service.Bootstrap(ctx, &models.Admin{Name: "Owner", Email: "owner@example.com"}, "secret"); err != nil {
+		t.Fatalf("expected the retried bootstrap to succeed, got %v", err)
+	}
+}
+
+func TestAdminAccountServiceInviteIsSingleUse(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, mailer := newTestAdminAccountService()
+	owner := &models.Admin{Name: "Owner", Email: "owner@example.com"}
+	if err := service.Bootstrap(ctx, owner, "secret"); err != nil {
+		t.Fatalf("Bootstrap returned error: %v", err)
+	}
+	admin := &models.Admin{Name: "Admin", Email: "admin@example.com"}
+	if err := service.Create(ctx, owner.ID, admin, "secret"); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if _, err := service.Invite(ctx, admin.ID, "new-owner@example.com", models.AdminRoleOwner); !errors.Is(err, ErrOwnerRequired) {
+		t.Fatalf("expected an admin to be unable to invite an owner, got %v", err)
+	}
+	invite, err := service.Invite(ctx, admin.ID, "new@example.com", "")
+	if err != nil {
+		t.Fatalf("Invite returned error: %v", err)
+	}
+	sent := mailer.Messages()
+	if invite.Role != models.AdminRoleAdmin || len(sent) != 1 || sent[0].To != "new@example.com" {
+		t.Fatalf("expected an admin invite to be emailed, got %+v, %+v", invite, sent)
+	}
+
+	token := tokenFromBody(t, sent[0].Body)
+	accepted, err := service.AcceptInvite(ctx, token, "New Admin", "secret")
+	if err != nil {
+		t.Fatalf("AcceptInvite returned error: %v", err)
+	}
+	if accepted.Email != "new@example.com" || accepted.Role != models.AdminRoleAdmin || accepted.CreatedBy != admin.ID {
+		t.Fatalf("unexpected admin from invite: %+v", accepted)
+	}
+	if _, err := service.AcceptInvite(ctx, token, "Again", "secret"); !errors.Is(err, ErrInvalidInvite) {
+		t.Fatalf("expected a used invite to be rejected, got %v", err)
+	}
+}
+
+func TestAdminAccountServiceRevokeRequiresOwnerAndEndsSessions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, _ := newTestAdminAccountService()
+	owner := &models.Admin{Name: "Owner", Email: "owner@example.com"}
+	if err := service.Bootstrap(ctx, owner, "secret"); err != nil {
+		t.Fatalf("Bootstrap returned error: %v", err)
+	}
+	admin := &models.Admin{Name: "Admin", Email: "admin@example.com"}
+	if err := service.Create(ctx, owner.ID, admin, "secret"); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	session, err := service.Tokens.IssueTokens(ctx, admin.ID, "admin")
+	if err != nil {
+		t.Fatalf("IssueTokens returned error: %v", err)
+	}
+
+	if err := service.Revoke(ctx, admin.ID, owner.ID); !errors.Is(err, ErrOwnerRequired) {
+		t.Fatalf("expected an admin to be unable to revoke, got %v", err)
+	}
+	if err := service.Revoke(ctx, owner.ID, owner.ID); !errors.Is(err, ErrCannotRevokeSelf) {
+		t.Fatalf("expected an owner to be unable to revoke themselves, got %v", err)
+	}
+	if err := service.Revoke(ctx, owner.ID, admin.ID); err != nil {
+		t.Fatalf("Revoke returned error: %v", err)
+	}
+	if _, err := service.AuthService.Authenticate(ctx, session.Token); err == nil {
+		t.Fatal("expected the revoked admin's access token to stop working")
+	}
+	if _, err := service.Tokens.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the revoked admin's refresh token to stop working, got %v", err)
+	}
+	if err := service.Create(ctx, admin.ID, &models.Admin{Name: "X", Email: "x@example.com"}, "secret"); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected a revoked admin to be unable to create admins, got %v", err)
+	}
+}
+
+func tokenFromBody(t *testing.T, body string) string {
+	t.Helper()
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "https://") {
+			link, err := url.Parse(line)
+			if err != nil {
+				t.Fatalf("invalid link %q: %v", line, err)
+			}
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no link in %q", body)
+	return ""
+}
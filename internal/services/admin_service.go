@@ -6,11 +6,14 @@ import (
 	"logi/internal/models"
 	"logi/internal/repositories"
 	"logi/pkg/auth"
-	"time"
+	"strings"
 
-	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ErrUserNotFound is returned when an admin operation targets an unknown user.
+var ErrUserNotFound = errors.New("user not found")
+
 type AdminService struct {
 	Repo        repositories.AdminRepository
 	AuthService *auth.AuthService
@@ -18,6 +21,7 @@ type AdminService struct {
 	DriverRepo  repositories.DriverRepository
 	BookingRepo repositories.BookingRepository
 	VehicleRepo repositories.VehicleRepository
+	Throttle    *LoginThrottle
 }
 
 func NewAdminService(repo repositories.AdminRepository, authService *auth.AuthService, userRepo repositories.UserRepository, driverRepo repositories.DriverRepository, bookingRepo repositories.BookingRepository, vehicleRepo repositories.VehicleRepository) *AdminService {
@@ -31,34 +35,18 @@ func NewAdminService(repo repositories.AdminRepository, authService *auth.AuthSe
 	}
 }
 
-func (s *AdminService) Register(ctx context.Context, admin *models.Admin, password string) error {
-	existingAdmin, _ := s.Repo.FindByEmail(ctx, admin.Email)
-	if existingAdmin != nil {
-		return errors.New("admin already exists")
-	}
-
-	hashedPassword, err := s.AuthService.HashPassword(password)
-	if err != nil {
-		return err
-	}
-
-	admin.ID = uuid.NewString()
-	admin.PasswordHash = hashedPassword
-	admin.CreatedAt = time.Now()
-
-	return s.Repo.Create(ctx, admin)
-}
-
 func (s *AdminService) Login(ctx context.Context, email, password string) (*models.Admin, error) {
-	admin, err := s.Repo.FindByEmail(ctx, email)
-	if err != nil {
-		return nil, errors.New("invalid email or password")
+	if err := s.Throttle.Check(ctx, "admin", email); err != nil {
+		return nil, err
 	}
 
-	if !s.AuthService.CheckPasswordHash(password, admin.PasswordHash) {
+	admin, err := s.Repo.FindByEmail(ctx, email)
+	if err != nil || !admin.IsActive() || !s.AuthService.CheckPasswordHash(password, admin.PasswordHash) {
+		s.Throttle.RecordFailure(ctx, "admin", email)
 		return nil, errors.New("invalid email or password")
 	}
 
+	s.Throttle.RecordSuccess(ctx, "admin", email)
 	return admin, nil
 }
 
@@ -127,3 +115,14 @@ func (s *AdminService) AssignVehicleToDriver(ctx context.Context, driverID, vehi
 
 	return nil
 }
+
+// SetUserOrganization moves a user into an organization, whose webhooks then
+// receive the user's booking events. An empty organizationID removes the user
+// from its organization.
+func (s *AdminService) SetUserOrganization(ctx context.Context, userID, organizationID string) error {
+	err := s.UserRepo.UpdateOrganization(ctx, userID, strings.TrimSpace(organizationID))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrUserNotFound
+	}
+	return err
+}
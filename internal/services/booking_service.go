@@ -3,34 +3,71 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"logi/internal/events"
 	"logi/internal/messaging"
 	"logi/internal/models"
+	"logi/internal/outbox"
 	"logi/internal/repositories"
+	"logi/internal/services/distance"
+	"logi/internal/services/geocoding"
 	"logi/internal/utils"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrInvalidPlace is returned when a pickup or dropoff cannot be turned into
+// coordinates from what the client sent.
+var ErrInvalidPlace = errors.New("invalid place")
+
 type BookingService struct {
 	Repo            repositories.BookingRepository
 	DriverRepo      repositories.DriverRepository
 	PricingService  *PricingService
 	MessagingClient messaging.MessagingClient
+	Geocoder        geocoding.Geocoder
+	// DistanceCalc, when set, orders booking offers by the drivers' travel
+	// time to the pickup rather than by straight-line distance.
+	DistanceCalc distance.DistanceCalculator
+	Events       events.Publisher
+	// Outbox, when set, records notifications and events in the same
+	// transaction as the state change; otherwise they are published directly.
+	Outbox *outbox.Writer
+
+	reverseGeocodeTimeout time.Duration
 }
 
-func NewBookingService(repo repositories.BookingRepository, driverRepo repositories.DriverRepository, pricingService *PricingService, messagingClient messaging.MessagingClient) *BookingService {
+func NewBookingService(repo repositories.BookingRepository, driverRepo repositories.DriverRepository, pricingService *PricingService, messagingClient messaging.MessagingClient, geocoder geocoding.Geocoder, distanceCalc distance.DistanceCalculator, eventPublisher events.Publisher, outboxWriter *outbox.Writer) *BookingService {
 	return &BookingService{
-		Repo:            repo,
-		DriverRepo:      driverRepo,
-		PricingService:  pricingService,
-		MessagingClient: messagingClient,
+		Repo:                  repo,
+		DriverRepo:            driverRepo,
+		PricingService:        pricingService,
+		MessagingClient:       messagingClient,
+		Geocoder:              geocoder,
+		DistanceCalc:          distanceCalc,
+		Events:                eventPublisher,
+		Outbox:                outboxWriter,
+		reverseGeocodeTimeout: defaultReverseGeocodeTimeout,
 	}
 }
 
 func (s *BookingService) CreateBooking(ctx context.Context, userID string, bookingReq *models.BookingRequest) (*models.Booking, error) {
+	pickupLocation, pickupPlace, err := s.resolveLocation(ctx, "pickup", bookingReq.PickupLocation, bookingReq.PickupPlace)
+	if err != nil {
+		return nil, err
+	}
+	dropoffLocation, dropoffPlace, err := s.resolveLocation(ctx, "dropoff", bookingReq.DropoffLocation, bookingReq.DropoffPlace)
+	if err != nil {
+		return nil, err
+	}
+	pickupPlace = s.describePlace(ctx, "pickup", pickupLocation, pickupPlace)
+	dropoffPlace = s.describePlace(ctx, "dropoff", dropoffLocation, dropoffPlace)
+
 	// Calculate price with surge pricing
-	price, err := s.PricingService.CalculatePrice(ctx, bookingReq.PickupLocation, bookingReq.DropoffLocation, bookingReq.VehicleType)
+	price, err := s.PricingService.CalculatePrice(ctx, pickupLocation, dropoffLocation, bookingReq.VehicleType)
 	if err != nil {
 		utils.Error(ctx, "failed to calculate price", "user_id", userID, "vehicle_type", bookingReq.VehicleType, "error", err)
 		return nil, errors.New("failed to calculate price")
@@ -39,8 +76,10 @@ func (s *BookingService) CreateBooking(ctx context.Context, userID string, booki
 	booking := &models.Booking{
 		ID:                   uuid.NewString(),
 		UserID:               userID,
-		PickupLocation:       bookingReq.PickupLocation,
-		DropoffLocation:      bookingReq.DropoffLocation,
+		PickupLocation:       pickupLocation,
+		DropoffLocation:      dropoffLocation,
+		PickupPlace:          pickupPlace,
+		DropoffPlace:         dropoffPlace,
 		VehicleType:          bookingReq.VehicleType,
 		PriceEstimate:        price,
 		Status:               models.BookingStatusPending,
@@ -53,7 +92,12 @@ func (s *BookingService) CreateBooking(ctx context.Context, userID string, booki
 	}
 
 	// Save booking to the database
-	err = s.Repo.Create(ctx, booking)
+	err = inTransaction(ctx, s.Outbox, func(ctx context.Context) error {
+		if err := s.Repo.Create(ctx, booking); err != nil {
+			return err
+		}
+		return publishEvent(ctx, s.Outbox, s.Events, events.BookingCreated, booking.ID, events.NewBookingCreated(booking))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -103,12 +147,25 @@ func (s *BookingService) assignBookingToDrivers(ctx context.Context, booking *mo
 		}
 		recipientDrivers = append(recipientDrivers, driver)
 	}
+	recipientDrivers = s.rankByTravelTime(ctx, booking, recipientDrivers)
 
 	booking.OfferedDriverIDs = make([]string, 0, len(recipientDrivers))
 	for _, driver := range recipientDrivers {
 		booking.OfferedDriverIDs = append(booking.OfferedDriverIDs, driver.ID)
 	}
-	if err := s.Repo.Update(ctx, booking); err != nil {
+	err = inTransaction(ctx, s.Outbox, func(ctx context.Context) error {
+		if err := s.Repo.Update(ctx, booking); err != nil {
+			return err
+		}
+		for _, driver := range recipientDrivers {
+			recipient := messaging.ToDriver(driver.ID).WithTopics(messaging.BookingTopic(booking.ID))
+			if err := notify(ctx, s.Outbox, s.MessagingClient, booking.ID, recipient, messaging.TypeNewBookingRequest, messaging.NewBookingRequest(booking)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
@@ -117,18 +174,54 @@ func (s *BookingService) assignBookingToDrivers(ctx context.Context, booking *mo
 		return errors.New("no eligible drivers available")
 	}
 
-	publishedCount := 0
-	for _, driver := range recipientDrivers {
-		err := s.MessagingClient.Publish(driver.ID, "new_booking_request", booking)
-		if err != nil {
-			utils.Warn(ctx, "failed to send booking request to driver", "booking_id", booking.ID, "driver_id", driver.ID, "error", err)
-			continue
+	utils.Info(ctx, "booking request dispatched", "booking_id", booking.ID, "recipient_count", len(recipientDrivers))
+	return nil
+}
+
+// maxRankedDrivers caps the drivers ranked by travel time, keeping the matrix
+// request within what routing backends accept in one call.
+const maxRankedDrivers = 25
+
+// rankByTravelTime orders the nearest drivers, as returned by the repository,
+// by their travel time to the pickup, using a single matrix request. Drivers
+// without a route, and those beyond maxRankedDrivers, follow in their
+// original order. Without DistanceCalc, or when the request fails, the order
+// is left unchanged.
+func (s *BookingService) rankByTravelTime(ctx context.Context, booking *models.Booking, drivers []*models.Driver) []*models.Driver {
+	if s.DistanceCalc == nil || len(drivers) < 2 {
+		return drivers
+	}
+	candidates := drivers[:min(len(drivers), maxRankedDrivers)]
+	origins := make([]models.Location, 0, len(candidates))
+	for _, driver := range candidates {
+		if len(driver.Location.Coordinates) != 2 {
+			return drivers
 		}
-		publishedCount++
+		origins = append(origins, driver.Location)
 	}
 
-	utils.Info(ctx, "booking request dispatched", "booking_id", booking.ID, "recipient_count", publishedCount)
-	return nil
+	matrix, err := s.DistanceCalc.CalculateMatrix(ctx, origins, []models.Location{booking.PickupLocation}, booking.VehicleType)
+	if err != nil || len(matrix) != len(candidates) {
+		utils.Warn(ctx, "failed to rank drivers by travel time", "booking_id", booking.ID, "error", err)
+		return drivers
+	}
+
+	minutes := make(map[string]float64, len(candidates))
+	for i, driver := range candidates {
+		if len(matrix[i]) == 1 && matrix[i][0] != nil {
+			minutes[driver.ID] = matrix[i][0].Duration
+		}
+	}
+	ranked := append([]*models.Driver(nil), drivers...)
+	sort.SliceStable(ranked[:len(candidates)], func(i, j int) bool {
+		a, aOK := minutes[ranked[i].ID]
+		b, bOK := minutes[ranked[j].ID]
+		if aOK != bOK {
+			return aOK
+		}
+		return aOK && a < b
+	})
+	return ranked
 }
 
 func (s *BookingService) ActivateScheduledBookings(ctx context.Context) error {
@@ -161,10 +254,19 @@ func (s *BookingService) ActivateScheduledBookings(ctx context.Context) error {
 }
 
 func (s *BookingService) GetPriceEstimate(ctx context.Context, bookingReq *models.PriceEstimateRequest) (float64, error) {
+	pickupLocation, _, err := s.resolveLocation(ctx, "pickup", bookingReq.PickupLocation, bookingReq.PickupPlace)
+	if err != nil {
+		return 0, err
+	}
+	dropoffLocation, _, err := s.resolveLocation(ctx, "dropoff", bookingReq.DropoffLocation, bookingReq.DropoffPlace)
+	if err != nil {
+		return 0, err
+	}
+
 	price, err := s.PricingService.CalculatePrice(
 		ctx,
-		bookingReq.PickupLocation,
-		bookingReq.DropoffLocation,
+		pickupLocation,
+		dropoffLocation,
 		bookingReq.VehicleType,
 	)
 	if err != nil {
@@ -175,6 +277,12 @@ func (s *BookingService) GetPriceEstimate(ctx context.Context, bookingReq *model
 
 // DriverAcceptsBooking handles driver's acceptance
 func (s *BookingService) DriverAcceptsBooking(ctx context.Context, driverID, bookingID string) error {
+	return inTransaction(ctx, s.Outbox, func(ctx context.Context) error {
+		return s.driverAcceptsBooking(ctx, driverID, bookingID)
+	})
+}
+
+func (s *BookingService) driverAcceptsBooking(ctx context.Context, driverID, bookingID string) error {
 	assigned, err := s.Repo.AssignDriverIfUnassigned(ctx, bookingID, driverID)
 	if err != nil {
 		return err
@@ -205,27 +313,23 @@ func (s *BookingService) DriverAcceptsBooking(ctx context.Context, driverID, boo
 	if err != nil {
 		return err
 	}
-
-	// Publish the status update to admins via MessagingClient
-	publishErr := s.MessagingClient.Publish("", "driver_status_update", map[string]interface{}{
-		"driver_id": driverID,
-		"status":    models.DriverStatusBusy,
-	})
-	if publishErr != nil {
-		// Log the error but do not fail the operation
-		utils.Warn(ctx, "failed to publish driver status update", "driver_id", driverID, "error", publishErr)
+	err = publishEvent(ctx, s.Outbox, s.Events, events.BookingAssigned, booking.ID, events.NewBookingAssigned(booking.ID, booking.UserID, driverID))
+	if err != nil {
+		return err
+	}
+	err = publishEvent(ctx, s.Outbox, s.Events, events.DriverStatusChanged, driverID, events.NewDriverStatusChanged(driverID, models.DriverStatusBusy))
+	if err != nil {
+		return err
 	}
 
-	// Notify user that a driver has accepted the booking
-	err = s.MessagingClient.Publish(booking.UserID, "booking_accepted", map[string]interface{}{
-		"booking_id": booking.ID,
-		"driver_id":  driverID,
-	})
+	// Publish the status update to admins via MessagingClient
+	err = notify(ctx, s.Outbox, s.MessagingClient, driverID, messaging.ToAdmins().WithTopics(messaging.TopicDriversStatus), messaging.TypeDriverStatusUpdate, messaging.NewDriverStatusUpdate(driverID, models.DriverStatusBusy))
 	if err != nil {
-		// Handle messaging errors
+		return err
 	}
 
-	return nil
+	// Notify user that a driver has accepted the booking
+	return notify(ctx, s.Outbox, s.MessagingClient, booking.ID, messaging.ToUser(booking.UserID).WithTopics(messaging.BookingTopic(booking.ID)), messaging.TypeBookingAccepted, messaging.NewBookingAccepted(booking.ID, driverID))
 }
 
 // DriverRejectsBooking handles driver's rejection
@@ -258,3 +362,64 @@ func (s *BookingService) DriverRejectsBooking(ctx context.Context, driverID, boo
 
 	return nil
 }
+
+// defaultReverseGeocodeTimeout bounds the address lookup for a booking created
+// from coordinates. The address is only shown to drivers, so a slow geocoder
+// must not hold up the booking.
+const defaultReverseGeocodeTimeout = 2 * time.Second
+
+// resolveLocation returns the point and a copy of the place details for one
+// end of a booking. Missing coordinates are geocoded from the place's address
+// line; given coordinates are used as they are.
+func (s *BookingService) resolveLocation(ctx context.Context, label string, location models.Location, place *models.Place) (models.Location, *models.Place, error) {
+	var resolved *models.Place
+	if place != nil {
+		copied := *place
+		resolved = &copied
+	}
+
+	if !location.HasCoordinates() {
+		if resolved == nil || strings.TrimSpace(resolved.AddressLine) == "" {
+			return models.Location{}, nil, fmt.Errorf("%w: %s location or address is required", ErrInvalidPlace, label)
+		}
+		if s.Geocoder == nil {
+			return models.Location{}, nil, fmt.Errorf("%w: %s address lookup is not available, provide coordinates", ErrInvalidPlace, label)
+		}
+
+		result, err := s.Geocoder.Geocode(ctx, resolved.AddressLine)
+		if err != nil {
+			utils.Warn(ctx, "failed to geocode address", "place", label, "error", err)
+			if errors.Is(err, geocoding.ErrAddressNotFound) {
+				return models.Location{}, nil, fmt.Errorf("%w: %s address could not be found", ErrInvalidPlace, label)
+			}
+			return models.Location{}, nil, fmt.Errorf("failed to resolve %s address", label)
+		}
+		resolved.FormattedAddress = result.FormattedAddress
+		return result.Location, resolved, nil
+	}
+
+	location.Type = "Point"
+	return location, resolved, nil
+}
+
+// describePlace fills in the formatted address of a place given only by
+// coordinates, so drivers can see where they are going. The lookup is best
+// effort: on failure or after the timeout the place is returned
+// unchanged.
+func (s *BookingService) describePlace(ctx context.Context, label string, location models.Location, place *models.Place) *models.Place {
+	if s.Geocoder == nil || (place != nil && place.FormattedAddress != "") {
+		return place
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, s.reverseGeocodeTimeout)
+	defer cancel()
+	result, err := s.Geocoder.ReverseGeocode(lookupCtx, location)
+	if err != nil {
+		utils.Warn(ctx, "failed to reverse geocode location", "place", label, "error", err)
+		return place
+	}
+	if place == nil {
+		place = &models.Place{}
+	}
+	place.FormattedAddress = result.FormattedAddress
+	return place
+}
@@ -2,8 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
+	"logi/internal/events"
 	"logi/internal/models"
+	"logi/internal/services/distance"
+	"logi/internal/services/geocoding"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestBookingServiceDriverAcceptsBookingUpdatesStateAndPublishes(t *testing.T) {
@@ -51,7 +57,8 @@ func TestBookingServiceDriverAcceptsBookingUpdatesStateAndPublishes(t *testing.T
 	}
 
 	messaging := &fakeMessagingClient{}
-	service := NewBookingService(bookingRepo, driverRepo, nil, messaging)
+	eventPublisher := &fakeEventPublisher{}
+	service := NewBookingService(bookingRepo, driverRepo, nil, messaging, nil, nil, eventPublisher, nil)
 
 	if err := service.DriverAcceptsBooking(context.Background(), "driver-1", "booking-1"); err != nil {
 		t.Fatalf("DriverAcceptsBooking returned error: %v", err)
@@ -69,12 +76,21 @@ func TestBookingServiceDriverAcceptsBookingUpdatesStateAndPublishes(t *testing.T
 	if len(messaging.published) != 2 {
 		t.Fatalf("expected 2 published messages, got %d", len(messaging.published))
 	}
-	if messaging.published[0].messageType != "driver_status_update" || messaging.published[0].userID != "" {
+	if messaging.published[0].messageType != "driver_status_update" || messaging.published[0].role != "admin" || messaging.published[0].userID != "" {
 		t.Fatalf("unexpected admin publish: %+v", messaging.published[0])
 	}
-	if messaging.published[1].messageType != "booking_accepted" || messaging.published[1].userID != "user-1" {
+	if messaging.published[1].messageType != "booking_accepted" || messaging.published[1].role != "user" || messaging.published[1].userID != "user-1" {
 		t.Fatalf("unexpected user publish: %+v", messaging.published[1])
 	}
+	if len(messaging.published[1].topics) != 1 || messaging.published[1].topics[0] != "bookings.booking-1" {
+		t.Fatalf("expected booking topic on user publish, got %v", messaging.published[1].topics)
+	}
+	if got := eventPublisher.types(); len(got) != 2 || got[0] != events.BookingAssigned || got[1] != events.DriverStatusChanged {
+		t.Fatalf("unexpected domain events: %v", got)
+	}
+	if eventPublisher.published[0].AggregateID != "booking-1" || eventPublisher.published[1].AggregateID != "driver-1" {
+		t.Fatalf("unexpected event aggregates: %+v", eventPublisher.published)
+	}
 }
 
 func TestBookingServiceDriverAcceptsBookingReturnsErrorWhenUnavailable(t *testing.T) {
@@ -95,6 +111,10 @@ func TestBookingServiceDriverAcceptsBookingReturnsErrorWhenUnavailable(t *testin
 		},
 		nil,
 		&fakeMessagingClient{},
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 
 	err := service.DriverAcceptsBooking(context.Background(), "driver-1", "booking-1")
@@ -131,7 +151,7 @@ func TestBookingServiceDriverRejectsBookingSkipsRejectingDriver(t *testing.T) {
 		},
 	}
 	messaging := &fakeMessagingClient{}
-	service := NewBookingService(bookingRepo, driverRepo, nil, messaging)
+	service := NewBookingService(bookingRepo, driverRepo, nil, messaging, nil, nil, nil, nil)
 
 	if err := service.DriverRejectsBooking(context.Background(), "driver-1", "booking-1"); err != nil {
 		t.Fatalf("DriverRejectsBooking returned error: %v", err)
@@ -140,7 +160,7 @@ func TestBookingServiceDriverRejectsBookingSkipsRejectingDriver(t *testing.T) {
 	if len(messaging.published) != 1 {
 		t.Fatalf("expected 1 published message after rejection, got %d", len(messaging.published))
 	}
-	if messaging.published[0].userID != "driver-2" || messaging.published[0].messageType != "new_booking_request" {
+	if messaging.published[0].role != "driver" || messaging.published[0].userID != "driver-2" || messaging.published[0].messageType != "new_booking_request" {
 		t.Fatalf("booking was not reassigned to the next eligible driver: %+v", messaging.published[0])
 	}
 }
@@ -169,6 +189,10 @@ func TestBookingServiceDriverRejectsBookingFailsWhenNoEligibleDriversRemain(t *t
 		},
 		nil,
 		&fakeMessagingClient{},
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 
 	err := service.DriverRejectsBooking(context.Background(), "driver-1", "booking-1")
@@ -176,3 +200,210 @@ func TestBookingServiceDriverRejectsBookingFailsWhenNoEligibleDriversRemain(t *t
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestBookingServiceCreateBookingGeocodesAddresses(t *testing.T) {
+	t.Parallel()
+
+	geocoder := geocoding.NewStaticGeocoder(map[string]geocoding.GeocodeResult{
+		"Gateway of India": {Location: models.Location{Type: "Point", Coordinates: []float64{72.8347, 18.9220}}},
+		"Bandra Station":   {Location: models.Location{Type: "Point", Coordinates: []float64{72.8400, 19.0544}}},
+	})
+	var created *models.Booking
+	bookingRepo := &fakeBookingRepository{
+		createFn: func(ctx context.Context, booking *models.Booking) error {
+			created = booking
+			return nil
+		},
+	}
+	driverRepo := &fakeDriverRepository{}
+	pricingService := NewPricingService(bookingRepo, driverRepo, distance.NewHaversineCalculator())
+	service := NewBookingService(bookingRepo, driverRepo, pricingService, &fakeMessagingClient{}, geocoder, nil, nil, nil)
+
+	scheduled := time.Now().Add(time.Hour)
+	_, err := service.CreateBooking(context.Background(), "user-1", &models.BookingRequest{
+		PickupPlace:     &models.Place{AddressLine: "gateway of india", Landmark: "Near the arch"},
+		DropoffLocation: models.Location{Coordinates: []float64{72.8401, 19.0545}},
+		VehicleType:     "car",
+		ScheduledTime:   &scheduled,
+	})
+	if err != nil {
+		t.Fatalf("CreateBooking returned error: %v", err)
+	}
+
+	if created.PickupLocation.Type != "Point" || created.PickupLocation.Coordinates[0] != 72.8347 {
+		t.Fatalf("pickup was not geocoded: %+v", created.PickupLocation)
+	}
+	if created.PickupPlace.FormattedAddress != "Gateway of India" || created.PickupPlace.Landmark != "Near the arch" {
+		t.Fatalf("unexpected pickup place: %+v", created.PickupPlace)
+	}
+	if created.DropoffLocation.Type != "Point" {
+		t.Fatalf("dropoff type was not normalized: %+v", created.DropoffLocation)
+	}
+	if created.DropoffPlace == nil || created.DropoffPlace.FormattedAddress != "Bandra Station" {
+		t.Fatalf("dropoff was not reverse geocoded: %+v", created.DropoffPlace)
+	}
+	if created.PriceEstimate <= 0 {
+		t.Fatalf("expected a price estimate, got %v", created.PriceEstimate)
+	}
+}
+
+func TestBookingServiceGetPriceEstimateRejectsUnresolvablePlaces(t *testing.T) {
+	t.Parallel()
+
+	geocoder := geocoding.NewStaticGeocoder(map[string]geocoding.GeocodeResult{
+		"Gateway of India": {Location: models.Location{Type: "Point", Coordinates: []float64{72.8347, 18.9220}}},
+	})
+	dropoff := models.Location{Type: "Point", Coordinates: []float64{72.84, 19.05}}
+
+	cases := []struct {
+		name     string
+		geocoder geocoding.Geocoder
+		req      models.PriceEstimateRequest
+	}{
+		{"missing pickup", geocoder, models.PriceEstimateRequest{DropoffLocation: dropoff, VehicleType: "car"}},
+		{"unknown address", geocoder, models.PriceEstimateRequest{PickupPlace: &models.Place{AddressLine: "Atlantis"}, DropoffLocation: dropoff, VehicleType: "car"}},
+		{"no geocoder", nil, models.PriceEstimateRequest{PickupPlace: &models.Place{AddressLine: "Gateway of India"}, DropoffLocation: dropoff, VehicleType: "car"}},
+	}
+	for _, tc := range cases {
+		service := NewBookingService(&fakeBookingRepository{}, &fakeDriverRepository{}, nil, &fakeMessagingClient{}, tc.geocoder, nil, nil, nil)
+		if _, err := service.GetPriceEstimate(context.Background(), &tc.req); !errors.Is(err, ErrInvalidPlace) {
+			t.Fatalf("%s: expected ErrInvalidPlace, got %v", tc.name, err)
+		}
+	}
+}
+
+// slowReverseGeocoder geocodes addresses from Static and counts reverse
+// lookups, each of which blocks until its context is done.
+type slowReverseGeocoder struct {
+	Static   geocoding.Geocoder
+	reverses atomic.Int32
+}
+
+func (g *slowReverseGeocoder) Geocode(ctx context.Context, address string) (*geocoding.GeocodeResult, error) {
+	return g.Static.Geocode(ctx, address)
+}
+
+func (g *slowReverseGeocoder) ReverseGeocode(ctx context.Context, location models.Location) (*geocoding.GeocodeResult, error) {
+	g.reverses.Add(1)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestBookingServiceGetPriceEstimateSkipsReverseGeocoding(t *testing.T) {
+	t.Parallel()
+
+	geocoder := &slowReverseGeocoder{Static: geocoding.NewStaticGeocoder(map[string]geocoding.GeocodeResult{
+		"Gateway of India": {Location: models.Location{Type: "Point", Coordinates: []float64{72.8347, 18.9220}}},
+	})}
+	bookingRepo := &fakeBookingRepository{}
+	driverRepo := &fakeDriverRepository{}
+	pricingService := NewPricingService(bookingRepo, driverRepo, distance.NewHaversineCalculator())
+	service := NewBookingService(bookingRepo, driverRepo, pricingService, &fakeMessagingClient{}, geocoder, nil, nil, nil)
+
+	price, err := service.GetPriceEstimate(context.Background(), &models.PriceEstimateRequest{
+		PickupPlace:     &models.Place{AddressLine: "Gateway of India"},
+		DropoffLocation: models.Location{Coordinates: []float64{72.84, 19.05}},
+		VehicleType:     "car",
+	})
+	if err != nil {
+		t.Fatalf("GetPriceEstimate returned error: %v", err)
+	}
+	if price <= 0 {
+		t.Fatalf("expected a price estimate, got %v", price)
+	}
+	if n := geocoder.reverses.Load(); n != 0 {
+		t.Fatalf("expected no reverse lookups for an estimate, got %d", n)
+	}
+}
+
+func TestBookingServiceCreateBookingDoesNotWaitForReverseGeocoding(t *testing.T) {
+	t.Parallel()
+
+	geocoder := &slowReverseGeocoder{Static: geocoding.NewStaticGeocoder(nil)}
+	var created *models.Booking
+	bookingRepo := &fakeBookingRepository{
+		createFn: func(ctx context.Context, booking *models.Booking) error {
+			created = booking
+			return nil
+		},
+	}
+	driverRepo := &fakeDriverRepository{}
+	pricingService := NewPricingService(bookingRepo, driverRepo, distance.NewHaversineCalculator())
+	service := NewBookingService(bookingRepo, driverRepo, pricingService, &fakeMessagingClient{}, geocoder, nil, nil, nil)
+	service.reverseGeocodeTimeout = 50 * time.Millisecond
+
+	scheduled := time.Now().Add(time.Hour)
+	_, err := service.CreateBooking(context.Background(), "user-1", &models.BookingRequest{
+		PickupLocation:  models.Location{Coordinates: []float64{72.8347, 18.9220}},
+		DropoffLocation: models.Location{Coordinates: []float64{72.84, 19.05}},
+		VehicleType:     "car",
+		ScheduledTime:   &scheduled,
+	})
+	if err != nil {
+		t.Fatalf("CreateBooking returned error: %v", err)
+	}
+	if created.PickupPlace != nil || created.DropoffPlace != nil {
+		t.Fatalf("expected places without addresses, got %+v and %+v", created.PickupPlace, created.DropoffPlace)
+	}
+	if n := geocoder.reverses.Load(); n != 2 {
+		t.Fatalf("expected one reverse lookup per end, got %d", n)
+	}
+}
+
+// matrixCalculator answers matrix requests with a duration per origin
+// longitude, or no route when the longitude is not listed.
+type matrixCalculator struct {
+	minutes map[float64]float64
+	calls   int
+}
+
+func (m *matrixCalculator) Calculate(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (*distance.DistanceResult, error) {
+	return nil, errors.New("not used")
+}
+
+func (m *matrixCalculator) CalculateMatrix(ctx context.Context, origins, destinations []models.Location, vehicleType string) ([][]*distance.DistanceResult, error) {
+	m.calls++
+	matrix := make([][]*distance.DistanceResult, len(origins))
+	for i, origin := range origins {
+		matrix[i] = make([]*distance.DistanceResult, len(destinations))
+		if minutes, ok := m.minutes[origin.Coordinates[0]]; ok {
+			matrix[i][0] = &distance.DistanceResult{Duration: minutes}
+		}
+	}
+	return matrix, nil
+}
+
+func TestBookingServiceAssignBookingToDriversOffersByTravelTime(t *testing.T) {
+	t.Parallel()
+
+	driverAt := func(id string, lon float64) *models.Driver {
+		return &models.Driver{ID: id, Location: models.Location{Type: "Point", Coordinates: []float64{lon, 19}}}
+	}
+	driverRepo := &fakeDriverRepository{
+		findAvailableDriversFn: func(ctx context.Context, location models.Location, vehicleType string) ([]*models.Driver, error) {
+			// Nearest first in a straight line.
+			return []*models.Driver{driverAt("driver-1", 1), driverAt("driver-2", 2), driverAt("driver-3", 3)}, nil
+		},
+	}
+	calc := &matrixCalculator{minutes: map[float64]float64{1: 20, 2: 5}}
+	messaging := &fakeMessagingClient{}
+	service := NewBookingService(&fakeBookingRepository{}, driverRepo, nil, messaging, nil, calc, nil, nil)
+
+	booking := &models.Booking{ID: "booking-1", PickupLocation: models.Location{Type: "Point", Coordinates: []float64{0, 19}}, VehicleType: "car"}
+	if err := service.AssignBookingToDrivers(context.Background(), booking); err != nil {
+		t.Fatalf("AssignBookingToDrivers returned error: %v", err)
+	}
+
+	if calc.calls != 1 {
+		t.Fatalf("expected one matrix request, got %d", calc.calls)
+	}
+	want := []string{"driver-2", "driver-1", "driver-3"}
+	if len(booking.OfferedDriverIDs) != len(want) {
+		t.Fatalf("unexpected offers: %v", booking.OfferedDriverIDs)
+	}
+	for i, id := range want {
+		if booking.OfferedDriverIDs[i] != id || messaging.published[i].userID != id {
+			t.Fatalf("expected offers in travel time order %v, got %v", want, booking.OfferedDriverIDs)
+		}
+	}
+}
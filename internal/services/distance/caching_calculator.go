@@ -0,0 +1,203 @@
+package distance
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheMaxEntries = 10000
+	defaultCacheTTL        = 15 * time.Minute
+	defaultCachePrecision  = 4 // decimal places, roughly 11 m at the equator
+
+	// cacheStatsLogInterval controls how often the hit rate is written to the log.
+	cacheStatsLogInterval = 1000
+)
+
+// CachingCalculator decorates a DistanceCalculator with an LRU cache whose
+// entries expire after a TTL. Coordinates are rounded before lookup so that
+// nearby requests for the same vehicle profile share an entry. Entries are
+// also keyed by hour of week, since durations from a speed profile change
+// with it.
+type CachingCalculator struct {
+	next       DistanceCalculator
+	maxEntries int
+	ttl        time.Duration
+	scale      float64
+	now        func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	hits    int64
+	misses  int64
+}
+
+type cacheEntry struct {
+	key       string
+	result    DistanceResult
+	expiresAt time.Time
+}
+
+// NewCachingCalculator wraps next with a cache of at most maxEntries results
+// kept for ttl. precision is the number of decimal places coordinates are rounded to.
+func NewCachingCalculator(next DistanceCalculator, maxEntries int, ttl time.Duration, precision int) *CachingCalculator {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	if precision <= 0 {
+		precision = defaultCachePrecision
+	}
+	return &CachingCalculator{
+		next:       next,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		scale:      math.Pow10(precision),
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Calculate returns a cached result when available and otherwise delegates to the wrapped calculator.
+func (c *CachingCalculator) Calculate(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (*DistanceResult, error) {
+	key := c.key(pickup, dropoff, vehicleType, models.HourOfWeek(c.now()))
+	if result, ok := c.get(key); ok {
+		c.record(ctx, true)
+		return result, nil
+	}
+	c.record(ctx, false)
+
+	result, err := c.next.Calculate(ctx, pickup, dropoff, vehicleType)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, result)
+	return result, nil
+}
+
+// CalculateMatrix serves the matrix from cache when every pair is present and
+// otherwise fetches the full matrix once and caches each resolved pair.
+func (c *CachingCalculator) CalculateMatrix(ctx context.Context, origins, destinations []models.Location, vehicleType string) ([][]*DistanceResult, error) {
+	hour := models.HourOfWeek(c.now())
+	matrix := make([][]*DistanceResult, len(origins))
+	complete := true
+	for i, origin := range origins {
+		matrix[i] = make([]*DistanceResult, len(destinations))
+		for j, destination := range destinations {
+			result, ok := c.get(c.key(origin, destination, vehicleType, hour))
+			c.record(ctx, ok)
+			if !ok {
+				complete = false
+				continue
+			}
+			matrix[i][j] = result
+		}
+	}
+	if complete {
+		return matrix, nil
+	}
+
+	fetched, err := c.next.CalculateMatrix(ctx, origins, destinations, vehicleType)
+	if err != nil {
+		return nil, err
+	}
+	for i, origin := range origins {
+		for j, destination := range destinations {
+			if fetched[i][j] != nil {
+				c.put(c.key(origin, destination, vehicleType, hour), fetched[i][j])
+			}
+		}
+	}
+	return fetched, nil
+}
+
+// Stats returns the number of cache hits and misses since creation.
+func (c *CachingCalculator) Stats() (hits, misses int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+func (c *CachingCalculator) key(pickup, dropoff models.Location, vehicleType string, hourOfWeek int) string {
+	return fmt.Sprintf("%s|%d|%s|%s", vehicleType, hourOfWeek, c.roundPoint(pickup), c.roundPoint(dropoff))
+}
+
+func (c *CachingCalculator) roundPoint(location models.Location) string {
+	return fmt.Sprintf("%g,%g",
+		math.Round(location.Coordinates[0]*c.scale)/c.scale,
+		math.Round(location.Coordinates[1]*c.scale)/c.scale,
+	)
+}
+
+func (c *CachingCalculator) get(key string) (*DistanceResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if c.now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	result := entry.result
+	return &result, true
+}
+
+func (c *CachingCalculator) put(key string, result *DistanceResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.result = *result
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: *result, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		utils.IncrementCounter("distance_cache_evictions", 1)
+	}
+	utils.SetGauge("distance_cache_entries", int64(c.order.Len()))
+}
+
+func (c *CachingCalculator) record(ctx context.Context, hit bool) {
+	c.mu.Lock()
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	hits, misses := c.hits, c.misses
+	c.mu.Unlock()
+
+	if hit {
+		utils.IncrementCounter("distance_cache_hits", 1)
+	} else {
+		utils.IncrementCounter("distance_cache_misses", 1)
+	}
+
+	if total := hits + misses; total%cacheStatsLogInterval == 0 {
+		utils.Info(ctx, "distance cache stats", "hits", hits, "misses", misses, "hit_rate", float64(hits)/float64(total))
+	}
+}
@@ -0,0 +1,186 @@
+package distance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"logi/internal/models"
+)
+
+type fakeCalculator struct {
+	calls     int
+	matrixErr error
+	err       error
+	result    DistanceResult
+}
+
+func (f *fakeCalculator) Calculate(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (*DistanceResult, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	result := f.result
+	return &result, nil
+}
+
+func (f *fakeCalculator) CalculateMatrix(ctx context.Context, origins, destinations []models.Location, vehicleType string) ([][]*DistanceResult, error) {
+	f.calls++
+	if f.matrixErr != nil {
+		return nil, f.matrixErr
+	}
+	matrix := make([][]*DistanceResult, len(origins))
+	for i := range origins {
+		matrix[i] = make([]*DistanceResult, len(destinations))
+		for j := range destinations {
+			result := f.result
+			matrix[i][j] = &result
+		}
+	}
+	return matrix, nil
+}
+
+func point(lon, lat float64) models.Location {
+	return models.Location{Type: "Point", Coordinates: []float64{lon, lat}}
+}
+
+func TestCachingCalculatorServesRoundedCoordinatesFromCache(t *testing.T) {
+	t.Parallel()
+
+	next := &fakeCalculator{result: DistanceResult{Distance: 5, Duration: 10}}
+	calc := NewCachingCalculator(next, 10, time.Minute, 3)
+
+	if _, err := calc.Calculate(context.Background(), point(72.87771, 19.07601), point(72.8311, 18.922), "car"); err != nil {
+		t.Fatalf("Calculate returned error: %v", err)
+	}
+	result, err := calc.Calculate(context.Background(), point(72.87774, 19.07604), point(72.8311, 18.922), "car")
+	if err != nil {
+		t.Fatalf("Calculate returned error: %v", err)
+	}
+
+	if next.calls != 1 {
+		t.Fatalf("expected rounded coordinates to hit the cache, got %d backend calls", next.calls)
+	}
+	if result.Distance != 5 || result.Duration != 10 {
+		t.Fatalf("unexpected cached result: %+v", result)
+	}
+	if hits, misses := calc.Stats(); hits != 1 || misses != 1 {
+		t.Fatalf("unexpected stats: hits=%d misses=%d", hits, misses)
+	}
+}
+
+func TestCachingCalculatorKeysByVehicleType(t *testing.T) {
+	t.Parallel()
+
+	next := &fakeCalculator{result: DistanceResult{Distance: 5}}
+	calc := NewCachingCalculator(next, 10, time.Minute, 4)
+
+	_, _ = calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+	_, _ = calc.Calculate(context.Background(), point(1, 1), point(2, 2), "truck")
+
+	if next.calls != 2 {
+		t.Fatalf("expected separate entries per vehicle type, got %d backend calls", next.calls)
+	}
+}
+
+func TestCachingCalculatorExpiresEntriesAfterTTL(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	next := &fakeCalculator{result: DistanceResult{Distance: 5}}
+	calc := NewCachingCalculator(next, 10, time.Minute, 4)
+	calc.now = func() time.Time { return now }
+
+	_, _ = calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+	now = now.Add(2 * time.Minute)
+	_, _ = calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+
+	if next.calls != 2 {
+		t.Fatalf("expected expired entry to be refetched, got %d backend calls", next.calls)
+	}
+}
+
+func TestCachingCalculatorKeysByHourOfWeek(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 8, 50, 0, 0, time.UTC)
+	next := &fakeCalculator{result: DistanceResult{Distance: 5}}
+	calc := NewCachingCalculator(next, 10, time.Hour, 4)
+	calc.now = func() time.Time { return now }
+
+	_, _ = calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+	now = now.Add(5 * time.Minute)
+	_, _ = calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+	if next.calls != 1 {
+		t.Fatalf("expected the same hour to hit the cache, got %d backend calls", next.calls)
+	}
+
+	// Rush hour starts: durations from a speed profile may differ.
+	now = now.Add(10 * time.Minute)
+	_, _ = calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+	if next.calls != 2 {
+		t.Fatalf("expected a new hour of week to miss the cache, got %d backend calls", next.calls)
+	}
+}
+
+func TestCachingCalculatorEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	next := &fakeCalculator{result: DistanceResult{Distance: 5}}
+	calc := NewCachingCalculator(next, 2, time.Minute, 4)
+	ctx := context.Background()
+
+	_, _ = calc.Calculate(ctx, point(1, 1), point(2, 2), "car") // a
+	_, _ = calc.Calculate(ctx, point(3, 3), point(4, 4), "car") // b
+	_, _ = calc.Calculate(ctx, point(1, 1), point(2, 2), "car") // touch a
+	_, _ = calc.Calculate(ctx, point(5, 5), point(6, 6), "car") // c evicts b
+	calls := next.calls
+
+	_, _ = calc.Calculate(ctx, point(1, 1), point(2, 2), "car")
+	if next.calls != calls {
+		t.Fatal("expected recently used entry to survive eviction")
+	}
+	_, _ = calc.Calculate(ctx, point(3, 3), point(4, 4), "car")
+	if next.calls != calls+1 {
+		t.Fatal("expected least recently used entry to be evicted")
+	}
+}
+
+func TestCachingCalculatorDoesNotCacheErrors(t *testing.T) {
+	t.Parallel()
+
+	next := &fakeCalculator{err: errors.New("backend down")}
+	calc := NewCachingCalculator(next, 10, time.Minute, 4)
+
+	_, _ = calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+	_, _ = calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+
+	if next.calls != 2 {
+		t.Fatalf("expected errors to bypass the cache, got %d backend calls", next.calls)
+	}
+}
+
+func TestCachingCalculatorMatrixFillsCacheForPairLookups(t *testing.T) {
+	t.Parallel()
+
+	next := &fakeCalculator{result: DistanceResult{Distance: 7}}
+	calc := NewCachingCalculator(next, 10, time.Minute, 4)
+	ctx := context.Background()
+
+	origins := []models.Location{point(1, 1), point(3, 3)}
+	destinations := []models.Location{point(2, 2)}
+	if _, err := calc.CalculateMatrix(ctx, origins, destinations, "car"); err != nil {
+		t.Fatalf("CalculateMatrix returned error: %v", err)
+	}
+	if _, err := calc.CalculateMatrix(ctx, origins, destinations, "car"); err != nil {
+		t.Fatalf("CalculateMatrix returned error: %v", err)
+	}
+	if _, err := calc.Calculate(ctx, point(3, 3), point(2, 2), "car"); err != nil {
+		t.Fatalf("Calculate returned error: %v", err)
+	}
+
+	if next.calls != 1 {
+		t.Fatalf("expected a single backend matrix call, got %d", next.calls)
+	}
+}
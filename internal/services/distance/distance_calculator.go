@@ -1,14 +1,22 @@
 package distance
 
-import "logi/internal/models"
+import (
+	"context"
+	"logi/internal/models"
+)
 
 // DistanceResult holds the distance and duration between two locations.
 type DistanceResult struct {
 	Distance float64 // in kilometers
 	Duration float64 // in minutes
+	Source   string  // backend that produced the result, set by FallbackCalculator
 }
 
 // DistanceCalculator defines the interface for distance and duration calculations.
+// The vehicle type lets routing backends pick a matching travel profile.
 type DistanceCalculator interface {
-	Calculate(pickup, dropoff models.Location) (*DistanceResult, error)
+	Calculate(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (*DistanceResult, error)
+	// CalculateMatrix returns one row per origin and one column per destination.
+	// A nil entry means the backend found no route for that pair.
+	CalculateMatrix(ctx context.Context, origins, destinations []models.Location, vehicleType string) ([][]*DistanceResult, error)
 }
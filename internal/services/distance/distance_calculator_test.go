@@ -0,0 +1,284 @@
+package distance
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"logi/internal/models"
+)
+
+var (
+	testPickup  = models.Location{Type: "Point", Coordinates: []float64{72.8777, 19.0760}}
+	testDropoff = models.Location{Type: "Point", Coordinates: []float64{72.8311, 18.9220}}
+)
+
+func TestOSRMCalculatorParsesRouteAndUsesVehicleProfile(t *testing.T) {
+	t.Parallel()
+
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"code":"Ok","routes":[{"distance":12500,"duration":1800}]}`))
+	}))
+	defer server.Close()
+
+	calc := NewOSRMCalculator(server.URL+"/", map[string]string{"truck": "hgv"})
+
+	result, err := calc.Calculate(context.Background(), testPickup, testDropoff, "truck")
+	if err != nil {
+		t.Fatalf("Calculate returned error: %v", err)
+	}
+
+	if requestedPath != "/route/v1/hgv/72.877700,19.076000;72.831100,18.922000" {
+		t.Fatalf("unexpected request path: %s", requestedPath)
+	}
+	if math.Abs(result.Distance-12.5) > 1e-9 || math.Abs(result.Duration-30) > 1e-9 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestOSRMCalculatorFallsBackToDefaultProfile(t *testing.T) {
+	t.Parallel()
+
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"code":"Ok","routes":[{"distance":1000,"duration":60}]}`))
+	}))
+	defer server.Close()
+
+	calc := NewOSRMCalculator(server.URL, nil)
+	if _, err := calc.Calculate(context.Background(), testPickup, testDropoff, "car"); err != nil {
+		t.Fatalf("Calculate returned error: %v", err)
+	}
+
+	if requestedPath != "/route/v1/driving/72.877700,19.076000;72.831100,18.922000" {
+		t.Fatalf("unexpected request path: %s", requestedPath)
+	}
+}
+
+func TestOSRMCalculatorReturnsErrorOnNoRoute(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":"NoRoute","message":"Impossible route between points"}`))
+	}))
+	defer server.Close()
+
+	calc := NewOSRMCalculator(server.URL, nil)
+	if _, err := calc.Calculate(context.Background(), testPickup, testDropoff, "car"); err == nil {
+		t.Fatal("expected error for NoRoute response")
+	}
+}
+
+func TestGraphHopperCalculatorParsesPathAndUsesVehicleProfile(t *testing.T) {
+	t.Parallel()
+
+	var query map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/route" {
+			t.Errorf("unexpected request path: %s", r.URL.Path)
+		}
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"paths":[{"distance":8400,"time":900000}]}`))
+	}))
+	defer server.Close()
+
+	calc := NewGraphHopperCalculator(server.URL, "gh-key", map[string]string{"bike": "scooter"})
+
+	result, err := calc.Calculate(context.Background(), testPickup, testDropoff, "bike")
+	if err != nil {
+		t.Fatalf("Calculate returned error: %v", err)
+	}
+
+	points := query["point"]
+	if len(points) != 2 || points[0] != "19.076000,72.877700" || points[1] != "18.922000,72.831100" {
+		t.Fatalf("unexpected points: %#v", points)
+	}
+	if got := query["profile"]; len(got) != 1 || got[0] != "scooter" {
+		t.Fatalf("expected scooter profile, got %#v", got)
+	}
+	if got := query["key"]; len(got) != 1 || got[0] != "gh-key" {
+		t.Fatalf("expected api key to be forwarded, got %#v", got)
+	}
+	if math.Abs(result.Distance-8.4) > 1e-9 || math.Abs(result.Duration-15) > 1e-9 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestGraphHopperCalculatorReturnsErrorOnAPIError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"Cannot find point 0"}`))
+	}))
+	defer server.Close()
+
+	calc := NewGraphHopperCalculator(server.URL, "", nil)
+	if _, err := calc.Calculate(context.Background(), testPickup, testDropoff, "car"); err == nil {
+		t.Fatal("expected error for GraphHopper API error")
+	}
+}
+
+func TestGraphHopperCalculatorMatrixFailsOnServerErrorsButNotOnNoRoute(t *testing.T) {
+	t.Parallel()
+
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`<html>bad gateway</html>`))
+			return
+		}
+		if r.URL.Query()["point"][1] == "18.922000,72.831100" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"Connection between locations not found","hints":[{"message":"Connection between locations not found","details":"com.graphhopper.util.exceptions.ConnectionNotFoundException"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"paths":[{"distance":8400,"time":900000}]}`))
+	}))
+	defer server.Close()
+
+	calc := NewGraphHopperCalculator(server.URL, "", nil)
+	origins := []models.Location{testPickup}
+	destinations := []models.Location{testDropoff, testPickup}
+
+	matrix, err := calc.CalculateMatrix(context.Background(), origins, destinations, "car")
+	if err != nil {
+		t.Fatalf("CalculateMatrix returned error: %v", err)
+	}
+	if matrix[0][0] != nil || matrix[0][1] == nil || math.Abs(matrix[0][1].Distance-8.4) > 1e-9 {
+		t.Fatalf("expected only the unconnected pair to be nil, got %+v", matrix[0])
+	}
+
+	failing.Store(true)
+	if matrix, err := calc.CalculateMatrix(context.Background(), origins, destinations, "car"); err == nil {
+		t.Fatalf("expected a server failure to fail the matrix, got %+v", matrix)
+	}
+}
+
+func TestOSRMCalculatorMatrixUsesSingleTableRequest(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	var requestedPath, sources, destinations string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		requestedPath = r.URL.Path
+		sources = r.URL.Query().Get("sources")
+		destinations = r.URL.Query().Get("destinations")
+		_, _ = w.Write([]byte(`{"code":"Ok","distances":[[1000,null]],"durations":[[120,null]]}`))
+	}))
+	defer server.Close()
+
+	calc := NewOSRMCalculator(server.URL, nil)
+	matrix, err := calc.CalculateMatrix(context.Background(), []models.Location{testPickup}, []models.Location{testDropoff, testPickup}, "car")
+	if err != nil {
+		t.Fatalf("CalculateMatrix returned error: %v", err)
+	}
+
+	if requests != 1 {
+		t.Fatalf("expected a single table request, got %d", requests)
+	}
+	if requestedPath != "/table/v1/driving/72.877700,19.076000;72.831100,18.922000;72.877700,19.076000" {
+		t.Fatalf("unexpected request path: %s", requestedPath)
+	}
+	if sources != "0" || destinations != "1;2" {
+		t.Fatalf("unexpected sources/destinations: %q %q", sources, destinations)
+	}
+	if matrix[0][0] == nil || matrix[0][0].Distance != 1 || matrix[0][0].Duration != 2 {
+		t.Fatalf("unexpected routable entry: %+v", matrix[0][0])
+	}
+	if matrix[0][1] != nil {
+		t.Fatalf("expected unreachable pair to be nil, got %+v", matrix[0][1])
+	}
+}
+
+func TestGoogleMapsCalculatorMatrixUsesSingleRequest(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	var origins, destinations string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		origins = r.URL.Query().Get("origins")
+		destinations = r.URL.Query().Get("destinations")
+		_, _ = w.Write([]byte(`{"status":"OK","rows":[
+			{"elements":[{"status":"OK","distance":{"value":2000},"duration":{"value":300}}]},
+			{"elements":[{"status":"ZERO_RESULTS"}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	calc := NewGoogleMapsCalculator("maps-key")
+	calc.Endpoint = server.URL
+
+	matrix, err := calc.CalculateMatrix(context.Background(), []models.Location{testPickup, testDropoff}, []models.Location{testDropoff}, "car")
+	if err != nil {
+		t.Fatalf("CalculateMatrix returned error: %v", err)
+	}
+
+	if requests != 1 {
+		t.Fatalf("expected a single distance matrix request, got %d", requests)
+	}
+	if origins != "19.076000,72.877700|18.922000,72.831100" || destinations != "18.922000,72.831100" {
+		t.Fatalf("unexpected origins/destinations: %q %q", origins, destinations)
+	}
+	if matrix[0][0] == nil || matrix[0][0].Distance != 2 || matrix[0][0].Duration != 5 {
+		t.Fatalf("unexpected routable entry: %+v", matrix[0][0])
+	}
+	if matrix[1][0] != nil {
+		t.Fatalf("expected ZERO_RESULTS element to be nil, got %+v", matrix[1][0])
+	}
+}
+
+func TestGoogleMapsCalculatorHonorsContextCancellation(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	calc := NewGoogleMapsCalculator("maps-key")
+	calc.Endpoint = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := calc.Calculate(ctx, testPickup, testDropoff, "car"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestHaversineCalculatorMatrixMatchesPairwiseCalculation(t *testing.T) {
+	t.Parallel()
+
+	calc := NewHaversineCalculator()
+	origins := []models.Location{testPickup, testDropoff}
+	destinations := []models.Location{testDropoff, testPickup, testDropoff}
+
+	matrix, err := calc.CalculateMatrix(context.Background(), origins, destinations, "car")
+	if err != nil {
+		t.Fatalf("CalculateMatrix returned error: %v", err)
+	}
+
+	for i, origin := range origins {
+		for j, destination := range destinations {
+			want, _ := calc.Calculate(context.Background(), origin, destination, "car")
+			got := matrix[i][j]
+			if math.Abs(got.Distance-want.Distance) > 1e-9 || math.Abs(got.Duration-want.Duration) > 1e-9 {
+				t.Fatalf("matrix[%d][%d] = %+v, want %+v", i, j, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package distance
+
+import (
+	"context"
+	"errors"
+	"logi/internal/models"
+	"logi/internal/utils"
+)
+
+var ErrNoDistanceBackend = errors.New("no distance backend available")
+
+// NamedCalculator pairs a DistanceCalculator with the name reported in results, logs and metrics.
+type NamedCalculator struct {
+	Name       string
+	Calculator DistanceCalculator
+}
+
+// FallbackCalculator tries each backend in order until one answers, e.g.
+// Google Maps, then OSRM, then Haversine. Results carry the answering backend in Source.
+type FallbackCalculator struct {
+	backends []NamedCalculator
+}
+
+// NewFallbackCalculator returns a calculator that walks backends in the given order.
+func NewFallbackCalculator(backends ...NamedCalculator) *FallbackCalculator {
+	return &FallbackCalculator{backends: backends}
+}
+
+// Calculate returns the first successful result from the backend chain.
+func (f *FallbackCalculator) Calculate(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (*DistanceResult, error) {
+	var lastErr error
+	for i, backend := range f.backends {
+		result, err := backend.Calculator.Calculate(ctx, pickup, dropoff, vehicleType)
+		if err == nil {
+			f.recordAnswer(ctx, i, backend.Name)
+			result.Source = backend.Name
+			return result, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		f.recordFailure(ctx, backend.Name, err)
+		lastErr = err
+	}
+	return nil, f.exhausted(lastErr)
+}
+
+// CalculateMatrix returns the first matrix a backend resolves without error.
+func (f *FallbackCalculator) CalculateMatrix(ctx context.Context, origins, destinations []models.Location, vehicleType string) ([][]*DistanceResult, error) {
+	var lastErr error
+	for i, backend := range f.backends {
+		matrix, err := backend.Calculator.CalculateMatrix(ctx, origins, destinations, vehicleType)
+		if err == nil {
+			f.recordAnswer(ctx, i, backend.Name)
+			for _, row := range matrix {
+				for _, result := range row {
+					if result != nil {
+						result.Source = backend.Name
+					}
+				}
+			}
+			return matrix, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		f.recordFailure(ctx, backend.Name, err)
+		lastErr = err
+	}
+	return nil, f.exhausted(lastErr)
+}
+
+func (f *FallbackCalculator) recordAnswer(ctx context.Context, position int, name string) {
+	utils.IncrementCounter("distance_backend_answers."+name, 1)
+	if position > 0 {
+		utils.IncrementCounter("distance_fallbacks", 1)
+		utils.Info(ctx, "distance answered by fallback backend", "backend", name, "position", position)
+	}
+}
+
+func (f *FallbackCalculator) recordFailure(ctx context.Context, name string, err error) {
+	utils.IncrementCounter("distance_backend_failures."+name, 1)
+	utils.Warn(ctx, "distance backend failed", "backend", name, "error", err)
+}
+
+func (f *FallbackCalculator) exhausted(lastErr error) error {
+	if lastErr == nil {
+		return ErrNoDistanceBackend
+	}
+	return errors.Join(ErrNoDistanceBackend, lastErr)
+}
@@ -0,0 +1,90 @@
+package distance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"logi/internal/models"
+)
+
+func TestFallbackCalculatorUsesNextBackendAndRecordsSource(t *testing.T) {
+	t.Parallel()
+
+	primary := &fakeCalculator{err: errors.New("quota exceeded")}
+	secondary := &fakeCalculator{result: DistanceResult{Distance: 3, Duration: 6}}
+	last := &fakeCalculator{result: DistanceResult{Distance: 99}}
+
+	calc := NewFallbackCalculator(
+		NamedCalculator{Name: "google_maps", Calculator: primary},
+		NamedCalculator{Name: "osrm", Calculator: secondary},
+		NamedCalculator{Name: "haversine", Calculator: last},
+	)
+
+	result, err := calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+	if err != nil {
+		t.Fatalf("Calculate returned error: %v", err)
+	}
+
+	if result.Source != "osrm" || result.Distance != 3 {
+		t.Fatalf("expected osrm to answer, got %+v", result)
+	}
+	if primary.calls != 1 || secondary.calls != 1 || last.calls != 0 {
+		t.Fatalf("unexpected backend calls: %d %d %d", primary.calls, secondary.calls, last.calls)
+	}
+}
+
+func TestFallbackCalculatorReturnsErrorWhenEveryBackendFails(t *testing.T) {
+	t.Parallel()
+
+	backendErr := errors.New("osrm unreachable")
+	calc := NewFallbackCalculator(
+		NamedCalculator{Name: "osrm", Calculator: &fakeCalculator{err: backendErr}},
+	)
+
+	_, err := calc.Calculate(context.Background(), point(1, 1), point(2, 2), "car")
+	if !errors.Is(err, ErrNoDistanceBackend) || !errors.Is(err, backendErr) {
+		t.Fatalf("expected wrapped backend error, got %v", err)
+	}
+}
+
+func TestFallbackCalculatorStopsOnContextCancellation(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	secondary := &fakeCalculator{result: DistanceResult{Distance: 3}}
+	calc := NewFallbackCalculator(
+		NamedCalculator{Name: "google_maps", Calculator: &fakeCalculator{err: context.Canceled}},
+		NamedCalculator{Name: "haversine", Calculator: secondary},
+	)
+
+	if _, err := calc.Calculate(ctx, point(1, 1), point(2, 2), "car"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if secondary.calls != 0 {
+		t.Fatal("fallback backend should not be called after cancellation")
+	}
+}
+
+func TestFallbackCalculatorMatrixFallsBack(t *testing.T) {
+	t.Parallel()
+
+	calc := NewFallbackCalculator(
+		NamedCalculator{Name: "google_maps", Calculator: &fakeCalculator{matrixErr: errors.New("boom")}},
+		NamedCalculator{Name: "haversine", Calculator: NewHaversineCalculatorWithDetour(1.3)},
+	)
+
+	matrix, err := calc.CalculateMatrix(context.Background(), []models.Location{point(0, 0)}, []models.Location{point(0, 1)}, "car")
+	if err != nil {
+		t.Fatalf("CalculateMatrix returned error: %v", err)
+	}
+	if matrix[0][0].Source != "haversine" {
+		t.Fatalf("expected haversine to answer, got %q", matrix[0][0].Source)
+	}
+	straight := haversineDistance(0, 0, 1, 0)
+	if got := matrix[0][0].Distance; got < straight*1.29 || got > straight*1.31 {
+		t.Fatalf("expected detour factor to be applied, got %f for straight line %f", got, straight)
+	}
+}
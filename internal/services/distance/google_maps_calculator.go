@@ -1,6 +1,7 @@
 package distance
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,77 +9,115 @@ import (
 	"logi/internal/utils"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
+const googleMapsDistanceMatrixEndpoint = "https://maps.googleapis.com/maps/api/distancematrix/json"
+
 // GoogleMapsCalculator implements the DistanceCalculator interface using Google Maps API.
 type GoogleMapsCalculator struct {
-	APIKey string
-	Client *http.Client
+	APIKey   string
+	Endpoint string
+	Client   *http.Client
 }
 
 // NewGoogleMapsCalculator returns a new instance of GoogleMapsCalculator.
 func NewGoogleMapsCalculator(apiKey string) *GoogleMapsCalculator {
 	return &GoogleMapsCalculator{
-		APIKey: apiKey,
-		Client: &http.Client{Timeout: 10 * time.Second},
+		APIKey:   apiKey,
+		Endpoint: googleMapsDistanceMatrixEndpoint,
+		Client:   &http.Client{Timeout: 10 * time.Second},
 	}
 }
 
 // Calculate computes the distance and duration using Google Maps Distance Matrix API.
-func (g *GoogleMapsCalculator) Calculate(pickup, dropoff models.Location) (*DistanceResult, error) {
-	origin := fmt.Sprintf("%f,%f", pickup.Coordinates[1], pickup.Coordinates[0])
-	destination := fmt.Sprintf("%f,%f", dropoff.Coordinates[1], dropoff.Coordinates[0])
+// Google Maps has no per-vehicle profile for road freight, so the vehicle type is ignored.
+func (g *GoogleMapsCalculator) Calculate(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (*DistanceResult, error) {
+	matrix, err := g.CalculateMatrix(ctx, []models.Location{pickup}, []models.Location{dropoff}, vehicleType)
+	if err != nil {
+		return nil, err
+	}
+
+	if matrix[0][0] == nil {
+		return nil, errors.New("error in distance element from Google Maps API")
+	}
+	return matrix[0][0], nil
+}
+
+// CalculateMatrix resolves every origin/destination pair with a single Distance Matrix request.
+func (g *GoogleMapsCalculator) CalculateMatrix(ctx context.Context, origins, destinations []models.Location, vehicleType string) ([][]*DistanceResult, error) {
+	if len(origins) == 0 || len(destinations) == 0 {
+		return [][]*DistanceResult{}, nil
+	}
 
-	endpoint := "https://maps.googleapis.com/maps/api/distancematrix/json"
 	params := url.Values{}
-	params.Add("origins", origin)
-	params.Add("destinations", destination)
+	params.Add("origins", joinLatLng(origins))
+	params.Add("destinations", joinLatLng(destinations))
 	params.Add("key", g.APIKey)
 	params.Add("units", "metric")
 
-	reqURL := fmt.Sprintf("%s?%s", endpoint, params.Encode())
+	reqURL := fmt.Sprintf("%s?%s", g.Endpoint, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := g.Client.Get(reqURL)
+	resp, err := g.Client.Do(req)
 	if err != nil {
-		utils.ErrorBackground("google maps request failed", "error", err)
+		utils.Error(ctx, "google maps request failed", "error", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		utils.ErrorBackground("google maps returned non-200 response", "status", resp.Status)
+		utils.Error(ctx, "google maps returned non-200 response", "status", resp.Status)
 		return nil, errors.New("failed to fetch distance from Google Maps API")
 	}
 
 	var result GoogleMapsResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		utils.ErrorBackground("failed to decode google maps response", "error", err)
+		utils.Error(ctx, "failed to decode google maps response", "error", err)
 		return nil, err
 	}
 
 	if result.Status != "OK" {
-		utils.ErrorBackground("google maps returned API error", "status", result.Status)
+		utils.Error(ctx, "google maps returned API error", "status", result.Status)
 		return nil, errors.New("error from Google Maps API")
 	}
 
-	if len(result.Rows) == 0 || len(result.Rows[0].Elements) == 0 {
+	if len(result.Rows) != len(origins) {
 		return nil, errors.New("no results from Google Maps API")
 	}
 
-	element := result.Rows[0].Elements[0]
-	if element.Status != "OK" {
-		utils.ErrorBackground("google maps returned element error", "status", element.Status)
-		return nil, errors.New("error in distance element from Google Maps API")
+	matrix := make([][]*DistanceResult, len(origins))
+	for i, row := range result.Rows {
+		if len(row.Elements) != len(destinations) {
+			return nil, errors.New("no results from Google Maps API")
+		}
+		matrix[i] = make([]*DistanceResult, len(destinations))
+		for j, element := range row.Elements {
+			if element.Status != "OK" {
+				utils.Warn(ctx, "google maps returned element error", "origin_index", i, "destination_index", j, "status", element.Status)
+				continue
+			}
+			matrix[i][j] = &DistanceResult{
+				Distance: float64(element.Distance.Value) / 1000.0, // meters to kilometers
+				Duration: float64(element.Duration.Value) / 60.0,   // seconds to minutes
+			}
+		}
 	}
 
-	distanceKm := float64(element.Distance.Value) / 1000.0 // meters to kilometers
-	durationMin := float64(element.Duration.Value) / 60.0  // seconds to minutes
+	return matrix, nil
+}
 
-	return &DistanceResult{
-		Distance: distanceKm,
-		Duration: durationMin,
-	}, nil
+func joinLatLng(locations []models.Location) string {
+	parts := make([]string, len(locations))
+	for i, location := range locations {
+		parts[i] = fmt.Sprintf("%f,%f", location.Coordinates[1], location.Coordinates[0])
+	}
+	return strings.Join(parts, "|")
 }
 
 // GoogleMapsResponse represents the JSON response from Google Maps Distance Matrix API.
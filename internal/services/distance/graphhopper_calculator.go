@@ -0,0 +1,149 @@
+package distance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultGraphHopperProfile = "car"
+
+// errGraphHopperNoRoute marks answers that say the pair cannot be routed, as
+// opposed to the server failing to answer.
+var errGraphHopperNoRoute = errors.New("no route from GraphHopper")
+
+// graphHopperNoRouteDetails are the exceptions GraphHopper reports, in a
+// hint's details, when a point cannot be snapped to the road network or the
+// points are not connected.
+var graphHopperNoRouteDetails = []string{
+	"ConnectionNotFoundException",
+	"PointNotFoundException",
+	"PointOutOfBoundsException",
+}
+
+// GraphHopperCalculator implements the DistanceCalculator interface using a GraphHopper routing server.
+type GraphHopperCalculator struct {
+	BaseURL  string
+	APIKey   string            // optional, only needed for the hosted GraphHopper API
+	Profiles map[string]string // vehicle type -> GraphHopper profile
+	Client   *http.Client
+}
+
+// NewGraphHopperCalculator returns a new instance of GraphHopperCalculator.
+func NewGraphHopperCalculator(baseURL, apiKey string, profiles map[string]string) *GraphHopperCalculator {
+	return &GraphHopperCalculator{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		APIKey:   apiKey,
+		Profiles: profiles,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Calculate computes the road distance and duration using the GraphHopper routing API.
+func (g *GraphHopperCalculator) Calculate(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (*DistanceResult, error) {
+	profile := resolveProfile(g.Profiles, vehicleType, defaultGraphHopperProfile)
+
+	params := url.Values{}
+	params.Add("point", fmt.Sprintf("%f,%f", pickup.Coordinates[1], pickup.Coordinates[0]))
+	params.Add("point", fmt.Sprintf("%f,%f", dropoff.Coordinates[1], dropoff.Coordinates[0]))
+	params.Add("profile", profile)
+	params.Add("calc_points", "false")
+	if g.APIKey != "" {
+		params.Add("key", g.APIKey)
+	}
+
+	reqURL := fmt.Sprintf("%s/route?%s", g.BaseURL, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		utils.Error(ctx, "graphhopper request failed", "profile", profile, "error", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result GraphHopperRouteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		utils.Error(ctx, "failed to decode graphhopper response", "status", resp.Status, "error", err)
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusBadRequest && result.noRoute() {
+		return nil, fmt.Errorf("%w: %s", errGraphHopperNoRoute, result.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		utils.Error(ctx, "graphhopper returned routing error", "status", resp.Status, "message", result.Message)
+		return nil, errors.New("failed to fetch route from GraphHopper")
+	}
+
+	if len(result.Paths) == 0 {
+		return nil, errGraphHopperNoRoute
+	}
+
+	path := result.Paths[0]
+	return &DistanceResult{
+		Distance: path.Distance / 1000.0,           // meters to kilometers
+		Duration: float64(path.Time) / 1000 / 60.0, // milliseconds to minutes
+	}, nil
+}
+
+// CalculateMatrix resolves each pair with the route API. The matrix API is only
+// available on the hosted GraphHopper service, so self-hosted servers get one
+// request per pair. Pairs GraphHopper cannot route are left nil; any other
+// failure fails the whole matrix, so callers can fall back instead of
+// reading an unreachable server as no routes at all.
+func (g *GraphHopperCalculator) CalculateMatrix(ctx context.Context, origins, destinations []models.Location, vehicleType string) ([][]*DistanceResult, error) {
+	matrix := make([][]*DistanceResult, len(origins))
+	for i, origin := range origins {
+		matrix[i] = make([]*DistanceResult, len(destinations))
+		for j, destination := range destinations {
+			result, err := g.Calculate(ctx, origin, destination, vehicleType)
+			if errors.Is(err, errGraphHopperNoRoute) {
+				continue
+			}
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return nil, ctxErr
+				}
+				return nil, err
+			}
+			matrix[i][j] = result
+		}
+	}
+	return matrix, nil
+}
+
+// GraphHopperRouteResponse represents the JSON response from the GraphHopper routing API.
+type GraphHopperRouteResponse struct {
+	Message string `json:"message"`
+	Hints   []struct {
+		Message string `json:"message"`
+		Details string `json:"details"`
+	} `json:"hints"`
+	Paths []struct {
+		Distance float64 `json:"distance"` // in meters
+		Time     int64   `json:"time"`     // in milliseconds
+	} `json:"paths"`
+}
+
+func (r *GraphHopperRouteResponse) noRoute() bool {
+	for _, hint := range r.Hints {
+		for _, detail := range graphHopperNoRouteDetails {
+			if strings.HasSuffix(hint.Details, detail) {
+				return true
+			}
+		}
+	}
+	return false
+}
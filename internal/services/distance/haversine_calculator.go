@@ -1,32 +1,140 @@
 package distance
 
 import (
+	"context"
 	"logi/internal/models"
 	"math"
+	"sync"
+	"time"
 )
 
+const earthRadiusKm = 6371
+
+// Assume average speed of 40 km/h for duration estimation when no speed profile applies
+const haversineAverageSpeedKmh = 40.0
+
 // HaversineCalculator implements the DistanceCalculator interface using the Haversine formula.
-type HaversineCalculator struct{}
+// Durations come from a speed profile keyed by vehicle type and hour of week, which can be
+// replaced at runtime after calibration.
+type HaversineCalculator struct {
+	// DetourFactor scales straight-line distance to approximate road distance.
+	DetourFactor float64
+
+	now       func() time.Time
+	profileMu sync.RWMutex
+	profile   *models.SpeedProfile
+}
 
 // NewHaversineCalculator returns a new instance of HaversineCalculator.
 func NewHaversineCalculator() *HaversineCalculator {
-	return &HaversineCalculator{}
+	return &HaversineCalculator{DetourFactor: 1, now: time.Now}
+}
+
+// NewHaversineCalculatorWithDetour returns a HaversineCalculator that inflates
+// straight-line distances by detourFactor, e.g. 1.3 when standing in for a road router.
+func NewHaversineCalculatorWithDetour(detourFactor float64) *HaversineCalculator {
+	if detourFactor < 1 {
+		detourFactor = 1
+	}
+	return &HaversineCalculator{DetourFactor: detourFactor, now: time.Now}
+}
+
+// SetSpeedProfile replaces the speed profile used for duration estimates.
+func (h *HaversineCalculator) SetSpeedProfile(profile *models.SpeedProfile) {
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+	h.profile = profile
+}
+
+// SpeedProfile returns the speed profile currently in use, or nil when durations use the flat default.
+func (h *HaversineCalculator) SpeedProfile() *models.SpeedProfile {
+	h.profileMu.RLock()
+	defer h.profileMu.RUnlock()
+	return h.profile
 }
 
 // Calculate computes the distance and duration using the Haversine formula.
-func (h *HaversineCalculator) Calculate(pickup, dropoff models.Location) (*DistanceResult, error) {
-	distance := haversineDistance(pickup.Coordinates[1], pickup.Coordinates[0], dropoff.Coordinates[1], dropoff.Coordinates[0])
-	// Assume average speed of 40 km/h for duration estimation
-	duration := (distance / 40.0) * 60.0
+func (h *HaversineCalculator) Calculate(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (*DistanceResult, error) {
+	distance := haversineDistance(pickup.Coordinates[1], pickup.Coordinates[0], dropoff.Coordinates[1], dropoff.Coordinates[0]) * h.detourFactor()
 	return &DistanceResult{
 		Distance: distance,
-		Duration: duration,
+		Duration: (distance / h.speedKmh(vehicleType)) * 60.0,
 	}, nil
 }
 
+// CalculateMatrix computes every origin/destination pair in a single pass,
+// converting each coordinate to radians only once.
+func (h *HaversineCalculator) CalculateMatrix(ctx context.Context, origins, destinations []models.Location, vehicleType string) ([][]*DistanceResult, error) {
+	originPoints := toRadianPoints(origins)
+	destinationPoints := toRadianPoints(destinations)
+
+	detourFactor := h.detourFactor()
+	speed := h.speedKmh(vehicleType)
+	matrix := make([][]*DistanceResult, len(originPoints))
+	for i, origin := range originPoints {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		row := make([]*DistanceResult, len(destinationPoints))
+		for j, destination := range destinationPoints {
+			distance := origin.distanceTo(destination) * detourFactor
+			row[j] = &DistanceResult{
+				Distance: distance,
+				Duration: (distance / speed) * 60.0,
+			}
+		}
+		matrix[i] = row
+	}
+	return matrix, nil
+}
+
+func (h *HaversineCalculator) detourFactor() float64 {
+	if h.DetourFactor < 1 {
+		return 1
+	}
+	return h.DetourFactor
+}
+
+func (h *HaversineCalculator) speedKmh(vehicleType string) float64 {
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+	if speed := h.SpeedProfile().SpeedKmh(vehicleType, now()); speed > 0 {
+		return speed
+	}
+	return haversineAverageSpeedKmh
+}
+
+// radianPoint caches the values of a coordinate that the Haversine formula reuses.
+type radianPoint struct {
+	lat    float64
+	lon    float64
+	cosLat float64
+}
+
+func toRadianPoints(locations []models.Location) []radianPoint {
+	points := make([]radianPoint, len(locations))
+	for i, location := range locations {
+		lat := degreesToRadians(location.Coordinates[1])
+		points[i] = radianPoint{
+			lat:    lat,
+			lon:    degreesToRadians(location.Coordinates[0]),
+			cosLat: math.Cos(lat),
+		}
+	}
+	return points
+}
+
+func (p radianPoint) distanceTo(other radianPoint) float64 {
+	sinDLat := math.Sin((other.lat - p.lat) / 2)
+	sinDLon := math.Sin((other.lon - p.lon) / 2)
+	a := sinDLat*sinDLat + sinDLon*sinDLon*p.cosLat*other.cosLat
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
 // haversineDistance calculates the distance between two points in kilometers.
 func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const EarthRadius = 6371 // Kilometers
 	dLat := degreesToRadians(lat2 - lat1)
 	dLon := degreesToRadians(lon2 - lon1)
 
@@ -34,9 +142,14 @@ func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
 		math.Sin(dLon/2)*math.Sin(dLon/2)*math.Cos(degreesToRadians(lat1))*math.Cos(degreesToRadians(lat2))
 	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
 
-	return EarthRadius * c
+	return earthRadiusKm * c
 }
 
 func degreesToRadians(degrees float64) float64 {
 	return degrees * math.Pi / 180
 }
+
+// StraightLineDistance returns the great-circle distance between two locations in kilometers.
+func StraightLineDistance(a, b models.Location) float64 {
+	return haversineDistance(a.Coordinates[1], a.Coordinates[0], b.Coordinates[1], b.Coordinates[0])
+}
@@ -0,0 +1,174 @@
+package distance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultOSRMProfile = "driving"
+
+// OSRMCalculator implements the DistanceCalculator interface using a self-hosted OSRM routing server.
+type OSRMCalculator struct {
+	BaseURL  string
+	Profiles map[string]string // vehicle type -> OSRM profile
+	Client   *http.Client
+}
+
+// NewOSRMCalculator returns a new instance of OSRMCalculator.
+func NewOSRMCalculator(baseURL string, profiles map[string]string) *OSRMCalculator {
+	return &OSRMCalculator{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Profiles: profiles,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Calculate computes the road distance and duration using the OSRM route service.
+func (o *OSRMCalculator) Calculate(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (*DistanceResult, error) {
+	profile := resolveProfile(o.Profiles, vehicleType, defaultOSRMProfile)
+
+	params := url.Values{}
+	params.Add("overview", "false")
+	params.Add("alternatives", "false")
+
+	reqURL := fmt.Sprintf("%s/route/v1/%s/%s?%s", o.BaseURL, url.PathEscape(profile), joinLngLat([]models.Location{pickup, dropoff}), params.Encode())
+
+	var result OSRMRouteResponse
+	if err := o.get(ctx, reqURL, profile, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Routes) == 0 {
+		return nil, errors.New("no routes from OSRM")
+	}
+
+	route := result.Routes[0]
+	return &DistanceResult{
+		Distance: route.Distance / 1000.0, // meters to kilometers
+		Duration: route.Duration / 60.0,   // seconds to minutes
+	}, nil
+}
+
+// CalculateMatrix resolves every origin/destination pair with a single OSRM table request.
+func (o *OSRMCalculator) CalculateMatrix(ctx context.Context, origins, destinations []models.Location, vehicleType string) ([][]*DistanceResult, error) {
+	if len(origins) == 0 || len(destinations) == 0 {
+		return [][]*DistanceResult{}, nil
+	}
+
+	profile := resolveProfile(o.Profiles, vehicleType, defaultOSRMProfile)
+
+	sources := make([]string, len(origins))
+	for i := range origins {
+		sources[i] = strconv.Itoa(i)
+	}
+	targets := make([]string, len(destinations))
+	for j := range destinations {
+		targets[j] = strconv.Itoa(len(origins) + j)
+	}
+
+	params := url.Values{}
+	params.Add("sources", strings.Join(sources, ";"))
+	params.Add("destinations", strings.Join(targets, ";"))
+	params.Add("annotations", "distance,duration")
+
+	coordinates := joinLngLat(append(append([]models.Location{}, origins...), destinations...))
+	reqURL := fmt.Sprintf("%s/table/v1/%s/%s?%s", o.BaseURL, url.PathEscape(profile), coordinates, params.Encode())
+
+	var result OSRMTableResponse
+	if err := o.get(ctx, reqURL, profile, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Distances) != len(origins) || len(result.Durations) != len(origins) {
+		return nil, errors.New("incomplete table from OSRM")
+	}
+
+	matrix := make([][]*DistanceResult, len(origins))
+	for i := range origins {
+		if len(result.Distances[i]) != len(destinations) || len(result.Durations[i]) != len(destinations) {
+			return nil, errors.New("incomplete table from OSRM")
+		}
+		matrix[i] = make([]*DistanceResult, len(destinations))
+		for j := range destinations {
+			distance, duration := result.Distances[i][j], result.Durations[i][j]
+			if distance == nil || duration == nil {
+				continue // OSRM returns null for unreachable pairs
+			}
+			matrix[i][j] = &DistanceResult{
+				Distance: *distance / 1000.0,
+				Duration: *duration / 60.0,
+			}
+		}
+	}
+
+	return matrix, nil
+}
+
+func (o *OSRMCalculator) get(ctx context.Context, reqURL, profile string, out osrmResponse) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		utils.Error(ctx, "osrm request failed", "profile", profile, "error", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		utils.Error(ctx, "failed to decode osrm response", "status", resp.Status, "error", err)
+		return err
+	}
+
+	if code, message := out.code(); resp.StatusCode != http.StatusOK || code != "Ok" {
+		utils.Error(ctx, "osrm returned routing error", "status", resp.Status, "code", code, "message", message)
+		return errors.New("failed to fetch route from OSRM")
+	}
+	return nil
+}
+
+// osrmResponse exposes the status fields shared by every OSRM service response.
+type osrmResponse interface {
+	code() (string, string)
+}
+
+func joinLngLat(locations []models.Location) string {
+	parts := make([]string, len(locations))
+	for i, location := range locations {
+		parts[i] = fmt.Sprintf("%f,%f", location.Coordinates[0], location.Coordinates[1])
+	}
+	return strings.Join(parts, ";")
+}
+
+// OSRMRouteResponse represents the JSON response from the OSRM route service.
+type OSRMRouteResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Routes  []struct {
+		Distance float64 `json:"distance"` // in meters
+		Duration float64 `json:"duration"` // in seconds
+	} `json:"routes"`
+}
+
+func (r *OSRMRouteResponse) code() (string, string) { return r.Code, r.Message }
+
+// OSRMTableResponse represents the JSON response from the OSRM table service.
+type OSRMTableResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Distances [][]*float64 `json:"distances"` // in meters, null when unreachable
+	Durations [][]*float64 `json:"durations"` // in seconds, null when unreachable
+}
+
+func (r *OSRMTableResponse) code() (string, string) { return r.Code, r.Message }
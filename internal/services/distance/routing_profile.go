@@ -0,0 +1,12 @@
+package distance
+
+import "strings"
+
+// resolveProfile maps a vehicle type to a routing profile, falling back to the
+// backend default when no explicit mapping is configured.
+func resolveProfile(profiles map[string]string, vehicleType, fallback string) string {
+	if profile := strings.TrimSpace(profiles[vehicleType]); profile != "" {
+		return profile
+	}
+	return fallback
+}
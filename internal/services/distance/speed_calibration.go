@@ -0,0 +1,113 @@
+package distance
+
+import (
+	"logi/internal/models"
+	"time"
+)
+
+// Trips outside these bounds are treated as bad data (e.g. a driver who forgot
+// to complete a booking) and ignored during calibration.
+const (
+	minCalibrationTripMinutes = 1.0
+	minCalibrationSpeedKmh    = 1.0
+	maxCalibrationSpeedKmh    = 150.0
+)
+
+// TripSample is a completed trip used to calibrate a SpeedProfile.
+type TripSample struct {
+	VehicleType string
+	Pickup      models.Location
+	Dropoff     models.Location
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+// CalibrateSpeedProfile derives road speeds from completed trips. Each trip's
+// straight-line distance is scaled by detourFactor so that the calibrated speeds
+// reproduce the observed durations when used by a HaversineCalculator with the
+// same factor. Hour-of-week buckets with fewer than minSamples trips are left
+// unset so lookups fall back to the vehicle-wide speed. The base profile's
+// default speed is kept as the fallback for vehicle types with no trips.
+func CalibrateSpeedProfile(trips []TripSample, base models.SpeedProfile, detourFactor float64, minSamples int, loc *time.Location) models.SpeedProfile {
+	if detourFactor < 1 {
+		detourFactor = 1
+	}
+	if minSamples <= 0 {
+		minSamples = 1
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	type bucket struct {
+		distanceKm float64
+		hours      float64
+		samples    int
+	}
+	vehicleBuckets := make(map[string]*bucket)
+	hourBuckets := make(map[string][]bucket)
+
+	used := 0
+	for _, trip := range trips {
+		hours := trip.CompletedAt.Sub(trip.StartedAt).Hours()
+		if hours*60 < minCalibrationTripMinutes {
+			continue
+		}
+		distance := StraightLineDistance(trip.Pickup, trip.Dropoff) * detourFactor
+		speed := distance / hours
+		if speed < minCalibrationSpeedKmh || speed > maxCalibrationSpeedKmh {
+			continue
+		}
+
+		if vehicleBuckets[trip.VehicleType] == nil {
+			vehicleBuckets[trip.VehicleType] = &bucket{}
+			hourBuckets[trip.VehicleType] = make([]bucket, models.HoursPerWeek)
+		}
+		vb := vehicleBuckets[trip.VehicleType]
+		vb.distanceKm += distance
+		vb.hours += hours
+		vb.samples++
+
+		hb := &hourBuckets[trip.VehicleType][models.HourOfWeek(trip.StartedAt.In(loc))]
+		hb.distanceKm += distance
+		hb.hours += hours
+		hb.samples++
+		used++
+	}
+
+	now := time.Now()
+	profile := models.SpeedProfile{
+		DefaultSpeedKmh:     base.DefaultSpeedKmh,
+		VehicleSpeedsKmh:    make(map[string]float64),
+		HourOfWeekSpeedsKmh: make(map[string][]float64),
+		SampleCount:         used,
+		CalibratedAt:        &now,
+	}
+	for vehicleType, speed := range base.VehicleSpeedsKmh {
+		profile.VehicleSpeedsKmh[vehicleType] = speed
+	}
+	for vehicleType, speeds := range base.HourOfWeekSpeedsKmh {
+		profile.HourOfWeekSpeedsKmh[vehicleType] = speeds
+	}
+
+	for vehicleType, vb := range vehicleBuckets {
+		if vb.samples < minSamples {
+			continue
+		}
+		profile.VehicleSpeedsKmh[vehicleType] = vb.distanceKm / vb.hours
+
+		speeds := make([]float64, models.HoursPerWeek)
+		filled := false
+		for hour, hb := range hourBuckets[vehicleType] {
+			if hb.samples >= minSamples {
+				speeds[hour] = hb.distanceKm / hb.hours
+				filled = true
+			}
+		}
+		if filled {
+			profile.HourOfWeekSpeedsKmh[vehicleType] = speeds
+		}
+	}
+
+	return profile
+}
@@ -0,0 +1,93 @@
+package distance
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"logi/internal/models"
+)
+
+func TestHaversineCalculatorUsesHourOfWeekSpeed(t *testing.T) {
+	t.Parallel()
+
+	monday9am := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC) // a Monday
+	hours := make([]float64, models.HoursPerWeek)
+	hours[models.HourOfWeek(monday9am)] = 15
+
+	calc := NewHaversineCalculator()
+	calc.now = func() time.Time { return monday9am }
+	calc.SetSpeedProfile(&models.SpeedProfile{
+		DefaultSpeedKmh:     40,
+		VehicleSpeedsKmh:    map[string]float64{"truck": 30},
+		HourOfWeekSpeedsKmh: map[string][]float64{"car": hours},
+	})
+
+	pickup, dropoff := point(0, 0), point(0, 0.1)
+	distance := StraightLineDistance(pickup, dropoff)
+
+	cases := map[string]float64{
+		"car":   15, // rush hour bucket
+		"truck": 30, // vehicle-wide speed
+		"bike":  40, // profile default
+	}
+	for vehicleType, speed := range cases {
+		result, err := calc.Calculate(context.Background(), pickup, dropoff, vehicleType)
+		if err != nil {
+			t.Fatalf("Calculate returned error: %v", err)
+		}
+		if want := distance / speed * 60; math.Abs(result.Duration-want) > 1e-9 {
+			t.Fatalf("%s: expected duration %f, got %f", vehicleType, want, result.Duration)
+		}
+	}
+}
+
+func TestCalibrateSpeedProfileFitsVehicleAndHourSpeeds(t *testing.T) {
+	t.Parallel()
+
+	pickup, dropoff := point(0, 0), point(0, 0.1)
+	straight := StraightLineDistance(pickup, dropoff)
+	monday9am := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	sunday3am := time.Date(2024, 1, 7, 3, 0, 0, 0, time.UTC)
+
+	trip := func(start time.Time, minutes float64) TripSample {
+		return TripSample{
+			VehicleType: "car",
+			Pickup:      pickup,
+			Dropoff:     dropoff,
+			StartedAt:   start,
+			CompletedAt: start.Add(time.Duration(minutes * float64(time.Minute))),
+		}
+	}
+
+	trips := []TripSample{
+		trip(monday9am, 60), trip(monday9am, 60),
+		trip(sunday3am, 20), trip(sunday3am, 20),
+		trip(sunday3am, 0.1),  // too short, ignored
+		trip(monday9am, 6000), // implausibly slow, ignored
+		{VehicleType: "van", Pickup: pickup, Dropoff: dropoff, StartedAt: monday9am, CompletedAt: monday9am.Add(time.Hour)},
+	}
+
+	profile := CalibrateSpeedProfile(trips, models.SpeedProfile{DefaultSpeedKmh: 40, VehicleSpeedsKmh: map[string]float64{"bike": 25}}, 1.2, 2, time.UTC)
+
+	if profile.SampleCount != 5 {
+		t.Fatalf("expected 5 usable samples, got %d", profile.SampleCount)
+	}
+	road := straight * 1.2
+	if got, want := profile.HourOfWeekSpeedsKmh["car"][models.HourOfWeek(monday9am)], road; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("expected rush hour speed %f, got %f", want, got)
+	}
+	if got, want := profile.HourOfWeekSpeedsKmh["car"][models.HourOfWeek(sunday3am)], road*3; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("expected night speed %f, got %f", want, got)
+	}
+	if got, want := profile.VehicleSpeedsKmh["car"], road*4/(2+2.0/3); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("expected vehicle speed %f, got %f", want, got)
+	}
+	if _, ok := profile.VehicleSpeedsKmh["van"]; ok {
+		t.Fatal("van has fewer samples than required and should not be calibrated")
+	}
+	if profile.VehicleSpeedsKmh["bike"] != 25 || profile.DefaultSpeedKmh != 40 {
+		t.Fatalf("expected base speeds to be preserved, got %+v", profile)
+	}
+}
@@ -3,9 +3,12 @@ package services
 import (
 	"context"
 	"errors"
+	"logi/internal/events"
 	"logi/internal/messaging"
 	"logi/internal/models"
+	"logi/internal/outbox"
 	"logi/internal/repositories"
+	"logi/internal/sms"
 	"logi/internal/utils"
 	"logi/pkg/auth"
 	"time"
@@ -21,9 +24,25 @@ type DriverService struct {
 	BookingService  BookingService
 	AuthService     *auth.AuthService
 	MessagingClient messaging.MessagingClient
+	Events          events.Publisher
+	Outbox          *outbox.Writer
+	Throttle        *LoginThrottle
+	// Accounts, when set, emails a verification link on registration.
+	Accounts *AccountService
+	// RequireVerifiedEmail rejects logins until the email is verified.
+	RequireVerifiedEmail bool
+	// OTP sends and checks the codes for phone registration and login.
+	OTP *OTPService
+	// PhoneCountryCode is prefixed to phone numbers given without one.
+	PhoneCountryCode string
 }
 
-func NewDriverService(repo repositories.DriverRepository, bookingRepo repositories.BookingRepository, userRepo repositories.UserRepository, bookingService BookingService, authService *auth.AuthService, messagingClient messaging.MessagingClient) *DriverService {
+var (
+	ErrPhoneNotRegistered = errors.New("no driver is registered with this phone number")
+	ErrPhoneTaken         = errors.New("a driver is already registered with this phone number")
+)
+
+func NewDriverService(repo repositories.DriverRepository, bookingRepo repositories.BookingRepository, userRepo repositories.UserRepository, bookingService BookingService, authService *auth.AuthService, messagingClient messaging.MessagingClient, eventPublisher events.Publisher, outboxWriter *outbox.Writer) *DriverService {
 	return &DriverService{
 		Repo:            repo,
 		AuthService:     authService,
@@ -31,6 +50,8 @@ func NewDriverService(repo repositories.DriverRepository, bookingRepo repositori
 		UserRepo:        userRepo,
 		BookingService:  bookingService,
 		MessagingClient: messagingClient,
+		Events:          eventPublisher,
+		Outbox:          outboxWriter,
 	}
 }
 
@@ -45,51 +66,154 @@ func (s *DriverService) Register(ctx context.Context, driver *models.Driver, pas
 		return err
 	}
 
-	driver.ID = uuid.NewString()
+	prepareNewDriver(driver)
 	driver.PasswordHash = hashedPassword
-	driver.Status = models.DriverStatusAvailable
-	driver.CreatedAt = time.Now()
-	driver.AcceptedBookingsCount = 0
-	driver.TotalBookingsCount = 0
-	driver.CompletedBookingsCount = 0
 
-	return s.Repo.Create(ctx, driver)
+	if err := s.Repo.Create(ctx, driver); err != nil {
+		return err
+	}
+	if err := s.Accounts.SendVerification(ctx, "driver", driver.Email); err != nil {
+		utils.Error(ctx, "failed to send verification email", "driver_id", driver.ID, "error", err)
+	}
+	return nil
 }
 
 func (s *DriverService) Login(ctx context.Context, email, password string) (*models.Driver, error) {
-	driver, err := s.Repo.FindByEmail(ctx, email)
-	if err != nil {
-		return nil, errors.New("invalid email or password")
+	if err := s.Throttle.Check(ctx, "driver", email); err != nil {
+		return nil, err
 	}
 
-	if !s.AuthService.CheckPasswordHash(password, driver.PasswordHash) {
+	driver, err := s.Repo.FindByEmail(ctx, email)
+	if err != nil || !s.AuthService.CheckPasswordHash(password, driver.PasswordHash) {
+		s.Throttle.RecordFailure(ctx, "driver", email)
 		return nil, errors.New("invalid email or password")
 	}
 
+	s.Throttle.RecordSuccess(ctx, "driver", email)
+	if s.RequireVerifiedEmail && driver.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
 	return driver, nil
 }
 
-// UpdateStatus updates the driver's status and notifies admins
-func (s *DriverService) UpdateStatus(ctx context.Context, driverID, status string) error {
-	// Update the driver's status in the repository
-	err := s.Repo.UpdateStatus(ctx, driverID, status)
+// SendLoginCode texts a one-time code to phone, for RegisterWithCode or
+// LoginWithCode. It does not reveal whether the number is registered.
+func (s *DriverService) SendLoginCode(ctx context.Context, phone string) error {
+	phone, err := sms.NormalizePhone(phone, s.PhoneCountryCode)
 	if err != nil {
 		return err
 	}
+	return s.OTP.Send(ctx, phone)
+}
 
-	// Publish the status update to admins via MessagingClient
-	publishErr := s.MessagingClient.Publish("", "driver_status_update", map[string]interface{}{
-		"driver_id": driverID,
-		"status":    status,
-	})
-	if publishErr != nil {
-		// Log the error but do not fail the operation
-		utils.Warn(ctx, "failed to publish driver status update", "driver_id", driverID, "status", status, "error", publishErr)
+// RegisterWithCode creates a driver who logs in by phone, once code proves
+// they hold driver.Phone. The code is kept when the number is already
+// registered, so it can still be used to log in.
+func (s *DriverService) RegisterWithCode(ctx context.Context, driver *models.Driver, code string) error {
+	phone, err := s.checkLoginCode(ctx, driver.Phone, code)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Repo.FindByPhone(ctx, phone); err == nil {
+		return ErrPhoneTaken
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	if err := s.OTP.Consume(ctx, phone); err != nil {
+		return err
 	}
 
+	now := time.Now()
+	prepareNewDriver(driver)
+	driver.Email = ""
+	driver.Phone = phone
+	driver.PhoneVerifiedAt = &now
+	err = s.Repo.Create(ctx, driver)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrPhoneTaken
+	}
+	if err != nil {
+		return err
+	}
+	s.Throttle.RecordSuccess(ctx, "driver", phone)
 	return nil
 }
 
+// LoginWithCode logs in the driver registered with phone. The code is kept
+// when the number is not registered, so it can still be used to register.
+func (s *DriverService) LoginWithCode(ctx context.Context, phone, code string) (*models.Driver, error) {
+	phone, err := s.checkLoginCode(ctx, phone, code)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := s.Repo.FindByPhone(ctx, phone)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrPhoneNotRegistered
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.OTP.Consume(ctx, phone); err != nil {
+		return nil, err
+	}
+	s.Throttle.RecordSuccess(ctx, "driver", phone)
+	return driver, nil
+}
+
+// checkLoginCode normalizes phone and checks code for it. Wrong codes count
+// towards the same lockout as wrong passwords, so requesting fresh codes does
+// not give unlimited guesses.
+func (s *DriverService) checkLoginCode(ctx context.Context, phone, code string) (string, error) {
+	phone, err := sms.NormalizePhone(phone, s.PhoneCountryCode)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Throttle.Check(ctx, "driver", phone); err != nil {
+		return "", err
+	}
+	err = s.OTP.Check(ctx, phone, code)
+	if errors.Is(err, ErrInvalidOTP) {
+		s.Throttle.RecordFailure(ctx, "driver", phone)
+	}
+	if err != nil {
+		return "", err
+	}
+	return phone, nil
+}
+
+func prepareNewDriver(driver *models.Driver) {
+	driver.ID = uuid.NewString()
+	driver.Status = models.DriverStatusAvailable
+	driver.CreatedAt = time.Now()
+	driver.AcceptedBookingsCount = 0
+	driver.TotalBookingsCount = 0
+	driver.CompletedBookingsCount = 0
+}
+
+// UpdateStatus updates the driver's status and notifies admins
+func (s *DriverService) UpdateStatus(ctx context.Context, driverID, status string) error {
+	switch status {
+	case models.DriverStatusAvailable, models.DriverStatusBusy, models.DriverStatusOffline:
+	default:
+		return errors.New("invalid driver status")
+	}
+
+	return inTransaction(ctx, s.Outbox, func(ctx context.Context) error {
+		// Update the driver's status in the repository
+		err := s.Repo.UpdateStatus(ctx, driverID, status)
+		if err != nil {
+			return err
+		}
+		err = publishEvent(ctx, s.Outbox, s.Events, events.DriverStatusChanged, driverID, events.NewDriverStatusChanged(driverID, status))
+		if err != nil {
+			return err
+		}
+
+		// Publish the status update to admins via MessagingClient
+		return notify(ctx, s.Outbox, s.MessagingClient, driverID, messaging.ToAdmins().WithTopics(messaging.TopicDriversStatus), messaging.TypeDriverStatusUpdate, messaging.NewDriverStatusUpdate(driverID, status))
+	})
+}
+
 // UpdateBookingStatus updates the status of a booking
 func (s *DriverService) UpdateBookingStatus(ctx context.Context, driverID string, bookingID string, status string) error {
 	// Find the booking by ID
@@ -117,6 +241,20 @@ func (s *DriverService) UpdateBookingStatus(ctx context.Context, driverID string
 		return errors.New("invalid status transition")
 	}
 
+	return inTransaction(ctx, s.Outbox, func(ctx context.Context) error {
+		return s.applyBookingStatus(ctx, driverID, booking, status)
+	})
+}
+
+// applyBookingStatus persists a validated status transition and its side
+// effects. It works on a copy of booking because a transaction may run it
+// more than once.
+func (s *DriverService) applyBookingStatus(ctx context.Context, driverID string, original *models.Booking, status string) error {
+	copied := *original
+	booking := &copied
+	bookingID := booking.ID
+	currentStatus := booking.Status
+
 	// Update timestamps based on status
 	currentTime := time.Now()
 	switch status {
@@ -134,17 +272,24 @@ func (s *DriverService) UpdateBookingStatus(ctx context.Context, driverID string
 	}
 
 	booking.Status = status
-	err = s.BookingRepo.Update(ctx, booking)
+	err := s.BookingRepo.Update(ctx, booking)
+	if err != nil {
+		return err
+	}
+	err = publishEvent(ctx, s.Outbox, s.Events, events.BookingStatusChanged, booking.ID, events.NewBookingStatusChanged(booking.ID, booking.UserID, driverID, currentStatus, status))
 	if err != nil {
 		return err
 	}
+	if status == models.BookingStatusCompleted {
+		if err := publishEvent(ctx, s.Outbox, s.Events, events.BookingCompleted, booking.ID, events.NewBookingCompleted(booking)); err != nil {
+			return err
+		}
+	}
 
 	// Notify user about status update
-	if publishErr := s.MessagingClient.Publish(booking.UserID, "status_update", map[string]interface{}{
-		"booking_id": booking.ID,
-		"status":     status,
-	}); publishErr != nil {
-		utils.Warn(ctx, "failed to publish booking status update", "booking_id", booking.ID, "user_id", booking.UserID, "error", publishErr)
+	err = notify(ctx, s.Outbox, s.MessagingClient, booking.ID, messaging.ToUser(booking.UserID).WithTopics(messaging.BookingTopic(booking.ID)), messaging.TypeStatusUpdate, messaging.NewStatusUpdate(booking.ID, status))
+	if err != nil {
+		return err
 	}
 
 	// If booking is marked as completed, update driver's status to Available
@@ -164,6 +309,10 @@ func (s *DriverService) UpdateBookingStatus(ctx context.Context, driverID string
 }
 
 func (s *DriverService) UpdateLocation(ctx context.Context, driverID string, latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return errors.New("invalid coordinates")
+	}
+
 	location := models.Location{
 		Type:        "Point",
 		Coordinates: []float64{longitude, latitude},
@@ -181,11 +330,8 @@ func (s *DriverService) UpdateLocation(ctx context.Context, driverID string, lat
 	}
 
 	// Notify user about driver's location update
-	if publishErr := s.MessagingClient.Publish(booking.UserID, "driver_location", map[string]interface{}{
-		"booking_id": booking.ID,
-		"latitude":   latitude,
-		"longitude":  longitude,
-	}); publishErr != nil {
+	recipient := messaging.ToUser(booking.UserID).WithTopics(messaging.BookingTopic(booking.ID), messaging.DriverLocationTopic(driverID))
+	if publishErr := s.MessagingClient.Publish(recipient, messaging.TypeDriverLocation, messaging.NewDriverLocation(booking.ID, latitude, longitude)); publishErr != nil {
 		utils.Warn(ctx, "failed to publish driver location", "booking_id", booking.ID, "user_id", booking.UserID, "error", publishErr)
 	}
 
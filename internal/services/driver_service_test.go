@@ -2,7 +2,12 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"logi/internal/events"
 	"logi/internal/models"
+	"logi/internal/outbox"
+	"strings"
 	"testing"
 )
 
@@ -19,10 +24,12 @@ func TestDriverServiceUpdateBookingStatusCompletedClearsDriverAndPublishes(t *te
 	bookingRepo := &fakeBookingRepository{
 		findByIDFn: func(ctx context.Context, id string) (*models.Booking, error) {
 			return &models.Booking{
-				ID:       id,
-				UserID:   "user-1",
-				DriverID: "driver-1",
-				Status:   "Delivered",
+				ID:                id,
+				UserID:            "user-1",
+				DriverID:          "driver-1",
+				Status:            "Delivered",
+				OfferedDriverIDs:  []string{"driver-1", "driver-2"},
+				RejectedDriverIDs: []string{"driver-2"},
 			}, nil
 		},
 		updateFn: func(ctx context.Context, booking *models.Booking) error {
@@ -48,12 +55,14 @@ func TestDriverServiceUpdateBookingStatusCompletedClearsDriverAndPublishes(t *te
 		},
 	}
 	messaging := &fakeMessagingClient{}
+	eventPublisher := &fakeEventPublisher{}
 
 	service := &DriverService{
 		Repo:            driverRepo,
 		BookingRepo:     bookingRepo,
 		UserRepo:        &fakeUserRepository{},
 		MessagingClient: messaging,
+		Events:          eventPublisher,
 	}
 
 	if err := service.UpdateBookingStatus(context.Background(), "driver-1", "booking-1", "Completed"); err != nil {
@@ -78,12 +87,23 @@ func TestDriverServiceUpdateBookingStatusCompletedClearsDriverAndPublishes(t *te
 	if len(messaging.published) != 2 {
 		t.Fatalf("expected 2 published messages, got %d", len(messaging.published))
 	}
-	if messaging.published[0].userID != "user-1" || messaging.published[0].messageType != "status_update" {
+	if messaging.published[0].role != "user" || messaging.published[0].userID != "user-1" || messaging.published[0].messageType != "status_update" {
 		t.Fatalf("unexpected user message: %+v", messaging.published[0])
 	}
-	if messaging.published[1].userID != "" || messaging.published[1].messageType != "driver_status_update" {
+	if messaging.published[1].role != "admin" || messaging.published[1].userID != "" || messaging.published[1].messageType != "driver_status_update" {
 		t.Fatalf("unexpected admin message: %+v", messaging.published[1])
 	}
+	want := []string{events.BookingStatusChanged, events.BookingCompleted, events.DriverStatusChanged}
+	if got := eventPublisher.types(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected domain events: got %v, want %v", got, want)
+	}
+	var completed map[string]interface{}
+	if err := json.Unmarshal(eventPublisher.published[1].Data, &completed); err != nil {
+		t.Fatalf("failed to decode the completed event: %v", err)
+	}
+	if completed["version"] != float64(events.BookingCompletedVersion) || completed["driver_id"] != "driver-1" || completed["offered_driver_ids"] != nil || completed["rejected_driver_ids"] != nil {
+		t.Fatalf("expected the completed event to carry its payload, not the booking: %v", completed)
+	}
 }
 
 func TestDriverServiceUpdateBookingStatusRejectsInvalidTransition(t *testing.T) {
@@ -118,3 +138,72 @@ func TestDriverServiceUpdateBookingStatusRejectsInvalidTransition(t *testing.T)
 		t.Fatal("booking should not be updated for an invalid transition")
 	}
 }
+
+func TestDriverServiceRejectsInvalidStatusAndCoordinates(t *testing.T) {
+	t.Parallel()
+
+	repoCalled := false
+	service := &DriverService{
+		Repo: &fakeDriverRepository{
+			updateStatusFn: func(ctx context.Context, driverID, status string) error {
+				repoCalled = true
+				return nil
+			},
+			updateLocationFn: func(ctx context.Context, driverID string, location models.Location) error {
+				repoCalled = true
+				return nil
+			},
+		},
+		BookingRepo:     &fakeBookingRepository{},
+		MessagingClient: &fakeMessagingClient{},
+	}
+
+	if err := service.UpdateStatus(context.Background(), "driver-1", "Sleeping"); err == nil {
+		t.Fatal("expected invalid driver status error")
+	}
+	if err := service.UpdateLocation(context.Background(), "driver-1", 91, 0); err == nil {
+		t.Fatal("expected invalid coordinates error")
+	}
+	if repoCalled {
+		t.Fatal("repository should not be called for invalid input")
+	}
+}
+
+func TestDriverServiceUpdateStatusRecordsOutboxEntriesInTransaction(t *testing.T) {
+	t.Parallel()
+
+	outboxRepo := &fakeEventOutboxRepository{}
+	transactor := &fakeTransactor{}
+	messaging := &fakeMessagingClient{}
+	service := &DriverService{
+		Repo:            &fakeDriverRepository{},
+		MessagingClient: messaging,
+		Events:          &fakeEventPublisher{},
+		Outbox:          outbox.NewWriter(outboxRepo, transactor, nil),
+	}
+
+	if err := service.UpdateStatus(context.Background(), "driver-1", models.DriverStatusOffline); err != nil {
+		t.Fatalf("UpdateStatus returned error: %v", err)
+	}
+	if transactor.transactions != 1 {
+		t.Fatalf("expected one transaction, got %d", transactor.transactions)
+	}
+	if len(messaging.published) != 0 {
+		t.Fatalf("messages should go through the outbox, got %d direct publishes", len(messaging.published))
+	}
+	if len(outboxRepo.inserted) != 2 {
+		t.Fatalf("expected event and notification in the outbox, got %d entries", len(outboxRepo.inserted))
+	}
+	event, message := outboxRepo.inserted[0], outboxRepo.inserted[1]
+	if event.Kind != models.EventOutboxKindEvent || event.Type != events.DriverStatusChanged || event.AggregateID != "driver-1" {
+		t.Fatalf("unexpected event entry: %+v", event)
+	}
+	if message.Kind != models.EventOutboxKindMessage || message.Type != "driver_status_update" || message.Role != "admin" {
+		t.Fatalf("unexpected message entry: %+v", message)
+	}
+
+	outboxRepo.insertErr = errors.New("write conflict")
+	if err := service.UpdateStatus(context.Background(), "driver-1", models.DriverStatusAvailable); err == nil {
+		t.Fatal("expected outbox failure to fail the status change")
+	}
+}
@@ -0,0 +1,21 @@
+package geocoding
+
+import (
+	"context"
+	"errors"
+	"logi/internal/models"
+)
+
+var ErrAddressNotFound = errors.New("address not found")
+
+// GeocodeResult holds a resolved point and its canonical address.
+type GeocodeResult struct {
+	Location         models.Location
+	FormattedAddress string
+}
+
+// Geocoder defines the interface for resolving addresses to points and back.
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (*GeocodeResult, error)
+	ReverseGeocode(ctx context.Context, location models.Location) (*GeocodeResult, error)
+}
@@ -0,0 +1,133 @@
+package geocoding
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"logi/internal/models"
+)
+
+func TestNominatimGeocoderParsesSearchResult(t *testing.T) {
+	t.Parallel()
+
+	var query, userAgent, email string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		query = r.URL.Query().Get("q")
+		email = r.URL.Query().Get("email")
+		userAgent = r.Header.Get("User-Agent")
+		_, _ = w.Write([]byte(`[{"lat":"19.0760","lon":"72.8777","display_name":"Mumbai, Maharashtra, India"}]`))
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.URL+"/", "logi-test", "ops@example.com")
+	result, err := geocoder.Geocode(context.Background(), "Mumbai")
+	if err != nil {
+		t.Fatalf("Geocode returned error: %v", err)
+	}
+
+	if query != "Mumbai" || userAgent != "logi-test" || email != "ops@example.com" {
+		t.Fatalf("unexpected request: q=%q user-agent=%q email=%q", query, userAgent, email)
+	}
+	if result.Location.Type != "Point" || result.Location.Coordinates[0] != 72.8777 || result.Location.Coordinates[1] != 19.0760 {
+		t.Fatalf("unexpected location: %+v", result.Location)
+	}
+	if result.FormattedAddress != "Mumbai, Maharashtra, India" {
+		t.Fatalf("unexpected formatted address: %q", result.FormattedAddress)
+	}
+}
+
+func TestNominatimGeocoderReturnsNotFound(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			_, _ = w.Write([]byte(`[]`))
+		case "/reverse":
+			_, _ = w.Write([]byte(`{"error":"Unable to geocode"}`))
+		}
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.URL, "logi-test", "")
+	if _, err := geocoder.Geocode(context.Background(), "nowhere"); !errors.Is(err, ErrAddressNotFound) {
+		t.Fatalf("expected ErrAddressNotFound from Geocode, got %v", err)
+	}
+	point := models.Location{Type: "Point", Coordinates: []float64{0, 0}}
+	if _, err := geocoder.ReverseGeocode(context.Background(), point); !errors.Is(err, ErrAddressNotFound) {
+		t.Fatalf("expected ErrAddressNotFound from ReverseGeocode, got %v", err)
+	}
+}
+
+func TestNominatimGeocoderReverseSendsLatLon(t *testing.T) {
+	t.Parallel()
+
+	var lat, lon string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lat = r.URL.Query().Get("lat")
+		lon = r.URL.Query().Get("lon")
+		_, _ = w.Write([]byte(`{"lat":"18.922","lon":"72.8311","display_name":"Colaba, Mumbai"}`))
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.URL, "logi-test", "")
+	result, err := geocoder.ReverseGeocode(context.Background(), models.Location{Type: "Point", Coordinates: []float64{72.8311, 18.922}})
+	if err != nil {
+		t.Fatalf("ReverseGeocode returned error: %v", err)
+	}
+	if lat != "18.922" || lon != "72.8311" {
+		t.Fatalf("unexpected lat/lon: %s/%s", lat, lon)
+	}
+	if result.FormattedAddress != "Colaba, Mumbai" {
+		t.Fatalf("unexpected formatted address: %q", result.FormattedAddress)
+	}
+}
+
+func TestNominatimGeocoderReturnsErrorOnNon200(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.URL, "logi-test", "")
+	_, err := geocoder.Geocode(context.Background(), "Mumbai")
+	if err == nil || errors.Is(err, ErrAddressNotFound) {
+		t.Fatalf("expected request error, got %v", err)
+	}
+}
+
+func TestStaticGeocoderLooksUpNormalizedAddresses(t *testing.T) {
+	t.Parallel()
+
+	geocoder := NewStaticGeocoder(map[string]GeocodeResult{
+		"Gateway of India": {Location: models.Location{Type: "Point", Coordinates: []float64{72.8347, 18.9220}}},
+		"Bandra Station":   {Location: models.Location{Type: "Point", Coordinates: []float64{72.8400, 19.0544}}},
+	})
+
+	result, err := geocoder.Geocode(context.Background(), "  gateway   OF india ")
+	if err != nil {
+		t.Fatalf("Geocode returned error: %v", err)
+	}
+	if result.FormattedAddress != "Gateway of India" {
+		t.Fatalf("unexpected formatted address: %q", result.FormattedAddress)
+	}
+	if _, err := geocoder.Geocode(context.Background(), "Juhu Beach"); !errors.Is(err, ErrAddressNotFound) {
+		t.Fatalf("expected ErrAddressNotFound, got %v", err)
+	}
+
+	nearest, err := geocoder.ReverseGeocode(context.Background(), models.Location{Type: "Point", Coordinates: []float64{72.841, 19.05}})
+	if err != nil {
+		t.Fatalf("ReverseGeocode returned error: %v", err)
+	}
+	if nearest.FormattedAddress != "Bandra Station" {
+		t.Fatalf("expected nearest entry Bandra Station, got %q", nearest.FormattedAddress)
+	}
+}
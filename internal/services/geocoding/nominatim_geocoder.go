@@ -0,0 +1,126 @@
+package geocoding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"logi/internal/models"
+	"logi/internal/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NominatimGeocoder implements the Geocoder interface against a Nominatim-compatible HTTP API.
+type NominatimGeocoder struct {
+	BaseURL   string
+	UserAgent string // Nominatim's usage policy requires an identifying User-Agent
+	Email     string // optional contact address sent with each request
+	Client    *http.Client
+}
+
+// NewNominatimGeocoder returns a new instance of NominatimGeocoder.
+func NewNominatimGeocoder(baseURL, userAgent, email string) *NominatimGeocoder {
+	return &NominatimGeocoder{
+		BaseURL:   strings.TrimRight(baseURL, "/"),
+		UserAgent: userAgent,
+		Email:     email,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Geocode resolves a free-form address to its best matching point.
+func (n *NominatimGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	params := url.Values{}
+	params.Add("q", address)
+	params.Add("format", "jsonv2")
+	params.Add("limit", "1")
+
+	var places []NominatimPlace
+	if err := n.get(ctx, "/search", params, &places); err != nil {
+		return nil, err
+	}
+	if len(places) == 0 {
+		return nil, ErrAddressNotFound
+	}
+	return places[0].toResult()
+}
+
+// ReverseGeocode resolves a point to the nearest address.
+func (n *NominatimGeocoder) ReverseGeocode(ctx context.Context, location models.Location) (*GeocodeResult, error) {
+	params := url.Values{}
+	params.Add("lat", strconv.FormatFloat(location.Coordinates[1], 'f', -1, 64))
+	params.Add("lon", strconv.FormatFloat(location.Coordinates[0], 'f', -1, 64))
+	params.Add("format", "jsonv2")
+
+	var place NominatimPlace
+	if err := n.get(ctx, "/reverse", params, &place); err != nil {
+		return nil, err
+	}
+	if place.Error != "" {
+		return nil, ErrAddressNotFound
+	}
+	return place.toResult()
+}
+
+func (n *NominatimGeocoder) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	if n.Email != "" {
+		params.Add("email", n.Email)
+	}
+	reqURL := fmt.Sprintf("%s%s?%s", n.BaseURL, path, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return err
+	}
+	if n.UserAgent != "" {
+		req.Header.Set("User-Agent", n.UserAgent)
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		utils.Error(ctx, "nominatim request failed", "path", path, "error", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		utils.Error(ctx, "nominatim returned non-200 response", "path", path, "status", resp.Status)
+		return errors.New("failed to geocode address with Nominatim")
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		utils.Error(ctx, "failed to decode nominatim response", "path", path, "error", err)
+		return err
+	}
+	return nil
+}
+
+// NominatimPlace represents a single place in a Nominatim search or reverse response.
+type NominatimPlace struct {
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+	Error       string `json:"error"`
+}
+
+func (p NominatimPlace) toResult() (*GeocodeResult, error) {
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude from Nominatim: %w", err)
+	}
+	lon, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude from Nominatim: %w", err)
+	}
+	return &GeocodeResult{
+		Location: models.Location{
+			Type:        "Point",
+			Coordinates: []float64{lon, lat},
+		},
+		FormattedAddress: p.DisplayName,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package geocoding
+
+import (
+	"context"
+	"logi/internal/models"
+	"math"
+	"strings"
+)
+
+// StaticGeocoder implements the Geocoder interface from a fixed lookup table.
+// It is meant for tests and offline development.
+type StaticGeocoder struct {
+	entries map[string]GeocodeResult
+}
+
+// NewStaticGeocoder returns a StaticGeocoder keyed by address. Keys are matched
+// case-insensitively with surrounding whitespace ignored.
+func NewStaticGeocoder(entries map[string]GeocodeResult) *StaticGeocoder {
+	normalized := make(map[string]GeocodeResult, len(entries))
+	for address, result := range entries {
+		if result.FormattedAddress == "" {
+			result.FormattedAddress = address
+		}
+		normalized[normalizeAddress(address)] = result
+	}
+	return &StaticGeocoder{entries: normalized}
+}
+
+// Geocode returns the table entry for address.
+func (s *StaticGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	result, ok := s.entries[normalizeAddress(address)]
+	if !ok {
+		return nil, ErrAddressNotFound
+	}
+	return &result, nil
+}
+
+// ReverseGeocode returns the table entry whose point is closest to location.
+func (s *StaticGeocoder) ReverseGeocode(ctx context.Context, location models.Location) (*GeocodeResult, error) {
+	var best *GeocodeResult
+	bestDistance := math.Inf(1)
+	for _, entry := range s.entries {
+		dLon := entry.Location.Coordinates[0] - location.Coordinates[0]
+		dLat := entry.Location.Coordinates[1] - location.Coordinates[1]
+		if d := dLon*dLon + dLat*dLat; d < bestDistance {
+			result := entry
+			best = &result
+			bestDistance = d
+		}
+	}
+	if best == nil {
+		return nil, ErrAddressNotFound
+	}
+	return best, nil
+}
+
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.Join(strings.Fields(address), " "))
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+	ErrLockoutNotFound      = errors.New("no failed logins recorded for that account or IP")
+	ErrInvalidLockout       = errors.New("give either a role (user, driver or admin) and email, or an ip")
+)
+
+// LoginLockedError is returned by Login while an account or IP must wait
+// before trying again. It unwraps to ErrTooManyLoginAttempts.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// LoginThrottle tracks failed logins per account and per client IP in
+// login_attempts, so every instance sees the same counts. Each failure on an
+// account doubles the wait before the next attempt, from BaseDelay up to
+// MaxDelay, and MaxAccountFailures failures within Window lock the account
+// for Lockout. An IP is locked after MaxIPFailures failures across any
+// accounts, which catches credential stuffing that never repeats an email.
+//
+// A nil *LoginThrottle allows every login. Storage errors are logged and the
+// login allowed, so an outage of login_attempts does not lock everyone out.
+type LoginThrottle struct {
+	Attempts           repositories.LoginAttemptRepository
+	Audit              repositories.AuditLogRepository
+	MaxAccountFailures int
+	MaxIPFailures      int
+	Window             time.Duration
+	Lockout            time.Duration
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+
+	now func() time.Time
+}
+
+func NewLoginThrottle(attempts repositories.LoginAttemptRepository, audit repositories.AuditLogRepository, maxAccountFailures, maxIPFailures int, window, lockout, baseDelay time.Duration) *LoginThrottle {
+	return &LoginThrottle{
+		Attempts:           attempts,
+		Audit:              audit,
+		MaxAccountFailures: maxAccountFailures,
+		MaxIPFailures:      maxIPFailures,
+		Window:             window,
+		Lockout:            lockout,
+		BaseDelay:          baseDelay,
+		MaxDelay:           time.Minute,
+		now:                time.Now,
+	}
+}
+
+// AccountLockKey identifies the account email logging in as role.
+func AccountLockKey(role, email string) string {
+	return "account:" + role + ":" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPLockKey identifies a client IP.
+func IPLockKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns a *LoginLockedError when the account or the client IP in ctx
+// may not attempt a login yet.
+func (t *LoginThrottle) Check(ctx context.Context, role, email string) error {
+	if t == nil {
+		return nil
+	}
+	now := t.now()
+	var wait time.Duration
+
+	if ip := utils.ClientIPFromContext(ctx); ip != "" {
+		if attempt := t.find(ctx, IPLockKey(ip)); attempt != nil && attempt.IsLocked(now) {
+			wait = attempt.LockedUntil.Sub(now)
+		}
+	}
+	if attempt := t.find(ctx, AccountLockKey(role, email)); attempt != nil {
+		if attempt.IsLocked(now) {
+			wait = max(wait, attempt.LockedUntil.Sub(now))
+		} else if now.Before(attempt.WindowStartedAt.Add(t.Window)) {
+			next := attempt.LastFailureAt.Add(t.delay(attempt.Failures))
+			wait = max(wait, next.Sub(now))
+		}
+	}
+
+	if wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login against the account and the client IP,
+// locking either once it reaches its limit.
+func (t *LoginThrottle) RecordFailure(ctx context.Context, role, email string) {
+	if t == nil {
+		return
+	}
+	now := t.now()
+	ip := utils.ClientIPFromContext(ctx)
+	t.recordFailure(ctx, AccountLockKey(role, email), t.MaxAccountFailures, now, ip, map[string]string{"role": role, "email": email})
+	if ip != "" {
+		t.recordFailure(ctx, IPLockKey(ip), t.MaxIPFailures, now, ip, nil)
+	}
+}
+
+// RecordSuccess clears the account's failures. The IP's failures stand, so a
+// client cannot reset its count by logging in to an account it owns.
+func (t *LoginThrottle) RecordSuccess(ctx context.Context, role, email string) {
+	if t == nil {
+		return
+	}
+	err := t.Attempts.Delete(ctx, AccountLockKey(role, email))
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		utils.Error(ctx, "failed to reset login attempts", "role", role, "error", err)
+	}
+}
+
+// Locked returns the accounts and IPs that are locked out now.
+func (t *LoginThrottle) Locked(ctx context.Context) ([]*models.LoginAttempt, error) {
+	return t.Attempts.FindLocked(ctx, t.now())
+}
+
+// Unlock clears the failures of an account, given role and email, or of an
+// ip, on behalf of the admin actorID.
+func (t *LoginThrottle) Unlock(ctx context.Context, actorID, role, email, ip string) error {
+	var key string
+	switch {
+	case ip != "" && role == "" && email == "":
+		key = IPLockKey(ip)
+	case ip == "" && email != "" && (role == "user" || role == "driver" || role == "admin"):
+		key = AccountLockKey(role, email)
+	default:
+		return ErrInvalidLockout
+	}
+
+	err := t.Attempts.Delete(ctx, key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrLockoutNotFound
+	}
+	if err != nil {
+		return err
+	}
+	t.audit(ctx, &models.AuditEvent{Type: models.AuditLoginUnlocked, ActorID: actorID, Subject: key, IP: utils.ClientIPFromContext(ctx)})
+	return nil
+}
+
+func (t *LoginThrottle) recordFailure(ctx context.Context, key string, limit int, now time.Time, ip string, details map[string]string) {
+	attempt, err := t.Attempts.RecordFailure(ctx, key, now, now.Add(-t.Window), now.Add(t.Window))
+	if err != nil {
+		utils.Error(ctx, "failed to record login failure", "key", key, "error", err)
+		return
+	}
+	if attempt.Failures < limit || attempt.IsLocked(now) {
+		return
+	}
+
+	until := now.Add(t.Lockout)
+	if err := t.Attempts.Lock(ctx, key, until); err != nil {
+		utils.Error(ctx, "failed to lock login", "key", key, "error", err)
+		return
+	}
+	utils.Warn(ctx, "login locked after repeated failures", "key", key, "failures", attempt.Failures, "locked_until", until)
+	utils.IncrementCounter("login_lockouts", 1)
+
+	if details == nil {
+		details = map[string]string{}
+	}
+	details["failures"] = fmt.Sprint(attempt.Failures)
+	details["locked_until"] = until.UTC().Format(time.RFC3339)
+	t.audit(ctx, &models.AuditEvent{Type: models.AuditLoginLocked, Subject: key, IP: ip, Details: details})
+}
+
+func (t *LoginThrottle) find(ctx context.Context, key string) *models.LoginAttempt {
+	attempt, err := t.Attempts.Find(ctx, key)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			utils.Error(ctx, "failed to load login attempts", "key", key, "error", err)
+		}
+		return nil
+	}
+	return attempt
+}
+
+// delay is the wait after the given number of consecutive failures.
+func (t *LoginThrottle) delay(failures int) time.Duration {
+	if failures <= 0 || t.BaseDelay <= 0 {
+		return 0
+	}
+	delay := t.BaseDelay
+	for i := 1; i < failures && delay < t.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.MaxDelay)
+}
+
+func (t *LoginThrottle) audit(ctx context.Context, event *models.AuditEvent) {
+	event.ID = uuid.NewString()
+	event.CreatedAt = t.now()
+	if err := t.Audit.Insert(ctx, event); err != nil {
+		utils.Error(ctx, "failed to write audit event", "type", event.Type, "subject", event.Subject, "error", err)
+	}
+}
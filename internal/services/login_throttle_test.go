@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"logi/internal/models"
+	"logi/internal/utils"
+)
+
+// newTestLoginThrottle returns a throttle on in-memory repositories, a user
+// service using it and the throttle's clock, which only moves when told to.
+func newTestLoginThrottle(t *testing.T) (*LoginThrottle, *UserService, *time.Time) {
+	t.Helper()
+	_, authService := newTestTokenService()
+	hash, err := authService.HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword returned error: %v", err)
+	}
+	users := &fakeUserRepository{findByEmailFn: func(ctx context.Context, email string) (*models.User, error) {
+		if email != "rider@example.com" {
+			return nil, errors.New("not found")
+		}
+		return &models.User{ID: "user-1", Email: email, PasswordHash: hash}, nil
+	}}
+
+	throttle := NewLoginThrottle(&fakeLoginAttemptRepository{}, &fakeAuditLogRepository{}, 3, 5, 15*time.Minute, 10*time.Minute, time.Second)
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+	return throttle, &UserService{Repo: users, AuthService: authService, Throttle: throttle}, &now
+}
+
+func retryAfter(err error) time.Duration {
+	var locked *LoginLockedError
+	if errors.As(err, &locked) {
+		return locked.RetryAfter
+	}
+	return 0
+}
+
+func TestLoginThrottleLocksAccountUntilLockoutEnds(t *testing.T) {
+	t.Parallel()
+
+	throttle, service, now := newTestLoginThrottle(t)
+	ctx := utils.WithClientIP(context.Background(), "203.0.113.7")
+
+	for i, wantDelay := range []time.Duration{time.Second, 2 * time.Second} {
+		if _, err := service.Login(ctx, "rider@example.com", "wrong"); err == nil || retryAfter(err) != 0 {
+			t.Fatalf("failure %d: expected invalid credentials, got %v", i+1, err)
+		}
+		if _, err := service.Login(ctx, "rider@example.com", "secret"); retryAfter(err) != wantDelay {
+			t.Fatalf("failure %d: expected a %v wait, got %v", i+1, wantDelay, err)
+		}
+		*now = now.Add(wantDelay)
+	}
+
+	if _, err := service.Login(ctx, "Rider@example.com", "wrong"); err == nil {
+		t.Fatal("expected the third failure to be rejected")
+	}
+	if _, err := service.Login(ctx, "rider@example.com", "secret"); !errors.Is(err, ErrTooManyLoginAttempts) || retryAfter(err) != 10*time.Minute {
+		t.Fatalf("expected the account to be locked for the lockout, got %v", err)
+	}
+	audit := throttle.Audit.(*fakeAuditLogRepository)
+	if len(audit.events) != 1 || audit.events[0].Type != models.AuditLoginLocked || audit.events[0].Subject != AccountLockKey("user", "rider@example.com") || audit.events[0].IP != "203.0.113.7" {
+		t.Fatalf("expected the lockout to be audited, got %+v", audit.events)
+	}
+
+	*now = now.Add(10*time.Minute - time.Second)
+	if _, err := service.Login(ctx, "rider@example.com", "secret"); !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Fatalf("expected the lockout to hold until it ends, got %v", err)
+	}
+	*now = now.Add(time.Second)
+	if _, err := service.Login(ctx, "rider@example.com", "secret"); err != nil {
+		t.Fatalf("expected login to work once the lockout ended, got %v", err)
+	}
+	if locked, _ := throttle.Locked(ctx); len(locked) != 0 {
+		t.Fatalf("expected no lockouts after a successful login, got %+v", locked)
+	}
+}
+
+func TestLoginThrottleResetsOnSuccessAndAfterWindow(t *testing.T) {
+	t.Parallel()
+
+	_, service, now := newTestLoginThrottle(t)
+	ctx := context.Background()
+	fail := func(times int) {
+		t.Helper()
+		for i := 0; i < times; i++ {
+			if _, err := service.Login(ctx, "rider@example.com", "wrong"); err == nil || retryAfter(err) != 0 {
+				t.Fatalf("expected invalid credentials, got %v", err)
+			}
+			*now = now.Add(time.Minute)
+		}
+	}
+
+	fail(2)
+	if _, err := service.Login(ctx, "rider@example.com", "secret"); err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	fail(2)
+	*now = now.Add(15 * time.Minute)
+	fail(2)
+	if _, err := service.Login(ctx, "rider@example.com", "secret"); err != nil {
+		t.Fatalf("expected failures before a success or outside the window not to count, got %v", err)
+	}
+}
+
+func TestLoginThrottleLocksIPAcrossAccountsAndUnlocks(t *testing.T) {
+	t.Parallel()
+
+	throttle, service, now := newTestLoginThrottle(t)
+	ctx := utils.WithClientIP(context.Background(), "198.51.100.4")
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		if _, err := service.Login(ctx, email, "guess"); err == nil || retryAfter(err) != 0 {
+			t.Fatalf("expected invalid credentials for %s, got %v", email, err)
+		}
+		*now = now.Add(time.Second)
+	}
+	if _, err := service.Login(ctx, "rider@example.com", "secret"); !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Fatalf("expected the IP to be locked for every account, got %v", err)
+	}
+	if _, err := service.Login(utils.WithClientIP(context.Background(), "192.0.2.1"), "rider@example.com", "secret"); err != nil {
+		t.Fatalf("expected other IPs to be unaffected, got %v", err)
+	}
+
+	if err := throttle.Unlock(ctx, "admin-1", "user", "rider@example.com", "198.51.100.4"); !errors.Is(err, ErrInvalidLockout) {
+		t.Fatalf("expected an account and an IP at once to be rejected, got %v", err)
+	}
+	if err := throttle.Unlock(ctx, "admin-1", "", "", "198.51.100.4"); err != nil {
+		t.Fatalf("Unlock returned error: %v", err)
+	}
+	if _, err := service.Login(ctx, "rider@example.com", "secret"); err != nil {
+		t.Fatalf("expected login to work after an unlock, got %v", err)
+	}
+	audit := throttle.Audit.(*fakeAuditLogRepository)
+	last := audit.events[len(audit.events)-1]
+	if last.Type != models.AuditLoginUnlocked || last.ActorID != "admin-1" || last.Subject != IPLockKey("198.51.100.4") {
+		t.Fatalf("expected the unlock to be audited, got %+v", last)
+	}
+	if err := throttle.Unlock(ctx, "admin-1", "", "", "198.51.100.4"); !errors.Is(err, ErrLockoutNotFound) {
+		t.Fatalf("expected a second unlock to find nothing, got %v", err)
+	}
+}
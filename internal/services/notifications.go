@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"logi/internal/events"
+	"logi/internal/messaging"
+	"logi/internal/outbox"
+	"logi/internal/utils"
+)
+
+// inTransaction runs fn atomically when an outbox is configured, so the state
+// changes in fn and the notifications and events it records commit together.
+func inTransaction(ctx context.Context, writer *outbox.Writer, fn func(ctx context.Context) error) error {
+	if writer == nil {
+		return fn(ctx)
+	}
+	return writer.InTransaction(ctx, fn)
+}
+
+// notify sends a websocket notification about aggregateID. With an outbox the
+// message is stored in ctx's transaction and a failure is returned so the
+// state change rolls back with it; without one it is published right away
+// and a failure is only logged.
+func notify(ctx context.Context, writer *outbox.Writer, client messaging.MessagingClient, aggregateID string, recipient messaging.Recipient, messageType string, payload interface{}) error {
+	if writer != nil {
+		return writer.AddMessage(ctx, aggregateID, recipient, messageType, payload)
+	}
+	if err := client.Publish(recipient, messageType, payload); err != nil {
+		utils.Warn(ctx, "failed to publish message", "type", messageType, "role", recipient.Role, "recipient_id", recipient.ID, "aggregate_id", aggregateID, "error", err)
+	}
+	return nil
+}
+
+// publishEvent records a domain event for downstream consumers when an event
+// stream is configured, through the outbox like notify.
+func publishEvent(ctx context.Context, writer *outbox.Writer, publisher events.Publisher, eventType, aggregateID string, data interface{}) error {
+	if publisher == nil {
+		return nil
+	}
+	event, err := events.New(eventType, aggregateID, data)
+	if writer != nil {
+		if err != nil {
+			return err
+		}
+		return writer.AddEvent(ctx, event)
+	}
+	if err != nil {
+		utils.Error(ctx, "failed to encode domain event", "event_type", eventType, "aggregate_id", aggregateID, "error", err)
+		return nil
+	}
+	if err := publisher.PublishEvent(ctx, event); err != nil {
+		utils.Warn(ctx, "failed to publish domain event", "event_type", eventType, "aggregate_id", aggregateID, "error", err)
+	}
+	return nil
+}
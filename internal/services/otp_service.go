@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/sms"
+	"logi/internal/utils"
+	"math/big"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidOTP       = errors.New("invalid or expired code")
+	ErrOTPResendTooSoon = errors.New("a code was sent to this number recently, try again later")
+)
+
+// OTPResendError is returned by Send when the number's last code was sent
+// less than ResendInterval ago, or its guesses for the current
+// AttemptWindow are used up. It unwraps to ErrOTPResendTooSoon.
+type OTPResendError struct {
+	RetryAfter time.Duration
+}
+
+func (e *OTPResendError) Error() string {
+	return ErrOTPResendTooSoon.Error()
+}
+
+func (e *OTPResendError) Unwrap() error {
+	return ErrOTPResendTooSoon
+}
+
+// OTPService sends one-time codes by SMS and checks them. Each number has at
+// most one outstanding code, stored as an HMAC under Secret in otp_codes. A
+// code expires after TTL, and a new one can be sent only every
+// ResendInterval. A number gets MaxAttempts guesses per AttemptWindow,
+// however many codes are sent to it in that time.
+type OTPService struct {
+	Codes          repositories.OTPRepository
+	Sender         sms.SMSSender
+	Secret         []byte
+	Length         int
+	TTL            time.Duration
+	MaxAttempts    int
+	ResendInterval time.Duration
+	AttemptWindow  time.Duration
+
+	now func() time.Time
+}
+
+func NewOTPService(codes repositories.OTPRepository, sender sms.SMSSender, secret []byte, length int, ttl time.Duration, maxAttempts int, resendInterval, attemptWindow time.Duration) *OTPService {
+	return &OTPService{
+		Codes:          codes,
+		Sender:         sender,
+		Secret:         secret,
+		Length:         length,
+		TTL:            ttl,
+		MaxAttempts:    maxAttempts,
+		ResendInterval: resendInterval,
+		AttemptWindow:  attemptWindow,
+		now:            time.Now,
+	}
+}
+
+// Send texts a new code to phone, an E.164 number, replacing any code sent
+// before. Once the number's guesses for the current window are used up, no
+// code is sent until the window ends.
+func (s *OTPService) Send(ctx context.Context, phone string) error {
+	now := s.now()
+	previous, err := s.Codes.Find(ctx, phone)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	windowStart := now
+	if previous != nil {
+		if wait := previous.SentAt.Add(s.ResendInterval).Sub(now); wait > 0 {
+			return &OTPResendError{RetryAfter: wait}
+		}
+		if windowEnd := previous.WindowStart.Add(s.AttemptWindow); now.Before(windowEnd) {
+			if previous.Attempts >= s.MaxAttempts {
+				return &OTPResendError{RetryAfter: windowEnd.Sub(now)}
+			}
+			windowStart = previous.WindowStart
+		}
+	}
+	purgeAt := windowStart.Add(s.AttemptWindow)
+	if expiresAt := now.Add(s.TTL); expiresAt.After(purgeAt) {
+		purgeAt = expiresAt
+	}
+
+	code, err := s.generate()
+	if err != nil {
+		return err
+	}
+	err = s.Codes.Save(ctx, &models.OTPCode{
+		Phone:       phone,
+		CodeHash:    s.hash(phone, code),
+		WindowStart: windowStart,
+		SentAt:      now,
+		ExpiresAt:   now.Add(s.TTL),
+		PurgeAt:     purgeAt,
+	})
+	if err != nil {
+		return err
+	}
+	err = s.Sender.Send(ctx, sms.Message{
+		To:   phone,
+		Body: fmt.Sprintf("Your Logi code is %s. It expires in %s. Do not share it with anyone.", code, formatTTL(s.TTL)),
+	})
+	if err != nil {
+		// Withdraw the undelivered code so a retry is not held back by
+		// ResendInterval. The record stays, so its guesses still count.
+		withdrawn := &models.OTPCode{Phone: phone, WindowStart: windowStart, ExpiresAt: now, PurgeAt: purgeAt}
+		if err := s.Codes.Save(ctx, withdrawn); err != nil {
+			utils.Error(ctx, "failed to withdraw undelivered otp code", "error", err)
+		}
+		return err
+	}
+	utils.IncrementCounter("otp_codes_sent", 1)
+	return nil
+}
+
+// Check returns ErrInvalidOTP unless code is phone's outstanding code. A
+// matching code stays valid until Consume, so a caller can check it and
+// then decide whether to use it. Every check counts as a guess, claimed
+// before the code is compared, so concurrent guesses cannot exceed
+// MaxAttempts.
+func (s *OTPService) Check(ctx context.Context, phone, code string) error {
+	stored, err := s.Codes.ClaimAttempt(ctx, phone, s.MaxAttempts, s.now())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInvalidOTP
+	}
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(stored.CodeHash), []byte(s.hash(phone, code))) {
+		return ErrInvalidOTP
+	}
+	return nil
+}
+
+// Consume uses up phone's code after a successful Check. It returns
+// ErrInvalidOTP when another request used it first.
+func (s *OTPService) Consume(ctx context.Context, phone string) error {
+	err := s.Codes.Delete(ctx, phone)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInvalidOTP
+	}
+	return err
+}
+
+func (s *OTPService) generate() (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(s.Length)), nil)
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", s.Length, n), nil
+}
+
+func (s *OTPService) hash(phone, code string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(phone + "\x00" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
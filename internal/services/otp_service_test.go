@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"logi/internal/models"
+	"logi/internal/sms"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var otpCodePattern = regexp.MustCompile(`\b[0-9]{6}\b`)
+
+func newTestOTPService() (*OTPService, *sms.MemorySender, *time.Time) {
+	sender := &sms.MemorySender{}
+	service := NewOTPService(&fakeOTPRepository{}, sender, []byte("otp-test-secret"), 6, 5*time.Minute, 3, 30*time.Second, time.Hour)
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return service, sender, &now
+}
+
+func lastCode(t *testing.T, sender *sms.MemorySender) string {
+	t.Helper()
+	sent := sender.Messages()
+	if len(sent) == 0 {
+		t.Fatal("no sms sent")
+	}
+	code := otpCodePattern.FindString(sent[len(sent)-1].Body)
+	if code == "" {
+		t.Fatalf("no code in %q", sent[len(sent)-1].Body)
+	}
+	return code
+}
+
+func TestDriverServiceRegistersAndLogsInWithCode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	otp, sender, now := newTestOTPService()
+	drivers := map[string]*models.Driver{}
+	repo := &fakeDriverRepository{
+		findByPhoneFn: func(ctx context.Context, phone string) (*models.Driver, error) {
+			if driver, ok := drivers[phone]; ok {
+				return driver, nil
+			}
+			return nil, mongo.ErrNoDocuments
+		},
+		createFn: func(ctx context.Context, driver *models.Driver) error {
+			drivers[driver.Phone] = driver
+			return nil
+		},
+	}
+	service := &DriverService{Repo: repo, OTP: otp, PhoneCountryCode: "44"}
+
+	if err := service.SendLoginCode(ctx, "12345"); !errors.Is(err, sms.ErrInvalidPhone) {
+		t.Fatalf("expected a short number to be rejected, got %v", err)
+	}
+	if err := service.SendLoginCode(ctx, "07700 900123"); err != nil {
+		t.Fatalf("SendLoginCode returned error: %v", err)
+	}
+	if sent := sender.Messages(); len(sent) != 1 || sent[0].To != "+447700900123" {
+		t.Fatalf("expected the code to go to the E.164 number, got %+v", sent)
+	}
+	var tooSoon *OTPResendError
+	if err := service.SendLoginCode(ctx, "+44 7700 900123"); !errors.As(err, &tooSoon) || tooSoon.RetryAfter != 30*time.Second {
+		t.Fatalf("expected a resend within 30s to be refused, got %v", err)
+	}
+	code := lastCode(t, sender)
+
+	// Logging in with an unregistered number keeps the code for registering.
+	if _, err := service.LoginWithCode(ctx, "+447700900123", code); !errors.Is(err, ErrPhoneNotRegistered) {
+		t.Fatalf("expected an unregistered number to be reported, got %v", err)
+	}
+	driver := &models.Driver{Name: "Asha", Email: "ignored@example.com", Phone: "0044 7700 900123", VehicleType: "bike"}
+	if err := service.RegisterWithCode(ctx, driver, code); err != nil {
+		t.Fatalf("RegisterWithCode returned error: %v", err)
+	}
+	if driver.ID == "" || driver.Phone != "+447700900123" || driver.PhoneVerifiedAt == nil || driver.Email != "" || driver.Status != models.DriverStatusAvailable {
+		t.Fatalf("unexpected registered driver: %+v", driver)
+	}
+	if _, err := service.LoginWithCode(ctx, "+447700900123", code); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("expected a used code to be rejected, got %v", err)
+	}
+
+	*now = now.Add(time.Minute)
+	if err := service.SendLoginCode(ctx, "+447700900123"); err != nil {
+		t.Fatalf("SendLoginCode returned error: %v", err)
+	}
+	code = lastCode(t, sender)
+	if err := service.RegisterWithCode(ctx, &models.Driver{Name: "Other", Phone: "+447700900123"}, code); !errors.Is(err, ErrPhoneTaken) {
+		t.Fatalf("expected a registered number to be refused, got %v", err)
+	}
+	loggedIn, err := service.LoginWithCode(ctx, "07700900123", code)
+	if err != nil {
+		t.Fatalf("LoginWithCode returned error: %v", err)
+	}
+	if loggedIn.ID != driver.ID {
+		t.Fatalf("expected driver %s, got %s", driver.ID, loggedIn.ID)
+	}
+}
+
+func TestOTPServiceExpiresCodesAndLimitsAttempts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	otp, sender, now := newTestOTPService()
+	const phone = "+14155550123"
+
+	if err := otp.Send(ctx, phone); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	code := lastCode(t, sender)
+	for i := 0; i < otp.MaxAttempts-1; i++ {
+		if err := otp.Check(ctx, phone, "wrong"); !errors.Is(err, ErrInvalidOTP) {
+			t.Fatalf("expected a wrong code to be rejected, got %v", err)
+		}
+	}
+
+	// A resent code does not hand out fresh guesses.
+	*now = now.Add(otp.ResendInterval)
+	if err := otp.Send(ctx, phone); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	code = lastCode(t, sender)
+	if err := otp.Check(ctx, phone, "wrong"); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("expected a wrong code to be rejected, got %v", err)
+	}
+	if err := otp.Check(ctx, phone, code); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("expected the code to be refused after %d guesses, got %v", otp.MaxAttempts, err)
+	}
+	*now = now.Add(otp.ResendInterval)
+	var tooSoon *OTPResendError
+	if err := otp.Send(ctx, phone); !errors.As(err, &tooSoon) || tooSoon.RetryAfter != otp.AttemptWindow-2*otp.ResendInterval {
+		t.Fatalf("expected sends to be refused until the attempt window ends, got %v", err)
+	}
+
+	*now = now.Add(tooSoon.RetryAfter)
+	if err := otp.Send(ctx, phone); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	code = lastCode(t, sender)
+	if err := otp.Check(ctx, phone, code); err != nil {
+		t.Fatalf("expected the new code to be accepted, got %v", err)
+	}
+	*now = now.Add(otp.TTL)
+	if err := otp.Check(ctx, phone, code); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("expected an expired code to be rejected, got %v", err)
+	}
+}
+
+func TestOTPServiceCountsConcurrentGuesses(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	otp, sender, _ := newTestOTPService()
+	const phone = "+14155550123"
+
+	if err := otp.Send(ctx, phone); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	code := lastCode(t, sender)
+
+	// Only MaxAttempts of a burst of checks get compared, so the right code
+	// sent after enough concurrent wrong guesses is still refused.
+	var wg sync.WaitGroup
+	for i := 0; i < 10*otp.MaxAttempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = otp.Check(ctx, phone, "wrong")
+		}()
+	}
+	wg.Wait()
+	if err := otp.Check(ctx, phone, code); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("expected the code to be refused after a burst of guesses, got %v", err)
+	}
+	stored, err := otp.Codes.Find(ctx, phone)
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if stored.Attempts != otp.MaxAttempts {
+		t.Fatalf("expected %d attempts to be counted, got %d", otp.MaxAttempts, stored.Attempts)
+	}
+}
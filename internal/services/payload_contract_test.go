@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"logi/internal/messaging"
+	"logi/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// schemaDir holds the documented message schemas, generated from the payload
+// structs by go generate in internal/messaging.
+const schemaDir = "../../schemas/messages"
+
+// TestEmittedPayloadsMatchDocumentedSchemas drives every flow that notifies a
+// client and checks each payload against the checked-in schema for its type.
+func TestEmittedPayloadsMatchDocumentedSchemas(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	scheduled := time.Date(2026, 1, 2, 9, 30, 0, 0, time.UTC)
+	pending := &models.Booking{
+		ID:                "booking-1",
+		UserID:            "user-1",
+		PickupLocation:    models.Location{Type: "Point", Coordinates: []float64{72.8777, 19.0760}},
+		DropoffLocation:   models.Location{Type: "Point", Coordinates: []float64{72.8311, 18.9220}},
+		PickupPlace:       &models.Place{AddressLine: "1 Marine Drive", FormattedAddress: "1 Marine Drive, Mumbai"},
+		VehicleType:       "car",
+		PriceEstimate:     320.5,
+		Status:            models.BookingStatusPending,
+		ScheduledTime:     &scheduled,
+		OfferedDriverIDs:  []string{"driver-1", "driver-2"},
+		RejectedDriverIDs: []string{"driver-3"},
+	}
+	active := &models.Booking{ID: "booking-2", UserID: "user-1", DriverID: "driver-1", Status: models.BookingStatusInTransit}
+
+	bookingRepo := &fakeBookingRepository{
+		findByIDFn: func(ctx context.Context, id string) (*models.Booking, error) {
+			if id == active.ID {
+				copied := *active
+				return &copied, nil
+			}
+			copied := *pending
+			return &copied, nil
+		},
+		assignDriverIfUnassignedFn: func(ctx context.Context, bookingID, driverID string) (bool, error) {
+			return true, nil
+		},
+		findActiveByDriverIDFn: func(ctx context.Context, driverID string) (*models.Booking, error) {
+			return active, nil
+		},
+	}
+	driverRepo := &fakeDriverRepository{
+		findAvailableDriversFn: func(ctx context.Context, location models.Location, vehicleType string) ([]*models.Driver, error) {
+			return []*models.Driver{{ID: "driver-1"}, {ID: "driver-2"}}, nil
+		},
+	}
+	client := &fakeMessagingClient{}
+	bookingService := NewBookingService(bookingRepo, driverRepo, nil, client, nil, nil, nil, nil)
+	driverService := &DriverService{
+		Repo:            driverRepo,
+		BookingRepo:     bookingRepo,
+		UserRepo:        &fakeUserRepository{},
+		MessagingClient: client,
+	}
+
+	if err := bookingService.DriverRejectsBooking(ctx, "driver-4", pending.ID); err != nil {
+		t.Fatalf("DriverRejectsBooking returned error: %v", err)
+	}
+	if err := bookingService.DriverAcceptsBooking(ctx, "driver-1", pending.ID); err != nil {
+		t.Fatalf("DriverAcceptsBooking returned error: %v", err)
+	}
+	if err := driverService.UpdateBookingStatus(ctx, "driver-1", active.ID, models.BookingStatusDelivered); err != nil {
+		t.Fatalf("UpdateBookingStatus returned error: %v", err)
+	}
+	if err := driverService.UpdateLocation(ctx, "driver-1", 19.07, 72.87); err != nil {
+		t.Fatalf("UpdateLocation returned error: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, message := range client.published {
+		if _, ok := messaging.LookupMessageType(message.messageType); !ok {
+			t.Fatalf("published unregistered message type %q", message.messageType)
+		}
+		schema, err := os.ReadFile(filepath.Join(schemaDir, messaging.SchemaFileName(message.messageType)))
+		if err != nil {
+			t.Fatalf("failed to read schema for %s: %v", message.messageType, err)
+		}
+		if err := messaging.ValidatePayload(schema, message.payload); err != nil {
+			t.Errorf("%s payload drifted from its schema: %v", message.messageType, err)
+		}
+		seen[message.messageType] = true
+	}
+	for _, messageType := range messaging.Registry {
+		if messageType.Name != messaging.TypeCommandResult && !seen[messageType.Name] {
+			t.Errorf("no flow emitted %s", messageType.Name)
+		}
+	}
+}
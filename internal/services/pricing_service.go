@@ -25,7 +25,7 @@ func NewPricingService(bookingRepo repositories.BookingRepository, driverRepo re
 
 // CalculatePrice calculates the price based on pickup and dropoff locations and vehicle type.
 func (s *PricingService) CalculatePrice(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (float64, error) {
-	basePrice, err := s.calculateBasePrice(pickup, dropoff, vehicleType)
+	basePrice, err := s.calculateBasePrice(ctx, pickup, dropoff, vehicleType)
 	if err != nil {
 		return 0, err
 	}
@@ -36,8 +36,8 @@ func (s *PricingService) CalculatePrice(ctx context.Context, pickup, dropoff mod
 	return math.Round(finalPrice*100) / 100, nil // Round to two decimal places
 }
 
-func (s *PricingService) calculateBasePrice(pickup, dropoff models.Location, vehicleType string) (float64, error) {
-	distanceResult, err := s.DistanceCalc.Calculate(pickup, dropoff)
+func (s *PricingService) calculateBasePrice(ctx context.Context, pickup, dropoff models.Location, vehicleType string) (float64, error) {
+	distanceResult, err := s.DistanceCalc.Calculate(ctx, pickup, dropoff, vehicleType)
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrRoleNotFound     = errors.New("role not found")
+	ErrInvalidRole      = errors.New("role names are 2-32 lowercase letters, digits or underscores, and permissions must be known")
+	ErrSystemRole       = errors.New("system roles cannot be changed")
+	ErrRoleInUse        = errors.New("role is assigned to admins")
+	ErrPermissionDenied = errors.New("cannot grant permissions you do not hold")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+const defaultRoleCacheTTL = 30 * time.Second
+
+// RoleService resolves admin permissions from roles stored in admin_roles.
+// Permissions are looked up per request rather than embedded in the JWT, so
+// role changes and revocations apply without a new login. Stored roles are
+// cached for CacheTTL, which bounds how long another instance's edit takes to
+// apply.
+type RoleService struct {
+	Roles    repositories.RoleRepository
+	Admins   repositories.AdminRepository
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedRole
+}
+
+type cachedRole struct {
+	role      *models.Role
+	expiresAt time.Time
+}
+
+func NewRoleService(roles repositories.RoleRepository, admins repositories.AdminRepository) *RoleService {
+	return &RoleService{
+		Roles:    roles,
+		Admins:   admins,
+		CacheTTL: defaultRoleCacheTTL,
+		cache:    make(map[string]cachedRole),
+	}
+}
+
+// SeedDefaultRoles stores the default roles that do not exist yet.
+func (s *RoleService) SeedDefaultRoles(ctx context.Context) error {
+	roles := models.DefaultRoles()
+	now := time.Now()
+	for _, role := range roles {
+		role.UpdatedAt = now
+	}
+	return s.Roles.Seed(ctx, roles)
+}
+
+// List returns the system roles followed by the stored ones.
+func (s *RoleService) List(ctx context.Context) ([]*models.Role, error) {
+	stored, err := s.Roles.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(models.SystemRoles(), stored...), nil
+}
+
+// Role returns the role called name.
+func (s *RoleService) Role(ctx context.Context, name string) (*models.Role, error) {
+	if name == "" {
+		name = models.AdminRoleAdmin
+	}
+	for _, role := range models.SystemRoles() {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache[name]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.role, nil
+	}
+
+	role, err := s.Roles.FindByName(ctx, name)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.cache[name] = cachedRole{role: role, expiresAt: time.Now().Add(s.CacheTTL)}
+	s.mu.Unlock()
+	return role, nil
+}
+
+// HasPermission reports whether the active admin adminID holds permission.
+// It is the check behind utils.RequirePermission.
+func (s *RoleService) HasPermission(ctx context.Context, adminID, permission string) (bool, error) {
+	role, err := s.adminRole(ctx, adminID)
+	if err != nil || role == nil {
+		return false, err
+	}
+	return role.HasPermission(permission), nil
+}
+
+// Permissions returns the effective permissions of adminID.
+func (s *RoleService) Permissions(ctx context.Context, adminID string) ([]string, error) {
+	role, err := s.adminRole(ctx, adminID)
+	if err != nil || role == nil {
+		return []string{}, err
+	}
+	granted := []string{}
+	for _, permission := range models.AllPermissions {
+		if role.HasPermission(permission) {
+			granted = append(granted, permission)
+		}
+	}
+	return granted, nil
+}
+
+// adminRole returns the role of the active admin adminID, or nil when there
+// is no such admin or their role was deleted.
+func (s *RoleService) adminRole(ctx context.Context, adminID string) (*models.Role, error) {
+	admin, err := s.Admins.FindByID(ctx, adminID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !admin.IsActive() {
+		return nil, nil
+	}
+	role, err := s.Role(ctx, admin.Role)
+	if errors.Is(err, ErrRoleNotFound) {
+		return nil, nil
+	}
+	return role, err
+}
+
+// CanGrant checks that actor may give role to an admin: owners can grant any
+// role, and other admins only roles whose permissions they hold themselves.
+func (s *RoleService) CanGrant(ctx context.Context, actor *models.Admin, role string) error {
+	target, err := s.Role(ctx, role)
+	if err != nil {
+		return err
+	}
+	if actor.IsOwner() {
+		return nil
+	}
+	if target.Name == models.AdminRoleOwner {
+		return ErrOwnerRequired
+	}
+	return s.holdsAll(ctx, actor, target.Permissions)
+}
+
+// Save creates or replaces a stored role on behalf of actorID, who must hold
+// every permission the role grants.
+func (s *RoleService) Save(ctx context.Context, actorID string, role *models.Role) error {
+	if role.Name == models.AdminRoleOwner || role.Name == models.AdminRoleAdmin {
+		return ErrSystemRole
+	}
+	if !roleNamePattern.MatchString(role.Name) || !validPermissions(role.Permissions) {
+		return ErrInvalidRole
+	}
+	actor, err := s.activeAdmin(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	if !actor.IsOwner() {
+		if err := s.holdsAll(ctx, actor, role.Permissions); err != nil {
+			return err
+		}
+	}
+
+	permissions := append([]string(nil), role.Permissions...)
+	sort.Strings(permissions)
+	role.Permissions = permissions
+	role.System = false
+	role.UpdatedAt = time.Now()
+	if err := s.Roles.Upsert(ctx, role); err != nil {
+		return err
+	}
+	s.forget(role.Name)
+	return nil
+}
+
+// Delete removes a stored role that no admin holds.
+func (s *RoleService) Delete(ctx context.Context, name string) error {
+	if name == models.AdminRoleOwner || name == models.AdminRoleAdmin {
+		return ErrSystemRole
+	}
+	holders, err := s.Admins.CountByRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if holders > 0 {
+		return ErrRoleInUse
+	}
+	err = s.Roles.Delete(ctx, name)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.forget(name)
+	return nil
+}
+
+func (s *RoleService) holdsAll(ctx context.Context, actor *models.Admin, permissions []string) error {
+	actorRole, err := s.Role(ctx, actor.Role)
+	if errors.Is(err, ErrRoleNotFound) {
+		return ErrPermissionDenied
+	}
+	if err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if !actorRole.HasPermission(permission) {
+			return ErrPermissionDenied
+		}
+	}
+	return nil
+}
+
+func (s *RoleService) activeAdmin(ctx context.Context, adminID string) (*models.Admin, error) {
+	admin, err := s.Admins.FindByID(ctx, adminID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrPermissionDenied
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !admin.IsActive() {
+		return nil, ErrPermissionDenied
+	}
+	return admin, nil
+}
+
+func (s *RoleService) forget(name string) {
+	s.mu.Lock()
+	delete(s.cache, name)
+	s.mu.Unlock()
+}
+
+func validPermissions(permissions []string) bool {
+	for _, permission := range permissions {
+		if !(&models.Role{Permissions: models.AllPermissions}).HasPermission(permission) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"logi/internal/models"
+)
+
+func TestRoleServiceResolvesPermissionsPerRequest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, _ := newTestAdminAccountService()
+	roles := service.Roles
+	owner := &models.Admin{Name: "Owner", Email: "owner@example.com"}
+	if err := service.Bootstrap(ctx, owner, "secret"); err != nil {
+		t.Fatalf("Bootstrap returned error: %v", err)
+	}
+	dispatcher := &models.Admin{Name: "Dispatch", Email: "dispatch@example.com", Role: models.AdminRoleDispatcher}
+	if err := service.Create(ctx, owner.ID, dispatcher, "secret"); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	for permission, want := range map[string]bool{
+		models.PermissionDriversWrite:  true,
+		models.PermissionVehiclesWrite: false,
+		models.PermissionAdminsWrite:   false,
+	} {
+		if got, err := roles.HasPermission(ctx, dispatcher.ID, permission); err != nil || got != want {
+			t.Fatalf("HasPermission(%s) = %v, %v; want %v", permission, got, err, want)
+		}
+	}
+
+	// Promoting the dispatcher applies without a new token.
+	if err := service.SetRole(ctx, owner.ID, dispatcher.ID, models.AdminRoleFleetManager); err != nil {
+		t.Fatalf("SetRole returned error: %v", err)
+	}
+	if ok, _ := roles.HasPermission(ctx, dispatcher.ID, models.PermissionVehiclesWrite); !ok {
+		t.Fatal("expected the new role's permissions to apply immediately")
+	}
+
+	if err := roles.Delete(ctx, models.AdminRoleFleetManager); !errors.Is(err, ErrRoleInUse) {
+		t.Fatalf("expected a role in use to be kept, got %v", err)
+	}
+	if err := service.Revoke(ctx, owner.ID, dispatcher.ID); err != nil {
+		t.Fatalf("Revoke returned error: %v", err)
+	}
+	if permissions, err := roles.Permissions(ctx, dispatcher.ID); err != nil || len(permissions) != 0 {
+		t.Fatalf("expected a revoked admin to hold no permissions, got %v, %v", permissions, err)
+	}
+}
+
+func TestRoleServicePreventsPrivilegeEscalation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, _ := newTestAdminAccountService()
+	roles := service.Roles
+	owner := &models.Admin{Name: "Owner", Email: "owner@example.com"}
+	if err := service.Bootstrap(ctx, owner, "secret"); err != nil {
+		t.Fatalf("Bootstrap returned error: %v", err)
+	}
+	// A role that may manage admins but not vehicles.
+	hr := &models.Role{Name: "hr", Permissions: []string{models.PermissionAdminsRead, models.PermissionAdminsWrite, models.PermissionRolesWrite}}
+	if err := roles.Save(ctx, owner.ID, hr); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	manager := &models.Admin{Name: "HR", Email: "hr@example.com", Role: "hr"}
+	if err := service.Create(ctx, owner.ID, manager, "secret"); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	admin := &models.Admin{Name: "Admin", Email: "admin@example.com"}
+	if err := service.Create(ctx, owner.ID, admin, "secret"); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if err := service.Create(ctx, manager.ID, &models.Admin{Name: "X", Email: "x@example.com", Role: models.AdminRoleFleetManager}, "secret"); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected granting permissions the actor lacks to fail, got %v", err)
+	}
+	if err := service.SetRole(ctx, manager.ID, admin.ID, "hr"); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected demoting an admin with more permissions to fail, got %v", err)
+	}
+	hr.Permissions = append(hr.Permissions, models.PermissionVehiclesWrite)
+	if err := roles.Save(ctx, manager.ID, hr); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected widening one's own role to fail, got %v", err)
+	}
+	if err := roles.Save(ctx, owner.ID, &models.Role{Name: models.AdminRoleAdmin}); !errors.Is(err, ErrSystemRole) {
+		t.Fatalf("expected system roles to be read-only, got %v", err)
+	}
+	if err := roles.Save(ctx, owner.ID, &models.Role{Name: "support", Permissions: []string{"bookings:everything"}}); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected unknown permissions to be rejected, got %v", err)
+	}
+}
@@ -2,11 +2,20 @@ package services
 
 import (
 	"context"
+	"logi/internal/events"
+	"logi/internal/messaging"
 	"logi/internal/models"
+	"logi/internal/repositories"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type publishedMessage struct {
+	role        string
 	userID      string
+	topics      []string
 	messageType string
 	payload     interface{}
 }
@@ -16,18 +25,237 @@ type fakeMessagingClient struct {
 	publishErr error
 }
 
-func (f *fakeMessagingClient) Publish(userID string, messageType string, payload interface{}) error {
+func (f *fakeMessagingClient) Publish(recipient messaging.Recipient, messageType string, payload interface{}) error {
 	if f.publishErr != nil {
 		return f.publishErr
 	}
 	f.published = append(f.published, publishedMessage{
-		userID:      userID,
+		role:        recipient.Role,
+		userID:      recipient.ID,
+		topics:      recipient.Topics,
 		messageType: messageType,
 		payload:     payload,
 	})
 	return nil
 }
 
+type fakeEventPublisher struct {
+	published  []events.Event
+	publishErr error
+}
+
+func (f *fakeEventPublisher) PublishEvent(ctx context.Context, event events.Event) error {
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.published = append(f.published, event)
+	return nil
+}
+
+func (f *fakeEventPublisher) types() []string {
+	out := make([]string, 0, len(f.published))
+	for _, event := range f.published {
+		out = append(out, event.Type)
+	}
+	return out
+}
+
+type fakeEventOutboxRepository struct {
+	inserted  []*models.EventOutboxEntry
+	insertErr error
+}
+
+func (f *fakeEventOutboxRepository) Insert(ctx context.Context, entry *models.EventOutboxEntry) error {
+	if f.insertErr != nil {
+		return f.insertErr
+	}
+	f.inserted = append(f.inserted, entry)
+	return nil
+}
+
+func (f *fakeEventOutboxRepository) FindPending(ctx context.Context, now time.Time, limit int64) ([]*models.EventOutboxEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeEventOutboxRepository) MarkPublished(ctx context.Context, id string, publishedAt time.Time) error {
+	return nil
+}
+
+func (f *fakeEventOutboxRepository) MarkFailed(ctx context.Context, id string, attempts int, lastError string, nextAttemptAt time.Time) error {
+	return nil
+}
+
+func (f *fakeEventOutboxRepository) MarkDead(ctx context.Context, id string, attempts int, lastError string) error {
+	return nil
+}
+
+func (f *fakeEventOutboxRepository) AcquireLease(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+// fakeRefreshTokenRepository keeps refresh tokens in memory, keyed by ID.
+type fakeRefreshTokenRepository struct {
+	tokens map[string]*models.RefreshToken
+}
+
+func (f *fakeRefreshTokenRepository) Insert(ctx context.Context, token *models.RefreshToken) error {
+	if f.tokens == nil {
+		f.tokens = make(map[string]*models.RefreshToken)
+	}
+	copied := *token
+	f.tokens[token.ID] = &copied
+	return nil
+}
+
+func (f *fakeRefreshTokenRepository) find(match func(token *models.RefreshToken) bool) (*models.RefreshToken, error) {
+	for _, token := range f.tokens {
+		if match(token) {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	return f.find(func(token *models.RefreshToken) bool { return token.TokenHash == tokenHash })
+}
+
+func (f *fakeRefreshTokenRepository) FindByAccessTokenID(ctx context.Context, accessTokenID string) (*models.RefreshToken, error) {
+	return f.find(func(token *models.RefreshToken) bool { return token.AccessTokenID == accessTokenID })
+}
+
+func (f *fakeRefreshTokenRepository) FindFamily(ctx context.Context, familyID string) ([]*models.RefreshToken, error) {
+	var family []*models.RefreshToken
+	for _, token := range f.tokens {
+		if token.FamilyID == familyID {
+			copied := *token
+			family = append(family, &copied)
+		}
+	}
+	return family, nil
+}
+
+func (f *fakeRefreshTokenRepository) FindActiveByUser(ctx context.Context, userID string) ([]*models.RefreshToken, error) {
+	var active []*models.RefreshToken
+	for _, token := range f.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			copied := *token
+			active = append(active, &copied)
+		}
+	}
+	return active, nil
+}
+
+func (f *fakeRefreshTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	token, ok := f.tokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &usedAt
+	return true, nil
+}
+
+func (f *fakeRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	for _, token := range f.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+type fakeRevokedTokenRepository struct {
+	revoked map[string]time.Time
+}
+
+func (f *fakeRevokedTokenRepository) Revoke(ctx context.Context, tokenID string, expiresAt, revokedAt time.Time) error {
+	if f.revoked == nil {
+		f.revoked = make(map[string]time.Time)
+	}
+	f.revoked[tokenID] = expiresAt
+	return nil
+}
+
+func (f *fakeRevokedTokenRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	_, ok := f.revoked[tokenID]
+	return ok, nil
+}
+
+type fakeWebhookEndpointRepository struct {
+	endpoints map[string]*models.WebhookEndpoint
+	created   []*models.WebhookEndpoint
+	deleted   []string
+}
+
+func (f *fakeWebhookEndpointRepository) Create(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	f.created = append(f.created, endpoint)
+	return nil
+}
+
+func (f *fakeWebhookEndpointRepository) FindByID(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	if endpoint, ok := f.endpoints[id]; ok {
+		return endpoint, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeWebhookEndpointRepository) FindByOwner(ctx context.Context, userID, organizationID string) ([]*models.WebhookEndpoint, error) {
+	return nil, nil
+}
+
+func (f *fakeWebhookEndpointRepository) Delete(ctx context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+type fakeWebhookDeliveryRepository struct {
+	deliveries map[string]*models.WebhookDelivery
+	inserted   []*models.WebhookDelivery
+}
+
+func (f *fakeWebhookDeliveryRepository) Insert(ctx context.Context, delivery *models.WebhookDelivery) error {
+	f.inserted = append(f.inserted, delivery)
+	return nil
+}
+
+func (f *fakeWebhookDeliveryRepository) FindByID(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	if delivery, ok := f.deliveries[id]; ok {
+		return delivery, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeWebhookDeliveryRepository) List(ctx context.Context, filter repositories.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (f *fakeWebhookDeliveryRepository) ClaimDue(ctx context.Context, now, lockUntil time.Time) (*models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (f *fakeWebhookDeliveryRepository) MarkSucceeded(ctx context.Context, id string, attempts int, statusCode int, deliveredAt time.Time) error {
+	return nil
+}
+
+func (f *fakeWebhookDeliveryRepository) MarkRetrying(ctx context.Context, id string, attempts int, statusCode int, lastError string, nextAttemptAt time.Time) error {
+	return nil
+}
+
+func (f *fakeWebhookDeliveryRepository) MarkFailed(ctx context.Context, id string, attempts int, statusCode int, lastError string) error {
+	return nil
+}
+
+// fakeTransactor runs fn directly and counts transactions.
+type fakeTransactor struct {
+	transactions int
+}
+
+func (f *fakeTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.transactions++
+	return fn(ctx)
+}
+
 type fakeBookingRepository struct {
 	createFn                   func(context.Context, *models.Booking) error
 	updateFn                   func(context.Context, *models.Booking) error
@@ -43,6 +271,7 @@ type fakeBookingRepository struct {
 	findByIDAndDriverIDFn      func(context.Context, string, string) (*models.Booking, error)
 	getAverageTripTimeFn       func(context.Context) (float64, error)
 	getTotalBookingsFn         func(context.Context) (int64, error)
+	findCompletedTripsFn       func(context.Context, time.Time, int64) ([]*models.Booking, error)
 }
 
 func (f *fakeBookingRepository) Create(ctx context.Context, booking *models.Booking) error {
@@ -143,9 +372,17 @@ func (f *fakeBookingRepository) GetTotalBookings(ctx context.Context) (int64, er
 	return 0, nil
 }
 
+func (f *fakeBookingRepository) FindCompletedTrips(ctx context.Context, since time.Time, limit int64) ([]*models.Booking, error) {
+	if f.findCompletedTripsFn != nil {
+		return f.findCompletedTripsFn(ctx, since, limit)
+	}
+	return nil, nil
+}
+
 type fakeDriverRepository struct {
 	createFn                   func(context.Context, *models.Driver) error
 	findByEmailFn              func(context.Context, string) (*models.Driver, error)
+	findByPhoneFn              func(context.Context, string) (*models.Driver, error)
 	findAvailableDriversFn     func(context.Context, models.Location, string) ([]*models.Driver, error)
 	updateStatusFn             func(context.Context, string, string) error
 	assignVehicleFn            func(context.Context, string, string, string) error
@@ -159,6 +396,8 @@ type fakeDriverRepository struct {
 	incrementTotalFn           func(context.Context, string) error
 	incrementCompletedFn       func(context.Context, string) error
 	getTotalDriversFn          func(context.Context) (int64, error)
+	markEmailVerifiedFn        func(context.Context, string, time.Time) error
+	updatePasswordFn           func(context.Context, string, string, string) error
 }
 
 func (f *fakeDriverRepository) Create(ctx context.Context, driver *models.Driver) error {
@@ -175,6 +414,13 @@ func (f *fakeDriverRepository) FindByEmail(ctx context.Context, email string) (*
 	return nil, nil
 }
 
+func (f *fakeDriverRepository) FindByPhone(ctx context.Context, phone string) (*models.Driver, error) {
+	if f.findByPhoneFn != nil {
+		return f.findByPhoneFn(ctx, phone)
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
 func (f *fakeDriverRepository) FindAvailableDrivers(ctx context.Context, location models.Location, vehicleType string) ([]*models.Driver, error) {
 	if f.findAvailableDriversFn != nil {
 		return f.findAvailableDriversFn(ctx, location, vehicleType)
@@ -266,6 +512,20 @@ func (f *fakeDriverRepository) GetTotalDrivers(ctx context.Context) (int64, erro
 	return 0, nil
 }
 
+func (f *fakeDriverRepository) MarkEmailVerified(ctx context.Context, driverID string, at time.Time) error {
+	if f.markEmailVerifiedFn != nil {
+		return f.markEmailVerifiedFn(ctx, driverID, at)
+	}
+	return nil
+}
+
+func (f *fakeDriverRepository) UpdatePassword(ctx context.Context, driverID, currentHash, newHash string) error {
+	if f.updatePasswordFn != nil {
+		return f.updatePasswordFn(ctx, driverID, currentHash, newHash)
+	}
+	return nil
+}
+
 type fakeVehicleRepository struct {
 	createFn       func(context.Context, *models.Vehicle) error
 	updateFn       func(context.Context, *models.Vehicle) error
@@ -318,10 +578,13 @@ func (f *fakeVehicleRepository) FindAll(ctx context.Context) ([]*models.Vehicle,
 }
 
 type fakeUserRepository struct {
-	createFn      func(context.Context, *models.User) error
-	findByEmailFn func(context.Context, string) (*models.User, error)
-	findByIDFn    func(context.Context, string) (*models.User, error)
-	getTotalFn    func(context.Context) (int64, error)
+	createFn         func(context.Context, *models.User) error
+	findByEmailFn    func(context.Context, string) (*models.User, error)
+	findByIDFn       func(context.Context, string) (*models.User, error)
+	getTotalFn       func(context.Context) (int64, error)
+	updateOrgFn      func(context.Context, string, string) error
+	markVerifiedFn   func(context.Context, string, time.Time) error
+	updatePasswordFn func(context.Context, string, string, string) error
 }
 
 func (f *fakeUserRepository) Create(ctx context.Context, user *models.User) error {
@@ -351,3 +614,339 @@ func (f *fakeUserRepository) GetTotalUsers(ctx context.Context) (int64, error) {
 	}
 	return 0, nil
 }
+
+func (f *fakeUserRepository) UpdateOrganization(ctx context.Context, userID, organizationID string) error {
+	if f.updateOrgFn != nil {
+		return f.updateOrgFn(ctx, userID, organizationID)
+	}
+	return nil
+}
+
+func (f *fakeUserRepository) MarkEmailVerified(ctx context.Context, userID string, at time.Time) error {
+	if f.markVerifiedFn != nil {
+		return f.markVerifiedFn(ctx, userID, at)
+	}
+	return nil
+}
+
+func (f *fakeUserRepository) UpdatePassword(ctx context.Context, userID, currentHash, newHash string) error {
+	if f.updatePasswordFn != nil {
+		return f.updatePasswordFn(ctx, userID, currentHash, newHash)
+	}
+	return nil
+}
+
+// fakeAdminRepository keeps admins in memory, keyed by ID.
+type fakeAdminRepository struct {
+	admins       map[string]*models.Admin
+	bootstrapped bool
+	createErr    error
+}
+
+func (f *fakeAdminRepository) Create(ctx context.Context, admin *models.Admin) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	if f.admins == nil {
+		f.admins = make(map[string]*models.Admin)
+	}
+	copied := *admin
+	f.admins[admin.ID] = &copied
+	return nil
+}
+
+func (f *fakeAdminRepository) FindByEmail(ctx context.Context, email string) (*models.Admin, error) {
+	for _, admin := range f.admins {
+		if admin.Email == email {
+			copied := *admin
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeAdminRepository) FindByID(ctx context.Context, id string) (*models.Admin, error) {
+	admin, ok := f.admins[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	copied := *admin
+	return &copied, nil
+}
+
+func (f *fakeAdminRepository) List(ctx context.Context) ([]*models.Admin, error) {
+	var admins []*models.Admin
+	for _, admin := range f.admins {
+		copied := *admin
+		admins = append(admins, &copied)
+	}
+	return admins, nil
+}
+
+func (f *fakeAdminRepository) CountActiveOwners(ctx context.Context) (int64, error) {
+	var owners int64
+	for _, admin := range f.admins {
+		if admin.IsOwner() && admin.IsActive() {
+			owners++
+		}
+	}
+	return owners, nil
+}
+
+func (f *fakeAdminRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	var holders int64
+	for _, admin := range f.admins {
+		if admin.Role == role {
+			holders++
+		}
+	}
+	return holders, nil
+}
+
+func (f *fakeAdminRepository) UpdateRole(ctx context.Context, id, role string) error {
+	admin, ok := f.admins[id]
+	if !ok {
+		return mongo.ErrNoDocuments
+	}
+	admin.Role = role
+	return nil
+}
+
+func (f *fakeAdminRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	admin, ok := f.admins[id]
+	if !ok || !admin.IsActive() {
+		return mongo.ErrNoDocuments
+	}
+	admin.RevokedAt = &revokedAt
+	return nil
+}
+
+func (f *fakeAdminRepository) MarkBootstrapped(ctx context.Context, at time.Time) (bool, error) {
+	if f.bootstrapped {
+		return false, nil
+	}
+	f.bootstrapped = true
+	return true, nil
+}
+
+func (f *fakeAdminRepository) ClearBootstrapped(ctx context.Context) error {
+	f.bootstrapped = false
+	return nil
+}
+
+// fakeRoleRepository keeps roles in memory, keyed by name.
+type fakeRoleRepository struct {
+	roles map[string]*models.Role
+}
+
+func (f *fakeRoleRepository) Seed(ctx context.Context, roles []*models.Role) error {
+	for _, role := range roles {
+		if _, ok := f.roles[role.Name]; !ok {
+			f.Upsert(ctx, role)
+		}
+	}
+	return nil
+}
+
+func (f *fakeRoleRepository) FindAll(ctx context.Context) ([]*models.Role, error) {
+	var roles []*models.Role
+	for _, role := range f.roles {
+		copied := *role
+		roles = append(roles, &copied)
+	}
+	return roles, nil
+}
+
+func (f *fakeRoleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	role, ok := f.roles[name]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	copied := *role
+	return &copied, nil
+}
+
+func (f *fakeRoleRepository) Upsert(ctx context.Context, role *models.Role) error {
+	if f.roles == nil {
+		f.roles = make(map[string]*models.Role)
+	}
+	copied := *role
+	f.roles[role.Name] = &copied
+	return nil
+}
+
+func (f *fakeRoleRepository) Delete(ctx context.Context, name string) error {
+	if _, ok := f.roles[name]; !ok {
+		return mongo.ErrNoDocuments
+	}
+	delete(f.roles, name)
+	return nil
+}
+
+// fakeAdminInviteRepository keeps invites in memory, keyed by ID.
+type fakeAdminInviteRepository struct {
+	invites map[string]*models.AdminInvite
+}
+
+func (f *fakeAdminInviteRepository) Create(ctx context.Context, invite *models.AdminInvite) error {
+	if f.invites == nil {
+		f.invites = make(map[string]*models.AdminInvite)
+	}
+	copied := *invite
+	f.invites[invite.ID] = &copied
+	return nil
+}
+
+func (f *fakeAdminInviteRepository) ListPending(ctx context.Context, now time.Time) ([]*models.AdminInvite, error) {
+	var pending []*models.AdminInvite
+	for _, invite := range f.invites {
+		if invite.AcceptedAt == nil && now.Before(invite.ExpiresAt) {
+			copied := *invite
+			pending = append(pending, &copied)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeAdminInviteRepository) Delete(ctx context.Context, id string) error {
+	if _, ok := f.invites[id]; !ok {
+		return mongo.ErrNoDocuments
+	}
+	delete(f.invites, id)
+	return nil
+}
+
+func (f *fakeAdminInviteRepository) Accept(ctx context.Context, tokenHash string, now time.Time) (*models.AdminInvite, error) {
+	for _, invite := range f.invites {
+		if invite.TokenHash == tokenHash && invite.AcceptedAt == nil && now.Before(invite.ExpiresAt) {
+			invite.AcceptedAt = &now
+			copied := *invite
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// fakeLoginAttemptRepository keeps login attempts in memory, keyed by key.
+type fakeLoginAttemptRepository struct {
+	attempts map[string]*models.LoginAttempt
+}
+
+func (f *fakeLoginAttemptRepository) Find(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	attempt, ok := f.attempts[key]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	copied := *attempt
+	return &copied, nil
+}
+
+func (f *fakeLoginAttemptRepository) RecordFailure(ctx context.Context, key string, now, windowStart, expiresAt time.Time) (*models.LoginAttempt, error) {
+	if f.attempts == nil {
+		f.attempts = make(map[string]*models.LoginAttempt)
+	}
+	attempt, ok := f.attempts[key]
+	if !ok {
+		attempt = &models.LoginAttempt{Key: key}
+		f.attempts[key] = attempt
+	}
+	if attempt.WindowStartedAt.Before(windowStart) {
+		attempt.Failures = 0
+		attempt.WindowStartedAt = now
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	attempt.ExpiresAt = expiresAt
+	copied := *attempt
+	return &copied, nil
+}
+
+func (f *fakeLoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	if attempt, ok := f.attempts[key]; ok {
+		attempt.LockedUntil = &until
+	}
+	return nil
+}
+
+func (f *fakeLoginAttemptRepository) Delete(ctx context.Context, key string) error {
+	if _, ok := f.attempts[key]; !ok {
+		return mongo.ErrNoDocuments
+	}
+	delete(f.attempts, key)
+	return nil
+}
+
+func (f *fakeLoginAttemptRepository) FindLocked(ctx context.Context, now time.Time) ([]*models.LoginAttempt, error) {
+	var locked []*models.LoginAttempt
+	for _, attempt := range f.attempts {
+		if attempt.IsLocked(now) {
+			copied := *attempt
+			locked = append(locked, &copied)
+		}
+	}
+	return locked, nil
+}
+
+// fakeAuditLogRepository records audit events.
+type fakeAuditLogRepository struct {
+	events []*models.AuditEvent
+}
+
+func (f *fakeAuditLogRepository) Insert(ctx context.Context, event *models.AuditEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+// fakeOTPRepository keeps codes in memory, keyed by phone.
+type fakeOTPRepository struct {
+	mu    sync.Mutex
+	codes map[string]*models.OTPCode
+}
+
+func (f *fakeOTPRepository) Find(ctx context.Context, phone string) (*models.OTPCode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	code, ok := f.codes[phone]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	copied := *code
+	return &copied, nil
+}
+
+func (f *fakeOTPRepository) Save(ctx context.Context, code *models.OTPCode) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.codes == nil {
+		f.codes = make(map[string]*models.OTPCode)
+	}
+	copied := *code
+	copied.Attempts = 0
+	if previous, ok := f.codes[code.Phone]; ok && previous.WindowStart.Equal(code.WindowStart) {
+		copied.Attempts = previous.Attempts
+	}
+	f.codes[code.Phone] = &copied
+	return nil
+}
+
+func (f *fakeOTPRepository) ClaimAttempt(ctx context.Context, phone string, maxAttempts int, now time.Time) (*models.OTPCode, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	code, ok := f.codes[phone]
+	if !ok || code.Attempts >= maxAttempts || !now.Before(code.ExpiresAt) {
+		return nil, mongo.ErrNoDocuments
+	}
+	code.Attempts++
+	copied := *code
+	return &copied, nil
+}
+
+func (f *fakeOTPRepository) Delete(ctx context.Context, phone string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.codes[phone]; !ok {
+		return mongo.ErrNoDocuments
+	}
+	delete(f.codes, phone)
+	return nil
+}
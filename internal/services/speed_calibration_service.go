@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/services/distance"
+	"logi/internal/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const maxCalibrationTrips = 50000
+
+// SpeedCalibrationService fits the HaversineCalculator speed profile to
+// historical completed bookings and persists the result.
+type SpeedCalibrationService struct {
+	BookingRepo repositories.BookingRepository
+	ProfileRepo repositories.SpeedProfileRepository
+	Calculator  *distance.HaversineCalculator
+	BaseProfile models.SpeedProfile
+	Lookback    time.Duration
+	MinSamples  int
+}
+
+func NewSpeedCalibrationService(bookingRepo repositories.BookingRepository, profileRepo repositories.SpeedProfileRepository, calculator *distance.HaversineCalculator, baseProfile models.SpeedProfile, lookback time.Duration, minSamples int) *SpeedCalibrationService {
+	return &SpeedCalibrationService{
+		BookingRepo: bookingRepo,
+		ProfileRepo: profileRepo,
+		Calculator:  calculator,
+		BaseProfile: baseProfile,
+		Lookback:    lookback,
+		MinSamples:  minSamples,
+	}
+}
+
+// LoadActiveProfile applies the last calibrated profile, falling back to the configured base profile.
+func (s *SpeedCalibrationService) LoadActiveProfile(ctx context.Context) error {
+	profile, err := s.ProfileRepo.FindActive(ctx)
+	if err != nil {
+		base := s.BaseProfile
+		s.Calculator.SetSpeedProfile(&base)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	}
+	s.Calculator.SetSpeedProfile(profile)
+	return nil
+}
+
+// Calibrate recomputes the speed profile from completed bookings in the lookback window,
+// saves it and applies it to the calculator.
+func (s *SpeedCalibrationService) Calibrate(ctx context.Context) (*models.SpeedProfile, error) {
+	bookings, err := s.BookingRepo.FindCompletedTrips(ctx, time.Now().Add(-s.Lookback), maxCalibrationTrips)
+	if err != nil {
+		return nil, err
+	}
+
+	trips := make([]distance.TripSample, 0, len(bookings))
+	for _, booking := range bookings {
+		if booking.StartedAt == nil || booking.CompletedAt == nil {
+			continue
+		}
+		if len(booking.PickupLocation.Coordinates) != 2 || len(booking.DropoffLocation.Coordinates) != 2 {
+			continue
+		}
+		trips = append(trips, distance.TripSample{
+			VehicleType: booking.VehicleType,
+			Pickup:      booking.PickupLocation,
+			Dropoff:     booking.DropoffLocation,
+			StartedAt:   *booking.StartedAt,
+			CompletedAt: *booking.CompletedAt,
+		})
+	}
+
+	profile := distance.CalibrateSpeedProfile(trips, s.BaseProfile, s.Calculator.DetourFactor, s.MinSamples, time.Local)
+	if profile.SampleCount == 0 {
+		return nil, errors.New("no usable completed trips to calibrate from")
+	}
+
+	if err := s.ProfileRepo.Save(ctx, &profile); err != nil {
+		return nil, err
+	}
+	s.Calculator.SetSpeedProfile(&profile)
+
+	utils.Info(ctx, "speed profile calibrated", "bookings", len(bookings), "samples", profile.SampleCount, "vehicle_types", len(profile.VehicleSpeedsKmh))
+	return &profile, nil
+}
+
+// ActiveProfile returns the profile currently used by the calculator.
+func (s *SpeedCalibrationService) ActiveProfile() *models.SpeedProfile {
+	return s.Calculator.SpeedProfile()
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"logi/internal/models"
+	"logi/internal/services/distance"
+)
+
+type fakeSpeedProfileRepository struct {
+	saved *models.SpeedProfile
+}
+
+func (f *fakeSpeedProfileRepository) Save(ctx context.Context, profile *models.SpeedProfile) error {
+	f.saved = profile
+	return nil
+}
+
+func (f *fakeSpeedProfileRepository) FindActive(ctx context.Context) (*models.SpeedProfile, error) {
+	return f.saved, nil
+}
+
+func TestSpeedCalibrationServiceCalibrateSavesAndAppliesProfile(t *testing.T) {
+	t.Parallel()
+
+	started := time.Now().Add(-2 * time.Hour)
+	completed := started.Add(30 * time.Minute)
+	var requestedSince time.Time
+
+	bookingRepo := &fakeBookingRepository{
+		findCompletedTripsFn: func(ctx context.Context, since time.Time, limit int64) ([]*models.Booking, error) {
+			requestedSince = since
+			return []*models.Booking{
+				{
+					VehicleType:     "car",
+					PickupLocation:  models.Location{Type: "Point", Coordinates: []float64{0, 0}},
+					DropoffLocation: models.Location{Type: "Point", Coordinates: []float64{0, 0.1}},
+					StartedAt:       &started,
+					CompletedAt:     &completed,
+				},
+				{VehicleType: "car"}, // missing timestamps, skipped
+			}, nil
+		},
+	}
+	profileRepo := &fakeSpeedProfileRepository{}
+	calc := distance.NewHaversineCalculator()
+
+	service := NewSpeedCalibrationService(bookingRepo, profileRepo, calc, models.SpeedProfile{DefaultSpeedKmh: 40}, 7*24*time.Hour, 1)
+
+	profile, err := service.Calibrate(context.Background())
+	if err != nil {
+		t.Fatalf("Calibrate returned error: %v", err)
+	}
+
+	if time.Since(requestedSince) < 7*24*time.Hour-time.Minute {
+		t.Fatalf("expected lookback window to be applied, got since=%v", requestedSince)
+	}
+	if profile.SampleCount != 1 || profile.VehicleSpeedsKmh["car"] <= 0 {
+		t.Fatalf("unexpected calibrated profile: %+v", profile)
+	}
+	if profileRepo.saved != profile {
+		t.Fatal("calibrated profile was not persisted")
+	}
+	if calc.SpeedProfile() != profile {
+		t.Fatal("calibrated profile was not applied to the calculator")
+	}
+}
+
+func TestSpeedCalibrationServiceCalibrateFailsWithoutTrips(t *testing.T) {
+	t.Parallel()
+
+	profileRepo := &fakeSpeedProfileRepository{}
+	service := NewSpeedCalibrationService(&fakeBookingRepository{}, profileRepo, distance.NewHaversineCalculator(), models.SpeedProfile{DefaultSpeedKmh: 40}, time.Hour, 1)
+
+	if _, err := service.Calibrate(context.Background()); err == nil {
+		t.Fatal("expected error when there are no completed trips")
+	}
+	if profileRepo.saved != nil {
+		t.Fatal("no profile should be saved without samples")
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/utils"
+	"logi/pkg/auth"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReused is returned when a refresh token is presented a second
+// time. The token's whole family is revoked, since either the client or an
+// attacker holds a stolen copy.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// TokenPair is returned by login and refresh. Token is the access token.
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the access token's lifetime in seconds.
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// TokenService issues access and refresh tokens, rotates refresh tokens and
+// maintains the access token revocation list.
+type TokenService struct {
+	Auth          *auth.AuthService
+	RefreshTokens repositories.RefreshTokenRepository
+	RevokedTokens repositories.RevokedTokenRepository
+	// RefreshTTL is how long a refresh token stays valid if unused.
+	RefreshTTL time.Duration
+}
+
+func NewTokenService(authService *auth.AuthService, refreshTokens repositories.RefreshTokenRepository, revokedTokens repositories.RevokedTokenRepository, refreshTTL time.Duration) *TokenService {
+	return &TokenService{
+		Auth:          authService,
+		RefreshTokens: refreshTokens,
+		RevokedTokens: revokedTokens,
+		RefreshTTL:    refreshTTL,
+	}
+}
+
+// IssueTokens starts a new session, with its own refresh token family.
+func (s *TokenService) IssueTokens(ctx context.Context, userID, role string) (*TokenPair, error) {
+	return s.issue(ctx, userID, role, uuid.NewString())
+}
+
+// Refresh uses up refreshToken and returns a new pair in the same family.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	stored, err := s.RefreshTokens.FindByHash(ctx, hashRefreshToken(refreshToken))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if stored.RevokedAt != nil || !now.Before(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return nil, s.reused(ctx, stored)
+	}
+
+	claimed, err := s.RefreshTokens.MarkUsed(ctx, stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		// Another request rotated the token between the read and the claim.
+		return nil, s.reused(ctx, stored)
+	}
+	return s.issue(ctx, stored.UserID, stored.Role, stored.FamilyID)
+}
+
+// Logout revokes the access token tokenID and ends the session it belongs to.
+func (s *TokenService) Logout(ctx context.Context, userID, tokenID string, expiresAt time.Time) error {
+	if err := s.RevokedTokens.Revoke(ctx, tokenID, expiresAt, time.Now()); err != nil {
+		return err
+	}
+	stored, err := s.RefreshTokens.FindByAccessTokenID(ctx, tokenID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stored.UserID != userID {
+		return nil
+	}
+	return s.revokeFamily(ctx, stored.FamilyID)
+}
+
+// RevokeUserSessions ends every session of userID, e.g. when the account is
+// disabled.
+func (s *TokenService) RevokeUserSessions(ctx context.Context, userID string) error {
+	tokens, err := s.RefreshTokens.FindActiveByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	families := make(map[string]bool)
+	for _, token := range tokens {
+		if families[token.FamilyID] {
+			continue
+		}
+		families[token.FamilyID] = true
+		if err := s.revokeFamily(ctx, token.FamilyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsRevoked implements auth.RevocationList.
+func (s *TokenService) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return s.RevokedTokens.IsRevoked(ctx, tokenID)
+}
+
+func (s *TokenService) issue(ctx context.Context, userID, role, familyID string) (*TokenPair, error) {
+	accessToken, claims, err := s.Auth.GenerateJWT(userID, role)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = s.RefreshTokens.Insert(ctx, &models.RefreshToken{
+		ID:              uuid.NewString(),
+		TokenHash:       hashRefreshToken(refreshToken),
+		FamilyID:        familyID,
+		UserID:          userID,
+		Role:            role,
+		AccessTokenID:   claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:       now.Add(s.RefreshTTL),
+		CreatedAt:       now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.Auth.AccessTokenTTL() / time.Second),
+	}, nil
+}
+
+func (s *TokenService) reused(ctx context.Context, stored *models.RefreshToken) error {
+	utils.Warn(ctx, "refresh token reuse detected; revoking token family", "user_id", stored.UserID, "role", stored.Role, "family_id", stored.FamilyID)
+	utils.IncrementCounter("refresh_token_reuse_detected", 1)
+	if err := s.revokeFamily(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// revokeFamily revokes every refresh token of a session, and every access
+// token issued with them that has not expired yet.
+func (s *TokenService) revokeFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	tokens, err := s.RefreshTokens.FindFamily(ctx, familyID)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if !now.Before(token.AccessExpiresAt) {
+			continue
+		}
+		if err := s.RevokedTokens.Revoke(ctx, token.AccessTokenID, token.AccessExpiresAt, now); err != nil {
+			return err
+		}
+	}
+	return s.RefreshTokens.RevokeFamily(ctx, familyID, now)
+}
+
+// newRefreshToken returns an opaque token with 256 bits of entropy.
+func newRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"logi/pkg/auth"
+	"testing"
+	"time"
+)
+
+func newTestTokenService() (*TokenService, *auth.AuthService) {
+	authService := auth.NewAuthService("test-secret-that-is-at-least-32-characters", time.Minute)
+	service := NewTokenService(authService, &fakeRefreshTokenRepository{}, &fakeRevokedTokenRepository{}, time.Hour)
+	authService.Revocations = service
+	return service, authService
+}
+
+func TestTokenServiceRefreshRotatesAndRevokesFamilyOnReuse(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, authService := newTestTokenService()
+
+	first, err := service.IssueTokens(ctx, "driver-1", "driver")
+	if err != nil {
+		t.Fatalf("IssueTokens returned error: %v", err)
+	}
+	second, err := service.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.Token == first.Token {
+		t.Fatal("expected refresh to rotate both tokens")
+	}
+	claims, err := authService.Authenticate(ctx, second.Token)
+	if err != nil || claims.Subject != "driver-1" || claims.Role != "driver" {
+		t.Fatalf("expected a valid driver token after refresh, got %+v, %v", claims, err)
+	}
+
+	// A stolen copy of the first refresh token is replayed.
+	if _, err := service.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected reuse to be detected, got %v", err)
+	}
+	if _, err := service.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the rest of the family to be revoked, got %v", err)
+	}
+	for _, token := range []string{first.Token, second.Token} {
+		if _, err := authService.Authenticate(ctx, token); !errors.Is(err, auth.ErrTokenRevoked) {
+			t.Fatalf("expected the family's access tokens to be revoked, got %v", err)
+		}
+	}
+}
+
+func TestTokenServiceLogoutEndsOnlyThatSession(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, authService := newTestTokenService()
+
+	phone, _ := service.IssueTokens(ctx, "user-1", "user")
+	laptop, _ := service.IssueTokens(ctx, "user-1", "user")
+
+	claims, err := authService.Authenticate(ctx, phone.Token)
+	if err != nil {
+		t.Fatalf("Authenticate returned error: %v", err)
+	}
+	if err := service.Logout(ctx, claims.Subject, claims.ID, claims.ExpiresAt.Time); err != nil {
+		t.Fatalf("Logout returned error: %v", err)
+	}
+
+	if _, err := authService.Authenticate(ctx, phone.Token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Fatalf("expected the logged out access token to be revoked, got %v", err)
+	}
+	if _, err := service.Refresh(ctx, phone.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the logged out refresh token to be rejected, got %v", err)
+	}
+	if _, err := authService.Authenticate(ctx, laptop.Token); err != nil {
+		t.Fatalf("expected the other session to stay valid, got %v", err)
+	}
+	if _, err := service.Refresh(ctx, laptop.RefreshToken); err != nil {
+		t.Fatalf("expected the other session to refresh, got %v", err)
+	}
+}
+
+func TestTokenServiceRejectsExpiredRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service, _ := newTestTokenService()
+	service.RefreshTTL = -time.Second
+
+	pair, err := service.IssueTokens(ctx, "admin-1", "admin")
+	if err != nil {
+		t.Fatalf("IssueTokens returned error: %v", err)
+	}
+	if _, err := service.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected an expired refresh token to be rejected, got %v", err)
+	}
+	if _, err := service.Refresh(ctx, "not-a-token"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected an unknown refresh token to be rejected, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"logi/internal/models"
 	"logi/internal/repositories"
+	"logi/internal/utils"
 	"logi/pkg/auth"
 	"time"
 
@@ -16,6 +17,11 @@ type UserService struct {
 	BookingRepo repositories.BookingRepository
 	DriverRepo  repositories.DriverRepository
 	AuthService *auth.AuthService
+	Throttle    *LoginThrottle
+	// Accounts, when set, emails a verification link on registration.
+	Accounts *AccountService
+	// RequireVerifiedEmail rejects logins until the email is verified.
+	RequireVerifiedEmail bool
 }
 
 func NewUserService(repo repositories.UserRepository, bookingRepo repositories.BookingRepository, driverRepo repositories.DriverRepository, authService *auth.AuthService) *UserService {
@@ -43,19 +49,30 @@ func (s *UserService) Register(ctx context.Context, user *models.User, password
 	user.CreatedAt = time.Now()
 	user.Role = "user" // Add this line
 
-	return s.Repo.Create(ctx, user)
+	if err := s.Repo.Create(ctx, user); err != nil {
+		return err
+	}
+	if err := s.Accounts.SendVerification(ctx, "user", user.Email); err != nil {
+		utils.Error(ctx, "failed to send verification email", "user_id", user.ID, "error", err)
+	}
+	return nil
 }
 
 func (s *UserService) Login(ctx context.Context, email, password string) (*models.User, error) {
-	user, err := s.Repo.FindByEmail(ctx, email)
-	if err != nil {
-		return nil, errors.New("invalid email or password")
+	if err := s.Throttle.Check(ctx, "user", email); err != nil {
+		return nil, err
 	}
 
-	if !s.AuthService.CheckPasswordHash(password, user.PasswordHash) {
+	user, err := s.Repo.FindByEmail(ctx, email)
+	if err != nil || !s.AuthService.CheckPasswordHash(password, user.PasswordHash) {
+		s.Throttle.RecordFailure(ctx, "user", email)
 		return nil, errors.New("invalid email or password")
 	}
 
+	s.Throttle.RecordSuccess(ctx, "user", email)
+	if s.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
 	return user, nil
 }
 
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/webhooks"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrWebhookNotFound is returned for unknown webhooks and for webhooks the
+// caller does not own.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// ErrWebhookDeliveryNotFound is returned when redelivering an unknown delivery.
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+// ErrInvalidWebhook is returned when an endpoint registration is rejected.
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+type WebhookService struct {
+	Endpoints  repositories.WebhookEndpointRepository
+	Deliveries repositories.WebhookDeliveryRepository
+	// RequireHTTPS rejects plain http endpoint URLs.
+	RequireHTTPS bool
+	// AllowPrivateNetworks accepts endpoints on loopback, private and
+	// link-local addresses, for local development.
+	AllowPrivateNetworks bool
+	// OnRedeliver, when set, is called after a redelivery was queued.
+	OnRedeliver func()
+
+	lookupHost func(context.Context, string) ([]net.IPAddr, error)
+}
+
+func NewWebhookService(endpoints repositories.WebhookEndpointRepository, deliveries repositories.WebhookDeliveryRepository, requireHTTPS bool, onRedeliver func()) *WebhookService {
+	return &WebhookService{
+		Endpoints:    endpoints,
+		Deliveries:   deliveries,
+		RequireHTTPS: requireHTTPS,
+		OnRedeliver:  onRedeliver,
+		lookupHost:   net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+// RegisterEndpoint validates and stores endpoint, owned by either a user or
+// an organization, and sets its ID and signing secret.
+func (s *WebhookService) RegisterEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	if (endpoint.UserID == "") == (endpoint.OrganizationID == "") {
+		return fmt.Errorf("%w: exactly one of user_id and organization_id is required", ErrInvalidWebhook)
+	}
+	parsed, err := url.Parse(strings.TrimSpace(endpoint.URL))
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if s.RequireHTTPS && parsed.Scheme != "https" {
+		return fmt.Errorf("%w: url must use https", ErrInvalidWebhook)
+	}
+	if !s.AllowPrivateNetworks {
+		if err := webhooks.CheckHost(ctx, parsed.Hostname(), s.lookupHost); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+	}
+	for _, eventType := range endpoint.EventTypes {
+		if !webhooks.IsEventType(eventType) {
+			return fmt.Errorf("%w: unknown event type %q, expected one of %s", ErrInvalidWebhook, eventType, strings.Join(webhooks.EventTypes, ", "))
+		}
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		return err
+	}
+	endpoint.ID = uuid.NewString()
+	endpoint.URL = parsed.String()
+	endpoint.Secret = secret
+	endpoint.CreatedAt = time.Now()
+	return s.Endpoints.Create(ctx, endpoint)
+}
+
+// ListEndpoints returns the endpoints of a user or an organization; with both
+// empty it returns every endpoint.
+func (s *WebhookService) ListEndpoints(ctx context.Context, userID, organizationID string) ([]*models.WebhookEndpoint, error) {
+	endpoints, err := s.Endpoints.FindByOwner(ctx, userID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	// A user's listing must not include the organization's endpoints.
+	if userID != "" && organizationID == "" {
+		owned := endpoints[:0]
+		for _, endpoint := range endpoints {
+			if endpoint.UserID == userID {
+				owned = append(owned, endpoint)
+			}
+		}
+		endpoints = owned
+	}
+	return endpoints, nil
+}
+
+// DeleteEndpoint removes an endpoint. A non-empty userID restricts it to that
+// user's endpoints; admins pass an empty one.
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, userID, endpointID string) error {
+	if _, err := s.findEndpoint(ctx, userID, endpointID); err != nil {
+		return err
+	}
+	return s.Endpoints.Delete(ctx, endpointID)
+}
+
+// ListDeliveries returns the delivery log of one endpoint, restricted to the
+// user's endpoints like DeleteEndpoint, newest first.
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID string, filter repositories.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	if userID != "" {
+		if _, err := s.findEndpoint(ctx, userID, filter.EndpointID); err != nil {
+			return nil, err
+		}
+	}
+	return s.Deliveries.List(ctx, filter)
+}
+
+// Redeliver queues a new delivery of the same payload to the same endpoint,
+// leaving the original in the log.
+func (s *WebhookService) Redeliver(ctx context.Context, deliveryID string) (*models.WebhookDelivery, error) {
+	original, err := s.Deliveries.FindByID(ctx, deliveryID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	redelivery := &models.WebhookDelivery{
+		ID:            primitive.NewObjectID().Hex(),
+		EndpointID:    original.EndpointID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		RedeliveryOf:  original.ID,
+		Status:        models.WebhookDeliveryStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if err := s.Deliveries.Insert(ctx, redelivery); err != nil {
+		return nil, err
+	}
+	if s.OnRedeliver != nil {
+		s.OnRedeliver()
+	}
+	return redelivery, nil
+}
+
+func (s *WebhookService) findEndpoint(ctx context.Context, userID, endpointID string) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.Endpoints.FindByID(ctx, endpointID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userID != "" && endpoint.UserID != userID {
+		return nil, ErrWebhookNotFound
+	}
+	return endpoint, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"logi/internal/events"
+	"logi/internal/models"
+)
+
+func TestWebhookServiceRegisterEndpointValidatesAndSetsSecret(t *testing.T) {
+	t.Parallel()
+
+	endpoints := &fakeWebhookEndpointRepository{}
+	service := NewWebhookService(endpoints, &fakeWebhookDeliveryRepository{}, true, nil)
+	service.lookupHost = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		addresses := map[string]string{"hooks.example.com": "93.184.216.34", "intranet.example.com": "10.0.0.5"}
+		if address, ok := addresses[host]; ok {
+			return []net.IPAddr{{IP: net.ParseIP(address)}}, nil
+		}
+		return net.DefaultResolver.LookupIPAddr(ctx, host)
+	}
+	ctx := context.Background()
+
+	invalid := []*models.WebhookEndpoint{
+		{UserID: "user-1", URL: "http://hooks.example.com/logi"},
+		{UserID: "user-1", URL: "https://intranet.example.com/logi"},
+		{UserID: "user-1", URL: "https://169.254.169.254/latest/meta-data"},
+		{UserID: "user-1", URL: "https://[::1]:8443/logi"},
+		{UserID: "user-1", URL: "/relative"},
+		{UserID: "user-1", URL: "https://hooks.example.com/logi", EventTypes: []string{events.DriverStatusChanged}},
+		{UserID: "user-1", OrganizationID: "acme", URL: "https://hooks.example.com/logi"},
+	}
+	for _, endpoint := range invalid {
+		if err := service.RegisterEndpoint(ctx, endpoint); !errors.Is(err, ErrInvalidWebhook) {
+			t.Fatalf("expected %+v to be rejected, got %v", endpoint, err)
+		}
+	}
+
+	endpoint := &models.WebhookEndpoint{OrganizationID: "acme", URL: "https://hooks.example.com/logi", EventTypes: []string{events.BookingCompleted}}
+	if err := service.RegisterEndpoint(ctx, endpoint); err != nil {
+		t.Fatalf("RegisterEndpoint returned error: %v", err)
+	}
+	if len(endpoints.created) != 1 || endpoint.ID == "" || !strings.HasPrefix(endpoint.Secret, "whsec_") {
+		t.Fatalf("expected a stored endpoint with an ID and secret, got %+v", endpoint)
+	}
+}
+
+func TestWebhookServiceHidesOtherUsersEndpoints(t *testing.T) {
+	t.Parallel()
+
+	endpoints := &fakeWebhookEndpointRepository{endpoints: map[string]*models.WebhookEndpoint{
+		"endpoint-1": {ID: "endpoint-1", UserID: "user-1"},
+	}}
+	service := NewWebhookService(endpoints, &fakeWebhookDeliveryRepository{}, false, nil)
+
+	if err := service.DeleteEndpoint(context.Background(), "user-2", "endpoint-1"); !errors.Is(err, ErrWebhookNotFound) {
+		t.Fatalf("expected another user's endpoint to be reported missing, got %v", err)
+	}
+	if err := service.DeleteEndpoint(context.Background(), "", "endpoint-1"); err != nil {
+		t.Fatalf("expected admins to delete any endpoint, got %v", err)
+	}
+	if len(endpoints.deleted) != 1 {
+		t.Fatalf("expected one deletion, got %v", endpoints.deleted)
+	}
+}
+
+func TestWebhookServiceRedeliverQueuesCopyAndKeepsOriginal(t *testing.T) {
+	t.Parallel()
+
+	original := &models.WebhookDelivery{
+		ID:         "delivery-1",
+		EndpointID: "endpoint-1",
+		EventID:    "event-1",
+		EventType:  events.BookingCreated,
+		Payload:    []byte(`{"id":"event-1"}`),
+		Status:     models.WebhookDeliveryStatusFailed,
+		Attempts:   8,
+	}
+	deliveries := &fakeWebhookDeliveryRepository{deliveries: map[string]*models.WebhookDelivery{original.ID: original}}
+	woken := false
+	service := NewWebhookService(&fakeWebhookEndpointRepository{}, deliveries, false, func() { woken = true })
+
+	redelivery, err := service.Redeliver(context.Background(), "delivery-1")
+	if err != nil {
+		t.Fatalf("Redeliver returned error: %v", err)
+	}
+	if redelivery.ID == original.ID || redelivery.RedeliveryOf != original.ID || redelivery.Status != models.WebhookDeliveryStatusPending || redelivery.Attempts != 0 {
+		t.Fatalf("expected a fresh pending copy, got %+v", redelivery)
+	}
+	if string(redelivery.Payload) != string(original.Payload) || len(deliveries.inserted) != 1 || !woken {
+		t.Fatalf("expected the copy to be stored with the original payload and the deliverer woken")
+	}
+	if original.Status != models.WebhookDeliveryStatusFailed {
+		t.Fatalf("expected the original delivery to stay in the log unchanged, got %+v", original)
+	}
+
+	if _, err := service.Redeliver(context.Background(), "missing"); !errors.Is(err, ErrWebhookDeliveryNotFound) {
+		t.Fatalf("expected ErrWebhookDeliveryNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package sms sends text messages, such as driver login codes, and
+// normalizes phone numbers.
+package sms
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	"logi/internal/utils"
+)
+
+// Message is a text message to one phone number in E.164 form.
+type Message struct {
+	To   string
+	Body string
+}
+
+// SMSSender delivers text messages. Implementations for SMS providers plug in
+// here.
+type SMSSender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender logs that a message would have been sent, without sending it. The
+// body is left out, since it holds a live login code. It is meant for local
+// development and is never used in production.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	utils.Info(ctx, "sms not sent (sms_sender_type is log)", "to", msg.To)
+	return nil
+}
+
+// MemorySender keeps sent messages in memory, for tests.
+type MemorySender struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *MemorySender) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (m *MemorySender) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
+
+var ErrInvalidPhone = errors.New("phone number must be in international format, e.g. +14155550123")
+
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// NormalizePhone returns phone in E.164 form (+ and up to 15 digits). Spaces,
+// dashes, dots and parentheses are dropped, and a leading 00 is read as +.
+// A number without either is taken as national: its trunk 0 is dropped and
+// defaultCountryCode (digits, without +) is put in front. With no
+// defaultCountryCode, national numbers are rejected.
+func NormalizePhone(phone, defaultCountryCode string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '\t':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+
+	switch {
+	case strings.HasPrefix(digits, "+"):
+	case strings.HasPrefix(digits, "00"):
+		digits = "+" + digits[2:]
+	case defaultCountryCode != "":
+		digits = "+" + defaultCountryCode + strings.TrimPrefix(digits, "0")
+	default:
+		return "", ErrInvalidPhone
+	}
+	if !e164Pattern.MatchString(digits) {
+		return "", ErrInvalidPhone
+	}
+	return digits, nil
+}
@@ -0,0 +1,31 @@
+package sms
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		phone, countryCode, want string
+	}{
+		{"+1 (415) 555-0123", "", "+14155550123"},
+		{"0044 20 7946 0958", "", "+442079460958"},
+		{"020 7946 0958", "44", "+442079460958"},
+		{"98765 43210", "91", "+919876543210"},
+		{"+91.98765.43210", "44", "+919876543210"},
+	} {
+		got, err := NormalizePhone(tc.phone, tc.countryCode)
+		if err != nil || got != tc.want {
+			t.Fatalf("NormalizePhone(%q, %q) = %q, %v; want %q", tc.phone, tc.countryCode, got, err, tc.want)
+		}
+	}
+
+	for _, phone := range []string{"", "4155550123", "+0123456789", "+1 415 CALL NOW", "+1234567890123456", "+12345"} {
+		if _, err := NormalizePhone(phone, ""); !errors.Is(err, ErrInvalidPhone) {
+			t.Fatalf("expected %q to be rejected, got %v", phone, err)
+		}
+	}
+}
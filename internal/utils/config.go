@@ -2,30 +2,118 @@ package utils
 
 import (
 	"fmt"
+	"logi/internal/models"
+	"net"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
+// countryCodePattern matches a calling code such as 1 or 44, without the +.
+var countryCodePattern = regexp.MustCompile(`^[1-9][0-9]{0,2}$`)
+
 type Config struct {
-	Environment               string   `yaml:"environment"`
-	ServerAddress             string   `yaml:"server_address"`
-	MongoURI                  string   `yaml:"mongo_uri"`
-	JWTSecret                 string   `yaml:"jwt_secret"`
-	JWTExpirationHours        int      `yaml:"jwt_expiration_hours"`
-	MessagingType             string   `yaml:"messaging_type"`
-	NATSURL                   string   `yaml:"nats_url"`
-	DistanceCalculatorType    string   `yaml:"distance_calculator_type"`
-	GoogleMapsAPIKey          string   `yaml:"google_maps_api_key"`
-	AllowedOrigins            []string `yaml:"allowed_origins"`
-	EnableTestRoutes          bool     `yaml:"enable_test_routes"`
-	DBOperationTimeoutSeconds int      `yaml:"db_operation_timeout_seconds"`
-	HTTPReadTimeoutSeconds    int      `yaml:"http_read_timeout_seconds"`
-	HTTPWriteTimeoutSeconds   int      `yaml:"http_write_timeout_seconds"`
-	HTTPIdleTimeoutSeconds    int      `yaml:"http_idle_timeout_seconds"`
-	ShutdownTimeoutSeconds    int      `yaml:"shutdown_timeout_seconds"`
+	Environment                    string              `yaml:"environment"`
+	ServerAddress                  string              `yaml:"server_address"`
+	MongoURI                       string              `yaml:"mongo_uri"`
+	JWTSecret                      string              `yaml:"jwt_secret"`
+	JWTAccessTokenTTLMinutes       int                 `yaml:"jwt_access_token_ttl_minutes"`
+	JWTSigningKeyID                string              `yaml:"jwt_signing_key_id"`
+	JWTKeyFiles                    map[string]string   `yaml:"jwt_key_files"`
+	JWTKeyVerifyUntil              map[string]string   `yaml:"jwt_key_verify_until"`
+	JWTAllowHS256                  bool                `yaml:"jwt_allow_hs256"`
+	RefreshTokenTTLHours           int                 `yaml:"refresh_token_ttl_hours"`
+	AdminBootstrapToken            string              `yaml:"admin_bootstrap_token"`
+	AdminInviteURL                 string              `yaml:"admin_invite_url"`
+	AdminInviteTTLHours            int                 `yaml:"admin_invite_ttl_hours"`
+	AccountTokenSecret             string              `yaml:"account_token_secret"`
+	EmailVerifyURL                 string              `yaml:"email_verify_url"`
+	EmailVerificationTTLHours      int                 `yaml:"email_verification_ttl_hours"`
+	PasswordResetURL               string              `yaml:"password_reset_url"`
+	PasswordResetTTLMinutes        int                 `yaml:"password_reset_ttl_minutes"`
+	RequireVerifiedEmail           bool                `yaml:"require_verified_email"`
+	MailerType                     string              `yaml:"mailer_type"`
+	MailFrom                       string              `yaml:"mail_from"`
+	SMTPHost                       string              `yaml:"smtp_host"`
+	SMTPPort                       int                 `yaml:"smtp_port"`
+	SMTPUsername                   string              `yaml:"smtp_username"`
+	SMTPPassword                   string              `yaml:"smtp_password"`
+	SMSSenderType                  string              `yaml:"sms_sender_type"`
+	PhoneDefaultCountryCode        string              `yaml:"phone_default_country_code"`
+	OTPCodeLength                  int                 `yaml:"otp_code_length"`
+	OTPTTLMinutes                  int                 `yaml:"otp_ttl_minutes"`
+	OTPMaxAttempts                 int                 `yaml:"otp_max_attempts"`
+	OTPResendSeconds               int                 `yaml:"otp_resend_seconds"`
+	OTPAttemptWindowMinutes        int                 `yaml:"otp_attempt_window_minutes"`
+	LoginMaxAccountFailures        int                 `yaml:"login_max_account_failures"`
+	LoginMaxIPFailures             int                 `yaml:"login_max_ip_failures"`
+	LoginFailureWindowMinutes      int                 `yaml:"login_failure_window_minutes"`
+	LoginLockoutMinutes            int                 `yaml:"login_lockout_minutes"`
+	LoginBaseDelaySeconds          int                 `yaml:"login_base_delay_seconds"`
+	MessagingType                  string              `yaml:"messaging_type"`
+	NATSURL                        string              `yaml:"nats_url"`
+	KafkaBrokers                   []string            `yaml:"kafka_brokers"`
+	KafkaTopic                     string              `yaml:"kafka_topic"`
+	RedisURL                       string              `yaml:"redis_url"`
+	RedisStream                    string              `yaml:"redis_stream"`
+	RedisStreamMaxLen              int                 `yaml:"redis_stream_max_len"`
+	WebSocketSendQueueSize         int                 `yaml:"websocket_send_queue_size"`
+	WebSocketWriteTimeoutSeconds   int                 `yaml:"websocket_write_timeout_seconds"`
+	WebSocketSlowConsumerPolicy    string              `yaml:"websocket_slow_consumer_policy"`
+	WebSocketPingIntervalSeconds   int                 `yaml:"websocket_ping_interval_seconds"`
+	WebSocketPongWaitSeconds       int                 `yaml:"websocket_pong_wait_seconds"`
+	WebSocketMaxMessageBytes       int                 `yaml:"websocket_max_message_bytes"`
+	WebSocketSessionCheckSeconds   int                 `yaml:"websocket_session_check_seconds"`
+	WebSocketOutboxType            string              `yaml:"websocket_outbox_type"`
+	WebSocketOutboxSize            int                 `yaml:"websocket_outbox_size"`
+	EventStreamType                string              `yaml:"event_stream_type"`
+	EventStreamName                string              `yaml:"event_stream_name"`
+	EventStreamDedupWindowSeconds  int                 `yaml:"event_stream_dedup_window_seconds"`
+	EventStreamMaxAgeHours         int                 `yaml:"event_stream_max_age_hours"`
+	EventOutboxEnabled             bool                `yaml:"event_outbox_enabled"`
+	EventOutboxPollIntervalSeconds int                 `yaml:"event_outbox_poll_interval_seconds"`
+	EventOutboxMaxAttempts         int                 `yaml:"event_outbox_max_attempts"`
+	EventOutboxRetentionHours      int                 `yaml:"event_outbox_retention_hours"`
+	WebhooksEnabled                bool                `yaml:"webhooks_enabled"`
+	WebhookTimeoutSeconds          int                 `yaml:"webhook_timeout_seconds"`
+	WebhookMaxAttempts             int                 `yaml:"webhook_max_attempts"`
+	WebhookRetryDelaySeconds       int                 `yaml:"webhook_retry_delay_seconds"`
+	WebhookDeliveryRetentionHours  int                 `yaml:"webhook_delivery_retention_hours"`
+	WebhookAllowPrivateNetworks    bool                `yaml:"webhook_allow_private_networks"`
+	DistanceCalculatorType         string              `yaml:"distance_calculator_type"`
+	GoogleMapsAPIKey               string              `yaml:"google_maps_api_key"`
+	OSRMBaseURL                    string              `yaml:"osrm_base_url"`
+	GraphHopperBaseURL             string              `yaml:"graphhopper_base_url"`
+	GraphHopperAPIKey              string              `yaml:"graphhopper_api_key"`
+	RoutingProfiles                map[string]string   `yaml:"routing_profiles"`
+	DistanceFallbackChain          []string            `yaml:"distance_fallback_chain"`
+	HaversineDetourFactor          float64             `yaml:"haversine_detour_factor"`
+	DistanceCacheEnabled           bool                `yaml:"distance_cache_enabled"`
+	DistanceCacheMaxEntries        int                 `yaml:"distance_cache_max_entries"`
+	DistanceCacheTTLSeconds        int                 `yaml:"distance_cache_ttl_seconds"`
+	DistanceCachePrecision         int                 `yaml:"distance_cache_precision"`
+	SpeedProfile                   models.SpeedProfile `yaml:"speed_profile"`
+	SpeedCalibrationDays           int                 `yaml:"speed_calibration_lookback_days"`
+	SpeedCalibrationMinTrips       int                 `yaml:"speed_calibration_min_samples"`
+	GeocoderType                   string              `yaml:"geocoder_type"`
+	NominatimBaseURL               string              `yaml:"nominatim_base_url"`
+	NominatimUserAgent             string              `yaml:"nominatim_user_agent"`
+	NominatimEmail                 string              `yaml:"nominatim_email"`
+	AllowedOrigins                 []string            `yaml:"allowed_origins"`
+	TrustedProxies                 []string            `yaml:"trusted_proxies"`
+	RateLimitEnabled               bool                `yaml:"rate_limit_enabled"`
+	RateLimitStore                 string              `yaml:"rate_limit_store"`
+	RateLimits                     map[string]string   `yaml:"rate_limits"`
+	EnableTestRoutes               bool                `yaml:"enable_test_routes"`
+	DBOperationTimeoutSeconds      int                 `yaml:"db_operation_timeout_seconds"`
+	HTTPReadTimeoutSeconds         int                 `yaml:"http_read_timeout_seconds"`
+	HTTPWriteTimeoutSeconds        int                 `yaml:"http_write_timeout_seconds"`
+	HTTPIdleTimeoutSeconds         int                 `yaml:"http_idle_timeout_seconds"`
+	ShutdownTimeoutSeconds         int                 `yaml:"shutdown_timeout_seconds"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -62,13 +150,80 @@ func (c *Config) AllowedOriginsSet() map[string]struct{} {
 
 func defaultConfig() Config {
 	return Config{
-		Environment:               "development",
-		ServerAddress:             ":8080",
-		JWTExpirationHours:        72,
-		MessagingType:             "websocket",
-		DistanceCalculatorType:    "haversine",
-		AllowedOrigins:            []string{"http://localhost:3000"},
-		EnableTestRoutes:          false,
+		Environment:                    "development",
+		ServerAddress:                  ":8080",
+		JWTAccessTokenTTLMinutes:       15,
+		JWTAllowHS256:                  true,
+		RefreshTokenTTLHours:           720,
+		AdminInviteTTLHours:            72,
+		EmailVerificationTTLHours:      48,
+		PasswordResetTTLMinutes:        30,
+		MailerType:                     "log",
+		SMTPPort:                       587,
+		SMSSenderType:                  "log",
+		OTPCodeLength:                  6,
+		OTPTTLMinutes:                  5,
+		OTPMaxAttempts:                 5,
+		OTPResendSeconds:               30,
+		OTPAttemptWindowMinutes:        60,
+		LoginMaxAccountFailures:        5,
+		LoginMaxIPFailures:             50,
+		LoginFailureWindowMinutes:      15,
+		LoginLockoutMinutes:            15,
+		LoginBaseDelaySeconds:          1,
+		MessagingType:                  "websocket",
+		KafkaBrokers:                   []string{"localhost:9092"},
+		KafkaTopic:                     "logi.messages",
+		RedisURL:                       "redis://localhost:6379/0",
+		RedisStream:                    "logi:messages",
+		RedisStreamMaxLen:              10000,
+		WebSocketSendQueueSize:         64,
+		WebSocketWriteTimeoutSeconds:   10,
+		WebSocketSlowConsumerPolicy:    "drop_oldest",
+		WebSocketPingIntervalSeconds:   54,
+		WebSocketPongWaitSeconds:       60,
+		WebSocketMaxMessageBytes:       4096,
+		WebSocketSessionCheckSeconds:   60,
+		WebSocketOutboxType:            "memory",
+		WebSocketOutboxSize:            100,
+		EventStreamType:                "none",
+		EventStreamName:                "LOGI_EVENTS",
+		EventStreamDedupWindowSeconds:  120,
+		EventStreamMaxAgeHours:         168,
+		EventOutboxEnabled:             true,
+		EventOutboxPollIntervalSeconds: 1,
+		EventOutboxMaxAttempts:         10,
+		EventOutboxRetentionHours:      72,
+		WebhooksEnabled:                true,
+		WebhookTimeoutSeconds:          10,
+		WebhookMaxAttempts:             8,
+		WebhookRetryDelaySeconds:       30,
+		WebhookDeliveryRetentionHours:  720,
+		DistanceCalculatorType:         "haversine",
+		HaversineDetourFactor:          1.0,
+		DistanceCacheEnabled:           false,
+		DistanceCacheMaxEntries:        10000,
+		DistanceCacheTTLSeconds:        900,
+		DistanceCachePrecision:         4,
+		SpeedProfile:                   models.SpeedProfile{DefaultSpeedKmh: 40},
+		SpeedCalibrationDays:           30,
+		SpeedCalibrationMinTrips:       5,
+		NominatimBaseURL:               "https://nominatim.openstreetmap.org",
+		NominatimUserAgent:             "logi-backend",
+		AllowedOrigins:                 []string{"http://localhost:3000"},
+		EnableTestRoutes:               false,
+		RateLimitEnabled:               true,
+		RateLimitStore:                 "memory",
+		RateLimits: map[string]string{
+			"POST /drivers/update-location": "user:1/1s:5",
+			"POST /bookings/estimate":       "user:20/1m:5",
+			"POST /bookings":                "user:10/1m",
+			"POST /auth/forgot-password":    "ip:5/15m",
+			"POST /auth/verify/resend":      "ip:5/15m",
+			"POST /drivers/otp/send":        "ip:5/15m",
+			"*":                             "ip:600/1m:100",
+			"auth":                          "ip:1200/1m:200",
+		},
 		DBOperationTimeoutSeconds: 5,
 		HTTPReadTimeoutSeconds:    15,
 		HTTPWriteTimeoutSeconds:   30,
@@ -85,12 +240,93 @@ func overrideFromEnv(cfg *Config) {
 	}
 	applyStringEnvWithFallback(&cfg.MongoURI, "LOGI_MONGO_URI", "MONGODB_URI", "MONGO_URI")
 	applyStringEnvWithFallback(&cfg.JWTSecret, "LOGI_JWT_SECRET", "JWT_SECRET")
-	applyIntEnv(&cfg.JWTExpirationHours, "LOGI_JWT_EXPIRATION_HOURS")
+	applyIntEnv(&cfg.JWTAccessTokenTTLMinutes, "LOGI_JWT_ACCESS_TOKEN_TTL_MINUTES")
+	applyStringEnv(&cfg.JWTSigningKeyID, "LOGI_JWT_SIGNING_KEY_ID")
+	applyMapEnv(&cfg.JWTKeyFiles, "LOGI_JWT_KEY_FILES")
+	applyMapEnv(&cfg.JWTKeyVerifyUntil, "LOGI_JWT_KEY_VERIFY_UNTIL")
+	applyBoolEnv(&cfg.JWTAllowHS256, "LOGI_JWT_ALLOW_HS256")
+	applyIntEnv(&cfg.RefreshTokenTTLHours, "LOGI_REFRESH_TOKEN_TTL_HOURS")
+	applyStringEnv(&cfg.AdminBootstrapToken, "LOGI_ADMIN_BOOTSTRAP_TOKEN")
+	applyStringEnv(&cfg.AdminInviteURL, "LOGI_ADMIN_INVITE_URL")
+	applyIntEnv(&cfg.AdminInviteTTLHours, "LOGI_ADMIN_INVITE_TTL_HOURS")
+	applyStringEnv(&cfg.AccountTokenSecret, "LOGI_ACCOUNT_TOKEN_SECRET")
+	applyStringEnv(&cfg.EmailVerifyURL, "LOGI_EMAIL_VERIFY_URL")
+	applyIntEnv(&cfg.EmailVerificationTTLHours, "LOGI_EMAIL_VERIFICATION_TTL_HOURS")
+	applyStringEnv(&cfg.PasswordResetURL, "LOGI_PASSWORD_RESET_URL")
+	applyIntEnv(&cfg.PasswordResetTTLMinutes, "LOGI_PASSWORD_RESET_TTL_MINUTES")
+	applyBoolEnv(&cfg.RequireVerifiedEmail, "LOGI_REQUIRE_VERIFIED_EMAIL")
+	applyStringEnv(&cfg.MailerType, "LOGI_MAILER_TYPE")
+	applyStringEnv(&cfg.MailFrom, "LOGI_MAIL_FROM")
+	applyStringEnv(&cfg.SMTPHost, "LOGI_SMTP_HOST")
+	applyIntEnv(&cfg.SMTPPort, "LOGI_SMTP_PORT")
+	applyStringEnv(&cfg.SMTPUsername, "LOGI_SMTP_USERNAME")
+	applyStringEnv(&cfg.SMTPPassword, "LOGI_SMTP_PASSWORD")
+	applyStringEnv(&cfg.SMSSenderType, "LOGI_SMS_SENDER_TYPE")
+	applyStringEnv(&cfg.PhoneDefaultCountryCode, "LOGI_PHONE_DEFAULT_COUNTRY_CODE")
+	applyIntEnv(&cfg.OTPCodeLength, "LOGI_OTP_CODE_LENGTH")
+	applyIntEnv(&cfg.OTPTTLMinutes, "LOGI_OTP_TTL_MINUTES")
+	applyIntEnv(&cfg.OTPMaxAttempts, "LOGI_OTP_MAX_ATTEMPTS")
+	applyIntEnv(&cfg.OTPResendSeconds, "LOGI_OTP_RESEND_SECONDS")
+	applyIntEnv(&cfg.OTPAttemptWindowMinutes, "LOGI_OTP_ATTEMPT_WINDOW_MINUTES")
+	applyIntEnv(&cfg.LoginMaxAccountFailures, "LOGI_LOGIN_MAX_ACCOUNT_FAILURES")
+	applyIntEnv(&cfg.LoginMaxIPFailures, "LOGI_LOGIN_MAX_IP_FAILURES")
+	applyIntEnv(&cfg.LoginFailureWindowMinutes, "LOGI_LOGIN_FAILURE_WINDOW_MINUTES")
+	applyIntEnv(&cfg.LoginLockoutMinutes, "LOGI_LOGIN_LOCKOUT_MINUTES")
+	applyIntEnv(&cfg.LoginBaseDelaySeconds, "LOGI_LOGIN_BASE_DELAY_SECONDS")
 	applyStringEnvWithFallback(&cfg.MessagingType, "LOGI_MESSAGING_TYPE")
 	applyStringEnvWithFallback(&cfg.NATSURL, "LOGI_NATS_URL", "NATS_URL")
+	applyCSVEnvWithFallback(&cfg.KafkaBrokers, "LOGI_KAFKA_BROKERS", "KAFKA_BROKERS")
+	applyStringEnv(&cfg.KafkaTopic, "LOGI_KAFKA_TOPIC")
+	applyStringEnvWithFallback(&cfg.RedisURL, "LOGI_REDIS_URL", "REDIS_URL")
+	applyStringEnv(&cfg.RedisStream, "LOGI_REDIS_STREAM")
+	applyIntEnv(&cfg.RedisStreamMaxLen, "LOGI_REDIS_STREAM_MAX_LEN")
+	applyIntEnv(&cfg.WebSocketSendQueueSize, "LOGI_WEBSOCKET_SEND_QUEUE_SIZE")
+	applyIntEnv(&cfg.WebSocketWriteTimeoutSeconds, "LOGI_WEBSOCKET_WRITE_TIMEOUT_SECONDS")
+	applyStringEnv(&cfg.WebSocketSlowConsumerPolicy, "LOGI_WEBSOCKET_SLOW_CONSUMER_POLICY")
+	applyIntEnv(&cfg.WebSocketPingIntervalSeconds, "LOGI_WEBSOCKET_PING_INTERVAL_SECONDS")
+	applyIntEnv(&cfg.WebSocketPongWaitSeconds, "LOGI_WEBSOCKET_PONG_WAIT_SECONDS")
+	applyIntEnv(&cfg.WebSocketMaxMessageBytes, "LOGI_WEBSOCKET_MAX_MESSAGE_BYTES")
+	applyIntEnv(&cfg.WebSocketSessionCheckSeconds, "LOGI_WEBSOCKET_SESSION_CHECK_SECONDS")
+	applyStringEnv(&cfg.WebSocketOutboxType, "LOGI_WEBSOCKET_OUTBOX_TYPE")
+	applyIntEnv(&cfg.WebSocketOutboxSize, "LOGI_WEBSOCKET_OUTBOX_SIZE")
+	applyStringEnv(&cfg.EventStreamType, "LOGI_EVENT_STREAM_TYPE")
+	applyStringEnv(&cfg.EventStreamName, "LOGI_EVENT_STREAM_NAME")
+	applyIntEnv(&cfg.EventStreamDedupWindowSeconds, "LOGI_EVENT_STREAM_DEDUP_WINDOW_SECONDS")
+	applyIntEnv(&cfg.EventStreamMaxAgeHours, "LOGI_EVENT_STREAM_MAX_AGE_HOURS")
+	applyBoolEnv(&cfg.EventOutboxEnabled, "LOGI_EVENT_OUTBOX_ENABLED")
+	applyIntEnv(&cfg.EventOutboxPollIntervalSeconds, "LOGI_EVENT_OUTBOX_POLL_INTERVAL_SECONDS")
+	applyIntEnv(&cfg.EventOutboxMaxAttempts, "LOGI_EVENT_OUTBOX_MAX_ATTEMPTS")
+	applyIntEnv(&cfg.EventOutboxRetentionHours, "LOGI_EVENT_OUTBOX_RETENTION_HOURS")
+	applyBoolEnv(&cfg.WebhooksEnabled, "LOGI_WEBHOOKS_ENABLED")
+	applyIntEnv(&cfg.WebhookTimeoutSeconds, "LOGI_WEBHOOK_TIMEOUT_SECONDS")
+	applyIntEnv(&cfg.WebhookMaxAttempts, "LOGI_WEBHOOK_MAX_ATTEMPTS")
+	applyIntEnv(&cfg.WebhookRetryDelaySeconds, "LOGI_WEBHOOK_RETRY_DELAY_SECONDS")
+	applyIntEnv(&cfg.WebhookDeliveryRetentionHours, "LOGI_WEBHOOK_DELIVERY_RETENTION_HOURS")
+	applyBoolEnv(&cfg.WebhookAllowPrivateNetworks, "LOGI_WEBHOOK_ALLOW_PRIVATE_NETWORKS")
 	applyStringEnvWithFallback(&cfg.DistanceCalculatorType, "LOGI_DISTANCE_CALCULATOR_TYPE")
 	applyStringEnvWithFallback(&cfg.GoogleMapsAPIKey, "LOGI_GOOGLE_MAPS_API_KEY", "GOOGLE_MAPS_API_KEY")
+	applyStringEnv(&cfg.OSRMBaseURL, "LOGI_OSRM_BASE_URL")
+	applyStringEnv(&cfg.GraphHopperBaseURL, "LOGI_GRAPHHOPPER_BASE_URL")
+	applyStringEnvWithFallback(&cfg.GraphHopperAPIKey, "LOGI_GRAPHHOPPER_API_KEY", "GRAPHHOPPER_API_KEY")
+	applyMapEnv(&cfg.RoutingProfiles, "LOGI_ROUTING_PROFILES")
+	applyCSVEnv(&cfg.DistanceFallbackChain, "LOGI_DISTANCE_FALLBACK_CHAIN")
+	applyFloatEnv(&cfg.HaversineDetourFactor, "LOGI_HAVERSINE_DETOUR_FACTOR")
+	applyBoolEnv(&cfg.DistanceCacheEnabled, "LOGI_DISTANCE_CACHE_ENABLED")
+	applyIntEnv(&cfg.DistanceCacheMaxEntries, "LOGI_DISTANCE_CACHE_MAX_ENTRIES")
+	applyIntEnv(&cfg.DistanceCacheTTLSeconds, "LOGI_DISTANCE_CACHE_TTL_SECONDS")
+	applyIntEnv(&cfg.DistanceCachePrecision, "LOGI_DISTANCE_CACHE_PRECISION")
+	applyFloatEnv(&cfg.SpeedProfile.DefaultSpeedKmh, "LOGI_DEFAULT_SPEED_KMH")
+	applyIntEnv(&cfg.SpeedCalibrationDays, "LOGI_SPEED_CALIBRATION_LOOKBACK_DAYS")
+	applyIntEnv(&cfg.SpeedCalibrationMinTrips, "LOGI_SPEED_CALIBRATION_MIN_SAMPLES")
+	applyStringEnv(&cfg.GeocoderType, "LOGI_GEOCODER_TYPE")
+	applyStringEnv(&cfg.NominatimBaseURL, "LOGI_NOMINATIM_BASE_URL")
+	applyStringEnv(&cfg.NominatimUserAgent, "LOGI_NOMINATIM_USER_AGENT")
+	applyStringEnv(&cfg.NominatimEmail, "LOGI_NOMINATIM_EMAIL")
 	applyCSVEnvWithFallback(&cfg.AllowedOrigins, "LOGI_ALLOWED_ORIGINS", "ALLOWED_ORIGINS")
+	applyCSVEnv(&cfg.TrustedProxies, "LOGI_TRUSTED_PROXIES")
+	applyBoolEnv(&cfg.RateLimitEnabled, "LOGI_RATE_LIMIT_ENABLED")
+	applyStringEnv(&cfg.RateLimitStore, "LOGI_RATE_LIMIT_STORE")
+	applyMapEnv(&cfg.RateLimits, "LOGI_RATE_LIMITS")
 	applyBoolEnv(&cfg.EnableTestRoutes, "LOGI_ENABLE_TEST_ROUTES")
 	applyIntEnv(&cfg.DBOperationTimeoutSeconds, "LOGI_DB_OPERATION_TIMEOUT_SECONDS")
 	applyIntEnv(&cfg.HTTPReadTimeoutSeconds, "LOGI_HTTP_READ_TIMEOUT_SECONDS")
@@ -99,6 +335,18 @@ func overrideFromEnv(cfg *Config) {
 	applyIntEnv(&cfg.ShutdownTimeoutSeconds, "LOGI_SHUTDOWN_TIMEOUT_SECONDS")
 }
 
+// UsesHS256 reports whether jwt_secret signs or verifies tokens: when no
+// signing key is set, or while HS256 tokens are still accepted.
+func (c *Config) UsesHS256() bool {
+	return c.JWTSigningKeyID == "" || c.JWTAllowHS256
+}
+
+// PhoneLoginEnabled reports whether the driver phone login routes are served.
+// In production they need a real SMS sender, which log is not.
+func (c *Config) PhoneLoginEnabled() bool {
+	return c.Environment != "production" || c.SMSSenderType != "log"
+}
+
 func validateConfig(cfg *Config) error {
 	if strings.TrimSpace(cfg.MongoURI) == "" {
 		return fmt.Errorf("mongo_uri is required (set LOGI_MONGO_URI, MONGODB_URI, or MONGO_URI)")
@@ -106,29 +354,188 @@ func validateConfig(cfg *Config) error {
 	if isCloudRuntime() && isLocalMongoURI(cfg.MongoURI) {
 		return fmt.Errorf("mongo_uri points to localhost in cloud runtime; set LOGI_MONGO_URI or MONGODB_URI to your external MongoDB connection string")
 	}
-	if len(strings.TrimSpace(cfg.JWTSecret)) < 32 {
-		return fmt.Errorf("jwt_secret must be at least 32 characters for production safety")
+	if cfg.UsesHS256() {
+		if len(strings.TrimSpace(cfg.JWTSecret)) < 32 {
+			return fmt.Errorf("jwt_secret must be at least 32 characters for production safety")
+		}
+		if (cfg.Environment == "production" || isCloudRuntime()) && isPlaceholderSecret(cfg.JWTSecret) {
+			return fmt.Errorf("jwt_secret placeholder detected; set LOGI_JWT_SECRET or JWT_SECRET to a strong random value")
+		}
+	}
+	if cfg.JWTSigningKeyID != "" && cfg.JWTKeyFiles[cfg.JWTSigningKeyID] == "" {
+		return fmt.Errorf("jwt_signing_key_id %q has no entry in jwt_key_files", cfg.JWTSigningKeyID)
+	}
+	for kid, until := range cfg.JWTKeyVerifyUntil {
+		if _, ok := cfg.JWTKeyFiles[kid]; !ok {
+			return fmt.Errorf("jwt_key_verify_until lists unknown key %q", kid)
+		}
+		if _, err := time.Parse(time.RFC3339, until); err != nil {
+			return fmt.Errorf("jwt_key_verify_until for key %q must be an RFC 3339 time", kid)
+		}
+	}
+	if cfg.JWTAccessTokenTTLMinutes <= 0 || cfg.RefreshTokenTTLHours <= 0 {
+		return fmt.Errorf("jwt_access_token_ttl_minutes and refresh_token_ttl_hours must be greater than 0")
+	}
+	if cfg.AdminBootstrapToken != "" && len(cfg.AdminBootstrapToken) < 32 {
+		return fmt.Errorf("admin_bootstrap_token must be at least 32 characters")
+	}
+	if cfg.AdminInviteTTLHours <= 0 {
+		return fmt.Errorf("admin_invite_ttl_hours must be greater than 0")
+	}
+	if cfg.AccountTokenSecret != "" && len(cfg.AccountTokenSecret) < 32 {
+		return fmt.Errorf("account_token_secret must be at least 32 characters")
+	}
+	if cfg.AccountTokenSecret == "" && len(strings.TrimSpace(cfg.JWTSecret)) < 32 {
+		return fmt.Errorf("account_token_secret is required when jwt_secret is not set")
+	}
+	if cfg.EmailVerificationTTLHours <= 0 || cfg.PasswordResetTTLMinutes <= 0 {
+		return fmt.Errorf("email_verification_ttl_hours and password_reset_ttl_minutes must be greater than 0")
+	}
+	switch cfg.MailerType {
+	case "log":
+		if cfg.Environment == "production" {
+			return fmt.Errorf("mailer_type log sends no email and cannot be used in production; set mailer_type smtp")
+		}
+	case "smtp":
+		if strings.TrimSpace(cfg.SMTPHost) == "" || strings.TrimSpace(cfg.MailFrom) == "" || cfg.SMTPPort <= 0 {
+			return fmt.Errorf("smtp_host, smtp_port and mail_from are required when mailer_type is smtp")
+		}
+	default:
+		return fmt.Errorf("mailer_type must be one of: log, smtp")
+	}
+	if cfg.SMSSenderType != "log" {
+		return fmt.Errorf("sms_sender_type must be log")
+	}
+	if cfg.PhoneDefaultCountryCode != "" && !countryCodePattern.MatchString(cfg.PhoneDefaultCountryCode) {
+		return fmt.Errorf("phone_default_country_code must be 1-3 digits without +, e.g. 44")
+	}
+	if cfg.OTPCodeLength < 4 || cfg.OTPCodeLength > 10 {
+		return fmt.Errorf("otp_code_length must be between 4 and 10")
 	}
-	if (cfg.Environment == "production" || isCloudRuntime()) && isPlaceholderSecret(cfg.JWTSecret) {
-		return fmt.Errorf("jwt_secret placeholder detected; set LOGI_JWT_SECRET or JWT_SECRET to a strong random value")
+	if cfg.OTPTTLMinutes <= 0 || cfg.OTPMaxAttempts <= 0 || cfg.OTPAttemptWindowMinutes <= 0 || cfg.OTPResendSeconds < 0 {
+		return fmt.Errorf("otp_ttl_minutes, otp_max_attempts and otp_attempt_window_minutes must be greater than 0, and otp_resend_seconds must not be negative")
+	}
+	if cfg.LoginMaxAccountFailures <= 0 || cfg.LoginMaxIPFailures <= 0 || cfg.LoginFailureWindowMinutes <= 0 || cfg.LoginLockoutMinutes <= 0 {
+		return fmt.Errorf("login_max_account_failures, login_max_ip_failures, login_failure_window_minutes and login_lockout_minutes must be greater than 0")
+	}
+	if cfg.LoginBaseDelaySeconds < 0 {
+		return fmt.Errorf("login_base_delay_seconds must not be negative")
+	}
+	if cfg.RateLimitEnabled {
+		switch cfg.RateLimitStore {
+		case "memory":
+		case "redis":
+			if strings.TrimSpace(cfg.RedisURL) == "" {
+				return fmt.Errorf("redis_url is required when rate_limit_store is redis")
+			}
+		default:
+			return fmt.Errorf("rate_limit_store must be one of: memory, redis")
+		}
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("trusted_proxies entry %q must be an IP address or CIDR range", proxy)
+			}
+		}
 	}
 
 	switch cfg.MessagingType {
-	case "websocket", "nats":
+	case "websocket", "nats", "kafka", "redis":
 	default:
-		return fmt.Errorf("messaging_type must be one of: websocket, nats")
+		return fmt.Errorf("messaging_type must be one of: websocket, nats, kafka, redis")
 	}
 	if cfg.MessagingType == "nats" && strings.TrimSpace(cfg.NATSURL) == "" {
 		return fmt.Errorf("nats_url is required when messaging_type is nats")
 	}
+	if cfg.MessagingType == "kafka" && (len(cfg.KafkaBrokers) == 0 || strings.TrimSpace(cfg.KafkaTopic) == "") {
+		return fmt.Errorf("kafka_brokers and kafka_topic are required when messaging_type is kafka")
+	}
+	if cfg.MessagingType == "redis" {
+		if strings.TrimSpace(cfg.RedisURL) == "" || strings.TrimSpace(cfg.RedisStream) == "" {
+			return fmt.Errorf("redis_url and redis_stream are required when messaging_type is redis")
+		}
+		if cfg.RedisStreamMaxLen <= 0 {
+			return fmt.Errorf("redis_stream_max_len must be greater than 0")
+		}
+	}
+	if cfg.WebSocketSendQueueSize <= 0 || cfg.WebSocketWriteTimeoutSeconds <= 0 {
+		return fmt.Errorf("websocket_send_queue_size and websocket_write_timeout_seconds must be greater than 0")
+	}
+	switch cfg.WebSocketSlowConsumerPolicy {
+	case "drop_oldest", "disconnect":
+	default:
+		return fmt.Errorf("websocket_slow_consumer_policy must be one of: drop_oldest, disconnect")
+	}
+	if cfg.WebSocketPingIntervalSeconds <= 0 || cfg.WebSocketPongWaitSeconds <= 0 || cfg.WebSocketMaxMessageBytes <= 0 || cfg.WebSocketSessionCheckSeconds <= 0 {
+		return fmt.Errorf("websocket ping interval, pong wait, max message bytes and session check seconds must be greater than 0")
+	}
+	if cfg.WebSocketPingIntervalSeconds >= cfg.WebSocketPongWaitSeconds {
+		return fmt.Errorf("websocket_ping_interval_seconds must be less than websocket_pong_wait_seconds")
+	}
+	switch cfg.WebSocketOutboxType {
+	case "none", "memory", "mongo":
+	default:
+		return fmt.Errorf("websocket_outbox_type must be one of: none, memory, mongo")
+	}
+	if cfg.WebSocketOutboxType != "none" && cfg.WebSocketOutboxSize <= 0 {
+		return fmt.Errorf("websocket_outbox_size must be greater than 0")
+	}
+	switch cfg.EventStreamType {
+	case "none", "jetstream":
+	default:
+		return fmt.Errorf("event_stream_type must be one of: none, jetstream")
+	}
+	if cfg.EventStreamType == "jetstream" {
+		if strings.TrimSpace(cfg.NATSURL) == "" || strings.TrimSpace(cfg.EventStreamName) == "" {
+			return fmt.Errorf("nats_url and event_stream_name are required when event_stream_type is jetstream")
+		}
+		if cfg.EventStreamDedupWindowSeconds <= 0 || cfg.EventStreamMaxAgeHours <= 0 {
+			return fmt.Errorf("event_stream_dedup_window_seconds and event_stream_max_age_hours must be greater than 0")
+		}
+	}
+	if cfg.EventOutboxEnabled && (cfg.EventOutboxPollIntervalSeconds <= 0 || cfg.EventOutboxMaxAttempts <= 0 || cfg.EventOutboxRetentionHours <= 0) {
+		return fmt.Errorf("event outbox poll interval, max attempts and retention must be greater than 0")
+	}
+	if cfg.WebhooksEnabled && (cfg.WebhookTimeoutSeconds <= 0 || cfg.WebhookMaxAttempts <= 0 || cfg.WebhookRetryDelaySeconds <= 0 || cfg.WebhookDeliveryRetentionHours <= 0) {
+		return fmt.Errorf("webhook timeout, max attempts, retry delay and delivery retention must be greater than 0")
+	}
+	if cfg.WebhookAllowPrivateNetworks && cfg.Environment == "production" {
+		return fmt.Errorf("webhook_allow_private_networks must be off in production")
+	}
+
+	if err := validateDistanceCalculator(cfg.DistanceCalculatorType, cfg); err != nil {
+		return err
+	}
+	for _, name := range cfg.DistanceFallbackChain {
+		if name == "" {
+			return fmt.Errorf("distance_fallback_chain entries must not be empty")
+		}
+		if err := validateDistanceCalculator(name, cfg); err != nil {
+			return err
+		}
+	}
+	if cfg.HaversineDetourFactor < 1 {
+		return fmt.Errorf("haversine_detour_factor must be at least 1")
+	}
+	if cfg.DistanceCacheEnabled && (cfg.DistanceCacheMaxEntries <= 0 || cfg.DistanceCacheTTLSeconds <= 0 || cfg.DistanceCachePrecision <= 0) {
+		return fmt.Errorf("distance cache max entries, ttl and precision must be greater than 0")
+	}
+
+	if err := validateSpeedProfile(&cfg.SpeedProfile); err != nil {
+		return err
+	}
+	if cfg.SpeedCalibrationDays <= 0 || cfg.SpeedCalibrationMinTrips <= 0 {
+		return fmt.Errorf("speed_calibration_lookback_days and speed_calibration_min_samples must be greater than 0")
+	}
 
-	switch cfg.DistanceCalculatorType {
-	case "haversine", "google_maps", "":
+	switch cfg.GeocoderType {
+	case "", "nominatim":
 	default:
-		return fmt.Errorf("distance_calculator_type must be one of: haversine, google_maps")
+		return fmt.Errorf("geocoder_type must be empty or nominatim")
 	}
-	if cfg.DistanceCalculatorType == "google_maps" && strings.TrimSpace(cfg.GoogleMapsAPIKey) == "" {
-		return fmt.Errorf("google_maps_api_key is required when distance_calculator_type is google_maps")
+	if cfg.GeocoderType == "nominatim" && (strings.TrimSpace(cfg.NominatimBaseURL) == "" || strings.TrimSpace(cfg.NominatimUserAgent) == "") {
+		return fmt.Errorf("nominatim_base_url and nominatim_user_agent are required when geocoder_type is nominatim")
 	}
 
 	if cfg.DBOperationTimeoutSeconds <= 0 || cfg.HTTPReadTimeoutSeconds <= 0 || cfg.HTTPWriteTimeoutSeconds <= 0 || cfg.HTTPIdleTimeoutSeconds <= 0 || cfg.ShutdownTimeoutSeconds <= 0 {
@@ -138,6 +545,41 @@ func validateConfig(cfg *Config) error {
 	return nil
 }
 
+func validateDistanceCalculator(name string, cfg *Config) error {
+	switch name {
+	case "haversine", "google_maps", "osrm", "graphhopper", "":
+	default:
+		return fmt.Errorf("distance_calculator_type must be one of: haversine, google_maps, osrm, graphhopper")
+	}
+	if name == "google_maps" && strings.TrimSpace(cfg.GoogleMapsAPIKey) == "" {
+		return fmt.Errorf("google_maps_api_key is required when distance_calculator_type is google_maps")
+	}
+	if name == "osrm" && strings.TrimSpace(cfg.OSRMBaseURL) == "" {
+		return fmt.Errorf("osrm_base_url is required when distance_calculator_type is osrm")
+	}
+	if name == "graphhopper" && strings.TrimSpace(cfg.GraphHopperBaseURL) == "" {
+		return fmt.Errorf("graphhopper_base_url is required when distance_calculator_type is graphhopper")
+	}
+	return nil
+}
+
+func validateSpeedProfile(profile *models.SpeedProfile) error {
+	if profile.DefaultSpeedKmh <= 0 {
+		return fmt.Errorf("speed_profile.default_speed_kmh must be greater than 0")
+	}
+	for vehicleType, speed := range profile.VehicleSpeedsKmh {
+		if speed <= 0 {
+			return fmt.Errorf("speed_profile.vehicle_speeds_kmh.%s must be greater than 0", vehicleType)
+		}
+	}
+	for vehicleType, speeds := range profile.HourOfWeekSpeedsKmh {
+		if len(speeds) != models.HoursPerWeek {
+			return fmt.Errorf("speed_profile.hour_of_week_speeds_kmh.%s must have %d entries", vehicleType, models.HoursPerWeek)
+		}
+	}
+	return nil
+}
+
 func applyStringEnv(target *string, key string) {
 	val := strings.TrimSpace(os.Getenv(key))
 	if val != "" {
@@ -165,6 +607,17 @@ func applyIntEnv(target *int, key string) {
 	}
 }
 
+func applyFloatEnv(target *float64, key string) {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return
+	}
+	parsed, err := strconv.ParseFloat(raw, 64)
+	if err == nil {
+		*target = parsed
+	}
+}
+
 func applyBoolEnv(target *bool, key string) {
 	raw := strings.TrimSpace(os.Getenv(key))
 	if raw == "" {
@@ -205,6 +658,24 @@ func applyCSVValue(target *[]string, raw string) {
 	*target = out
 }
 
+// applyMapEnv parses comma-separated key=value pairs, e.g. "bike=bike,truck=hgv".
+func applyMapEnv(target *map[string]string, key string) {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return
+	}
+	out := make(map[string]string)
+	for _, part := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if ok && name != "" && value != "" {
+			out[name] = value
+		}
+	}
+	*target = out
+}
+
 func applyPortEnv(target *string, key string) {
 	raw := strings.TrimSpace(os.Getenv(key))
 	if raw == "" {
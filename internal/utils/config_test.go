@@ -68,3 +68,211 @@ func TestLoadConfigRejectsPlaceholderJWTSecretInCloudRuntime(t *testing.T) {
 		t.Fatalf("expected JWT env guidance in error, got %v", err)
 	}
 }
+
+func TestLoadConfigParsesRoutingProfilesFromEnv(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOGI_DISTANCE_CALCULATOR_TYPE", "osrm")
+	t.Setenv("LOGI_OSRM_BASE_URL", "http://osrm.internal:5000")
+	t.Setenv("LOGI_ROUTING_PROFILES", "bike=bike, truck = hgv,invalid")
+
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	if cfg.OSRMBaseURL != "http://osrm.internal:5000" {
+		t.Fatalf("expected osrm base url from env, got %q", cfg.OSRMBaseURL)
+	}
+	if len(cfg.RoutingProfiles) != 2 || cfg.RoutingProfiles["bike"] != "bike" || cfg.RoutingProfiles["truck"] != "hgv" {
+		t.Fatalf("unexpected routing profiles: %#v", cfg.RoutingProfiles)
+	}
+}
+
+func TestLoadConfigRequiresBaseURLForRoutingBackends(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOGI_DISTANCE_CALCULATOR_TYPE", "graphhopper")
+
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "graphhopper_base_url") {
+		t.Fatalf("expected graphhopper_base_url validation error, got %v", err)
+	}
+}
+
+func TestLoadConfigRejectsUnknownSlowConsumerPolicy(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOGI_WEBSOCKET_SLOW_CONSUMER_POLICY", "block")
+
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "websocket_slow_consumer_policy") {
+		t.Fatalf("expected websocket_slow_consumer_policy validation error, got %v", err)
+	}
+}
+
+func TestLoadConfigRequiresPingIntervalBelowPongWait(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOGI_WEBSOCKET_PING_INTERVAL_SECONDS", "60")
+	t.Setenv("LOGI_WEBSOCKET_PONG_WAIT_SECONDS", "30")
+
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "websocket_ping_interval_seconds") {
+		t.Fatalf("expected ping interval validation error, got %v", err)
+	}
+}
+
+func TestLoadConfigParsesKafkaBrokersFromEnv(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOGI_MESSAGING_TYPE", "kafka")
+	t.Setenv("LOGI_KAFKA_BROKERS", "kafka-1:9092, kafka-2:9092")
+
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.KafkaBrokers) != 2 || cfg.KafkaBrokers[0] != "kafka-1:9092" || cfg.KafkaBrokers[1] != "kafka-2:9092" {
+		t.Fatalf("expected kafka brokers from env, got %#v", cfg.KafkaBrokers)
+	}
+	if cfg.KafkaTopic != "logi.messages" {
+		t.Fatalf("expected default kafka topic, got %q", cfg.KafkaTopic)
+	}
+}
+
+func TestLoadConfigSkipsJWTSecretOnceHS256IsDisabled(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SIGNING_KEY_ID", "2026-10")
+	t.Setenv("LOGI_JWT_KEY_FILES", "2026-10=/etc/logi/jwt/2026-10.pem, 2026-07=/etc/logi/jwt/2026-07.pub.pem")
+	t.Setenv("LOGI_JWT_KEY_VERIFY_UNTIL", "2026-07=2026-10-20T00:00:00Z")
+	t.Setenv("LOGI_JWT_ALLOW_HS256", "false")
+
+	// Account tokens then need their own secret.
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml")); err == nil {
+		t.Fatal("expected account_token_secret to be required without jwt_secret")
+	}
+	t.Setenv("LOGI_ACCOUNT_TOKEN_SECRET", "fedcba9876543210fedcba9876543210")
+
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.JWTKeyFiles) != 2 || cfg.JWTKeyFiles["2026-07"] != "/etc/logi/jwt/2026-07.pub.pem" {
+		t.Fatalf("unexpected jwt key files: %#v", cfg.JWTKeyFiles)
+	}
+
+	t.Setenv("LOGI_JWT_SIGNING_KEY_ID", "2026-11")
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml")); err == nil {
+		t.Fatal("expected a signing key without a key file to be rejected")
+	}
+}
+
+func TestLoadConfigRequiresSMTPSettingsForSMTPMailer(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOGI_MAILER_TYPE", "smtp")
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml")); err == nil {
+		t.Fatal("expected the smtp mailer without smtp_host to be rejected")
+	}
+
+	t.Setenv("LOGI_SMTP_HOST", "smtp.example.com")
+	t.Setenv("LOGI_MAIL_FROM", "Logi <no-reply@example.com>")
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.SMTPPort != 587 || cfg.AdminInviteTTLHours != 72 {
+		t.Fatalf("unexpected defaults: smtp_port %d, admin_invite_ttl_hours %d", cfg.SMTPPort, cfg.AdminInviteTTLHours)
+	}
+}
+
+func TestLoadConfigRejectsLogMailerInProduction(t *testing.T) {
+	t.Setenv("LOGI_ENVIRONMENT", "production")
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml")); err == nil || !strings.Contains(err.Error(), "mailer_type") {
+		t.Fatalf("expected the log mailer to be rejected in production, got %v", err)
+	}
+
+	t.Setenv("LOGI_MAILER_TYPE", "smtp")
+	t.Setenv("LOGI_SMTP_HOST", "smtp.example.com")
+	t.Setenv("LOGI_MAIL_FROM", "Logi <no-reply@example.com>")
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml")); err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+}
+
+func TestLoadConfigValidatesTrustedProxies(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOGI_TRUSTED_PROXIES", "10.0.0.0/8,not-a-proxy")
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml")); err == nil {
+		t.Fatal("expected an invalid trusted proxy to be rejected")
+	}
+
+	t.Setenv("LOGI_TRUSTED_PROXIES", "10.0.0.0/8,192.0.2.10")
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.TrustedProxies) != 2 || cfg.LoginMaxAccountFailures != 5 || cfg.LoginLockoutMinutes != 15 {
+		t.Fatalf("unexpected config: trusted_proxies %v, login_max_account_failures %d, login_lockout_minutes %d", cfg.TrustedProxies, cfg.LoginMaxAccountFailures, cfg.LoginLockoutMinutes)
+	}
+}
+
+func TestLoadConfigValidatesRateLimits(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOGI_RATE_LIMIT_STORE", "dynamodb")
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml")); err == nil {
+		t.Fatal("expected an unknown rate limit store to be rejected")
+	}
+
+	t.Setenv("LOGI_RATE_LIMIT_STORE", "memory")
+
+	t.Setenv("LOGI_RATE_LIMITS", "POST /bookings=user:10/1m,*=ip:600/1m")
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.RateLimits) != 2 || cfg.RateLimits["POST /bookings"] != "user:10/1m" {
+		t.Fatalf("expected LOGI_RATE_LIMITS to replace the defaults, got %v", cfg.RateLimits)
+	}
+}
+
+func TestLoadConfigValidatesOTPSettings(t *testing.T) {
+	t.Setenv("LOGI_MONGO_URI", "mongodb://db.example.com:27017/logi")
+	t.Setenv("LOGI_JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOGI_PHONE_DEFAULT_COUNTRY_CODE", "+44")
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml")); err == nil {
+		t.Fatal("expected a country code with + to be rejected")
+	}
+
+	t.Setenv("LOGI_PHONE_DEFAULT_COUNTRY_CODE", "44")
+	t.Setenv("LOGI_OTP_CODE_LENGTH", "3")
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml")); err == nil {
+		t.Fatal("expected a 3-digit code length to be rejected")
+	}
+
+	t.Setenv("LOGI_OTP_CODE_LENGTH", "8")
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.OTPCodeLength != 8 || cfg.OTPTTLMinutes != 5 || cfg.OTPMaxAttempts != 5 || cfg.OTPAttemptWindowMinutes != 60 || cfg.SMSSenderType != "log" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if !cfg.PhoneLoginEnabled() {
+		t.Fatal("expected phone login outside production")
+	}
+	cfg.Environment = "production"
+	if cfg.PhoneLoginEnabled() {
+		t.Fatal("expected phone login to be off in production with the log sender")
+	}
+}
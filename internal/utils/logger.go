@@ -12,7 +12,10 @@ const RequestIDHeader = "X-Request-ID"
 
 type contextKey string
 
-const requestIDContextKey contextKey = "request_id"
+const (
+	requestIDContextKey contextKey = "request_id"
+	clientIPContextKey  contextKey = "client_ip"
+)
 
 var defaultLogger atomic.Pointer[slog.Logger]
 
@@ -64,6 +67,27 @@ func RequestIDFromContext(ctx context.Context) string {
 	return requestID
 }
 
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ip == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, clientIPContextKey, ip)
+}
+
+// ClientIPFromContext returns the IP of the client whose request ctx serves,
+// as set by ClientIPMiddleware.
+func ClientIPFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	ip, _ := ctx.Value(clientIPContextKey).(string)
+	return ip
+}
+
 func LoggerFromContext(ctx context.Context) *slog.Logger {
 	if requestID := RequestIDFromContext(ctx); requestID != "" {
 		return baseLogger().With("request_id", requestID)
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"expvar"
+	"net/http"
+	"sync"
+)
+
+// Process-wide metrics are published through expvar under the "logi" key so
+// they can be scraped as JSON without an extra dependency.
+var (
+	metrics   = expvar.NewMap("logi")
+	metricsMu sync.Mutex
+)
+
+// IncrementCounter adds delta to the named counter.
+func IncrementCounter(name string, delta int64) {
+	metrics.Add(name, delta)
+}
+
+// SetGauge sets the named gauge to value.
+func SetGauge(name string, value int64) {
+	gauge(name).Set(value)
+}
+
+// AddGauge moves the named gauge by delta, which may be negative.
+func AddGauge(name string, delta int64) {
+	gauge(name).Add(delta)
+}
+
+// MetricValue returns the current value of a counter or gauge, or 0 if it was never set.
+func MetricValue(name string) int64 {
+	if v, ok := metrics.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// MetricsHandler serves every published expvar, including the "logi" metrics map.
+func MetricsHandler() http.Handler {
+	return expvar.Handler()
+}
+
+func gauge(name string) *expvar.Int {
+	if v, ok := metrics.Get(name).(*expvar.Int); ok {
+		return v
+	}
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if v, ok := metrics.Get(name).(*expvar.Int); ok {
+		return v
+	}
+	v := new(expvar.Int)
+	metrics.Set(name, v)
+	return v
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"logi/pkg/auth"
 	"net/http"
 	"strings"
@@ -27,16 +29,31 @@ func JWTAuthMiddleware(authService *auth.AuthService, requiredRoles ...string) g
 		}
 
 		token := parts[1]
-		userID, role, err := authService.ValidateJWT(token)
-		if err != nil {
+		claims, err := authService.Authenticate(c.Request.Context(), token)
+		if errors.Is(err, auth.ErrTokenRevoked) {
+			Warn(c.Request.Context(), "revoked token used", "method", c.Request.Method, "path", c.Request.URL.Path)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+		if errors.Is(err, auth.ErrInvalidToken) {
 			Warn(c.Request.Context(), "jwt validation failed", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
+		if err != nil {
+			Error(c.Request.Context(), "failed to check token revocation", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+			c.Abort()
+			return
+		}
+		userID, role := claims.Subject, claims.Role
 
 		c.Set("userID", userID)
 		c.Set("role", role)
+		c.Set("tokenID", claims.ID)
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
 
 		// Check if the role is allowed
 		if len(requiredRoles) > 0 {
@@ -58,3 +75,30 @@ func JWTAuthMiddleware(authService *auth.AuthService, requiredRoles ...string) g
 		c.Next()
 	}
 }
+
+// PermissionChecker resolves whether an admin holds a permission.
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, adminID, permission string) (bool, error)
+}
+
+// RequirePermission rejects callers without permission. It runs after
+// JWTAuthMiddleware, which sets the caller's ID.
+func RequirePermission(checker PermissionChecker, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		allowed, err := checker.HasPermission(c.Request.Context(), userID, permission)
+		if err != nil {
+			Error(c.Request.Context(), "failed to resolve permissions", "user_id", userID, "permission", permission, "error", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify permissions"})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			Warn(c.Request.Context(), "missing permission", "user_id", userID, "permission", permission, "method", c.Request.Method, "path", c.Request.URL.Path)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions", "required_permission": permission})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
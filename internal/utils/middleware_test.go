@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakePermissionChecker map[string][]string
+
+func (f fakePermissionChecker) HasPermission(ctx context.Context, adminID, permission string) (bool, error) {
+	if adminID == "broken" {
+		return false, errors.New("database unavailable")
+	}
+	for _, granted := range f[adminID] {
+		if granted == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	checker := fakePermissionChecker{"dispatcher-1": {"drivers:read"}}
+	for _, tc := range []struct {
+		adminID    string
+		permission string
+		want       int
+	}{
+		{"dispatcher-1", "drivers:read", http.StatusOK},
+		{"dispatcher-1", "vehicles:write", http.StatusForbidden},
+		{"broken", "drivers:read", http.StatusServiceUnavailable},
+	} {
+		router := gin.New()
+		router.GET("/admin/resource", func(c *gin.Context) {
+			c.Set("userID", tc.adminID)
+		}, RequirePermission(checker, tc.permission), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin/resource", nil))
+		if resp.Code != tc.want {
+			t.Fatalf("%s with %s: expected status %d, got %d", tc.adminID, tc.permission, tc.want, resp.Code)
+		}
+	}
+}
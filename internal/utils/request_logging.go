@@ -28,6 +28,16 @@ func RequestIDMiddleware() gin.HandlerFunc {
 	}
 }
 
+// ClientIPMiddleware stores the client IP in the request context for services
+// that key on it. X-Forwarded-For is only honoured from the engine's trusted
+// proxies.
+func ClientIPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
 func RequestIDFromGin(c *gin.Context) string {
 	if c == nil {
 		return ""
@@ -0,0 +1,195 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/utils"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DelivererConfig controls how often the deliverer polls and how it retries.
+type DelivererConfig struct {
+	PollInterval time.Duration
+	// Timeout bounds one HTTP request to an endpoint.
+	Timeout time.Duration
+	// MaxAttempts is how often a delivery is tried before it is marked failed.
+	MaxAttempts int
+	// RetryDelay is the wait after the first failure; it doubles with each
+	// further failure up to MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// AllowPrivateNetworks lets deliveries reach loopback, private and
+	// link-local addresses, for local development.
+	AllowPrivateNetworks bool
+}
+
+// DefaultDelivererConfig returns the settings used for zero fields.
+func DefaultDelivererConfig() DelivererConfig {
+	return DelivererConfig{
+		PollInterval:  2 * time.Second,
+		Timeout:       10 * time.Second,
+		MaxAttempts:   8,
+		RetryDelay:    30 * time.Second,
+		MaxRetryDelay: time.Hour,
+	}
+}
+
+// Deliverer POSTs pending deliveries to their endpoints. Any 2xx response
+// counts as delivered; anything else is retried with exponential backoff
+// until MaxAttempts. Several instances can run one: each delivery is claimed
+// before it is sent.
+type Deliverer struct {
+	Deliveries repositories.WebhookDeliveryRepository
+	Endpoints  repositories.WebhookEndpointRepository
+	Client     *http.Client
+	config     DelivererConfig
+	wake       chan struct{}
+}
+
+func NewDeliverer(deliveries repositories.WebhookDeliveryRepository, endpoints repositories.WebhookEndpointRepository, cfg DelivererConfig) *Deliverer {
+	defaults := DefaultDelivererConfig()
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaults.RetryDelay
+	}
+	if cfg.MaxRetryDelay <= 0 {
+		cfg.MaxRetryDelay = defaults.MaxRetryDelay
+	}
+	return &Deliverer{
+		Deliveries: deliveries,
+		Endpoints:  endpoints,
+		Client:     NewHTTPClient(cfg.Timeout, cfg.AllowPrivateNetworks),
+		config:     cfg,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Wake asks the deliverer to poll now instead of at its next interval.
+func (d *Deliverer) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls until ctx is done.
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+		if _, err := d.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			utils.ErrorBackground("webhook delivery failed", "error", err)
+		}
+	}
+}
+
+// DeliverDue sends every delivery that is due and returns how many were sent
+// successfully.
+func (d *Deliverer) DeliverDue(ctx context.Context) (int, error) {
+	delivered := 0
+	for ctx.Err() == nil {
+		now := time.Now()
+		// Held long enough for the request to finish before anyone else
+		// may claim the delivery again.
+		delivery, err := d.Deliveries.ClaimDue(ctx, now, now.Add(2*d.config.Timeout))
+		if err != nil {
+			return delivered, err
+		}
+		if delivery == nil {
+			return delivered, nil
+		}
+		ok, err := d.deliver(ctx, delivery)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, ctx.Err()
+}
+
+func (d *Deliverer) deliver(ctx context.Context, delivery *models.WebhookDelivery) (bool, error) {
+	delivery.Attempts++
+	endpoint, err := d.Endpoints.FindByID(ctx, delivery.EndpointID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		utils.IncrementCounter("webhook_deliveries_failed", 1)
+		return false, d.Deliveries.MarkFailed(ctx, delivery.ID, delivery.Attempts, 0, "endpoint deleted")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	statusCode, sendErr := d.send(ctx, endpoint, delivery)
+	if sendErr == nil {
+		utils.IncrementCounter("webhook_deliveries_succeeded", 1)
+		return true, d.Deliveries.MarkSucceeded(ctx, delivery.ID, delivery.Attempts, statusCode, time.Now())
+	}
+
+	if delivery.Attempts >= d.config.MaxAttempts {
+		utils.IncrementCounter("webhook_deliveries_failed", 1)
+		utils.ErrorBackground("webhook delivery failed permanently", "delivery_id", delivery.ID, "endpoint_id", endpoint.ID, "event_type", delivery.EventType, "attempts", delivery.Attempts, "error", sendErr)
+		return false, d.Deliveries.MarkFailed(ctx, delivery.ID, delivery.Attempts, statusCode, sendErr.Error())
+	}
+	delay := d.config.RetryDelay
+	for i := 1; i < delivery.Attempts && delay < d.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.config.MaxRetryDelay {
+		delay = d.config.MaxRetryDelay
+	}
+	utils.IncrementCounter("webhook_delivery_retries", 1)
+	utils.WarnBackground("webhook delivery failed, will retry", "delivery_id", delivery.ID, "endpoint_id", endpoint.ID, "event_type", delivery.EventType, "attempts", delivery.Attempts, "retry_in", delay.String(), "error", sendErr)
+	return false, d.Deliveries.MarkRetrying(ctx, delivery.ID, delivery.Attempts, statusCode, sendErr.Error(), time.Now().Add(delay))
+}
+
+// send POSTs the signed payload and returns the response status code.
+func (d *Deliverer) send(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "logi-webhooks")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,110 @@
+// Package webhooks sends signed booking events to endpoints registered by
+// business customers: the Dispatcher records a delivery per interested
+// endpoint and the Deliverer sends them, retrying failures.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"logi/internal/events"
+	"logi/internal/models"
+	"logi/internal/repositories"
+	"logi/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EventTypes are the booking lifecycle events endpoints can subscribe to.
+var EventTypes = []string{
+	events.BookingCreated,
+	events.BookingAssigned,
+	events.BookingStatusChanged,
+	events.BookingCompleted,
+}
+
+// IsEventType reports whether eventType can be delivered to webhooks.
+func IsEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatcher records a delivery of each booking event for the endpoints of
+// the booking's user and of the user's organization. It is an
+// events.Publisher, so it is fed from the same service calls, directly or
+// through the event outbox, as the event stream.
+type Dispatcher struct {
+	Endpoints  repositories.WebhookEndpointRepository
+	Deliveries repositories.WebhookDeliveryRepository
+	Users      repositories.UserRepository
+	// OnEnqueue, when set, is called after deliveries were recorded so a
+	// local Deliverer can send them without waiting for its next poll.
+	OnEnqueue func()
+}
+
+func NewDispatcher(endpoints repositories.WebhookEndpointRepository, deliveries repositories.WebhookDeliveryRepository, users repositories.UserRepository, onEnqueue func()) *Dispatcher {
+	return &Dispatcher{Endpoints: endpoints, Deliveries: deliveries, Users: users, OnEnqueue: onEnqueue}
+}
+
+func (d *Dispatcher) PublishEvent(ctx context.Context, event events.Event) error {
+	if !IsEventType(event.Type) {
+		return nil
+	}
+	var data struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(event.Data, &data); err != nil || data.UserID == "" {
+		utils.Warn(ctx, "booking event without user, skipping webhooks", "event_id", event.ID, "event_type", event.Type)
+		return nil
+	}
+
+	organizationID := ""
+	user, err := d.Users.FindByID(ctx, data.UserID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	if user != nil {
+		organizationID = user.OrganizationID
+	}
+	endpoints, err := d.Endpoints.FindByOwner(ctx, data.UserID, organizationID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	queued := 0
+	for _, endpoint := range endpoints {
+		if !endpoint.Wants(event.Type) {
+			continue
+		}
+		now := time.Now()
+		delivery := &models.WebhookDelivery{
+			ID:            primitive.NewObjectID().Hex(),
+			EndpointID:    endpoint.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		if err := d.Deliveries.Insert(ctx, delivery); err != nil {
+			return err
+		}
+		queued++
+	}
+	if queued > 0 && d.OnEnqueue != nil {
+		d.OnEnqueue()
+	}
+	return nil
+}
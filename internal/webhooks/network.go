@@ -0,0 +1,103 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for endpoints that resolve to an address
+// inside the server's own network: loopback, private, link-local, shared or
+// unspecified. Delivering there would let an endpoint owner probe internal
+// services and read the answers from the delivery log.
+var ErrForbiddenAddress = errors.New("webhook host resolves to a loopback, private or link-local address")
+
+// Ranges that net.IP has no predicate for.
+var forbiddenNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "this network"
+	"100.64.0.0/10", // carrier-grade NAT, also used for cloud metadata
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved, including broadcast
+	"64:ff9b::/96",  // NAT64, which can reach any IPv4 address
+)
+
+// IsPublicIP reports whether ip may receive webhook deliveries.
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range forbiddenNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost resolves host with lookup and returns ErrForbiddenAddress unless
+// every address is public. It is the check at registration; the dialer of
+// NewHTTPClient repeats it for the address actually connected to, so a DNS
+// answer that changes afterwards cannot get around it.
+func CheckHost(ctx context.Context, host string, lookup func(context.Context, string) ([]net.IPAddr, error)) error {
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve webhook host %q: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("cannot resolve webhook host %q", host)
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient returns the client deliveries are sent with. It connects
+// only to public addresses unless allowPrivate is set, ignores proxy
+// settings (a proxy would hide the address connected to), and does not
+// follow redirects: a 3xx answer is returned as is and counts as a failure.
+func NewHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,45 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers sent with every delivery.
+const (
+	// SignatureHeader holds Sign(secret, timestamp, body).
+	SignatureHeader = "X-Logi-Signature"
+	// TimestampHeader holds the Unix time the delivery was sent; receivers
+	// should reject old timestamps to stop replays.
+	TimestampHeader = "X-Logi-Timestamp"
+	EventHeader     = "X-Logi-Event"
+	DeliveryHeader  = "X-Logi-Delivery"
+)
+
+// Sign returns "sha256=" followed by the hex HMAC-SHA256, keyed with secret,
+// of the timestamp, a dot and the body.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is Sign(secret, timestamp, body), in
+// constant time.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,403 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"logi/internal/events"
+	"logi/internal/models"
+	"logi/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type memoryEndpoints struct {
+	endpoints []*models.WebhookEndpoint
+}
+
+func (r *memoryEndpoints) Create(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	r.endpoints = append(r.endpoints, endpoint)
+	return nil
+}
+
+func (r *memoryEndpoints) FindByID(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	for _, endpoint := range r.endpoints {
+		if endpoint.ID == id {
+			return endpoint, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memoryEndpoints) FindByOwner(ctx context.Context, userID, organizationID string) ([]*models.WebhookEndpoint, error) {
+	var found []*models.WebhookEndpoint
+	for _, endpoint := range r.endpoints {
+		if (userID != "" && endpoint.UserID == userID) || (organizationID != "" && endpoint.OrganizationID == organizationID) {
+			found = append(found, endpoint)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryEndpoints) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+// memoryDeliveries is an in-memory WebhookDeliveryRepository that, like the
+// unique index, ignores a second delivery of an event to an endpoint.
+type memoryDeliveries struct {
+	mu         sync.Mutex
+	deliveries []*models.WebhookDelivery
+}
+
+func (r *memoryDeliveries) Insert(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.deliveries {
+		if delivery.RedeliveryOf == "" && existing.RedeliveryOf == "" && existing.EventID == delivery.EventID && existing.EndpointID == delivery.EndpointID {
+			return nil
+		}
+	}
+	copied := *delivery
+	r.deliveries = append(r.deliveries, &copied)
+	return nil
+}
+
+func (r *memoryDeliveries) FindByID(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, delivery := range r.deliveries {
+		if delivery.ID == id {
+			copied := *delivery
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memoryDeliveries) List(ctx context.Context, filter repositories.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *memoryDeliveries) ClaimDue(ctx context.Context, now, lockUntil time.Time) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, delivery := range r.deliveries {
+		if delivery.Status == models.WebhookDeliveryStatusPending && !delivery.NextAttemptAt.After(now) {
+			delivery.NextAttemptAt = lockUntil
+			copied := *delivery
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryDeliveries) update(id string, apply func(delivery *models.WebhookDelivery)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, delivery := range r.deliveries {
+		if delivery.ID == id {
+			apply(delivery)
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (r *memoryDeliveries) MarkSucceeded(ctx context.Context, id string, attempts int, statusCode int, deliveredAt time.Time) error {
+	return r.update(id, func(delivery *models.WebhookDelivery) {
+		delivery.Status = models.WebhookDeliveryStatusSucceeded
+		delivery.Attempts = attempts
+		delivery.LastStatusCode = statusCode
+		delivery.DeliveredAt = &deliveredAt
+	})
+}
+
+func (r *memoryDeliveries) MarkRetrying(ctx context.Context, id string, attempts int, statusCode int, lastError string, nextAttemptAt time.Time) error {
+	return r.update(id, func(delivery *models.WebhookDelivery) {
+		delivery.Attempts = attempts
+		delivery.LastStatusCode = statusCode
+		delivery.LastError = lastError
+		delivery.NextAttemptAt = nextAttemptAt
+	})
+}
+
+func (r *memoryDeliveries) MarkFailed(ctx context.Context, id string, attempts int, statusCode int, lastError string) error {
+	return r.update(id, func(delivery *models.WebhookDelivery) {
+		delivery.Status = models.WebhookDeliveryStatusFailed
+		delivery.Attempts = attempts
+		delivery.LastStatusCode = statusCode
+		delivery.LastError = lastError
+	})
+}
+
+type memoryUsers struct {
+	repositories.UserRepository
+	users map[string]*models.User
+}
+
+func (r *memoryUsers) FindByID(ctx context.Context, userID string) (*models.User, error) {
+	if user, ok := r.users[userID]; ok {
+		return user, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func TestDispatcherRecordsOneDeliveryPerInterestedEndpoint(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	endpoints := &memoryEndpoints{endpoints: []*models.WebhookEndpoint{
+		{ID: "own", UserID: "user-1"},
+		{ID: "org", OrganizationID: "acme", EventTypes: []string{events.BookingCompleted}},
+		{ID: "org-created-only", OrganizationID: "acme", EventTypes: []string{events.BookingCreated}},
+		{ID: "other-user", UserID: "user-2"},
+		{ID: "other-org", OrganizationID: "globex"},
+	}}
+	deliveries := &memoryDeliveries{}
+	users := &memoryUsers{users: map[string]*models.User{"user-1": {ID: "user-1", OrganizationID: "acme"}}}
+	woken := 0
+	dispatcher := NewDispatcher(endpoints, deliveries, users, func() { woken++ })
+
+	completed, _ := events.New(events.BookingCompleted, "booking-1", map[string]string{"booking_id": "booking-1", "user_id": "user-1"})
+	driverStatus, _ := events.New(events.DriverStatusChanged, "driver-1", map[string]string{"driver_id": "driver-1"})
+	// The outbox relay may publish an event again after a partial failure.
+	for _, event := range []events.Event{completed, completed, driverStatus} {
+		if err := dispatcher.PublishEvent(ctx, event); err != nil {
+			t.Fatalf("PublishEvent returned error: %v", err)
+		}
+	}
+
+	var got []string
+	for _, delivery := range deliveries.deliveries {
+		if delivery.EventID != completed.ID || delivery.Status != models.WebhookDeliveryStatusPending {
+			t.Fatalf("unexpected delivery %+v", delivery)
+		}
+		got = append(got, delivery.EndpointID)
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != "org,own" {
+		t.Fatalf("expected deliveries to the user's and the organization's endpoints, got %v", got)
+	}
+	if woken != 2 {
+		t.Fatalf("expected the deliverer to be woken for each booking event, got %d", woken)
+	}
+}
+
+func TestDelivererSignsAndRetriesUntilDelivered(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	requests := 0
+	var verified bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		verified = Verify("secret", timestamp, body, r.Header.Get(SignatureHeader)) && r.Header.Get(EventHeader) == events.BookingCreated
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	endpoints := &memoryEndpoints{endpoints: []*models.WebhookEndpoint{{ID: "endpoint-1", URL: server.URL, Secret: "secret"}}}
+	deliveries := &memoryDeliveries{}
+	_ = deliveries.Insert(ctx, &models.WebhookDelivery{
+		ID:         "delivery-1",
+		EndpointID: "endpoint-1",
+		EventID:    "event-1",
+		EventType:  events.BookingCreated,
+		Payload:    []byte(`{"id":"event-1"}`),
+		Status:     models.WebhookDeliveryStatusPending,
+	})
+	deliverer := NewDeliverer(deliveries, endpoints, DelivererConfig{RetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond, AllowPrivateNetworks: true})
+
+	if delivered, err := deliverer.DeliverDue(ctx); err != nil || delivered != 0 {
+		t.Fatalf("expected the first attempt to fail, got %d, %v", delivered, err)
+	}
+	delivery, _ := deliveries.FindByID(ctx, "delivery-1")
+	if delivery.Status != models.WebhookDeliveryStatusPending || delivery.LastStatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a pending retry after the 503, got %+v", delivery)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if delivered, err := deliverer.DeliverDue(ctx); err != nil || delivered != 1 {
+		t.Fatalf("expected the retry to succeed, got %d, %v", delivered, err)
+	}
+	delivery, _ = deliveries.FindByID(ctx, "delivery-1")
+	if delivery.Status != models.WebhookDeliveryStatusSucceeded || delivery.Attempts != 2 || delivery.DeliveredAt == nil {
+		t.Fatalf("expected a succeeded delivery after two attempts, got %+v", delivery)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !verified {
+		t.Fatal("expected a valid signature and event header")
+	}
+}
+
+// TestWebhookBodyCarriesBookingEventContract pins the fields receivers see in
+// the data of booking.created and booking.completed. Changing them needs a
+// new payload version.
+func TestWebhookBodyCarriesBookingEventContract(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	bodies := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies[r.Header.Get(EventHeader)] = body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	endpoints := &memoryEndpoints{endpoints: []*models.WebhookEndpoint{{ID: "endpoint-1", UserID: "user-1", URL: server.URL, Secret: "secret"}}}
+	deliveries := &memoryDeliveries{}
+	dispatcher := NewDispatcher(endpoints, deliveries, &memoryUsers{}, nil)
+	deliverer := NewDeliverer(deliveries, endpoints, DelivererConfig{AllowPrivateNetworks: true})
+
+	completedAt := time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+	booking := &models.Booking{
+		ID:                "booking-1",
+		UserID:            "user-1",
+		DriverID:          "driver-1",
+		VehicleType:       "bike",
+		PriceEstimate:     12.5,
+		Status:            models.BookingStatusCompleted,
+		CreatedAt:         completedAt.Add(-time.Hour),
+		StartedAt:         &completedAt,
+		CompletedAt:       &completedAt,
+		OfferedDriverIDs:  []string{"driver-1", "driver-2"},
+		RejectedDriverIDs: []string{"driver-2"},
+	}
+	created, _ := events.New(events.BookingCreated, booking.ID, events.NewBookingCreated(booking))
+	completed, _ := events.New(events.BookingCompleted, booking.ID, events.NewBookingCompleted(booking))
+	for _, event := range []events.Event{created, completed} {
+		if err := dispatcher.PublishEvent(ctx, event); err != nil {
+			t.Fatalf("PublishEvent returned error: %v", err)
+		}
+	}
+	if delivered, err := deliverer.DeliverDue(ctx); err != nil || delivered != 2 {
+		t.Fatalf("expected both events to be delivered, got %d, %v", delivered, err)
+	}
+
+	want := map[string]string{
+		events.BookingCreated:   "booking_id,created_at,dropoff_location,pickup_location,price_estimate,status,user_id,vehicle_type,version",
+		events.BookingCompleted: "booking_id,completed_at,created_at,driver_id,dropoff_location,pickup_location,price_estimate,started_at,user_id,vehicle_type,version",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for eventType, fields := range want {
+		var body struct {
+			Type string                     `json:"type"`
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(bodies[eventType], &body); err != nil {
+			t.Fatalf("failed to decode the %s body %q: %v", eventType, bodies[eventType], err)
+		}
+		var got []string
+		for field := range body.Data {
+			got = append(got, field)
+		}
+		sort.Strings(got)
+		if body.Type != eventType || strings.Join(got, ",") != fields {
+			t.Fatalf("unexpected %s data fields: got %v, want %s", eventType, got, fields)
+		}
+		if string(body.Data["version"]) != "1" {
+			t.Fatalf("expected %s data version 1, got %s", eventType, body.Data["version"])
+		}
+	}
+}
+
+func TestDelivererMarksDeliveryFailedAfterMaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	endpoints := &memoryEndpoints{endpoints: []*models.WebhookEndpoint{{ID: "endpoint-1", URL: server.URL, Secret: "secret"}}}
+	deliveries := &memoryDeliveries{}
+	_ = deliveries.Insert(ctx, &models.WebhookDelivery{ID: "delivery-1", EndpointID: "endpoint-1", EventID: "event-1", Status: models.WebhookDeliveryStatusPending})
+	deliverer := NewDeliverer(deliveries, endpoints, DelivererConfig{MaxAttempts: 2, RetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond, AllowPrivateNetworks: true})
+
+	for i := 0; i < 3; i++ {
+		if _, err := deliverer.DeliverDue(ctx); err != nil {
+			t.Fatalf("DeliverDue returned error: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	delivery, _ := deliveries.FindByID(ctx, "delivery-1")
+	if delivery.Status != models.WebhookDeliveryStatusFailed || delivery.Attempts != 2 || delivery.LastError == "" {
+		t.Fatalf("expected the delivery to fail after two attempts, got %+v", delivery)
+	}
+}
+
+func TestDelivererRefusesPrivateAddressesAndRedirects(t *testing.T) {
+	t.Parallel()
+
+	internal := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			internal = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	}))
+	t.Cleanup(server.Close)
+
+	if _, err := NewHTTPClient(time.Second, false).Post(server.URL, "application/json", nil); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("expected a loopback endpoint to be refused at dial time, got %v", err)
+	}
+	resp, err := NewHTTPClient(time.Second, true).Post(server.URL, "application/json", nil)
+	if err != nil {
+		t.Fatalf("Post returned error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || internal {
+		t.Fatalf("expected the redirect to be returned, not followed; got %d, followed %v", resp.StatusCode, internal)
+	}
+
+	for address, public := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.100.100.200": false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+	} {
+		if got := IsPublicIP(net.ParseIP(address)); got != public {
+			t.Fatalf("IsPublicIP(%s) = %v, want %v", address, got, public)
+		}
+	}
+}
@@ -1,16 +1,51 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidToken is returned for tokens that fail verification.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrTokenRevoked is returned for valid tokens on the revocation list.
+var ErrTokenRevoked = errors.New("token revoked")
+
+// RevocationList reports whether the token with the given jti was revoked.
+type RevocationList interface {
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
 type AuthService struct {
-	jwtSecret []byte
-	tokenTTL  time.Duration
+	jwtSecret  []byte
+	allowHS256 bool
+	signingKey *Key
+	keys       map[string]*Key
+	tokenTTL   time.Duration
+	// Revocations, when set, is checked by Authenticate.
+	Revocations RevocationList
+}
+
+// Config selects how AuthService signs and verifies tokens.
+type Config struct {
+	// HS256Secret signs tokens when SigningKeyID is empty.
+	HS256Secret string
+	// AllowHS256 keeps accepting HS256 tokens while signing with a key, so
+	// tokens issued before a migration stay valid until they expire.
+	AllowHS256 bool
+	// SigningKeyID is the kid of the key in Keys that signs new tokens.
+	SigningKeyID string
+	// Keys verify tokens by kid. Keep the previous signing key here after a
+	// rotation until the tokens it signed have expired.
+	Keys           []*Key
+	AccessTokenTTL time.Duration
 }
 
 type Claims struct {
@@ -18,58 +53,157 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func NewAuthService(secret string, expirationHours int) *AuthService {
-	if expirationHours <= 0 {
-		expirationHours = 72
+// NewAuthService signs and verifies HS256 tokens with secret. Access tokens
+// are valid for accessTokenTTL, 15 minutes when it is not positive.
+func NewAuthService(secret string, accessTokenTTL time.Duration) *AuthService {
+	a, _ := NewAuthServiceWithConfig(Config{HS256Secret: secret, AllowHS256: true, AccessTokenTTL: accessTokenTTL})
+	return a
+}
+
+func NewAuthServiceWithConfig(cfg Config) (*AuthService, error) {
+	if cfg.AccessTokenTTL <= 0 {
+		cfg.AccessTokenTTL = 15 * time.Minute
 	}
-	return &AuthService{
-		jwtSecret: []byte(secret),
-		tokenTTL:  time.Duration(expirationHours) * time.Hour,
+	a := &AuthService{
+		jwtSecret:  []byte(cfg.HS256Secret),
+		allowHS256: cfg.AllowHS256 && cfg.HS256Secret != "",
+		keys:       make(map[string]*Key, len(cfg.Keys)),
+		tokenTTL:   cfg.AccessTokenTTL,
 	}
+	for _, key := range cfg.Keys {
+		if _, exists := a.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.ID)
+		}
+		a.keys[key.ID] = key
+	}
+	if cfg.SigningKeyID == "" {
+		if cfg.HS256Secret == "" {
+			return nil, errors.New("an HS256 secret or a signing key is required")
+		}
+		a.allowHS256 = true
+		return a, nil
+	}
+	key, ok := a.keys[cfg.SigningKeyID]
+	if !ok || key.Private == nil {
+		return nil, fmt.Errorf("signing key %q must be configured with its private key", cfg.SigningKeyID)
+	}
+	a.signingKey = key
+	return a, nil
+}
+
+// AccessTokenTTL is how long issued access tokens are valid.
+func (a *AuthService) AccessTokenTTL() time.Duration {
+	return a.tokenTTL
 }
 
-func (a *AuthService) GenerateJWT(userID string, role string) (string, error) {
+// GenerateJWT issues an access token with a unique jti and returns it with
+// its claims, so callers can record the jti and expiry.
+func (a *AuthService) GenerateJWT(userID string, role string) (string, *Claims, error) {
 	now := time.Now()
-	claims := Claims{
+	claims := &Claims{
 		Role: role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			Subject:   userID,
 			ExpiresAt: jwt.NewNumericDate(now.Add(a.tokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(a.jwtSecret)
+	var signed string
+	var err error
+	if a.signingKey != nil {
+		token := jwt.NewWithClaims(a.signingKey.signingMethod(), claims)
+		token.Header["kid"] = a.signingKey.ID
+		signed, err = token.SignedString(a.signingKey.Private)
+	} else {
+		signed, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.jwtSecret)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
-func (a *AuthService) ValidateJWT(tokenString string) (string, string, error) {
+// Authenticate verifies tokenString and rejects revoked tokens. Errors other
+// than ErrInvalidToken and ErrTokenRevoked mean the revocation list could not
+// be read.
+func (a *AuthService) Authenticate(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := a.ParseJWT(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if a.Revocations == nil {
+		return claims, nil
+	}
+	revoked, err := a.Revocations.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// ParseJWT verifies tokenString's signature, expiry and required claims.
+func (a *AuthService) ParseJWT(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&Claims{},
-		func(token *jwt.Token) (interface{}, error) {
-			if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-				return nil, errors.New("unexpected signing method")
-			}
-			return a.jwtSecret, nil
-		},
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		a.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), AlgorithmRS256, AlgorithmEdDSA}),
 	)
-	if err != nil || !token.Valid {
-		return "", "", errors.New("invalid token")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.Subject == "" || claims.Role == "" || claims.ID == "" || claims.ExpiresAt == nil {
+		// Tokens issued before jti was added cannot be revoked, so they are refused.
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// verificationKey picks the key for token by its alg and kid headers.
+func (a *AuthService) verificationKey(token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+	if alg == jwt.SigningMethodHS256.Alg() {
+		if !a.allowHS256 {
+			return nil, errors.New("HS256 tokens are no longer accepted")
+		}
+		return a.jwtSecret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := a.keys[kid]
 	if !ok {
-		return "", "", errors.New("invalid token claims")
+		return nil, fmt.Errorf("unknown key id %q", kid)
 	}
-	if claims.Subject == "" {
-		return "", "", errors.New("invalid token subject")
+	if key.Algorithm != alg {
+		return nil, fmt.Errorf("key %q does not sign %s", kid, alg)
 	}
-	if claims.Role == "" {
-		return "", "", errors.New("invalid token role")
+	if !key.usableAt(time.Now()) {
+		return nil, fmt.Errorf("key %q has been retired", kid)
 	}
+	return key.Public, nil
+}
 
-	return claims.Subject, claims.Role, nil
+// JWKS returns the public keys that currently verify tokens, for services
+// that verify tokens without being able to mint them.
+func (a *AuthService) JWKS() JWKSet {
+	now := time.Now()
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range a.keys {
+		if key.usableAt(now) {
+			set.Keys = append(set.Keys, key.jwk())
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
 }
 
 func (a *AuthService) HashPassword(password string) (string, error) {
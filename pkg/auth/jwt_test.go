@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+func newRSAKey(t *testing.T, id string) *Key {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	key, err := LoadKeyFile(id, writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private)))
+	if err != nil {
+		t.Fatalf("LoadKeyFile returned error: %v", err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T, id string) *Key {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	key, err := LoadKeyFile(id, writePEM(t, "PRIVATE KEY", der))
+	if err != nil {
+		t.Fatalf("LoadKeyFile returned error: %v", err)
+	}
+	return key
+}
+
+// publicOnly returns key as loaded from its public PEM file.
+func publicOnly(t *testing.T, key *Key) *Key {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.Public)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	public, err := LoadKeyFile(key.ID, writePEM(t, "PUBLIC KEY", der))
+	if err != nil {
+		t.Fatalf("LoadKeyFile returned error: %v", err)
+	}
+	if public.Private != nil {
+		t.Fatal("expected a verify-only key")
+	}
+	return public
+}
+
+func TestAuthServiceRotatesKeysWithOverlap(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	oldKey := newRSAKey(t, "2026-07")
+	newKey := newEd25519Key(t, "2026-10")
+
+	before, err := NewAuthServiceWithConfig(Config{SigningKeyID: oldKey.ID, Keys: []*Key{oldKey}})
+	if err != nil {
+		t.Fatalf("NewAuthServiceWithConfig returned error: %v", err)
+	}
+	oldToken, _, err := before.GenerateJWT("driver-1", "driver")
+	if err != nil {
+		t.Fatalf("GenerateJWT returned error: %v", err)
+	}
+
+	// After the rotation the old key only verifies, and only for a while.
+	verifyOnly := publicOnly(t, oldKey)
+	verifyOnly.VerifyUntil = time.Now().Add(time.Hour)
+	after, err := NewAuthServiceWithConfig(Config{SigningKeyID: newKey.ID, Keys: []*Key{newKey, verifyOnly}})
+	if err != nil {
+		t.Fatalf("NewAuthServiceWithConfig returned error: %v", err)
+	}
+	newToken, _, err := after.GenerateJWT("driver-1", "driver")
+	if err != nil {
+		t.Fatalf("GenerateJWT returned error: %v", err)
+	}
+	for _, token := range []string{oldToken, newToken} {
+		if claims, err := after.Authenticate(ctx, token); err != nil || claims.Subject != "driver-1" {
+			t.Fatalf("expected token to verify during the overlap, got %+v, %v", claims, err)
+		}
+	}
+	if _, err := before.Authenticate(ctx, newToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a token signed with an unknown kid to be rejected, got %v", err)
+	}
+
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "2026-07" || jwks.Keys[0].KeyType != "RSA" || jwks.Keys[1].KeyType != "OKP" || jwks.Keys[1].Curve != "Ed25519" {
+		t.Fatalf("unexpected JWKS: %+v", jwks)
+	}
+
+	verifyOnly.VerifyUntil = time.Now().Add(-time.Second)
+	if _, err := after.Authenticate(ctx, oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a retired key to stop verifying, got %v", err)
+	}
+	if jwks := after.JWKS(); len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "2026-10" {
+		t.Fatalf("expected the retired key to leave the JWKS, got %+v", jwks)
+	}
+}
+
+func TestAuthServiceAcceptsHS256OnlyDuringMigration(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	key := newRSAKey(t, "2026-10")
+	legacy := NewAuthService(testSecret, time.Minute)
+	legacyToken, _, err := legacy.GenerateJWT("user-1", "user")
+	if err != nil {
+		t.Fatalf("GenerateJWT returned error: %v", err)
+	}
+
+	migrating, err := NewAuthServiceWithConfig(Config{HS256Secret: testSecret, AllowHS256: true, SigningKeyID: key.ID, Keys: []*Key{key}})
+	if err != nil {
+		t.Fatalf("NewAuthServiceWithConfig returned error: %v", err)
+	}
+	if _, err := migrating.Authenticate(ctx, legacyToken); err != nil {
+		t.Fatalf("expected HS256 tokens to verify during the migration, got %v", err)
+	}
+
+	migrated, err := NewAuthServiceWithConfig(Config{SigningKeyID: key.ID, Keys: []*Key{key}})
+	if err != nil {
+		t.Fatalf("NewAuthServiceWithConfig returned error: %v", err)
+	}
+	if _, err := migrated.Authenticate(ctx, legacyToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected HS256 tokens to be rejected after the migration, got %v", err)
+	}
+	// An HS256 token forged with the public key as secret must not verify.
+	der, _ := x509.MarshalPKIXPublicKey(key.Public)
+	forger := NewAuthService(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), time.Minute)
+	forged, _, _ := forger.GenerateJWT("admin-1", "admin")
+	if _, err := migrated.Authenticate(ctx, forged); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a token forged with the public key to be rejected, got %v", err)
+	}
+}
+
+func TestNewAuthServiceWithConfigRequiresPrivateSigningKey(t *testing.T) {
+	t.Parallel()
+
+	key := publicOnly(t, newEd25519Key(t, "2026-10"))
+	if _, err := NewAuthServiceWithConfig(Config{SigningKeyID: key.ID, Keys: []*Key{key}}); err == nil {
+		t.Fatal("expected a verify-only signing key to be rejected")
+	}
+	if _, err := NewAuthServiceWithConfig(Config{}); err == nil {
+		t.Fatal("expected a config without secret or signing key to be rejected")
+	}
+}
@@ -0,0 +1,119 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Outbox assigns per-recipient sequence numbers and keeps recent messages so
+// reconnecting clients can replay what they missed. Implementations keep at
+// most a bounded number of unacked messages per recipient, dropping the oldest.
+//
+// A recipient's seqs belong to a stream. An outbox that can lose its
+// sequence, for example on restart, starts a new stream at seq 1, so clients
+// compare a message's Stream with the last one they saw before trusting its
+// seq. Callers pass the stream their seq came from; a seq from another
+// stream says nothing about the current one.
+type Outbox interface {
+	// Append stores msg for its recipient and returns it with Seq and Stream set.
+	Append(ctx context.Context, msg WebSocketMessage) (WebSocketMessage, error)
+	// Since returns the recipient's stored messages with Seq > afterSeq,
+	// oldest first. If stream is not the recipient's current stream, it
+	// returns every stored message.
+	Since(ctx context.Context, role, userID, stream string, afterSeq uint64) ([]WebSocketMessage, error)
+	// Ack discards the recipient's messages with Seq <= seq. It does nothing
+	// if stream is not the recipient's current stream.
+	Ack(ctx context.Context, role, userID, stream string, seq uint64) error
+}
+
+// MemoryOutbox is an in-process Outbox. Its contents are lost on restart and
+// are not shared between instances. Each mailbox gets a new stream when it is
+// created, so a restart, or a mailbox evicted once everything in it was
+// acked, starts a stream clients can tell apart from the old one.
+type MemoryOutbox struct {
+	maxPerRecipient int
+
+	mu         sync.Mutex
+	recipients map[string]*memoryMailbox
+}
+
+type memoryMailbox struct {
+	stream   string
+	lastSeq  uint64
+	messages []WebSocketMessage
+}
+
+// NewMemoryOutbox returns a MemoryOutbox keeping up to maxPerRecipient unacked messages per recipient.
+func NewMemoryOutbox(maxPerRecipient int) *MemoryOutbox {
+	return &MemoryOutbox{
+		maxPerRecipient: maxPerRecipient,
+		recipients:      make(map[string]*memoryMailbox),
+	}
+}
+
+func (o *MemoryOutbox) Append(ctx context.Context, msg WebSocketMessage) (WebSocketMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	key := msg.Role + ":" + msg.UserID
+	box := o.recipients[key]
+	if box == nil {
+		box = &memoryMailbox{stream: uuid.NewString()}
+		o.recipients[key] = box
+	}
+
+	box.lastSeq++
+	msg.Seq = box.lastSeq
+	msg.Stream = box.stream
+	box.messages = append(box.messages, msg)
+	if overflow := len(box.messages) - o.maxPerRecipient; overflow > 0 {
+		box.messages = append(box.messages[:0:0], box.messages[overflow:]...)
+	}
+	return msg, nil
+}
+
+func (o *MemoryOutbox) Since(ctx context.Context, role, userID, stream string, afterSeq uint64) ([]WebSocketMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	box := o.recipients[role+":"+userID]
+	if box == nil {
+		return nil, nil
+	}
+	if stream != box.stream {
+		afterSeq = 0
+	}
+	var out []WebSocketMessage
+	for _, msg := range box.messages {
+		if msg.Seq > afterSeq {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
+
+func (o *MemoryOutbox) Ack(ctx context.Context, role, userID, stream string, seq uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	key := role + ":" + userID
+	box := o.recipients[key]
+	if box == nil || stream != box.stream {
+		return nil
+	}
+	kept := box.messages[:0]
+	for _, msg := range box.messages {
+		if msg.Seq > seq {
+			kept = append(kept, msg)
+		}
+	}
+	box.messages = kept
+	// Nothing is left to replay, so drop the mailbox rather than keep one for
+	// every recipient ever messaged. Its next message starts a new stream.
+	if len(box.messages) == 0 {
+		delete(o.recipients, key)
+	}
+	return nil
+}
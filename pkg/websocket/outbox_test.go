@@ -0,0 +1,244 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMemoryOutboxSequencesPerRecipientAndBoundsBacklog(t *testing.T) {
+	outbox := NewMemoryOutbox(2)
+	ctx := context.Background()
+
+	var stream string
+	for i := 0; i < 3; i++ {
+		msg, err := outbox.Append(ctx, WebSocketMessage{UserID: "driver-1", Role: RoleDriver, Type: "new_booking_request"})
+		if err != nil {
+			t.Fatalf("Append returned error: %v", err)
+		}
+		if msg.Seq != uint64(i+1) {
+			t.Fatalf("expected seq %d, got %d", i+1, msg.Seq)
+		}
+		if i == 0 {
+			stream = msg.Stream
+		}
+		if msg.Stream == "" || msg.Stream != stream {
+			t.Fatalf("expected one stream for the recipient, got %q after %q", msg.Stream, stream)
+		}
+	}
+	other, _ := outbox.Append(ctx, WebSocketMessage{UserID: "driver-1", Role: RoleUser, Type: "booking_accepted"})
+	if other.Seq != 1 || other.Stream == stream {
+		t.Fatalf("expected a separate sequence per role and ID, got %d", other.Seq)
+	}
+
+	pending, _ := outbox.Since(ctx, RoleDriver, "driver-1", stream, 0)
+	if len(pending) != 2 || pending[0].Seq != 2 || pending[1].Seq != 3 {
+		t.Fatalf("expected the two newest messages, got %+v", pending)
+	}
+
+	if err := outbox.Ack(ctx, RoleDriver, "driver-1", stream, 2); err != nil {
+		t.Fatalf("Ack returned error: %v", err)
+	}
+	pending, _ = outbox.Since(ctx, RoleDriver, "driver-1", stream, 0)
+	if len(pending) != 1 || pending[0].Seq != 3 {
+		t.Fatalf("expected only seq 3 after ack, got %+v", pending)
+	}
+
+	next, _ := outbox.Append(ctx, WebSocketMessage{UserID: "driver-1", Role: RoleDriver, Type: "new_booking_request"})
+	if next.Seq != 4 {
+		t.Fatalf("sequence must keep increasing after acks, got %d", next.Seq)
+	}
+}
+
+func TestMemoryOutboxStartsANewStreamAfterEvictingAnAckedMailbox(t *testing.T) {
+	outbox := NewMemoryOutbox(10)
+	ctx := context.Background()
+
+	var last WebSocketMessage
+	for i := 0; i < 3; i++ {
+		last, _ = outbox.Append(ctx, WebSocketMessage{UserID: "driver-1", Role: RoleDriver, Type: "new_booking_request"})
+	}
+	if err := outbox.Ack(ctx, RoleDriver, "driver-1", last.Stream, last.Seq); err != nil {
+		t.Fatalf("Ack returned error: %v", err)
+	}
+	if len(outbox.recipients) != 0 {
+		t.Fatalf("expected the empty mailbox to be evicted, got %d mailboxes", len(outbox.recipients))
+	}
+
+	// Seqs restart, like after a restart, so the new stream tells the client
+	// that seq 1 is new rather than one it already handled.
+	next, _ := outbox.Append(ctx, WebSocketMessage{UserID: "driver-1", Role: RoleDriver, Type: "status_update"})
+	if next.Seq != 1 || next.Stream == "" || next.Stream == last.Stream {
+		t.Fatalf("expected seq 1 of a new stream, got %+v", next)
+	}
+	if pending, _ := outbox.Since(ctx, RoleDriver, "driver-1", last.Stream, last.Seq); len(pending) != 1 || pending[0].Seq != 1 {
+		t.Fatalf("expected a last_seq from the old stream to replay everything, got %+v", pending)
+	}
+	if err := outbox.Ack(ctx, RoleDriver, "driver-1", last.Stream, last.Seq); err != nil {
+		t.Fatalf("Ack returned error: %v", err)
+	}
+	if pending, _ := outbox.Since(ctx, RoleDriver, "driver-1", next.Stream, 0); len(pending) != 1 {
+		t.Fatalf("expected an ack from the old stream to be ignored, got %+v", pending)
+	}
+}
+
+func TestWebSocketHubReplaysMissedMessagesOnReconnect(t *testing.T) {
+	outbox := NewMemoryOutbox(10)
+	hub := NewWebSocketHubWithConfig(HubConfig{Outbox: outbox})
+	go hub.Run()
+
+	// Published while the driver is offline.
+	for i := 0; i < 3; i++ {
+		if err := hub.Broadcast(WebSocketMessage{UserID: "driver-1", Role: RoleDriver, Type: "new_booking_request"}); err != nil {
+			t.Fatalf("Broadcast returned error: %v", err)
+		}
+	}
+	if !waitFor(t, 2*time.Second, func() bool {
+		pending, _ := outbox.Since(context.Background(), RoleDriver, "driver-1", "", 0)
+		return len(pending) == 3
+	}) {
+		t.Fatal("messages were not stored in the outbox")
+	}
+
+	pending, _ := outbox.Since(context.Background(), RoleDriver, "driver-1", "", 0)
+	stream := pending[0].Stream
+
+	serverConn, clientConn := connPair(t)
+	hub.RegisterClient("driver-1", RoleDriver, serverConn)
+	if err := hub.Replay(context.Background(), "driver-1", RoleDriver, serverConn, stream, 1); err != nil {
+		t.Fatalf("Replay returned error: %v", err)
+	}
+
+	for _, want := range []uint64{2, 3} {
+		_ = clientConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg WebSocketMessage
+		if err := clientConn.ReadJSON(&msg); err != nil {
+			t.Fatalf("did not receive replayed message: %v", err)
+		}
+		if msg.Seq != want || msg.Type != "new_booking_request" {
+			t.Fatalf("expected replayed seq %d, got %+v", want, msg)
+		}
+	}
+
+	if err := hub.Ack(context.Background(), "driver-1", RoleDriver, stream, 3); err != nil {
+		t.Fatalf("Ack returned error: %v", err)
+	}
+	if pending, _ := outbox.Since(context.Background(), RoleDriver, "driver-1", stream, 0); len(pending) != 0 {
+		t.Fatalf("expected ack to trim the outbox, got %+v", pending)
+	}
+}
+
+func TestWebSocketHubDoesNotSequenceAdminMirrors(t *testing.T) {
+	hub := NewWebSocketHubWithConfig(HubConfig{Outbox: NewMemoryOutbox(10)})
+	go hub.Run()
+
+	driverServer, driverClient := connPair(t)
+	adminServer, adminClient := connPair(t)
+	hub.RegisterClient("driver-1", RoleDriver, driverServer)
+	hub.RegisterClient("admin-1", RoleAdmin, adminServer, TopicAll)
+
+	if err := hub.Broadcast(WebSocketMessage{UserID: "driver-1", Role: RoleDriver, Type: "new_booking_request", Topics: []string{"bookings.b-1"}}); err != nil {
+		t.Fatalf("Broadcast returned error: %v", err)
+	}
+
+	var direct, mirror WebSocketMessage
+	_ = driverClient.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := driverClient.ReadJSON(&direct); err != nil {
+		t.Fatalf("driver did not receive message: %v", err)
+	}
+	_ = adminClient.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := adminClient.ReadJSON(&mirror); err != nil {
+		t.Fatalf("admin did not receive mirror: %v", err)
+	}
+	if direct.Seq != 1 || mirror.Seq != 0 {
+		t.Fatalf("expected seq only on the direct copy, got direct=%d mirror=%d", direct.Seq, mirror.Seq)
+	}
+}
+
+// blockingOutbox holds every Append for driver-1 until release is closed.
+type blockingOutbox struct {
+	*MemoryOutbox
+	release chan struct{}
+}
+
+func (o *blockingOutbox) Append(ctx context.Context, msg WebSocketMessage) (WebSocketMessage, error) {
+	if msg.UserID == "driver-1" {
+		select {
+		case <-o.release:
+		case <-ctx.Done():
+			return msg, ctx.Err()
+		}
+	}
+	return o.MemoryOutbox.Append(ctx, msg)
+}
+
+func TestWebSocketHubDoesNotWaitOnOutbox(t *testing.T) {
+	outbox := &blockingOutbox{MemoryOutbox: NewMemoryOutbox(10), release: make(chan struct{})}
+	defer close(outbox.release)
+	hub := NewWebSocketHubWithConfig(HubConfig{Outbox: outbox, EphemeralTypes: []string{"driver_location"}})
+	go hub.Run()
+
+	userServer, userClient := connPair(t)
+	hub.RegisterClient("user-1", RoleUser, userServer)
+
+	// driver-1's writes block; user-1 must not wait behind them.
+	if sequencerIndex(WebSocketMessage{UserID: "driver-1", Role: RoleDriver}, outboxWorkers) ==
+		sequencerIndex(WebSocketMessage{UserID: "user-1", Role: RoleUser}, outboxWorkers) {
+		t.Fatal("test recipients must use different sequencers")
+	}
+	if err := hub.Broadcast(WebSocketMessage{UserID: "driver-1", Role: RoleDriver, Type: "new_booking_request"}); err != nil {
+		t.Fatalf("Broadcast returned error: %v", err)
+	}
+	if err := hub.Broadcast(WebSocketMessage{UserID: "user-1", Role: RoleUser, Type: "booking_accepted"}); err != nil {
+		t.Fatalf("Broadcast returned error: %v", err)
+	}
+	if err := hub.Broadcast(WebSocketMessage{UserID: "user-1", Role: RoleUser, Type: "driver_location"}); err != nil {
+		t.Fatalf("Broadcast returned error: %v", err)
+	}
+
+	received := map[string]uint64{}
+	for i := 0; i < 2; i++ {
+		_ = userClient.SetReadDeadline(time.Now().Add(time.Second))
+		var msg WebSocketMessage
+		if err := userClient.ReadJSON(&msg); err != nil {
+			t.Fatalf("user-1 was held up by another recipient's outbox write: %v", err)
+		}
+		received[msg.Type] = msg.Seq
+	}
+	if received["booking_accepted"] != 1 || received["driver_location"] != 0 {
+		t.Fatalf("expected only booking_accepted to be sequenced, got %+v", received)
+	}
+	if pending, _ := outbox.Since(context.Background(), RoleUser, "user-1", "", 0); len(pending) != 1 || pending[0].Type != "booking_accepted" {
+		t.Fatalf("expected ephemeral messages to stay out of the outbox, got %+v", pending)
+	}
+}
+
+func TestWebSocketHubDisconnectsClientStalledDuringReplay(t *testing.T) {
+	outbox := NewMemoryOutbox(10)
+	hub := NewWebSocketHubWithConfig(HubConfig{Outbox: outbox, WriteTimeout: 100 * time.Millisecond})
+	for i := 0; i < 3; i++ {
+		_, _ = outbox.Append(context.Background(), WebSocketMessage{UserID: "user-1", Role: RoleUser, Type: "booking_accepted"})
+	}
+
+	// A client without a write pump never drains its one-slot queue.
+	serverConn, _ := connPair(t)
+	c := newTestClient(serverConn, 1)
+	hub.clients[RoleUser] = map[string]map[*websocket.Conn]*client{"user-1": {serverConn: c}}
+
+	done := make(chan error, 1)
+	go func() { done <- hub.Replay(context.Background(), "user-1", RoleUser, serverConn, "", 0) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrReplayStalled) {
+			t.Fatalf("expected ErrReplayStalled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Replay blocked on a client that stopped reading")
+	}
+	if hub.hasUserClient("user-1") {
+		t.Fatal("expected the stalled client to be disconnected")
+	}
+}
@@ -0,0 +1,60 @@
+package websocket
+
+import (
+	"errors"
+	"strings"
+)
+
+// Topics use NATS subject syntax: dot-separated tokens, where "*" in a
+// subscription matches exactly one token and a trailing ">" matches one or
+// more tokens. A pattern such as "driver.*.location" can therefore be used
+// unchanged as a NATS subscription subject.
+const (
+	topicSeparator   = "."
+	topicWildcardOne = "*"
+	topicWildcardAll = ">"
+)
+
+// TopicAll subscribes an admin connection to every topic.
+const TopicAll = topicWildcardAll
+
+var ErrInvalidTopic = errors.New("invalid topic")
+
+// ValidateTopicPattern checks that pattern is a well-formed subscription.
+func ValidateTopicPattern(pattern string) error {
+	if pattern == "" {
+		return ErrInvalidTopic
+	}
+	tokens := strings.Split(pattern, topicSeparator)
+	for i, token := range tokens {
+		if token == "" || strings.ContainsAny(token, " \t\r\n") {
+			return ErrInvalidTopic
+		}
+		if token == topicWildcardAll && i != len(tokens)-1 {
+			return ErrInvalidTopic
+		}
+		if len(token) > 1 && strings.ContainsAny(token, topicWildcardOne+topicWildcardAll) {
+			return ErrInvalidTopic
+		}
+	}
+	return nil
+}
+
+// MatchTopic reports whether topic is matched by the subscription pattern.
+func MatchTopic(pattern, topic string) bool {
+	patternTokens := strings.Split(pattern, topicSeparator)
+	topicTokens := strings.Split(topic, topicSeparator)
+
+	for i, token := range patternTokens {
+		if token == topicWildcardAll {
+			return len(topicTokens) > i
+		}
+		if i >= len(topicTokens) {
+			return false
+		}
+		if token != topicWildcardOne && token != topicTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(topicTokens)
+}
@@ -0,0 +1,41 @@
+package websocket
+
+import "testing"
+
+func TestMatchTopicFollowsNATSWildcards(t *testing.T) {
+	cases := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"drivers.status", "drivers.status", true},
+		{"drivers.status", "drivers.status.extra", false},
+		{"bookings.*", "bookings.b-1", true},
+		{"bookings.*", "bookings", false},
+		{"bookings.*", "bookings.b-1.location", false},
+		{"driver.*.location", "driver.d-1.location", true},
+		{"driver.*.location", "driver.d-1.status", false},
+		{"zone.>", "zone.north", true},
+		{"zone.>", "zone.north.depot", true},
+		{"zone.>", "zone", false},
+		{">", "bookings.b-1", true},
+	}
+	for _, tc := range cases {
+		if got := MatchTopic(tc.pattern, tc.topic); got != tc.want {
+			t.Fatalf("MatchTopic(%q, %q) = %v, want %v", tc.pattern, tc.topic, got, tc.want)
+		}
+	}
+}
+
+func TestValidateTopicPattern(t *testing.T) {
+	for _, pattern := range []string{"drivers.status", "bookings.*", "driver.*.location", "zone.>", ">"} {
+		if err := ValidateTopicPattern(pattern); err != nil {
+			t.Fatalf("expected %q to be valid, got %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"", "bookings.", ".bookings", "zone.>.north", "bookings.b*", "zone.north depot"} {
+		if err := ValidateTopicPattern(pattern); err == nil {
+			t.Fatalf("expected %q to be rejected", pattern)
+		}
+	}
+}
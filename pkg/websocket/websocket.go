@@ -1,123 +1,887 @@
 package websocket
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"hash/fnv"
+	"net"
+	"sort"
 	"sync"
+	"time"
+
+	"logi/internal/utils"
 
 	"github.com/gorilla/websocket"
 )
 
-var ErrBroadcastQueueFull = errors.New("websocket broadcast queue is full")
+var (
+	ErrBroadcastQueueFull = errors.New("websocket broadcast queue is full")
+	ErrReplayStalled      = errors.New("websocket client stopped reading during replay")
+)
+
+// Recipient roles. Every connection is registered under exactly one role.
+const (
+	RoleUser   = "user"
+	RoleDriver = "driver"
+	RoleAdmin  = "admin"
+)
+
+// Slow consumer policies applied when a connection's send queue is full.
+const (
+	SlowConsumerDropOldest = "drop_oldest"
+	SlowConsumerDisconnect = "disconnect"
+)
+
+// HubConfig controls per-connection buffering, write behaviour and keepalive.
+type HubConfig struct {
+	SendQueueSize      int
+	WriteTimeout       time.Duration
+	SlowConsumerPolicy string
+	// PingInterval must be shorter than PongWait so a healthy peer always
+	// answers before its read deadline passes.
+	PingInterval   time.Duration
+	PongWait       time.Duration
+	MaxMessageSize int64
+	// SessionCheckInterval is how often the credentials of a connection
+	// passed to Authorize are checked again between inbound frames.
+	SessionCheckInterval time.Duration
+	// Outbox, when set, sequences and stores messages addressed to a single
+	// recipient so they can be replayed after a reconnect.
+	Outbox Outbox
+	// EphemeralTypes lists message types that are only worth delivering live,
+	// such as location updates. They are never stored in the Outbox.
+	EphemeralTypes []string
+}
+
+// DefaultHubConfig returns the settings used by NewWebSocketHub.
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		SendQueueSize:        64,
+		WriteTimeout:         10 * time.Second,
+		SlowConsumerPolicy:   SlowConsumerDropOldest,
+		PingInterval:         54 * time.Second,
+		PongWait:             60 * time.Second,
+		MaxMessageSize:       4096,
+		SessionCheckInterval: time.Minute,
+	}
+}
 
 type WebSocketHub struct {
-	userClients  map[string]map[*websocket.Conn]struct{}
-	adminClients map[string]map[*websocket.Conn]struct{}
-	clientsMu    sync.RWMutex
-	broadcast    chan WebSocketMessage
+	// clients is keyed by role, then user/driver/admin ID.
+	clients   map[string]map[string]map[*websocket.Conn]*client
+	clientsMu sync.RWMutex
+	// topicRefs counts the admin connections subscribed to each pattern.
+	topicRefs map[string]int
+	presence  PresenceListener
+	broadcast chan delivery
+	config    HubConfig
+	// sequencers store messages in the Outbox off the fan-out loop. Each
+	// recipient always maps to the same one, which keeps its messages in order.
+	sequencers []chan sequencing
+	ephemeral  map[string]struct{}
+}
+
+// PresenceListener is told when the hub starts or stops needing messages for
+// a recipient or a topic pattern, so a transport shared between instances can
+// subscribe to just what the local connections need. Calls are made in order
+// with the hub's client lock held and must not call back into the hub.
+type PresenceListener interface {
+	RecipientOnline(role, userID string)
+	RecipientOffline(role, userID string)
+	TopicSubscribed(pattern string)
+	TopicUnsubscribed(pattern string)
+}
+
+// Delivery scopes for messages queued on the hub.
+const (
+	// deliverAll reaches the addressed connections and subscribed admins.
+	deliverAll = iota
+	// deliverDirect reaches the addressed connections only.
+	deliverDirect
+	// deliverMirror reaches the admins subscribed to one topic pattern.
+	deliverMirror
+)
+
+type delivery struct {
+	msg     WebSocketMessage
+	scope   int
+	topic   string
+	pattern string
 }
 
+// sequencing is a message waiting for its Outbox seq before delivery.
+type sequencing struct {
+	msg        WebSocketMessage
+	scope      int
+	mirrorData []byte
+}
+
+const (
+	// outboxWorkers is the number of sequencers; outboxQueueSize bounds each.
+	outboxWorkers   = 8
+	outboxQueueSize = 256
+	// outboxTimeout bounds one Outbox call.
+	outboxTimeout = 5 * time.Second
+)
+
+// WebSocketMessage is addressed by Role plus UserID. A message for RoleAdmin
+// without a UserID goes to every admin. Topics tag the message for admin
+// subscriptions. Seq is the recipient's sequence number in Stream when the hub
+// has an Outbox; admin broadcasts and topic mirrors carry neither.
 type WebSocketMessage struct {
 	UserID  string      `json:"user_id"`
+	Role    string      `json:"role,omitempty"`
+	Seq     uint64      `json:"seq,omitempty"`
+	Stream  string      `json:"stream,omitempty"`
 	Type    string      `json:"type"`
+	Topics  []string    `json:"topics,omitempty"`
 	Payload interface{} `json:"payload"`
 }
 
-type clientConnection struct {
+// client owns a connection's send queue. Only its write pump writes to conn.
+type client struct {
 	userID string
 	role   string
 	conn   *websocket.Conn
+	send   chan []byte
+	done   chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	// topics holds the subscription patterns an admin connection mirrors.
+	topics map[string]struct{}
+	// check, when set by Authorize, revalidates the connection's credentials.
+	check SessionCheck
 }
 
 func NewWebSocketHub() *WebSocketHub {
-	return &WebSocketHub{
-		userClients:  make(map[string]map[*websocket.Conn]struct{}),
-		adminClients: make(map[string]map[*websocket.Conn]struct{}),
-		broadcast:    make(chan WebSocketMessage, 256),
+	return NewWebSocketHubWithConfig(DefaultHubConfig())
+}
+
+// NewWebSocketHubWithConfig returns a hub using cfg; zero fields fall back to the defaults.
+func NewWebSocketHubWithConfig(cfg HubConfig) *WebSocketHub {
+	defaults := DefaultHubConfig()
+	if cfg.SendQueueSize <= 0 {
+		cfg.SendQueueSize = defaults.SendQueueSize
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = defaults.WriteTimeout
+	}
+	if cfg.SlowConsumerPolicy == "" {
+		cfg.SlowConsumerPolicy = defaults.SlowConsumerPolicy
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = defaults.PingInterval
+	}
+	if cfg.PongWait <= 0 {
+		cfg.PongWait = defaults.PongWait
 	}
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = defaults.MaxMessageSize
+	}
+	if cfg.SessionCheckInterval <= 0 {
+		cfg.SessionCheckInterval = defaults.SessionCheckInterval
+	}
+	hub := &WebSocketHub{
+		clients:   make(map[string]map[string]map[*websocket.Conn]*client),
+		topicRefs: make(map[string]int),
+		broadcast: make(chan delivery, 256),
+		config:    cfg,
+		ephemeral: make(map[string]struct{}, len(cfg.EphemeralTypes)),
+	}
+	for _, messageType := range cfg.EphemeralTypes {
+		hub.ephemeral[messageType] = struct{}{}
+	}
+	if cfg.Outbox != nil {
+		hub.sequencers = make([]chan sequencing, outboxWorkers)
+		for i := range hub.sequencers {
+			hub.sequencers[i] = make(chan sequencing, outboxQueueSize)
+		}
+	}
+	return hub
 }
 
+// Run fans broadcast messages out to the send queues of matching connections.
+// It never writes to a socket or waits on the Outbox itself, so one slow
+// client or a slow store cannot stall the rest.
 func (hub *WebSocketHub) Run() {
-	for msg := range hub.broadcast {
-		adminSnapshot, userSnapshot := hub.snapshotConnections(msg.UserID)
+	for _, queue := range hub.sequencers {
+		go hub.sequence(queue)
+	}
+	defer func() {
+		for _, queue := range hub.sequencers {
+			close(queue)
+		}
+	}()
+
+	for d := range hub.broadcast {
+		msg := normalizeRole(d.msg)
+		mirror := msg
+		mirror.Seq, mirror.Stream = 0, ""
+		mirrorData, err := json.Marshal(mirror)
+		if err != nil {
+			utils.ErrorBackground("failed to encode websocket message", "type", msg.Type, "error", err)
+			continue
+		}
+
+		if d.scope == deliverMirror {
+			for _, c := range hub.mirrorRecipients(msg, d.topic, d.pattern) {
+				hub.enqueue(c, mirrorData)
+			}
+			continue
+		}
+
+		// Messages relayed from another instance may already have been
+		// sequenced by the publisher.
+		if hub.sequencers != nil && msg.UserID != "" && msg.Seq == 0 && !hub.isEphemeral(msg.Type) {
+			select {
+			case hub.sequencers[sequencerIndex(msg, len(hub.sequencers))] <- sequencing{msg: msg, scope: d.scope, mirrorData: mirrorData}:
+				continue
+			default:
+				// Deliver live without a seq rather than wait for the store.
+				utils.IncrementCounter("websocket_outbox_skipped", 1)
+			}
+		}
+		hub.deliver(msg, d.scope, mirrorData)
+	}
+}
+
+// sequence stores each queued message in the Outbox, then delivers it.
+func (hub *WebSocketHub) sequence(queue <-chan sequencing) {
+	for item := range queue {
+		msg := item.msg
+		ctx, cancel := context.WithTimeout(context.Background(), outboxTimeout)
+		sequenced, err := hub.config.Outbox.Append(ctx, msg)
+		cancel()
+		if err != nil {
+			utils.IncrementCounter("websocket_outbox_errors", 1)
+			utils.ErrorBackground("failed to store websocket message in outbox", "user_id", msg.UserID, "role", msg.Role, "type", msg.Type, "error", err)
+		} else {
+			msg = sequenced
+		}
+		hub.deliver(msg, item.scope, item.mirrorData)
+	}
+}
+
+// deliver queues msg for its addressed connections and, unless scope is
+// deliverDirect, for the admins mirroring it.
+func (hub *WebSocketHub) deliver(msg WebSocketMessage, scope int, mirrorData []byte) {
+	directData := mirrorData
+	if msg.Seq != 0 {
+		var err error
+		if directData, err = json.Marshal(msg); err != nil {
+			directData = mirrorData
+		}
+	}
+
+	direct, mirrors := hub.recipients(msg)
+	for _, c := range direct {
+		hub.enqueue(c, directData)
+	}
+	if scope == deliverDirect {
+		return
+	}
+	for _, c := range mirrors {
+		hub.enqueue(c, mirrorData)
+	}
+}
+
+func (hub *WebSocketHub) isEphemeral(messageType string) bool {
+	_, ok := hub.ephemeral[messageType]
+	return ok
+}
+
+func sequencerIndex(msg WebSocketMessage, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(msg.Role + ":" + msg.UserID))
+	return int(h.Sum32() % uint32(n))
+}
+
+// normalizeRole keeps the old convention for messages without a role: an
+// empty UserID means admins, anything else a user.
+func normalizeRole(msg WebSocketMessage) WebSocketMessage {
+	if msg.Role == "" {
+		msg.Role = RoleUser
+		if msg.UserID == "" {
+			msg.Role = RoleAdmin
+		}
+	}
+	return msg
+}
+
+// recipients returns the connections of the addressed user, driver or admin
+// (every admin for an admin broadcast), and separately the admins mirroring
+// the message type through a topic subscription.
+func (hub *WebSocketHub) recipients(msg WebSocketMessage) (direct []*client, mirrors []*client) {
+	msg = normalizeRole(msg)
+
+	hub.clientsMu.RLock()
+	defer hub.clientsMu.RUnlock()
 
-		for _, client := range adminSnapshot {
-			if err := client.conn.WriteJSON(msg); err != nil {
-				hub.UnregisterClient(client.userID, client.role, client.conn)
+	if msg.Role == RoleAdmin && msg.UserID == "" {
+		for _, conns := range hub.clients[RoleAdmin] {
+			for _, c := range conns {
+				direct = append(direct, c)
 			}
 		}
+		return direct, nil
+	}
 
-		for _, client := range userSnapshot {
-			if err := client.conn.WriteJSON(msg); err != nil {
-				hub.UnregisterClient(client.userID, client.role, client.conn)
+	for _, c := range hub.clients[msg.Role][msg.UserID] {
+		direct = append(direct, c)
+	}
+	if msg.Role == RoleAdmin {
+		return direct, nil
+	}
+	for _, conns := range hub.clients[RoleAdmin] {
+		for _, c := range conns {
+			if c.subscribed(msg.Topics) {
+				mirrors = append(mirrors, c)
 			}
 		}
 	}
+	return direct, mirrors
 }
 
-func (hub *WebSocketHub) snapshotConnections(userID string) ([]clientConnection, []clientConnection) {
+// mirrorRecipients returns the admins for which pattern is the first of their
+// subscriptions matching msg's first matching topic. A message relayed once
+// per topic and subscribed pattern thus reaches each admin exactly once.
+func (hub *WebSocketHub) mirrorRecipients(msg WebSocketMessage, topic string, pattern string) []*client {
+	if msg.Role == RoleAdmin {
+		return nil
+	}
+
 	hub.clientsMu.RLock()
 	defer hub.clientsMu.RUnlock()
 
-	adminSnapshot := make([]clientConnection, 0, len(hub.adminClients))
-	for id, conns := range hub.adminClients {
-		for conn := range conns {
-			adminSnapshot = append(adminSnapshot, clientConnection{userID: id, role: "admin", conn: conn})
+	var mirrors []*client
+	for _, conns := range hub.clients[RoleAdmin] {
+		for _, c := range conns {
+			if matchedTopic, matchedPattern, ok := c.firstMatch(msg.Topics); ok && matchedTopic == topic && matchedPattern == pattern {
+				mirrors = append(mirrors, c)
+			}
+		}
+	}
+	return mirrors
+}
+
+func (c *client) subscribed(topics []string) bool {
+	_, _, ok := c.firstMatch(topics)
+	return ok
+}
+
+// firstMatch returns the first of topics matched by the client's
+// subscriptions, along with the lowest sorting pattern that matches it.
+func (c *client) firstMatch(topics []string) (topic string, pattern string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, topic := range topics {
+		for candidate := range c.topics {
+			if MatchTopic(candidate, topic) && (!ok || candidate < pattern) {
+				pattern, ok = candidate, true
+			}
+		}
+		if ok {
+			return topic, pattern, true
+		}
+	}
+	return "", "", false
+}
+
+// enqueue adds data to the client's send queue, applying the slow consumer
+// policy when the queue is full.
+func (hub *WebSocketHub) enqueue(c *client, data []byte) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+
+	select {
+	case c.send <- data:
+		utils.AddGauge("websocket_send_queue_depth", 1)
+		c.mu.Unlock()
+		return
+	default:
+	}
+
+	if hub.config.SlowConsumerPolicy == SlowConsumerDisconnect {
+		c.mu.Unlock()
+		utils.IncrementCounter("websocket_slow_consumer_disconnects", 1)
+		utils.WarnBackground("disconnecting slow websocket consumer", "user_id", c.userID, "role", c.role, "queue_size", cap(c.send))
+		hub.UnregisterClient(c.userID, c.role, c.conn)
+		return
+	}
+
+	// Drop the oldest queued message to make room. The write pump may have
+	// drained the queue in the meantime, in which case nothing is dropped.
+	select {
+	case <-c.send:
+		utils.AddGauge("websocket_send_queue_depth", -1)
+		utils.IncrementCounter("websocket_dropped_messages", 1)
+	default:
+	}
+	select {
+	case c.send <- data:
+		utils.AddGauge("websocket_send_queue_depth", 1)
+	default:
+		utils.IncrementCounter("websocket_dropped_messages", 1)
+	}
+	c.mu.Unlock()
+}
+
+// writePump delivers queued messages and keepalive pings to the connection
+// until the client is closed or a write fails.
+func (hub *WebSocketHub) writePump(c *client) {
+	ticker := time.NewTicker(hub.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			utils.AddGauge("websocket_send_queue_depth", -1)
+			_ = c.conn.SetWriteDeadline(time.Now().Add(hub.config.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				utils.IncrementCounter("websocket_write_errors", 1)
+				hub.UnregisterClient(c.userID, c.role, c.conn)
+				return
+			}
+			utils.IncrementCounter("websocket_messages_sent", 1)
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(hub.config.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				utils.IncrementCounter("websocket_write_errors", 1)
+				hub.UnregisterClient(c.userID, c.role, c.conn)
+				return
+			}
+		}
+	}
+}
+
+// Session identifies the connection an inbound frame arrived on.
+type Session struct {
+	hub    *WebSocketHub
+	userID string
+	role   string
+	conn   *websocket.Conn
+}
+
+func (s *Session) UserID() string { return s.userID }
+
+func (s *Session) Role() string { return s.role }
+
+// Subscribe adds topic patterns to this connection. Only admin connections
+// mirror topics.
+func (s *Session) Subscribe(patterns ...string) error {
+	return s.hub.updateTopics(s, patterns, true)
+}
+
+// Unsubscribe removes topic patterns from this connection.
+func (s *Session) Unsubscribe(patterns ...string) error {
+	return s.hub.updateTopics(s, patterns, false)
+}
+
+// Topics returns this connection's current subscription patterns.
+func (s *Session) Topics() []string {
+	c, ok := s.hub.lookup(s.userID, s.role, s.conn)
+	if !ok {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// InboundHandler handles one frame sent by a client and returns the frame to
+// send back to the same connection, or nil for no reply.
+type InboundHandler func(session *Session, data []byte) interface{}
+
+// ReadPump reads from a registered connection until it fails, then
+// unregisters it. Each pong extends the read deadline, so a peer that stops
+// answering pings is reaped after PongWait. Frames larger than
+// MaxMessageSize close the connection. Frames are passed to handler in
+// order; a nil handler discards them. On an authorized connection each frame
+// waits for the session check, and revoked credentials close the connection.
+func (hub *WebSocketHub) ReadPump(userID string, role string, conn *websocket.Conn, handler InboundHandler) {
+	defer hub.UnregisterClient(userID, role, conn)
+	session := &Session{hub: hub, userID: userID, role: role, conn: conn}
+
+	conn.SetReadLimit(hub.config.MaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(hub.config.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(hub.config.PongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				utils.IncrementCounter("websocket_dead_connections_reaped", 1)
+				utils.InfoBackground("reaping websocket connection that missed pongs", "user_id", userID, "role", role)
+			} else if errors.Is(err, websocket.ErrReadLimit) {
+				utils.WarnBackground("closing websocket connection that exceeded read limit", "user_id", userID, "role", role)
+			}
+			return
+		}
+		if handler == nil {
+			continue
+		}
+		valid, err := hub.checkSession(userID, role, conn)
+		if err != nil {
+			// Fail closed: a command is only run for credentials known to be valid.
+			utils.IncrementCounter("websocket_session_check_errors", 1)
+			utils.WarnBackground("discarding websocket frame, session could not be checked", "user_id", userID, "role", role, "error", err)
+			continue
+		}
+		if !valid {
+			utils.IncrementCounter("websocket_sessions_revoked", 1)
+			utils.InfoBackground("closing websocket connection with revoked credentials", "user_id", userID, "role", role)
+			return
+		}
+
+		if reply := handler(session, data); reply != nil {
+			hub.reply(userID, role, conn, reply)
 		}
 	}
+}
 
-	userSnapshot := make([]clientConnection, 0)
-	if userID != "" {
-		for conn := range hub.userClients[userID] {
-			userSnapshot = append(userSnapshot, clientConnection{userID: userID, role: "user", conn: conn})
+// SessionCheck reports whether the credentials a connection was opened with
+// are still valid. It returns false once they are revoked or expired, and an
+// error when that cannot be determined.
+type SessionCheck func(ctx context.Context) (bool, error)
+
+// sessionCheckTimeout bounds one SessionCheck call.
+const sessionCheckTimeout = 5 * time.Second
+
+// Authorize ties a registered connection to the credentials it was opened
+// with. The connection is closed at expiresAt, and when check reports the
+// credentials invalid, which is asked before each inbound frame and every
+// SessionCheckInterval. A zero expiresAt or nil check disables that part.
+func (hub *WebSocketHub) Authorize(userID string, role string, conn *websocket.Conn, expiresAt time.Time, check SessionCheck) {
+	c, ok := hub.lookup(userID, role, conn)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	c.check = check
+	c.mu.Unlock()
+	go hub.watchSession(c, expiresAt)
+}
+
+// watchSession closes c when its credentials expire or are revoked.
+func (hub *WebSocketHub) watchSession(c *client, expiresAt time.Time) {
+	var expired <-chan time.Time
+	if !expiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(expiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	ticker := time.NewTicker(hub.config.SessionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-expired:
+			utils.IncrementCounter("websocket_sessions_expired", 1)
+			utils.InfoBackground("closing websocket connection with expired token", "user_id", c.userID, "role", c.role)
+			hub.UnregisterClient(c.userID, c.role, c.conn)
+			return
+		case <-ticker.C:
+			valid, err := hub.checkSession(c.userID, c.role, c.conn)
+			if err != nil {
+				// Keep the connection: it only receives until the next check.
+				utils.IncrementCounter("websocket_session_check_errors", 1)
+				utils.WarnBackground("failed to check websocket session", "user_id", c.userID, "role", c.role, "error", err)
+				continue
+			}
+			if !valid {
+				utils.IncrementCounter("websocket_sessions_revoked", 1)
+				utils.InfoBackground("closing websocket connection with revoked credentials", "user_id", c.userID, "role", c.role)
+				hub.UnregisterClient(c.userID, c.role, c.conn)
+				return
+			}
 		}
 	}
+}
 
-	return adminSnapshot, userSnapshot
+// checkSession runs the connection's SessionCheck, if any. A connection that
+// is no longer registered is reported invalid.
+func (hub *WebSocketHub) checkSession(userID string, role string, conn *websocket.Conn) (bool, error) {
+	c, ok := hub.lookup(userID, role, conn)
+	if !ok {
+		return false, nil
+	}
+	c.mu.Lock()
+	check := c.check
+	c.mu.Unlock()
+	if check == nil {
+		return true, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionCheckTimeout)
+	defer cancel()
+	return check(ctx)
 }
 
-func (hub *WebSocketHub) RegisterClient(userID string, role string, conn *websocket.Conn) {
+// reply queues a frame for one connection, bypassing role routing.
+func (hub *WebSocketHub) reply(userID string, role string, conn *websocket.Conn, frame interface{}) {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		utils.ErrorBackground("failed to encode websocket reply", "user_id", userID, "role", role, "error", err)
+		return
+	}
+
+	if c, ok := hub.lookup(userID, role, conn); ok {
+		hub.enqueue(c, data)
+	}
+}
+
+func (hub *WebSocketHub) lookup(userID string, role string, conn *websocket.Conn) (*client, bool) {
+	hub.clientsMu.RLock()
+	defer hub.clientsMu.RUnlock()
+	c, ok := hub.clients[role][userID][conn]
+	return c, ok
+}
+
+func (hub *WebSocketHub) updateTopics(s *Session, patterns []string, subscribe bool) error {
+	if s.role != RoleAdmin {
+		return errors.New("topic subscriptions are only available to admins")
+	}
+	for _, pattern := range patterns {
+		if err := ValidateTopicPattern(pattern); err != nil {
+			return err
+		}
+	}
+
 	hub.clientsMu.Lock()
 	defer hub.clientsMu.Unlock()
+	c, ok := hub.clients[s.role][s.userID][s.conn]
+	if !ok {
+		return nil
+	}
 
-	if role == "admin" {
-		if hub.adminClients[userID] == nil {
-			hub.adminClients[userID] = make(map[*websocket.Conn]struct{})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, pattern := range patterns {
+		_, had := c.topics[pattern]
+		if subscribe && !had {
+			c.topics[pattern] = struct{}{}
+			hub.retainTopic(pattern)
+		} else if !subscribe && had {
+			delete(c.topics, pattern)
+			hub.releaseTopic(pattern)
 		}
-		hub.adminClients[userID][conn] = struct{}{}
-	} else {
-		if hub.userClients[userID] == nil {
-			hub.userClients[userID] = make(map[*websocket.Conn]struct{})
-		}
-		hub.userClients[userID][conn] = struct{}{}
 	}
+	return nil
 }
 
-func (hub *WebSocketHub) UnregisterClient(userID string, role string, conn *websocket.Conn) {
+// retainTopic and releaseTopic must be called with clientsMu held.
+func (hub *WebSocketHub) retainTopic(pattern string) {
+	hub.topicRefs[pattern]++
+	if hub.topicRefs[pattern] == 1 && hub.presence != nil {
+		hub.presence.TopicSubscribed(pattern)
+	}
+}
+
+func (hub *WebSocketHub) releaseTopic(pattern string) {
+	hub.topicRefs[pattern]--
+	if hub.topicRefs[pattern] > 0 {
+		return
+	}
+	delete(hub.topicRefs, pattern)
+	if hub.presence != nil {
+		hub.presence.TopicUnsubscribed(pattern)
+	}
+}
+
+// SetPresenceListener registers l and immediately reports the recipients and
+// topic patterns already connected.
+func (hub *WebSocketHub) SetPresenceListener(l PresenceListener) {
 	hub.clientsMu.Lock()
 	defer hub.clientsMu.Unlock()
+	hub.presence = l
+	if l == nil {
+		return
+	}
+	for role, byID := range hub.clients {
+		for userID := range byID {
+			l.RecipientOnline(role, userID)
+		}
+	}
+	for pattern := range hub.topicRefs {
+		l.TopicSubscribed(pattern)
+	}
+}
 
-	if role == "admin" {
-		if conns, ok := hub.adminClients[userID]; ok {
-			conn.Close()
-			delete(conns, conn)
-			if len(conns) == 0 {
-				delete(hub.adminClients, userID)
+// close stops the write pump and discards anything still queued.
+func (c *client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.done)
+	c.conn.Close()
+	utils.AddGauge("websocket_send_queue_depth", -int64(len(c.send)))
+}
+
+// RegisterClient starts delivering messages addressed to role and userID to
+// conn. Admin connections also mirror user and driver messages with a topic
+// matching one of topics; pass TopicAll to receive everything.
+func (hub *WebSocketHub) RegisterClient(userID string, role string, conn *websocket.Conn, topics ...string) {
+	c := &client{
+		userID: userID,
+		role:   role,
+		conn:   conn,
+		send:   make(chan []byte, hub.config.SendQueueSize),
+		done:   make(chan struct{}),
+		topics: make(map[string]struct{}, len(topics)),
+	}
+	for _, topic := range topics {
+		c.topics[topic] = struct{}{}
+	}
+
+	hub.clientsMu.Lock()
+	if hub.clients[role] == nil {
+		hub.clients[role] = make(map[string]map[*websocket.Conn]*client)
+	}
+	if hub.clients[role][userID] == nil {
+		hub.clients[role][userID] = make(map[*websocket.Conn]*client)
+	}
+	first := len(hub.clients[role][userID]) == 0
+	hub.clients[role][userID][conn] = c
+	if role == RoleAdmin {
+		for topic := range c.topics {
+			hub.retainTopic(topic)
+		}
+	}
+	if first && hub.presence != nil {
+		hub.presence.RecipientOnline(role, userID)
+	}
+	hub.clientsMu.Unlock()
+
+	utils.AddGauge("websocket_connections", 1)
+	utils.AddGauge("websocket_connections."+role, 1)
+	go hub.writePump(c)
+}
+
+func (hub *WebSocketHub) UnregisterClient(userID string, role string, conn *websocket.Conn) {
+	hub.clientsMu.Lock()
+	c, ok := hub.clients[role][userID][conn]
+	if ok {
+		delete(hub.clients[role][userID], conn)
+		if len(hub.clients[role][userID]) == 0 {
+			delete(hub.clients[role], userID)
+			if hub.presence != nil {
+				hub.presence.RecipientOffline(role, userID)
 			}
 		}
-	} else {
-		if conns, ok := hub.userClients[userID]; ok {
-			conn.Close()
-			delete(conns, conn)
-			if len(conns) == 0 {
-				delete(hub.userClients, userID)
+		if role == RoleAdmin {
+			c.mu.Lock()
+			for topic := range c.topics {
+				hub.releaseTopic(topic)
 			}
+			c.mu.Unlock()
 		}
 	}
+	hub.clientsMu.Unlock()
+
+	if ok {
+		c.close()
+		utils.AddGauge("websocket_connections", -1)
+		utils.AddGauge("websocket_connections."+role, -1)
+	}
 }
 
+// Replay queues the recipient's stored messages with Seq > afterSeq on conn,
+// or all of them if stream, where afterSeq came from, is no longer current.
+// Live messages may interleave with replayed ones, so clients should ignore
+// any seq they have already seen. A client that does not make room for the next
+// message within WriteTimeout is disconnected and ErrReplayStalled returned.
+func (hub *WebSocketHub) Replay(ctx context.Context, userID string, role string, conn *websocket.Conn, stream string, afterSeq uint64) error {
+	if hub.config.Outbox == nil {
+		return nil
+	}
+	messages, err := hub.config.Outbox.Since(ctx, role, userID, stream, afterSeq)
+	if err != nil {
+		return err
+	}
+
+	c, ok := hub.lookup(userID, role, conn)
+	if !ok {
+		return nil
+	}
+
+	for _, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		// Wait for queue space rather than applying the slow consumer policy,
+		// since the backlog can be larger than the send queue. A client that
+		// stops reading is disconnected and can resume from its last seq.
+		timer := time.NewTimer(hub.config.WriteTimeout)
+		select {
+		case c.send <- data:
+			timer.Stop()
+			utils.AddGauge("websocket_send_queue_depth", 1)
+			utils.IncrementCounter("websocket_replayed_messages", 1)
+		case <-c.done:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+			utils.IncrementCounter("websocket_slow_consumer_disconnects", 1)
+			hub.UnregisterClient(userID, role, conn)
+			return ErrReplayStalled
+		}
+	}
+	return nil
+}
+
+// Ack discards the recipient's stored messages up to and including seq of
+// stream.
+func (hub *WebSocketHub) Ack(ctx context.Context, userID string, role string, stream string, seq uint64) error {
+	if hub.config.Outbox == nil {
+		return nil
+	}
+	return hub.config.Outbox.Ack(ctx, role, userID, stream, seq)
+}
+
+// Broadcast delivers msg to the addressed connections and to the admins
+// subscribed to one of its topics.
 func (hub *WebSocketHub) Broadcast(msg WebSocketMessage) error {
+	return hub.queue(delivery{msg: msg, scope: deliverAll})
+}
+
+// BroadcastDirect delivers msg to the addressed connections only. It is used
+// for messages relayed from another instance, whose topic copies arrive
+// separately through BroadcastMirror.
+func (hub *WebSocketHub) BroadcastDirect(msg WebSocketMessage) error {
+	return hub.queue(delivery{msg: msg, scope: deliverDirect})
+}
+
+// BroadcastMirror delivers msg to the admins subscribed to pattern, where topic
+// is the one of msg.Topics the copy was relayed for. Each admin receives only
+// the copy for its first matching topic and pattern, so relaying msg once per
+// topic and pattern does not produce duplicates.
+func (hub *WebSocketHub) BroadcastMirror(msg WebSocketMessage, topic string, pattern string) error {
+	return hub.queue(delivery{msg: msg, scope: deliverMirror, topic: topic, pattern: pattern})
+}
+
+func (hub *WebSocketHub) queue(d delivery) error {
 	select {
-	case hub.broadcast <- msg:
+	case hub.broadcast <- d:
 		return nil
 	default:
 		return ErrBroadcastQueueFull
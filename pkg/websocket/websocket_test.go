@@ -0,0 +1,410 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connPair returns the server and client ends of a real websocket connection.
+func connPair(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+
+	serverConns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		serverConns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	clientConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { clientConn.Close() })
+
+	return <-serverConns, clientConn
+}
+
+func newTestClient(conn *websocket.Conn, queueSize int) *client {
+	return &client{
+		userID: "user-1",
+		role:   "user",
+		conn:   conn,
+		send:   make(chan []byte, queueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+func TestWebSocketHubDeliversToAddressedUser(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+
+	userServer, userClient := connPair(t)
+	otherServer, otherClient := connPair(t)
+	hub.RegisterClient("user-1", RoleUser, userServer)
+	hub.RegisterClient("user-2", RoleUser, otherServer)
+
+	if err := hub.Broadcast(WebSocketMessage{UserID: "user-1", Role: RoleUser, Type: "booking_accepted"}); err != nil {
+		t.Fatalf("Broadcast returned error: %v", err)
+	}
+
+	_ = userClient.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg WebSocketMessage
+	if err := userClient.ReadJSON(&msg); err != nil {
+		t.Fatalf("user did not receive message: %v", err)
+	}
+	if msg.Type != "booking_accepted" || msg.UserID != "user-1" || msg.Role != RoleUser {
+		t.Fatalf("user received unexpected message: %+v", msg)
+	}
+
+	_ = otherClient.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := otherClient.ReadMessage(); err == nil {
+		t.Fatal("message for user-1 was delivered to user-2")
+	}
+}
+
+func TestWebSocketHubRoutesByRoleAndAdminTopics(t *testing.T) {
+	hub := NewWebSocketHub()
+
+	userConn, _ := connPair(t)
+	driverConn, _ := connPair(t)
+	watcherConn, _ := connPair(t)
+	quietAdminConn, _ := connPair(t)
+	hub.RegisterClient("id-1", RoleUser, userConn)
+	hub.RegisterClient("id-1", RoleDriver, driverConn)
+	hub.RegisterClient("admin-1", RoleAdmin, watcherConn, "bookings.*")
+	hub.RegisterClient("admin-2", RoleAdmin, quietAdminConn)
+
+	recipientConns := func(msg WebSocketMessage) map[*websocket.Conn]bool {
+		out := make(map[*websocket.Conn]bool)
+		direct, mirrors := hub.recipients(msg)
+		for _, c := range append(direct, mirrors...) {
+			out[c.conn] = true
+		}
+		return out
+	}
+
+	got := recipientConns(WebSocketMessage{UserID: "id-1", Role: RoleDriver, Type: "new_booking_request", Topics: []string{"bookings.b-1"}})
+	if len(got) != 2 || !got[driverConn] || !got[watcherConn] {
+		t.Fatalf("driver message should reach the driver and the subscribed admin, got %d recipients", len(got))
+	}
+
+	got = recipientConns(WebSocketMessage{UserID: "id-1", Role: RoleUser, Type: "driver_location", Topics: []string{"driver.d-1.location"}})
+	if len(got) != 1 || !got[userConn] {
+		t.Fatalf("user message should only reach the user, got %d recipients", len(got))
+	}
+
+	got = recipientConns(WebSocketMessage{Role: RoleAdmin, Type: "driver_status_update"})
+	if len(got) != 2 || !got[watcherConn] || !got[quietAdminConn] {
+		t.Fatalf("admin broadcast should reach every admin, got %d recipients", len(got))
+	}
+
+	got = recipientConns(WebSocketMessage{UserID: "admin-2", Role: RoleAdmin, Type: "status_update"})
+	if len(got) != 1 || !got[quietAdminConn] {
+		t.Fatalf("message for admin-2 should only reach admin-2, got %d recipients", len(got))
+	}
+}
+
+func TestWebSocketHubDropOldestKeepsNewestMessages(t *testing.T) {
+	hub := NewWebSocketHubWithConfig(HubConfig{SendQueueSize: 2, SlowConsumerPolicy: SlowConsumerDropOldest})
+	serverConn, _ := connPair(t)
+	c := newTestClient(serverConn, 2)
+
+	// No write pump is running, so the queue only fills up.
+	for _, data := range []string{"1", "2", "3"} {
+		hub.enqueue(c, []byte(data))
+	}
+
+	if got := string(<-c.send) + string(<-c.send); got != "23" {
+		t.Fatalf("expected queue to hold the two newest messages, got %q", got)
+	}
+	if c.closed {
+		t.Fatal("drop_oldest policy must not close the client")
+	}
+}
+
+func TestWebSocketHubDisconnectsSlowConsumer(t *testing.T) {
+	hub := NewWebSocketHubWithConfig(HubConfig{SendQueueSize: 1, SlowConsumerPolicy: SlowConsumerDisconnect})
+	serverConn, clientConn := connPair(t)
+	c := newTestClient(serverConn, 1)
+	hub.clients[RoleUser] = map[string]map[*websocket.Conn]*client{"user-1": {serverConn: c}}
+
+	hub.enqueue(c, []byte("1"))
+	hub.enqueue(c, []byte("2"))
+
+	if !c.closed {
+		t.Fatal("expected slow consumer to be closed")
+	}
+	if hub.hasUserClient("user-1") {
+		t.Fatal("expected slow consumer to be unregistered")
+	}
+	_ = clientConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := clientConn.ReadMessage(); err == nil {
+		t.Fatal("expected the client side of the connection to be closed")
+	}
+}
+
+func (hub *WebSocketHub) hasUserClient(userID string) bool {
+	hub.clientsMu.RLock()
+	defer hub.clientsMu.RUnlock()
+	_, ok := hub.clients[RoleUser][userID]
+	return ok
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestWebSocketHubReapsConnectionsThatMissPongs(t *testing.T) {
+	hub := NewWebSocketHubWithConfig(HubConfig{PingInterval: 50 * time.Millisecond, PongWait: 200 * time.Millisecond})
+
+	deadServer, _ := connPair(t)
+	liveServer, liveClient := connPair(t)
+	hub.RegisterClient("dead", "user", deadServer)
+	hub.RegisterClient("live", "user", liveServer)
+	go hub.ReadPump("dead", "user", deadServer, nil)
+	go hub.ReadPump("live", "user", liveServer, nil)
+
+	// Reading lets gorilla answer pings with pongs; the dead client never reads.
+	go func() {
+		for {
+			if _, _, err := liveClient.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if !waitFor(t, 2*time.Second, func() bool { return !hub.hasUserClient("dead") }) {
+		t.Fatal("expected connection without pongs to be reaped")
+	}
+	if !hub.hasUserClient("live") {
+		t.Fatal("expected connection answering pings to stay registered")
+	}
+}
+
+func TestWebSocketHubClosesConnectionsExceedingReadLimit(t *testing.T) {
+	hub := NewWebSocketHubWithConfig(HubConfig{MaxMessageSize: 64})
+
+	serverConn, clientConn := connPair(t)
+	hub.RegisterClient("user-1", "user", serverConn)
+	go hub.ReadPump("user-1", "user", serverConn, nil)
+
+	if err := clientConn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 128))); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	if !waitFor(t, 2*time.Second, func() bool { return !hub.hasUserClient("user-1") }) {
+		t.Fatal("expected oversized frame to close the connection")
+	}
+}
+
+func TestWebSocketHubReadPumpRepliesToSender(t *testing.T) {
+	hub := NewWebSocketHub()
+
+	serverConn, clientConn := connPair(t)
+	otherServer, otherClient := connPair(t)
+	hub.RegisterClient("driver-1", RoleDriver, serverConn)
+	hub.RegisterClient("driver-1", RoleDriver, otherServer)
+
+	var gotUserID, gotRole, gotData string
+	go hub.ReadPump("driver-1", RoleDriver, serverConn, func(session *Session, data []byte) interface{} {
+		gotUserID, gotRole, gotData = session.UserID(), session.Role(), string(data)
+		return WebSocketMessage{UserID: session.UserID(), Role: session.Role(), Type: "command_result"}
+	})
+
+	if err := clientConn.WriteMessage(websocket.TextMessage, []byte(`{"id":"1","type":"ack"}`)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	_ = clientConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var reply WebSocketMessage
+	if err := clientConn.ReadJSON(&reply); err != nil {
+		t.Fatalf("did not receive reply: %v", err)
+	}
+	if reply.Type != "command_result" {
+		t.Fatalf("unexpected reply: %+v", reply)
+	}
+	if gotUserID != "driver-1" || gotRole != RoleDriver || gotData != `{"id":"1","type":"ack"}` {
+		t.Fatalf("handler got unexpected frame: %s %s %s", gotUserID, gotRole, gotData)
+	}
+
+	_ = otherClient.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := otherClient.ReadMessage(); err == nil {
+		t.Fatal("reply was delivered to another connection of the same driver")
+	}
+}
+
+func TestWebSocketHubClosesConnectionsWhenTokenExpires(t *testing.T) {
+	hub := NewWebSocketHub()
+
+	serverConn, _ := connPair(t)
+	hub.RegisterClient("user-1", "user", serverConn)
+	hub.Authorize("user-1", "user", serverConn, time.Now().Add(100*time.Millisecond), nil)
+
+	if !hub.hasUserClient("user-1") {
+		t.Fatal("expected connection to stay open before its token expires")
+	}
+	if !waitFor(t, 2*time.Second, func() bool { return !hub.hasUserClient("user-1") }) {
+		t.Fatal("expected connection to close when its token expires")
+	}
+}
+
+func TestWebSocketHubClosesRevokedSessions(t *testing.T) {
+	hub := NewWebSocketHubWithConfig(HubConfig{SessionCheckInterval: time.Hour})
+
+	serverConn, clientConn := connPair(t)
+	hub.RegisterClient("user-1", "user", serverConn)
+	var revoked atomic.Bool
+	hub.Authorize("user-1", "user", serverConn, time.Now().Add(time.Hour), func(context.Context) (bool, error) {
+		return !revoked.Load(), nil
+	})
+	var handled atomic.Int32
+	go hub.ReadPump("user-1", "user", serverConn, func(*Session, []byte) interface{} {
+		handled.Add(1)
+		return nil
+	})
+
+	revoked.Store(true)
+	if err := clientConn.WriteMessage(websocket.TextMessage, []byte(`{"id":"1","type":"ack"}`)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	if !waitFor(t, 2*time.Second, func() bool { return !hub.hasUserClient("user-1") }) {
+		t.Fatal("expected a command on a revoked session to close the connection")
+	}
+	if handled.Load() != 0 {
+		t.Fatal("command on a revoked session was handled")
+	}
+}
+
+func TestWebSocketHubChecksIdleSessionsPeriodically(t *testing.T) {
+	hub := NewWebSocketHubWithConfig(HubConfig{SessionCheckInterval: 50 * time.Millisecond})
+
+	serverConn, _ := connPair(t)
+	hub.RegisterClient("user-1", "user", serverConn)
+	var revoked atomic.Bool
+	hub.Authorize("user-1", "user", serverConn, time.Time{}, func(context.Context) (bool, error) {
+		if revoked.Load() {
+			return false, nil
+		}
+		return false, errors.New("revocation store unavailable")
+	})
+
+	time.Sleep(150 * time.Millisecond)
+	if !hub.hasUserClient("user-1") {
+		t.Fatal("expected a failed session check to keep the connection open")
+	}
+	revoked.Store(true)
+	if !waitFor(t, 2*time.Second, func() bool { return !hub.hasUserClient("user-1") }) {
+		t.Fatal("expected a revoked session to be closed by the periodic check")
+	}
+}
+
+func TestSessionSubscribeChangesAdminMirrors(t *testing.T) {
+	hub := NewWebSocketHub()
+
+	adminConn, _ := connPair(t)
+	driverConn, _ := connPair(t)
+	hub.RegisterClient("admin-1", RoleAdmin, adminConn)
+	hub.RegisterClient("driver-1", RoleDriver, driverConn)
+	admin := &Session{hub: hub, userID: "admin-1", role: RoleAdmin, conn: adminConn}
+	driver := &Session{hub: hub, userID: "driver-1", role: RoleDriver, conn: driverConn}
+
+	location := WebSocketMessage{UserID: "user-1", Role: RoleUser, Type: "driver_location", Topics: []string{"driver.driver-1.location"}}
+	mirrored := func() bool {
+		_, mirrors := hub.recipients(location)
+		return len(mirrors) == 1
+	}
+
+	if mirrored() {
+		t.Fatal("admin without subscriptions should not mirror user traffic")
+	}
+	if err := admin.Subscribe("driver.*.location"); err != nil {
+		t.Fatalf("Subscribe returned error: %v", err)
+	}
+	if !mirrored() {
+		t.Fatal("expected subscribed admin to mirror driver location")
+	}
+	if err := admin.Unsubscribe("driver.*.location"); err != nil {
+		t.Fatalf("Unsubscribe returned error: %v", err)
+	}
+	if mirrored() || len(admin.Topics()) != 0 {
+		t.Fatal("expected unsubscribe to stop mirroring")
+	}
+
+	if err := admin.Subscribe("bookings.>.x"); err == nil {
+		t.Fatal("expected invalid pattern to be rejected")
+	}
+	if err := driver.Subscribe("bookings.*"); err == nil {
+		t.Fatal("expected drivers to be refused topic subscriptions")
+	}
+}
+
+type recordingPresence struct {
+	events []string
+}
+
+func (p *recordingPresence) RecipientOnline(role, userID string) {
+	p.events = append(p.events, "+"+role+"."+userID)
+}
+func (p *recordingPresence) RecipientOffline(role, userID string) {
+	p.events = append(p.events, "-"+role+"."+userID)
+}
+func (p *recordingPresence) TopicSubscribed(pattern string) {
+	p.events = append(p.events, "+topic "+pattern)
+}
+func (p *recordingPresence) TopicUnsubscribed(pattern string) {
+	p.events = append(p.events, "-topic "+pattern)
+}
+
+func TestWebSocketHubReportsPresenceChanges(t *testing.T) {
+	hub := NewWebSocketHub()
+	presence := &recordingPresence{}
+
+	firstConn, _ := connPair(t)
+	secondConn, _ := connPair(t)
+	otherAdminConn, _ := connPair(t)
+	hub.RegisterClient("admin-1", RoleAdmin, firstConn, "bookings.*")
+	hub.SetPresenceListener(presence)
+
+	hub.RegisterClient("admin-1", RoleAdmin, secondConn, "bookings.*")
+	hub.RegisterClient("admin-2", RoleAdmin, otherAdminConn)
+	other := &Session{hub: hub, userID: "admin-2", role: RoleAdmin, conn: otherAdminConn}
+	if err := other.Subscribe("drivers.status"); err != nil {
+		t.Fatalf("Subscribe returned error: %v", err)
+	}
+	hub.UnregisterClient("admin-1", RoleAdmin, firstConn)
+	hub.UnregisterClient("admin-1", RoleAdmin, secondConn)
+	hub.UnregisterClient("admin-2", RoleAdmin, otherAdminConn)
+
+	want := []string{
+		"+admin.admin-1", "+topic bookings.*", // reported on registration of the listener
+		"+admin.admin-2", "+topic drivers.status",
+		"-admin.admin-1", "-topic bookings.*",
+		"-admin.admin-2", "-topic drivers.status",
+	}
+	if strings.Join(presence.events, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("unexpected presence events:\n got %v\nwant %v", presence.events, want)
+	}
+}